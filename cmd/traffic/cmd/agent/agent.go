@@ -10,8 +10,6 @@ import (
 	"strings"
 	"time"
 
-	"github.com/pkg/sftp"
-
 	"github.com/datawire/dlib/dgroup"
 	"github.com/datawire/dlib/dlog"
 	rpc "github.com/telepresenceio/telepresence/rpc/v2/manager"
@@ -19,6 +17,7 @@ import (
 	"github.com/telepresenceio/telepresence/v2/pkg/dos"
 	"github.com/telepresenceio/telepresence/v2/pkg/forwarder"
 	"github.com/telepresenceio/telepresence/v2/pkg/iputil"
+	"github.com/telepresenceio/telepresence/v2/pkg/nfs"
 	"github.com/telepresenceio/telepresence/v2/pkg/restapi"
 	"github.com/telepresenceio/telepresence/v2/pkg/tunnel"
 	"github.com/telepresenceio/telepresence/v2/pkg/version"
@@ -26,11 +25,17 @@ import (
 
 // AppEnvironment returns the environment visible to this agent together with environment variables
 // explicitly declared for the app container and minus the environment variables provided by this
-// config.
-func AppEnvironment(ctx context.Context, ag *agentconfig.Container) (map[string]string, error) {
+// config. The given policy is applied to the result, and the names of the variables that it redacted
+// or withheld are returned.
+func AppEnvironment(ctx context.Context, ag *agentconfig.Container, ep *agentconfig.EnvPolicy) (map[string]string, []string, error) {
 	osEnv := dos.Environ(ctx)
 	prefix := agentconfig.EnvPrefixApp + ag.EnvPrefix
+	secretPrefix := agentconfig.EnvPrefixAppSecret + ag.EnvPrefix
 	fullEnv := make(map[string]string, len(osEnv))
+	secretKeys := make(map[string]struct{}, len(ag.SecretEnv))
+	for _, k := range ag.SecretEnv {
+		secretKeys[k] = struct{}{}
+	}
 
 	// Add prefixed variables separately last, so that we can
 	// ensure that they have higher precedence.
@@ -46,25 +51,32 @@ func AppEnvironment(ctx context.Context, ag *agentconfig.Container) (map[string]
 		}
 	}
 	for _, env := range osEnv {
-		if strings.HasPrefix(env, prefix) {
-			pair := strings.SplitN(env, "=", 2)
-			if len(pair) == 2 {
-				k := pair[0][len(prefix):]
-				fullEnv[k] = pair[1]
-			}
+		pair := strings.SplitN(env, "=", 2)
+		if len(pair) != 2 {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(pair[0], prefix):
+			fullEnv[pair[0][len(prefix):]] = pair[1]
+		case strings.HasPrefix(pair[0], secretPrefix):
+			// Variables that the app container gets from a Secret using envFrom
+			k := pair[0][len(secretPrefix):]
+			fullEnv[k] = pair[1]
+			secretKeys[k] = struct{}{}
 		}
 	}
+	redacted := ep.Apply(fullEnv, secretKeys)
 	fullEnv[agentconfig.EnvInterceptContainer] = ag.Name
 	if len(ag.Mounts) > 0 {
 		fullEnv[agentconfig.EnvInterceptMounts] = strings.Join(ag.Mounts, ":")
 	}
-	return fullEnv, nil
+	return fullEnv, redacted, nil
 }
 
 // SftpServer creates a listener on the next available port, writes that port on the
 // given channel, and then starts accepting connections on that port. Each connection
 // starts a sftp-server that communicates with that connection using its stdin and stdout.
-func SftpServer(ctx context.Context, sftpPortCh chan<- uint16) error {
+func SftpServer(ctx context.Context, sftpPortCh chan<- uint16, guard *MountGuard) error {
 	defer close(sftpPortCh)
 
 	// start an sftp-server for remote sshfs mounts
@@ -96,12 +108,9 @@ func SftpServer(ctx context.Context, sftpPortCh chan<- uint16) error {
 			return nil
 		}
 		go func() {
-			s, err := sftp.NewServer(conn)
-			if err != nil {
-				dlog.Error(ctx, err)
-			}
+			s := newSftpServer(conn, guard)
 			dlog.Debugf(ctx, "Serving sftp connection from %s", conn.RemoteAddr())
-			if err = s.Serve(); err != nil {
+			if err := s.Serve(); err != nil {
 				if !errors.Is(err, io.EOF) {
 					dlog.Errorf(ctx, "sftp server completed with error %v", err)
 				}
@@ -110,6 +119,30 @@ func SftpServer(ctx context.Context, sftpPortCh chan<- uint16) error {
 	}
 }
 
+// NfsServer creates a listener on the next available port, writes that port on the given
+// channel, and then serves the app container mounts found below agentconfig.ExportsMountPoint
+// over NFSv3 on that port. Those are the mounts listed in TELEPRESENCE_MOUNTS, so a client
+// that mounts an intercept's mount point sees the same volumes as an sshfs client would.
+func NfsServer(ctx context.Context, nfsPortCh chan<- uint16, guard *MountGuard) error {
+	defer close(nfsPortCh)
+
+	lc := net.ListenConfig{}
+	l, err := lc.Listen(ctx, "tcp4", ":0")
+	if err != nil {
+		return err
+	}
+	_, nfsPort, err := iputil.SplitToIPPort(l.Addr())
+	if err != nil {
+		_ = l.Close()
+		return err
+	}
+	nfsPortCh <- nfsPort
+	s := nfs.NewServer(agentconfig.ExportsMountPoint, false)
+	s.Check = guard.Check
+	s.Files = guard
+	return s.Serve(ctx, l)
+}
+
 func Main(ctx context.Context, args ...string) error {
 	dlog.Infof(ctx, "Traffic Agent %s", version.Version)
 
@@ -141,14 +174,23 @@ func Main(ctx context.Context, args ...string) error {
 		EnableSignalHandling: true,
 	})
 
+	state := NewSimpleState(config)
 	sftpPortCh := make(chan uint16)
+	nfsPortCh := make(chan uint16)
 	if config.HasMounts(ctx) {
+		if mp := config.AgentConfig().MountPolicy; !mp.IsEmpty() {
+			dlog.Infof(ctx, "Mount policy of the workload: %s", mp)
+		}
 		g.Go("sftp-server", func(ctx context.Context) error {
-			return SftpServer(ctx, sftpPortCh)
+			return SftpServer(ctx, sftpPortCh, state.MountGuard())
+		})
+		g.Go("nfs-server", func(ctx context.Context) error {
+			return NfsServer(ctx, nfsPortCh, state.MountGuard())
 		})
 	} else {
 		close(sftpPortCh)
-		dlog.Info(ctx, "Not starting sftp-server because there's nothing to mount")
+		close(nfsPortCh)
+		dlog.Info(ctx, "Not starting sftp-server and nfs-server because there's nothing to mount")
 	}
 
 	// Talk to the Traffic Manager
@@ -160,17 +202,31 @@ func Main(ctx context.Context, args ...string) error {
 		ticker := time.NewTicker(5 * time.Second)
 		defer ticker.Stop()
 
-		state := NewSimpleState(config)
 		if err := state.WaitForSftpPort(ctx, sftpPortCh); err != nil {
 			return err
 		}
+		if err := state.WaitForNfsPort(ctx, nfsPortCh); err != nil {
+			return err
+		}
+		info.SftpPort = int32(state.SftpPort())
 
 		// Manage the forwarders
+		var cnEnvs []*rpc.ContainerEnvironment
 		for _, cn := range ac.Containers {
-			env, err := AppEnvironment(ctx, cn)
+			env, redacted, err := AppEnvironment(ctx, cn, ac.EnvPolicy)
 			if err != nil {
 				return err
 			}
+			cnMountPoint := filepath.Join(agentconfig.ExportsMountPoint, filepath.Base(cn.MountPoint))
+			info.Containers = append(info.Containers, &rpc.AgentInfo_ContainerInfo{
+				Name:       cn.Name,
+				MountPoint: cnMountPoint,
+			})
+			cnEnvs = append(cnEnvs, &rpc.ContainerEnvironment{
+				Name:                cn.Name,
+				Environment:         env,
+				RedactedEnvironment: redacted,
+			})
 
 			// Group the containers intercepts by agent port
 			icStates := make(map[agentconfig.PortAndProto][]*agentconfig.Intercept, len(cn.Intercepts))
@@ -186,11 +242,11 @@ func Main(ctx context.Context, args ...string) error {
 					return err
 				}
 				fwd := forwarder.NewInterceptor(lisAddr, "127.0.0.1", cp)
+				fwd.SetTLSConfig(config.TLSConfig())
 				g.Go(fmt.Sprintf("forward-%s:%d", cn.Name, cp), func(ctx context.Context) error {
 					return fwd.Serve(tunnel.WithPool(ctx, tunnel.NewPool()), nil)
 				})
-				cnMountPoint := filepath.Join(agentconfig.ExportsMountPoint, filepath.Base(cn.MountPoint))
-				state.AddInterceptState(NewInterceptState(state, fwd, ics, cnMountPoint, env))
+				state.AddInterceptState(NewInterceptState(state, fwd, ics, cnMountPoint, env, redacted))
 			}
 		}
 
@@ -201,7 +257,7 @@ func Main(ctx context.Context, args ...string) error {
 		}
 
 		for {
-			if err := TalkToManager(ctx, gRPCAddress, info, state); err != nil {
+			if err := TalkToManager(ctx, gRPCAddress, info, cnEnvs, state); err != nil {
 				dlog.Info(ctx, err)
 			}
 
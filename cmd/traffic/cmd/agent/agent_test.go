@@ -106,8 +106,9 @@ func Test_AppEnvironment(t *testing.T) {
 	require.NoError(t, err)
 
 	cn := config.AgentConfig().Containers[0]
-	env, err := agent.AppEnvironment(ctx, cn)
+	env, redacted, err := agent.AppEnvironment(ctx, cn, nil)
 	require.NoError(t, err)
+	require.Empty(t, redacted)
 	require.Equal(t, map[string]string{
 		"ALPHA":                           "alpha",
 		"ZULU":                            "zulu",
@@ -124,3 +125,31 @@ func Test_AppEnvironment(t *testing.T) {
 	require.NoError(t, f.Close())
 	require.Equal(t, "default\n", string(data))
 }
+
+func Test_AppEnvironmentPolicy(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("skipped on windows")
+	}
+	ctx := testContext(t, dos.MapEnv{
+		"ZULU": "zulu",
+		agentconfig.EnvPrefixApp + "A_" + "ALPHA":         "alpha",
+		agentconfig.EnvPrefixApp + "A_" + "API_TOKEN":     "token",
+		agentconfig.EnvPrefixApp + "A_" + "DB_PASSWORD":   "password", // from a secretKeyRef
+		agentconfig.EnvPrefixAppSecret + "A_" + "TLS_KEY": "key",      // from a secretRef in envFrom
+	})
+	config, err := agent.LoadConfig(ctx)
+	require.NoError(t, err)
+
+	cn := *config.AgentConfig().Containers[0]
+	cn.SecretEnv = []string{"DB_PASSWORD"}
+	ep := &agentconfig.EnvPolicy{Redact: []string{"*_TOKEN"}, Withhold: []string{"ZULU"}, Secrets: agentconfig.EnvWithhold}
+	env, redacted, err := agent.AppEnvironment(ctx, &cn, ep)
+	require.NoError(t, err)
+	require.Equal(t, []string{"API_TOKEN", "DB_PASSWORD", "TLS_KEY", "ZULU"}, redacted)
+	require.Equal(t, map[string]string{
+		"ALPHA":                           "alpha",
+		"API_TOKEN":                       agentconfig.RedactedValue,
+		agentconfig.EnvInterceptContainer: "test-echo",
+		agentconfig.EnvInterceptMounts:    "/home/bob",
+	}, env)
+}
@@ -11,14 +11,19 @@ import (
 	"time"
 
 	"github.com/blang/semver"
+	"github.com/miekg/dns"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
 	empty "google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
 
 	"github.com/datawire/dlib/dcontext"
 	"github.com/datawire/dlib/dgroup"
 	"github.com/datawire/dlib/dlog"
 	rpc "github.com/telepresenceio/telepresence/rpc/v2/manager"
+	"github.com/telepresenceio/telepresence/v2/pkg/dnsproxy"
 	"github.com/telepresenceio/telepresence/v2/pkg/dos"
 	"github.com/telepresenceio/telepresence/v2/pkg/install"
 	"github.com/telepresenceio/telepresence/v2/pkg/iputil"
@@ -61,7 +66,9 @@ func (is interceptsStringer) String() string {
 	return sb.String()
 }
 
-func TalkToManager(ctx context.Context, address string, info *rpc.AgentInfo, state State) error {
+// TalkToManager arrives as an agent in the traffic-manager at the given address, reports the environment
+// of the app containers, and then serves the manager's requests until the session ends.
+func TalkToManager(ctx context.Context, address string, info *rpc.AgentInfo, env []*rpc.ContainerEnvironment, state State) error {
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
@@ -92,6 +99,12 @@ func TalkToManager(ctx context.Context, address string, info *rpc.AgentInfo, sta
 
 	state.SetManager(session, manager, mgrVer)
 
+	// The environment is not a part of the AgentInfo, because that is broadcast to all clients.
+	_, err = manager.ReportEnvironment(ctx, &rpc.AgentEnvironment{Session: session, Containers: env})
+	if err != nil && status.Code(err) != codes.Unimplemented {
+		return err
+	}
+
 	// Create the /tmp/agent directory if it doesn't exist
 	// We use this to place a file which conveys 'readiness'
 	// The presence of this file is used in the readiness check.
@@ -132,6 +145,15 @@ func TalkToManager(ctx context.Context, address string, info *rpc.AgentInfo, sta
 		return lookupHostWaitLoop(ctx, manager, session, lrStream)
 	})
 
+	// Deal with DNS requests dispatched to this agent during intercepts
+	dnsStream, err := manager.WatchLookupDNS(ctx, session)
+	if err != nil {
+		return err
+	}
+	wg.Go("lookupDNSWait", func(ctx context.Context) error {
+		return lookupDNSWaitLoop(ctx, manager, session, dnsStream)
+	})
+
 	// Deal with dial requests from the manager
 	dialerStream, err := manager.WatchDial(ctx, session)
 	if err != nil {
@@ -150,6 +172,24 @@ func TalkToManager(ctx context.Context, address string, info *rpc.AgentInfo, sta
 		return logLevelWaitLoop(ctx, logLevelStream)
 	})
 
+	// Deal with requests for this agent's log output
+	logsStream, err := manager.WatchAgentLogsRequests(ctx, session)
+	if err != nil {
+		return err
+	}
+	wg.Go("logsWait", func(ctx context.Context) error {
+		return logsWaitLoop(ctx, manager, session, logsStream)
+	})
+
+	// Deal with requests to run commands
+	execStream, err := manager.WatchExecRequests(ctx, session)
+	if err != nil {
+		return err
+	}
+	wg.Go("execWait", func(ctx context.Context) error {
+		return execWaitLoop(ctx, manager, session, state, execStream)
+	})
+
 	snapshots := make(chan *rpc.InterceptInfoSnapshot)
 
 	// Call WatchIntercepts
@@ -271,6 +311,51 @@ func lookupAndRespond(ctx context.Context, manager rpc.ManagerClient, session *r
 	}
 }
 
+func lookupDNSWaitLoop(ctx context.Context, manager rpc.ManagerClient, session *rpc.SessionInfo, dnsStream rpc.Manager_WatchLookupDNSClient) error {
+	for ctx.Err() == nil {
+		rq, err := dnsStream.Recv()
+		if err != nil {
+			if status.Code(err) == codes.Unimplemented {
+				dlog.Debug(ctx, "traffic-manager doesn't dispatch DNS requests")
+				return nil
+			}
+			if ctx.Err() == nil && !errors.Is(err, io.EOF) {
+				return fmt.Errorf("dns request stream recv: %w", err)
+			}
+			return nil
+		}
+		go lookupDNSAndRespond(ctx, manager, session, rq)
+	}
+	return nil
+}
+
+func lookupDNSAndRespond(ctx context.Context, manager rpc.ManagerClient, session *rpc.SessionInfo, rq *rpc.DNSRequest) {
+	qts := dns.TypeToString[uint16(rq.Type)]
+	dlog.Debugf(ctx, "DNSRequest for %s %s", qts, rq.Name)
+	response := rpc.DNSAgentResponse{
+		Session:  session,
+		Request:  rq,
+		Response: &rpc.DNSResponse{},
+	}
+
+	sections, rCode, err := dnsproxy.Lookup(ctx, uint16(rq.Type), rq.Name)
+	if err == nil {
+		response.Response.Rrs, err = sections.ToPacked()
+	}
+	if err != nil {
+		dlog.Errorf(ctx, "LookupDNS: %v", err)
+		rCode = dns.RcodeServerFailure
+	} else {
+		dlog.Debugf(ctx, "DNS response for %s %s -> %s %v", qts, rq.Name, dns.RcodeToString[rCode], sections.Answer)
+	}
+	response.Response.RCode = int32(rCode)
+	if _, err = manager.AgentLookupDNSResponse(ctx, &response); err != nil {
+		if ctx.Err() == nil {
+			dlog.Debugf(ctx, "dns response: %+v %v", err, &response)
+		}
+	}
+}
+
 // GetLogLevel will return the log level that this agent should use
 func GetLogLevel(ctx context.Context) string {
 	level, ok := dos.LookupEnv(ctx, install.EnvPrefix+"LOG_LEVEL")
@@ -299,3 +384,109 @@ func logLevelWaitLoop(ctx context.Context, logLevelStream rpc.Manager_WatchLogLe
 	}
 	return nil
 }
+
+func logsWaitLoop(ctx context.Context, manager rpc.ManagerClient, session *rpc.SessionInfo, logsStream rpc.Manager_WatchAgentLogsRequestsClient) error {
+	for ctx.Err() == nil {
+		rq, err := logsStream.Recv()
+		if err != nil {
+			if status.Code(err) == codes.Unimplemented {
+				dlog.Debug(ctx, "traffic-manager doesn't request log output")
+				return nil
+			}
+			if ctx.Err() == nil && !errors.Is(err, io.EOF) {
+				return fmt.Errorf("logs request stream recv: %w", err)
+			}
+			return nil
+		}
+		go func() {
+			if err := sendLogs(ctx, manager, session, rq); err != nil && ctx.Err() == nil {
+				dlog.Errorf(ctx, "failed to send log output: %v", err)
+			}
+		}()
+	}
+	return nil
+}
+
+// maxLogsBatch is the maximum number of log entries sent in one AgentLogsResponse.
+const maxLogsBatch = 100
+
+// sendLogs sends the retained log output that was logged within the requested duration, and then,
+// if the request says so, all new output until the traffic-manager ends the call.
+func sendLogs(ctx context.Context, manager rpc.ManagerClient, session *rpc.SessionInfo, rq *rpc.AgentLogsRequest) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stream, err := manager.AgentLogs(ctx)
+	if err != nil {
+		return err
+	}
+
+	var since time.Time
+	if d := rq.Since.AsDuration(); d > 0 {
+		since = time.Now().Add(-d)
+	}
+	var es []log.Entry
+	var ch <-chan log.Entry
+	if buffer := log.GetBuffer(ctx); buffer != nil {
+		if rq.Follow {
+			es, ch = buffer.Follow(ctx, since)
+		} else {
+			es = buffer.Since(since)
+		}
+	}
+
+	podName, _ := os.Hostname()
+	send := func(es []log.Entry) error {
+		r := &rpc.AgentLogsResponse{Session: session, RequestId: rq.Id, Entries: make([]*rpc.LogEntry, len(es))}
+		for i, e := range es {
+			r.Entries[i] = &rpc.LogEntry{PodName: podName, Time: timestamppb.New(e.Time), Text: e.Text}
+		}
+		return stream.Send(r)
+	}
+
+	// Always send one message, so that the traffic-manager knows what request the call belongs to.
+	for first := true; first || len(es) > 0; first = false {
+		n := len(es)
+		if n > maxLogsBatch {
+			n = maxLogsBatch
+		}
+		if err = send(es[:n]); err != nil {
+			break
+		}
+		es = es[n:]
+	}
+	for err == nil && ch != nil {
+		select {
+		case <-ctx.Done():
+			ch = nil
+		case e, ok := <-ch:
+			if !ok {
+				ch = nil
+				break
+			}
+			// Include what's immediately available in the same batch
+			es = append(es[:0], e)
+		more:
+			for len(es) < maxLogsBatch {
+				select {
+				case e, ok = <-ch:
+					if !ok {
+						break more
+					}
+					es = append(es, e)
+				default:
+					break more
+				}
+			}
+			err = send(es)
+		}
+	}
+	if err == io.EOF {
+		// The traffic-manager ended the call, because the output is no longer wanted
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	_, err = stream.CloseAndRecv()
+	return err
+}
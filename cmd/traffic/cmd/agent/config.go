@@ -2,11 +2,13 @@ package agent
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"os"
 	"path/filepath"
 
 	"gopkg.in/yaml.v2"
+	core "k8s.io/api/core/v1"
 
 	"github.com/datawire/dlib/dlog"
 	"github.com/telepresenceio/telepresence/v2/pkg/agentconfig"
@@ -18,11 +20,13 @@ type Config interface {
 	AgentConfig() *agentconfig.Sidecar
 	HasMounts(ctx context.Context) bool
 	PodIP() string
+	TLSConfig() *tls.Config
 }
 
 type config struct {
 	agentconfig.Sidecar
-	podIP string
+	podIP     string
+	tlsConfig *tls.Config
 }
 
 // Keys that aren't useful when running on the local machine
@@ -52,6 +56,11 @@ func LoadConfig(ctx context.Context) (Config, error) {
 			return nil, err
 		}
 	}
+	if c.TLSSecret != "" {
+		if c.tlsConfig, err = loadTLSConfig(agentconfig.TLSMountPoint); err != nil {
+			return nil, err
+		}
+	}
 	if c.LogLevel != "" {
 		// Override default from environment
 		log.SetLevel(ctx, c.LogLevel)
@@ -76,6 +85,24 @@ func (c *config) PodIP() string {
 	return c.podIP
 }
 
+// TLSConfig returns the configuration used when terminating TLS for intercepted connections,
+// or nil if no TLS Secret was declared for the agent.
+func (c *config) TLSConfig() *tls.Config {
+	return c.tlsConfig
+}
+
+// loadTLSConfig loads the certificate and key from the Secret that is mounted at the
+// given directory, normally the agentconfig.TLSMountPoint.
+func loadTLSConfig(dir string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(
+		filepath.Join(dir, core.TLSCertKey),
+		filepath.Join(dir, core.TLSPrivateKeyKey))
+	if err != nil {
+		return nil, fmt.Errorf("unable to load TLS certificate and key: %w", err)
+	}
+	return &tls.Config{Certificates: []tls.Certificate{cert}}, nil
+}
+
 // addAppMounts adds each of the mounts present under the containers MountPoint as a
 // symlink under the agentconfig.ExportsMountPoint/<container mount>/
 func addAppMounts(ctx context.Context, ag *agentconfig.Container) error {
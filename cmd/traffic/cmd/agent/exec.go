@@ -0,0 +1,198 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/datawire/dlib/dexec"
+	"github.com/datawire/dlib/dlog"
+	rpc "github.com/telepresenceio/telepresence/rpc/v2/manager"
+	"github.com/telepresenceio/telepresence/v2/pkg/agentconfig"
+)
+
+func execWaitLoop(ctx context.Context, manager rpc.ManagerClient, session *rpc.SessionInfo, state State, execStream rpc.Manager_WatchExecRequestsClient) error {
+	for ctx.Err() == nil {
+		rq, err := execStream.Recv()
+		if err != nil {
+			if status.Code(err) == codes.Unimplemented {
+				dlog.Debug(ctx, "traffic-manager doesn't request commands")
+				return nil
+			}
+			if ctx.Err() == nil && !errors.Is(err, io.EOF) {
+				return fmt.Errorf("exec request stream recv: %w", err)
+			}
+			return nil
+		}
+		go func() {
+			if err := runExec(ctx, manager, session, state, rq); err != nil && ctx.Err() == nil {
+				dlog.Errorf(ctx, "failed to run command %q: %v", rq.Command, err)
+			}
+		}()
+	}
+	return nil
+}
+
+// execContainer returns the configuration of the app container that the given request targets.
+func execContainer(ac *agentconfig.Sidecar, rq *rpc.ExecRequest) (*agentconfig.Container, error) {
+	if len(ac.Containers) == 0 {
+		return nil, errors.New("the traffic-agent has no app containers")
+	}
+	if rq.Container == "" {
+		return ac.Containers[0], nil
+	}
+	for _, cn := range ac.Containers {
+		if cn.Name == rq.Container {
+			return cn, nil
+		}
+	}
+	return nil, fmt.Errorf("container %q not found", rq.Container)
+}
+
+// execCommand creates the command for the given request. The command runs in the app container's
+// mounts with the app container's environment, and TELEPRESENCE_ROOT set to the root of those mounts.
+// The environment is the one that the env policy permits. Nothing from the agent's own environment is
+// passed on, except PATH when the app container doesn't declare one.
+func execCommand(ctx context.Context, state State, rq *rpc.ExecRequest) (*dexec.Cmd, error) {
+	ac := state.AgentConfig()
+	cn, err := execContainer(ac, rq)
+	if err != nil {
+		return nil, err
+	}
+	appEnv, _, err := AppEnvironment(ctx, cn, ac.EnvPolicy)
+	if err != nil {
+		return nil, err
+	}
+	cnMountPoint := filepath.Join(agentconfig.ExportsMountPoint, filepath.Base(cn.MountPoint))
+	appEnv["TELEPRESENCE_ROOT"] = cnMountPoint
+	if _, ok := appEnv["PATH"]; !ok {
+		appEnv["PATH"] = os.Getenv("PATH")
+	}
+
+	env := make([]string, 0, len(appEnv))
+	keys := make([]string, 0, len(appEnv))
+	for k := range appEnv {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		env = append(env, k+"="+appEnv[k])
+	}
+
+	cmd := dexec.CommandContext(ctx, rq.Command[0], rq.Command[1:]...)
+	cmd.Env = env
+	if _, err := os.Stat(cnMountPoint); err == nil {
+		cmd.Dir = cnMountPoint
+	}
+	// The output belongs to the client, not to the agent's log.
+	cmd.DisableLogging = true
+	return cmd, nil
+}
+
+// runExec runs the command of the given request and relays its standard I/O through an AgentExec
+// call to the traffic-manager. The command is killed when the call ends.
+func runExec(ctx context.Context, manager rpc.ManagerClient, session *rpc.SessionInfo, state State, rq *rpc.ExecRequest) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stream, err := manager.AgentExec(ctx)
+	if err != nil {
+		return err
+	}
+	if err = stream.Send(&rpc.ExecMessage{Session: session, RequestId: rq.Id}); err != nil {
+		return err
+	}
+	dlog.Infof(ctx, "running command %q", rq.Command)
+
+	var sendMu sync.Mutex // stream.Send must not be called concurrently
+	send := func(msg *rpc.ExecMessage) error {
+		sendMu.Lock()
+		defer sendMu.Unlock()
+		return stream.Send(msg)
+	}
+
+	cmd, err := execCommand(ctx, state, rq)
+	if err != nil {
+		_ = send(&rpc.ExecMessage{Error: err.Error()})
+		return stream.CloseSend()
+	}
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return err
+	}
+	if err = cmd.Start(); err != nil {
+		_ = send(&rpc.ExecMessage{Error: err.Error()})
+		return stream.CloseSend()
+	}
+
+	// Relay standard input from the traffic-manager. The call ends when the manager is done with it.
+	go func() {
+		defer cancel()
+		for {
+			msg, err := stream.Recv()
+			if err != nil {
+				return
+			}
+			if len(msg.Stdin) > 0 {
+				if _, err = stdin.Write(msg.Stdin); err != nil {
+					dlog.Debugf(ctx, "unable to write to command's standard input: %v", err)
+				}
+			}
+			if msg.CloseStdin {
+				_ = stdin.Close()
+			}
+		}
+	}()
+
+	relay := func(r io.Reader, mk func([]byte) *rpc.ExecMessage, wg *sync.WaitGroup) {
+		defer wg.Done()
+		buf := make([]byte, 0x8000)
+		for {
+			n, err := r.Read(buf)
+			if n > 0 {
+				if send(mk(append([]byte(nil), buf[:n]...))) != nil {
+					return
+				}
+			}
+			if err != nil {
+				return
+			}
+		}
+	}
+	wg := sync.WaitGroup{}
+	wg.Add(2)
+	go relay(stdout, func(b []byte) *rpc.ExecMessage { return &rpc.ExecMessage{Stdout: b} }, &wg)
+	go relay(stderr, func(b []byte) *rpc.ExecMessage { return &rpc.ExecMessage{Stderr: b} }, &wg)
+	wg.Wait()
+
+	msg := &rpc.ExecMessage{Exited: true}
+	if err = cmd.Wait(); err != nil {
+		var ee *exec.ExitError
+		if !errors.As(err, &ee) {
+			_ = send(&rpc.ExecMessage{Error: err.Error()})
+			return stream.CloseSend()
+		}
+		msg.ExitCode = int32(ee.ExitCode())
+	}
+	dlog.Infof(ctx, "command %q exited with status %d", rq.Command, msg.ExitCode)
+	if err = send(msg); err != nil {
+		return err
+	}
+	return stream.CloseSend()
+}
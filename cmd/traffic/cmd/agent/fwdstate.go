@@ -18,17 +18,19 @@ type fwdState struct {
 	forwarder  forwarder.Interceptor
 	mountPoint string
 	env        map[string]string
+	redacted   []string
 }
 
 // NewInterceptState creates a InterceptState that performs intercepts by using an Interceptor which indiscriminately
 // intercepts all traffic to the port that it forwards.
-func NewInterceptState(s State, forwarder forwarder.Interceptor, intercepts []*agentconfig.Intercept, mountPoint string, env map[string]string) InterceptState {
+func NewInterceptState(s State, forwarder forwarder.Interceptor, intercepts []*agentconfig.Intercept, mountPoint string, env map[string]string, redacted []string) InterceptState {
 	return &fwdState{
 		simpleState: s.(*simpleState),
 		mountPoint:  mountPoint,
 		intercepts:  intercepts,
 		forwarder:   forwarder,
 		env:         env,
+		redacted:    redacted,
 	}
 }
 
@@ -82,6 +84,13 @@ func (fs *fwdState) HandleIntercepts(ctx context.Context, cepts []*manager.Inter
 		}
 	}
 
+	if activeIntercept != nil && activeIntercept.LocalProcessRestarting {
+		// The client is restarting the process that serves the intercept. Let the intercepted
+		// container serve the traffic until the process is back, rather than resetting connections.
+		dlog.Debugf(ctx, "Local process of intercept %q is restarting, forwarding to container", activeIntercept.Id)
+		activeIntercept = nil
+	}
+
 	// Update forwarding.
 	fs.forwarder.SetManager(fs.SessionInfo(), fs.ManagerClient(), fs.ManagerVersion())
 	fs.forwarder.SetIntercepting(activeIntercept)
@@ -97,13 +106,24 @@ func (fs *fwdState) HandleIntercepts(ctx context.Context, cepts []*manager.Inter
 				// snapshot. Let's go ahead and tell the manager to mark it ACTIVE.
 				dlog.Infof(ctx, "Setting intercept %q as ACTIVE (again?)", cept.Id)
 				reviews = append(reviews, &manager.ReviewInterceptRequest{
-					Id:                cept.Id,
-					Disposition:       manager.InterceptDispositionType_ACTIVE,
-					PodIp:             fs.PodIP(),
-					SftpPort:          int32(fs.SftpPort()),
-					MountPoint:        fs.mountPoint,
+					Id:                  cept.Id,
+					Disposition:         manager.InterceptDispositionType_ACTIVE,
+					PodIp:               fs.PodIP(),
+					SftpPort:            int32(fs.SftpPort()),
+					NfsPort:             int32(fs.NfsPort()),
+					MountPoint:          fs.mountPoint,
+					MechanismArgsDesc:   "all TCP connections",
+					Environment:         fs.env,
+					RedactedEnvironment: fs.redacted,
+				})
+			case cept.Spec.TerminateTls && fs.TLSConfig() == nil:
+				dlog.Infof(ctx, "Setting intercept %q as AGENT_ERROR; TLS termination requested but no TLS Secret is configured", cept.Id)
+				reviews = append(reviews, &manager.ReviewInterceptRequest{
+					Id:          cept.Id,
+					Disposition: manager.InterceptDispositionType_AGENT_ERROR,
+					Message: fmt.Sprintf("unable to terminate TLS: the workload has no %s annotation",
+						agentconfig.TLSSecretAnnotation),
 					MechanismArgsDesc: "all TCP connections",
-					Environment:       fs.env,
 				})
 			case fs.chosenIntercept == nil:
 				// We don't have an intercept in play, so choose this one. All
@@ -115,13 +135,15 @@ func (fs *fwdState) HandleIntercepts(ctx context.Context, cepts []*manager.Inter
 				fs.chosenIntercept = cept
 				myChoice = cept
 				reviews = append(reviews, &manager.ReviewInterceptRequest{
-					Id:                cept.Id,
-					Disposition:       manager.InterceptDispositionType_ACTIVE,
-					PodIp:             fs.PodIP(),
-					SftpPort:          int32(fs.SftpPort()),
-					MountPoint:        fs.mountPoint,
-					MechanismArgsDesc: "all TCP connections",
-					Environment:       fs.env,
+					Id:                  cept.Id,
+					Disposition:         manager.InterceptDispositionType_ACTIVE,
+					PodIp:               fs.PodIP(),
+					SftpPort:            int32(fs.SftpPort()),
+					NfsPort:             int32(fs.NfsPort()),
+					MountPoint:          fs.mountPoint,
+					MechanismArgsDesc:   "all TCP connections",
+					Environment:         fs.env,
+					RedactedEnvironment: fs.redacted,
 				})
 			default:
 				// We already have an intercept in play, so reject this one.
@@ -142,5 +164,18 @@ func (fs *fwdState) HandleIntercepts(ctx context.Context, cepts []*manager.Inter
 			}
 		}
 	}
+
+	// Enforce the mount policy of the chosen intercept, and tell the client about it.
+	var mp *agentconfig.MountPolicy
+	if fs.chosenIntercept != nil {
+		mp = agentconfig.MountPolicyFromRPC(fs.chosenIntercept.Spec.MountPolicy)
+	}
+	mg := fs.MountGuard()
+	mg.SetInterceptPolicy(fs.mountPoint, fs, mp)
+	for _, r := range reviews {
+		if r.Disposition == manager.InterceptDispositionType_ACTIVE {
+			r.MountPolicy = mg.Policy(fs.mountPoint).RPC()
+		}
+	}
 	return reviews
 }
@@ -0,0 +1,344 @@
+package agent
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/telepresenceio/telepresence/v2/pkg/agentconfig"
+)
+
+// MountGuard enforces the mount policies of the containers whose mounts are exported below the
+// root directory. The policy of a container is the policy declared by the workload, merged with
+// the policies of the intercepts that are active on the container.
+type MountGuard struct {
+	sync.RWMutex
+	root       string
+	workload   *agentconfig.MountPolicy
+	intercepts map[string]map[InterceptState]*agentconfig.MountPolicy
+}
+
+// NewMountGuard returns a MountGuard for the container mounts exported below root.
+func NewMountGuard(root string, workload *agentconfig.MountPolicy) *MountGuard {
+	return &MountGuard{
+		root:       filepath.Clean(root),
+		workload:   workload,
+		intercepts: make(map[string]map[InterceptState]*agentconfig.MountPolicy),
+	}
+}
+
+// SetInterceptPolicy sets the policy requested by the intercept that the given InterceptState
+// has chosen for the container mounts at mountPoint. A nil policy removes it.
+func (g *MountGuard) SetInterceptPolicy(mountPoint string, is InterceptState, mp *agentconfig.MountPolicy) {
+	g.Lock()
+	defer g.Unlock()
+	ps := g.intercepts[mountPoint]
+	if mp == nil {
+		delete(ps, is)
+		return
+	}
+	if ps == nil {
+		ps = make(map[InterceptState]*agentconfig.MountPolicy)
+		g.intercepts[mountPoint] = ps
+	}
+	ps[is] = mp
+}
+
+// Policy returns the effective policy of the container mounts at mountPoint.
+func (g *MountGuard) Policy(mountPoint string) *agentconfig.MountPolicy {
+	g.RLock()
+	defer g.RUnlock()
+	mp := g.workload
+	for _, ip := range g.intercepts[mountPoint] {
+		mp = mp.Merge(ip)
+	}
+	return mp
+}
+
+// Check returns an error unless the given path can be accessed, or modified when write is true.
+// Paths that the policy hides yield ENOENT, so that they appear to be absent, and paths outside
+// the root cannot be accessed at all. Symbolic links in the path are resolved, and the policy
+// applies to what they lead to as well. The errors wrap a syscall.Errno, because that's what the
+// sftp and nfs servers translate into status codes.
+//
+// A client can swap a symbolic link into the path after it was checked, so files must be opened
+// and modified using the OpenFile, Mkdir, Remove, etc. methods of the guard, which check the path
+// and make sure that what they open or modify is what was checked.
+func (g *MountGuard) Check(path string, write bool) error {
+	_, err := g.check(path, write)
+	return err
+}
+
+// check is like Check, but also returns the path below the root that the given path resolves to.
+func (g *MountGuard) check(path string, write bool) (string, error) {
+	path = filepath.Clean(path)
+	if err := g.checkLexical(path, write); err != nil {
+		return "", err
+	}
+	resolved, err := g.resolve(path)
+	if err != nil {
+		return "", err
+	}
+	if resolved != path {
+		if err = g.checkLexical(resolved, write); err != nil {
+			return "", err
+		}
+	}
+	return resolved, nil
+}
+
+// checkLexical checks the given clean path against the policy without looking at the file system.
+func (g *MountGuard) checkLexical(path string, write bool) error {
+	if path == g.root {
+		if write {
+			return &os.PathError{Op: "write", Path: path, Err: syscall.EPERM}
+		}
+		return nil
+	}
+	rel := strings.TrimPrefix(path, g.root+string(filepath.Separator))
+	if rel == path {
+		return &os.PathError{Op: "access", Path: path, Err: syscall.EPERM}
+	}
+
+	// The first element is the directory of a container. The rest is a path in that container.
+	cnDir, cnPath, _ := strings.Cut(filepath.ToSlash(rel), "/")
+	if write && !strings.Contains(cnPath, "/") {
+		// The directory of the container and the exports in it are created by the agent.
+		return &os.PathError{Op: "write", Path: path, Err: syscall.EPERM}
+	}
+	mp := g.Policy(filepath.Join(g.root, cnDir))
+	cnPath = "/" + cnPath
+	if !mp.Visible(cnPath) {
+		return &os.PathError{Op: "access", Path: path, Err: syscall.ENOENT}
+	}
+	if write && (mp.ReadOnly || !mp.Permits(cnPath)) {
+		return &os.PathError{Op: "write", Path: path, Err: syscall.EPERM}
+	}
+	return nil
+}
+
+// resolve follows the symbolic links of the given clean path below the root, and returns the path
+// below the root that it leads to. The first link of a path is one that the agent created to export
+// a volume of the app container. Links after that are confined to that volume, so a link that
+// leads out of it yields EPERM. A path that doesn't exist is resolved as far as it exists.
+func (g *MountGuard) resolve(path string) (string, error) {
+	phys, err := filepath.EvalSymlinks(g.root)
+	if err != nil {
+		// Nothing is exported.
+		return path, nil
+	}
+	rel := strings.TrimPrefix(path, g.root+string(filepath.Separator))
+	if rel == path {
+		return path, nil
+	}
+	// lex is the path below the root that corresponds to the physical path phys, and vol and
+	// volPhys are the lexical and the physical path of the exported volume, once it has been found.
+	lex := g.root
+	vol, volPhys := "", ""
+	deny := &os.PathError{Op: "access", Path: path, Err: syscall.EPERM}
+	elems := strings.Split(rel, string(filepath.Separator))
+	for i, elem := range elems {
+		lex = filepath.Join(lex, elem)
+		phys = filepath.Join(phys, elem)
+		fi, err := os.Lstat(phys)
+		if err != nil {
+			// The rest doesn't exist, so there are no more links to follow.
+			return filepath.Join(append([]string{lex}, elems[i+1:]...)...), nil
+		}
+		if fi.Mode()&os.ModeSymlink == 0 {
+			continue
+		}
+		target, err := filepath.EvalSymlinks(phys)
+		if err != nil {
+			// A dangling link. It's harmless as long as it doesn't point out of its volume.
+			if target, err = os.Readlink(phys); err != nil || vol == "" {
+				return "", deny
+			}
+			if !filepath.IsAbs(target) {
+				target = filepath.Join(filepath.Dir(phys), target)
+			}
+			if !within(volPhys, target) {
+				return "", deny
+			}
+			continue
+		}
+		if vol == "" {
+			vol, volPhys = lex, target
+		} else if !within(volPhys, target) {
+			return "", deny
+		}
+		phys = target
+		lex = vol + strings.TrimPrefix(target, volPhys)
+	}
+	return lex, nil
+}
+
+// within reports whether the given path is the given directory or a path below it.
+func within(dir, path string) bool {
+	return path == dir || strings.HasPrefix(path, dir+string(filepath.Separator))
+}
+
+// OpenFile is like os.OpenFile, but the path is checked first. The path may be modified when the flag
+// contains os.O_WRONLY, os.O_RDWR, os.O_CREATE, or os.O_TRUNC.
+func (g *MountGuard) OpenFile(path string, flag int, perm os.FileMode) (*os.File, error) {
+	write := flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE|os.O_TRUNC) != 0
+	lex, err := g.check(path, write)
+	if err != nil {
+		return nil, err
+	}
+	if g.depth(lex) < 3 {
+		// The root, the directory of a container, or an export. They're created by the agent and
+		// cannot be replaced by clients.
+		return os.OpenFile(lex, flag, perm)
+	}
+	d, err := g.openDir(filepath.Dir(lex))
+	if err != nil {
+		return nil, err
+	}
+	defer d.Close()
+	return openAt(d, filepath.Base(lex), flag, perm)
+}
+
+// Mkdir is like os.Mkdir, but the path is checked first.
+func (g *MountGuard) Mkdir(path string, perm os.FileMode) error {
+	return g.modifyAt(path, func(d *os.File, name string) error {
+		return mkdirAt(d, name, perm)
+	})
+}
+
+// Symlink is like os.Symlink, but the path of the link is checked first. The target is not checked.
+func (g *MountGuard) Symlink(target, path string) error {
+	return g.modifyAt(path, func(d *os.File, name string) error {
+		return symlinkAt(target, d, name)
+	})
+}
+
+// Remove is like os.Remove, but the path is checked first.
+func (g *MountGuard) Remove(path string) error {
+	return g.modifyAt(path, removeAt)
+}
+
+// Rename is like os.Rename, but both paths are checked first.
+func (g *MountGuard) Rename(from, to string) error {
+	return g.modifyAt(from, func(fd *os.File, fromName string) error {
+		return g.modifyAt(to, func(td *os.File, toName string) error {
+			return renameAt(fd, fromName, td, toName)
+		})
+	})
+}
+
+// Link is like os.Link, but both paths are checked first.
+func (g *MountGuard) Link(path, link string) error {
+	return g.modifyAt(path, func(pd *os.File, pathName string) error {
+		return g.modifyAt(link, func(ld *os.File, linkName string) error {
+			return linkAt(pd, pathName, ld, linkName)
+		})
+	})
+}
+
+// Chmod is like os.Chmod, but the path is checked first.
+func (g *MountGuard) Chmod(path string, mode os.FileMode) error {
+	return g.modifyAt(path, func(d *os.File, name string) error {
+		return withPathAt(d, name, func(p string) error { return os.Chmod(p, mode) })
+	})
+}
+
+// Chown is like os.Chown, but the path is checked first.
+func (g *MountGuard) Chown(path string, uid, gid int) error {
+	return g.modifyAt(path, func(d *os.File, name string) error {
+		return withPathAt(d, name, func(p string) error { return os.Chown(p, uid, gid) })
+	})
+}
+
+// Truncate is like os.Truncate, but the path is checked first.
+func (g *MountGuard) Truncate(path string, size int64) error {
+	return g.modifyAt(path, func(d *os.File, name string) error {
+		return withPathAt(d, name, func(p string) error { return os.Truncate(p, size) })
+	})
+}
+
+// Chtimes is like os.Chtimes, but the path is checked first.
+func (g *MountGuard) Chtimes(path string, atime, mtime time.Time) error {
+	return g.modifyAt(path, func(d *os.File, name string) error {
+		return withPathAt(d, name, func(p string) error { return os.Chtimes(p, atime, mtime) })
+	})
+}
+
+// modifyAt checks that the given path can be modified, and calls fn with the directory that contains
+// what the path resolves to and the name of it in that directory. The entry must be accessed relative
+// to the directory without following a symbolic link in its name.
+func (g *MountGuard) modifyAt(path string, fn func(d *os.File, name string) error) error {
+	lex, err := g.check(path, true)
+	if err != nil {
+		return err
+	}
+	// Only paths in exports can be modified, so the directory is at least an export.
+	d, err := g.openDir(filepath.Dir(lex))
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return fn(d, filepath.Base(lex))
+}
+
+// openDir opens the directory at the given path below the root, which must be a path that has been
+// resolved, and verifies that the directory that was opened is the one at that path. That fails when
+// a symbolic link has been swapped into the path after it was resolved.
+func (g *MountGuard) openDir(lex string) (*os.File, error) {
+	d, err := os.Open(lex)
+	if err != nil {
+		return nil, err
+	}
+	if err = g.verify(d, lex); err != nil {
+		_ = d.Close()
+		return nil, err
+	}
+	return d, nil
+}
+
+// verify returns an error unless the given open file is the file at the given resolved path. Nothing
+// is verified on platforms that cannot tell the path of an open file.
+func (g *MountGuard) verify(f *os.File, lex string) error {
+	got, ok := fdPath(f)
+	if !ok {
+		return nil
+	}
+	want, err := g.physical(lex)
+	if err != nil {
+		return err
+	}
+	if got != want {
+		return &os.PathError{Op: "access", Path: lex, Err: syscall.EPERM}
+	}
+	return nil
+}
+
+// physical returns the path of the file system that the given resolved path below the root leads
+// to, i.e. the target of its export, followed by the rest of the path.
+func (g *MountGuard) physical(lex string) (string, error) {
+	rel := strings.TrimPrefix(lex, g.root+string(filepath.Separator))
+	if rel == lex {
+		return filepath.EvalSymlinks(lex)
+	}
+	elems := strings.SplitN(rel, string(filepath.Separator), 3)
+	if len(elems) < 3 {
+		return filepath.EvalSymlinks(lex)
+	}
+	export, err := filepath.EvalSymlinks(filepath.Join(g.root, elems[0], elems[1]))
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(export, elems[2]), nil
+}
+
+// depth returns the number of elements of the given path below the root.
+func (g *MountGuard) depth(lex string) int {
+	rel := strings.TrimPrefix(lex, g.root+string(filepath.Separator))
+	if rel == lex {
+		return 0
+	}
+	return strings.Count(rel, string(filepath.Separator)) + 1
+}
@@ -0,0 +1,77 @@
+package agent
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"golang.org/x/sys/unix"
+)
+
+// fdPath returns the path of the given open file.
+func fdPath(f *os.File) (string, bool) {
+	path, err := os.Readlink("/proc/self/fd/" + strconv.Itoa(int(f.Fd())))
+	return path, err == nil
+}
+
+// openAt opens the named entry of the given directory without following a symbolic link in the name.
+func openAt(d *os.File, name string, flag int, perm os.FileMode) (*os.File, error) {
+	fd, err := unix.Openat(int(d.Fd()), name, flag|unix.O_NOFOLLOW|unix.O_CLOEXEC, uint32(perm.Perm()))
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: filepath.Join(d.Name(), name), Err: err}
+	}
+	return os.NewFile(uintptr(fd), filepath.Join(d.Name(), name)), nil
+}
+
+func mkdirAt(d *os.File, name string, perm os.FileMode) error {
+	if err := unix.Mkdirat(int(d.Fd()), name, uint32(perm.Perm())); err != nil {
+		return &os.PathError{Op: "mkdir", Path: filepath.Join(d.Name(), name), Err: err}
+	}
+	return nil
+}
+
+func symlinkAt(target string, d *os.File, name string) error {
+	if err := unix.Symlinkat(target, int(d.Fd()), name); err != nil {
+		return &os.LinkError{Op: "symlink", Old: target, New: filepath.Join(d.Name(), name), Err: err}
+	}
+	return nil
+}
+
+// removeAt removes the named entry of the given directory, which is either a file or an empty directory.
+func removeAt(d *os.File, name string) error {
+	err := unix.Unlinkat(int(d.Fd()), name, 0)
+	if err == unix.EISDIR || err == unix.EPERM {
+		if rerr := unix.Unlinkat(int(d.Fd()), name, unix.AT_REMOVEDIR); rerr != unix.ENOTDIR {
+			err = rerr
+		}
+	}
+	if err != nil {
+		return &os.PathError{Op: "remove", Path: filepath.Join(d.Name(), name), Err: err}
+	}
+	return nil
+}
+
+func renameAt(fromDir *os.File, fromName string, toDir *os.File, toName string) error {
+	if err := unix.Renameat(int(fromDir.Fd()), fromName, int(toDir.Fd()), toName); err != nil {
+		return &os.LinkError{Op: "rename", Old: filepath.Join(fromDir.Name(), fromName), New: filepath.Join(toDir.Name(), toName), Err: err}
+	}
+	return nil
+}
+
+func linkAt(fromDir *os.File, fromName string, toDir *os.File, toName string) error {
+	if err := unix.Linkat(int(fromDir.Fd()), fromName, int(toDir.Fd()), toName, 0); err != nil {
+		return &os.LinkError{Op: "link", Old: filepath.Join(fromDir.Name(), fromName), New: filepath.Join(toDir.Name(), toName), Err: err}
+	}
+	return nil
+}
+
+// withPathAt calls fn with a path that leads to the named entry of the given directory and nowhere
+// else, i.e. a path that doesn't follow a symbolic link in the name.
+func withPathAt(d *os.File, name string, fn func(path string) error) error {
+	fd, err := unix.Openat(int(d.Fd()), name, unix.O_PATH|unix.O_NOFOLLOW|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return &os.PathError{Op: "open", Path: filepath.Join(d.Name(), name), Err: err}
+	}
+	defer unix.Close(fd)
+	return fn("/proc/self/fd/" + strconv.Itoa(fd))
+}
@@ -0,0 +1,78 @@
+package agent
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/telepresenceio/telepresence/v2/pkg/agentconfig"
+)
+
+func TestMountGuard_SwappedLink(t *testing.T) {
+	// The app's volume, and the export of it that the agent creates
+	vol := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(vol, "pub"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(vol, "pub", "app.yaml"), []byte("port: 8080\n"), 0o644))
+	require.NoError(t, os.MkdirAll(filepath.Join(vol, "secrets"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(vol, "secrets", "app.yaml"), []byte("token\n"), 0o644))
+
+	root := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(root, "echo"), 0o755))
+	require.NoError(t, os.Symlink(vol, filepath.Join(root, "echo", "config")))
+	cfg := filepath.Join(root, "echo", "config")
+
+	g := NewMountGuard(root, &agentconfig.MountPolicy{Deny: []string{"/config/secrets"}})
+	f, err := g.OpenFile(filepath.Join(cfg, "pub", "app.yaml"), os.O_RDONLY, 0)
+	require.NoError(t, err)
+	_ = f.Close()
+
+	// The path is checked, and then a client swaps a link to the hidden directory into it.
+	path := filepath.Join(cfg, "pub", "app.yaml")
+	lex, err := g.check(path, true)
+	require.NoError(t, err)
+	require.NoError(t, os.Rename(filepath.Join(vol, "pub"), filepath.Join(vol, "pub.orig")))
+	require.NoError(t, os.Symlink("secrets", filepath.Join(vol, "pub")))
+	_, err = g.openDir(filepath.Dir(lex))
+	assert.True(t, os.IsPermission(err))
+
+	// Checking again reveals that the path leads to the hidden directory.
+	_, err = g.OpenFile(path, os.O_WRONLY|os.O_TRUNC, 0)
+	assert.True(t, os.IsNotExist(err))
+	data, err := os.ReadFile(filepath.Join(vol, "secrets", "app.yaml"))
+	require.NoError(t, err)
+	assert.Equal(t, "token\n", string(data))
+}
+
+func TestMountGuard_Modify(t *testing.T) {
+	vol := t.TempDir()
+	root := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(root, "echo"), 0o755))
+	require.NoError(t, os.Symlink(vol, filepath.Join(root, "echo", "data")))
+	data := filepath.Join(root, "echo", "data")
+
+	g := NewMountGuard(root, &agentconfig.MountPolicy{})
+	require.NoError(t, g.Mkdir(filepath.Join(data, "sub"), 0o755))
+	f, err := g.OpenFile(filepath.Join(data, "sub", "a.txt"), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	require.NoError(t, err)
+	_, err = f.WriteString("hello")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	require.NoError(t, g.Chmod(filepath.Join(data, "sub", "a.txt"), 0o600))
+	require.NoError(t, g.Truncate(filepath.Join(data, "sub", "a.txt"), 4))
+	require.NoError(t, g.Rename(filepath.Join(data, "sub", "a.txt"), filepath.Join(data, "b.txt")))
+	fi, err := os.Stat(filepath.Join(vol, "b.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), fi.Mode().Perm())
+	assert.Equal(t, int64(4), fi.Size())
+	require.NoError(t, g.Remove(filepath.Join(data, "b.txt")))
+	require.NoError(t, g.Remove(filepath.Join(data, "sub")))
+	assert.NoFileExists(t, filepath.Join(vol, "b.txt"))
+	assert.NoDirExists(t, filepath.Join(vol, "sub"))
+
+	// The exports themselves cannot be modified
+	assert.True(t, os.IsPermission(g.Remove(data)))
+	assert.True(t, os.IsPermission(g.Symlink("/", filepath.Join(root, "echo", "etc"))))
+}
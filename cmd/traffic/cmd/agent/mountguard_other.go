@@ -0,0 +1,42 @@
+//go:build !linux
+// +build !linux
+
+package agent
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// fdPath returns false, because the path of an open file cannot be determined on this platform.
+func fdPath(*os.File) (string, bool) {
+	return "", false
+}
+
+func openAt(d *os.File, name string, flag int, perm os.FileMode) (*os.File, error) {
+	return os.OpenFile(filepath.Join(d.Name(), name), flag, perm)
+}
+
+func mkdirAt(d *os.File, name string, perm os.FileMode) error {
+	return os.Mkdir(filepath.Join(d.Name(), name), perm)
+}
+
+func symlinkAt(target string, d *os.File, name string) error {
+	return os.Symlink(target, filepath.Join(d.Name(), name))
+}
+
+func removeAt(d *os.File, name string) error {
+	return os.Remove(filepath.Join(d.Name(), name))
+}
+
+func renameAt(fromDir *os.File, fromName string, toDir *os.File, toName string) error {
+	return os.Rename(filepath.Join(fromDir.Name(), fromName), filepath.Join(toDir.Name(), toName))
+}
+
+func linkAt(fromDir *os.File, fromName string, toDir *os.File, toName string) error {
+	return os.Link(filepath.Join(fromDir.Name(), fromName), filepath.Join(toDir.Name(), toName))
+}
+
+func withPathAt(d *os.File, name string, fn func(path string) error) error {
+	return fn(filepath.Join(d.Name(), name))
+}
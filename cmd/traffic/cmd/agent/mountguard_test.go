@@ -0,0 +1,110 @@
+package agent_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pkg/sftp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/datawire/dlib/dlog"
+	"github.com/telepresenceio/telepresence/v2/cmd/traffic/cmd/agent"
+	"github.com/telepresenceio/telepresence/v2/pkg/agentconfig"
+)
+
+func TestMountGuard_Check(t *testing.T) {
+	g := agent.NewMountGuard("/exports", &agentconfig.MountPolicy{Deny: []string{"/var/run/secrets"}})
+	assert.NoError(t, g.Check("/exports", false))
+	assert.NoError(t, g.Check("/exports/echo/var/run", true))
+	assert.True(t, os.IsNotExist(g.Check("/exports/echo/var/run/secrets/token", false)))
+	assert.True(t, os.IsPermission(g.Check("/etc/passwd", false)))
+
+	// An intercept can restrict the policy of the workload further
+	g.SetInterceptPolicy("/exports/echo", nil, &agentconfig.MountPolicy{ReadOnly: true})
+	assert.True(t, os.IsPermission(g.Check("/exports/echo/var/run", true)))
+	assert.NoError(t, g.Check("/exports/other/var/run", true))
+	assert.Equal(t, "read-only, deny /var/run/secrets", g.Policy("/exports/echo").String())
+
+	g.SetInterceptPolicy("/exports/echo", nil, nil)
+	assert.NoError(t, g.Check("/exports/echo/var/run", true))
+}
+
+func TestMountGuard_CheckLinks(t *testing.T) {
+	// The app's volume, and the export of it that the agent creates
+	vol := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(vol, "..data", "secrets"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(vol, "..data", "app.yaml"), []byte("port: 8080\n"), 0o644))
+	require.NoError(t, os.Symlink("..data/app.yaml", filepath.Join(vol, "app.yaml")))
+	require.NoError(t, os.Symlink("..data/secrets", filepath.Join(vol, "creds")))
+	require.NoError(t, os.Symlink("/etc", filepath.Join(vol, "etc")))
+	require.NoError(t, os.Symlink("../../outside", filepath.Join(vol, "dangling")))
+	require.NoError(t, os.Symlink("..data/new.txt", filepath.Join(vol, "new.txt")))
+
+	root := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(root, "echo"), 0o755))
+	require.NoError(t, os.Symlink(vol, filepath.Join(root, "echo", "config")))
+
+	g := agent.NewMountGuard(root, &agentconfig.MountPolicy{Deny: []string{"/config/..data/secrets"}})
+	cfg := filepath.Join(root, "echo", "config")
+
+	// Links within the volume are followed.
+	assert.NoError(t, g.Check(filepath.Join(cfg, "app.yaml"), false))
+	assert.NoError(t, g.Check(filepath.Join(cfg, "new.txt"), true))
+	assert.NoError(t, g.Check(filepath.Join(cfg, "sub", "new.txt"), true))
+
+	// The policy applies to what a link leads to.
+	assert.True(t, os.IsNotExist(g.Check(filepath.Join(cfg, "creds", "token"), false)))
+
+	// Links that lead out of the volume are denied, even when they're dangling.
+	assert.True(t, os.IsPermission(g.Check(filepath.Join(cfg, "etc", "passwd"), false)))
+	assert.True(t, os.IsPermission(g.Check(filepath.Join(cfg, "etc"), false)))
+	assert.True(t, os.IsPermission(g.Check(filepath.Join(cfg, "dangling"), false)))
+}
+
+func TestSftpServer_MountPolicy(t *testing.T) {
+	ctx, cancel := context.WithCancel(dlog.NewTestContext(t, false))
+	defer cancel()
+
+	root := t.TempDir()
+	cnDir := filepath.Join(root, "echo")
+	require.NoError(t, os.MkdirAll(filepath.Join(cnDir, "var", "run", "secrets"), 0o755))
+	require.NoError(t, os.MkdirAll(filepath.Join(cnDir, "etc"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(cnDir, "etc", "app.yaml"), []byte("port: 8080\n"), 0o644))
+
+	g := agent.NewMountGuard(root, &agentconfig.MountPolicy{ReadOnly: true, Deny: []string{"/var/run/secrets"}})
+	portCh := make(chan uint16)
+	go func() { _ = agent.SftpServer(ctx, portCh, g) }()
+	port := <-portCh
+	conn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+	require.NoError(t, err)
+	c, err := sftp.NewClientPipe(conn, conn)
+	require.NoError(t, err)
+	defer c.Close()
+
+	f, err := c.Open(cnDir + "/etc/app.yaml")
+	require.NoError(t, err)
+	data, err := io.ReadAll(f)
+	_ = f.Close()
+	require.NoError(t, err)
+	assert.Equal(t, "port: 8080\n", string(data))
+
+	fis, err := c.ReadDir(cnDir + "/var/run")
+	require.NoError(t, err)
+	assert.Empty(t, fis)
+
+	_, err = c.Stat(cnDir + "/var/run/secrets")
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	_, err = c.Create(cnDir + "/etc/new.txt")
+	assert.ErrorIs(t, err, os.ErrPermission)
+	assert.NoFileExists(t, filepath.Join(cnDir, "etc", "new.txt"))
+
+	_, err = c.Stat("/etc/passwd")
+	assert.Error(t, err)
+}
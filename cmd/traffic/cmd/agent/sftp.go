@@ -0,0 +1,193 @@
+package agent
+
+import (
+	"io"
+	"os"
+	"time"
+
+	"github.com/pkg/sftp"
+)
+
+// sftpHandler serves SFTP requests using the file system of the agent. Each path that a request
+// accesses is checked by the MountGuard first, and files are opened and modified through it.
+type sftpHandler struct {
+	guard *MountGuard
+}
+
+// newSftpServer returns an SFTP server that serves the given connection and enforces the mount
+// policies of the guard.
+func newSftpServer(conn io.ReadWriteCloser, guard *MountGuard) *sftp.RequestServer {
+	h := &sftpHandler{guard: guard}
+	return sftp.NewRequestServer(conn, sftp.Handlers{FileGet: h, FilePut: h, FileCmd: h, FileList: h})
+}
+
+func (h *sftpHandler) Fileread(r *sftp.Request) (io.ReaderAt, error) {
+	return h.guard.OpenFile(r.Filepath, os.O_RDONLY, 0)
+}
+
+func (h *sftpHandler) Filewrite(r *sftp.Request) (io.WriterAt, error) {
+	return h.OpenFile(r)
+}
+
+func (h *sftpHandler) OpenFile(r *sftp.Request) (sftp.WriterAtReaderAt, error) {
+	pf := r.Pflags()
+	var flags int
+	switch {
+	case pf.Read && pf.Write:
+		flags = os.O_RDWR
+	case pf.Write:
+		flags = os.O_WRONLY
+	default:
+		flags = os.O_RDONLY
+	}
+	// O_APPEND isn't used, because SFTP writes are positional and os.File.WriteAt refuses to
+	// write to files opened with O_APPEND.
+	if pf.Creat {
+		flags |= os.O_CREATE
+	}
+	if pf.Trunc {
+		flags |= os.O_TRUNC
+	}
+	if pf.Excl {
+		flags |= os.O_EXCL
+	}
+	return h.guard.OpenFile(r.Filepath, flags, 0o644)
+}
+
+func (h *sftpHandler) Filecmd(r *sftp.Request) error {
+	switch r.Method {
+	case "Setstat":
+		return h.setStat(r)
+	case "Rename":
+		if err := h.guard.Check(r.Filepath, true); err != nil {
+			return err
+		}
+		if err := h.guard.Check(r.Target, true); err != nil {
+			return err
+		}
+		if _, err := os.Lstat(r.Target); err == nil {
+			// SFTP requires that rename fails when the target exists
+			return &os.LinkError{Op: "rename", Old: r.Filepath, New: r.Target, Err: os.ErrExist}
+		}
+		return h.guard.Rename(r.Filepath, r.Target)
+	case "Rmdir", "Remove":
+		return h.guard.Remove(r.Filepath)
+	case "Mkdir":
+		return h.guard.Mkdir(r.Filepath, 0o755)
+	case "Link":
+		return h.guard.Link(r.Filepath, r.Target)
+	case "Symlink":
+		// The Filepath is the target of the link, and the Target is the link itself.
+		if err := h.guard.Check(r.Filepath, false); err != nil {
+			return err
+		}
+		return h.guard.Symlink(r.Filepath, r.Target)
+	}
+	return sftp.ErrSSHFxOpUnsupported
+}
+
+func (h *sftpHandler) PosixRename(r *sftp.Request) error {
+	return h.guard.Rename(r.Filepath, r.Target)
+}
+
+func (h *sftpHandler) setStat(r *sftp.Request) error {
+	if err := h.guard.Check(r.Filepath, true); err != nil {
+		return err
+	}
+	af := r.AttrFlags()
+	a := r.Attributes()
+	if af.Size {
+		if err := h.guard.Truncate(r.Filepath, int64(a.Size)); err != nil {
+			return err
+		}
+	}
+	if af.Permissions {
+		if err := h.guard.Chmod(r.Filepath, a.FileMode().Perm()); err != nil {
+			return err
+		}
+	}
+	if af.UidGid {
+		if err := h.guard.Chown(r.Filepath, int(a.UID), int(a.GID)); err != nil {
+			return err
+		}
+	}
+	if af.Acmodtime {
+		if err := h.guard.Chtimes(r.Filepath, time.Unix(int64(a.Atime), 0), time.Unix(int64(a.Mtime), 0)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (h *sftpHandler) Filelist(r *sftp.Request) (sftp.ListerAt, error) {
+	if err := h.guard.Check(r.Filepath, false); err != nil {
+		return nil, err
+	}
+	switch r.Method {
+	case "List":
+		f, err := h.guard.OpenFile(r.Filepath, os.O_RDONLY, 0)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		fis, err := f.Readdir(-1)
+		if err != nil {
+			return nil, err
+		}
+		// Entries that the guard hides are omitted
+		vis := fis[:0]
+		for _, fi := range fis {
+			if h.guard.Check(r.Filepath+"/"+fi.Name(), false) == nil {
+				vis = append(vis, fi)
+			}
+		}
+		return listerAt(vis), nil
+	case "Stat":
+		fi, err := os.Stat(r.Filepath)
+		if err != nil {
+			return nil, err
+		}
+		return listerAt{fi}, nil
+	case "Readlink":
+		target, err := os.Readlink(r.Filepath)
+		if err != nil {
+			return nil, err
+		}
+		return listerAt{linkInfo(target)}, nil
+	}
+	return nil, sftp.ErrSSHFxOpUnsupported
+}
+
+func (h *sftpHandler) Lstat(r *sftp.Request) (sftp.ListerAt, error) {
+	if err := h.guard.Check(r.Filepath, false); err != nil {
+		return nil, err
+	}
+	fi, err := os.Lstat(r.Filepath)
+	if err != nil {
+		return nil, err
+	}
+	return listerAt{fi}, nil
+}
+
+type listerAt []os.FileInfo
+
+func (l listerAt) ListAt(fis []os.FileInfo, offset int64) (int, error) {
+	if offset >= int64(len(l)) {
+		return 0, io.EOF
+	}
+	n := copy(fis, l[offset:])
+	if n < len(fis) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// linkInfo is the os.FileInfo of a Readlink response, where the name is the target of the link.
+type linkInfo string
+
+func (l linkInfo) Name() string       { return string(l) }
+func (l linkInfo) Size() int64        { return 0 }
+func (l linkInfo) Mode() os.FileMode  { return os.ModeSymlink }
+func (l linkInfo) ModTime() time.Time { return time.Time{} }
+func (l linkInfo) IsDir() bool        { return false }
+func (l linkInfo) Sys() any           { return nil }
@@ -25,6 +25,9 @@ type State interface {
 	SetManager(sessionInfo *manager.SessionInfo, manager manager.ManagerClient, version semver.Version)
 	SftpPort() uint16
 	WaitForSftpPort(ctx context.Context, ch <-chan uint16) error
+	NfsPort() uint16
+	WaitForNfsPort(ctx context.Context, ch <-chan uint16) error
+	MountGuard() *MountGuard
 }
 
 // An InterceptState implements what's needed to intercept one port.
@@ -39,6 +42,10 @@ type InterceptState interface {
 type state struct {
 	Config
 	sftpPort uint16
+	nfsPort  uint16
+
+	// The mountGuard enforces the mount policies in the sftp-server and the nfs-server
+	mountGuard *MountGuard
 
 	// The sessionInfo and manager client are needed when forwarders establish their
 	// tunnel to the traffic-manager.
@@ -67,11 +74,15 @@ func (s *state) SessionInfo() *manager.SessionInfo {
 }
 
 func NewState(config Config) State {
-	return &state{Config: config}
+	return &state{Config: config, mountGuard: newMountGuard(config)}
 }
 
 func NewSimpleState(config Config) State {
-	return &simpleState{state: state{Config: config}}
+	return &simpleState{state: state{Config: config, mountGuard: newMountGuard(config)}}
+}
+
+func newMountGuard(config Config) *MountGuard {
+	return NewMountGuard(agentconfig.ExportsMountPoint, config.AgentConfig().MountPolicy)
 }
 
 func (s *state) AddInterceptState(is InterceptState) {
@@ -152,3 +163,20 @@ func (s *state) WaitForSftpPort(ctx context.Context, ch <-chan uint16) error {
 		return nil
 	}
 }
+
+func (s *state) MountGuard() *MountGuard {
+	return s.mountGuard
+}
+
+func (s *state) NfsPort() uint16 {
+	return s.nfsPort
+}
+
+func (s *state) WaitForNfsPort(ctx context.Context, ch <-chan uint16) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case s.nfsPort = <-ch:
+		return nil
+	}
+}
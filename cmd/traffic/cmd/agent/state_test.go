@@ -43,7 +43,7 @@ func makeFS(t *testing.T, ctx context.Context) (forwarder.Interceptor, agent.Sta
 	s := agent.NewSimpleState(c)
 	cn := c.AgentConfig().Containers[0]
 	cnMountPoint := filepath.Join(agentconfig.ExportsMountPoint, filepath.Base(cn.MountPoint))
-	s.AddInterceptState(agent.NewInterceptState(s, f, cn.Intercepts, cnMountPoint, map[string]string{}))
+	s.AddInterceptState(agent.NewInterceptState(s, f, cn.Intercepts, cnMountPoint, map[string]string{}, nil))
 
 	return f, s
 }
@@ -143,6 +143,20 @@ func TestState_HandleIntercepts(t *testing.T) {
 
 	a.Equal(rpc.InterceptDispositionType_AGENT_ERROR, reviews[0].Disposition)
 	a.Equal("Conflicts with the currently-served intercept \"intercept-01\"", reviews[0].Message)
+	a.Equal(cepts[0].Id, f.InterceptId())
+
+	// Traffic goes to the container while the client restarts its local process
+
+	cepts[0].LocalProcessRestarting = true
+	cepts = cepts[:1]
+	reviews = s.HandleIntercepts(ctx, cepts)
+	a.Len(reviews, 0)
+	a.Equal("", f.InterceptId())
+
+	cepts[0].LocalProcessRestarting = false
+	reviews = s.HandleIntercepts(ctx, cepts)
+	a.Len(reviews, 0)
+	a.Equal(cepts[0].Id, f.InterceptId())
 
 	// Handle resets state on an empty intercept list again
 
@@ -0,0 +1,55 @@
+package manager
+
+import (
+	"context"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	empty "google.golang.org/protobuf/types/known/emptypb"
+
+	"github.com/datawire/dlib/dlog"
+	rpc "github.com/telepresenceio/telepresence/rpc/v2/manager"
+	"github.com/telepresenceio/telepresence/v2/cmd/traffic/cmd/manager/managerutil"
+)
+
+// ReportEnvironment records the environment of the app containers of an agent. The environment is
+// kept out of the AgentInfo, because that is broadcast to all clients, and is instead handed out by
+// GetEnvironment to clients that have access to the agent's namespace.
+func (m *Manager) ReportEnvironment(ctx context.Context, rq *rpc.AgentEnvironment) (*empty.Empty, error) {
+	ctx = managerutil.WithSessionInfo(ctx, rq.GetSession())
+	sessionID := rq.GetSession().GetSessionId()
+	dlog.Debug(ctx, "ReportEnvironment called")
+	if !m.state.SetAgentEnvironment(sessionID, rq.Containers) {
+		return nil, status.Errorf(codes.NotFound, "Agent session %q not found", sessionID)
+	}
+	return &empty.Empty{}, nil
+}
+
+// GetEnvironment returns the environment of a workload's app container as reported by the workload's
+// traffic-agent, together with the environment policy of the traffic-manager. The container is nil when
+// the workload has no traffic-agent that serves the container.
+func (m *Manager) GetEnvironment(ctx context.Context, rq *rpc.EnvironmentRequest) (*rpc.EnvironmentResponse, error) {
+	ctx = managerutil.WithSessionInfo(ctx, rq.GetSession())
+	sessionID := rq.GetSession().GetSessionId()
+	dlog.Debugf(ctx, "GetEnvironment called: %s.%s", rq.Name, rq.Namespace)
+	if m.state.GetClient(sessionID) == nil {
+		return nil, status.Errorf(codes.NotFound, "Client session %q not found", sessionID)
+	}
+	if !m.namespaceInScope(ctx, sessionID, rq.Namespace) {
+		return nil, status.Errorf(codes.PermissionDenied, "namespace %q is not managed by the traffic-manager or not mapped by this session", rq.Namespace)
+	}
+	rsp := &rpc.EnvironmentResponse{}
+	if ep, _ := managerutil.GetEnv(ctx).EnvPolicy(); ep != nil {
+		rsp.EnvPolicy = &rpc.EnvPolicy{
+			Redact:   ep.Redact,
+			Withhold: ep.Withhold,
+			Secrets:  string(ep.Secrets),
+		}
+	}
+	for agentSessionID := range m.state.GetAgentsByName(rq.Name, rq.Namespace) {
+		if rsp.Container = m.state.GetAgentEnvironment(agentSessionID, rq.Container); rsp.Container != nil {
+			break
+		}
+	}
+	return rsp, nil
+}
@@ -0,0 +1,146 @@
+package manager
+
+import (
+	"errors"
+	"io"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/datawire/dlib/dlog"
+	rpc "github.com/telepresenceio/telepresence/rpc/v2/manager"
+	"github.com/telepresenceio/telepresence/v2/cmd/traffic/cmd/manager/managerutil"
+)
+
+// Exec runs a command in the traffic-agent container of a workload on behalf of a client, and relays
+// the standard I/O of the command between the client and the agent. Calls are refused unless exec has
+// been enabled, and each call is logged with the name of the client.
+func (m *Manager) Exec(stream rpc.Manager_ExecServer) error {
+	msg, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	rq := msg.Request
+	if rq == nil {
+		return status.Error(codes.InvalidArgument, "the first message must contain the request")
+	}
+	ctx := managerutil.WithSessionInfo(stream.Context(), rq.GetSession())
+	sessionID := rq.GetSession().GetSessionId()
+	client := m.state.GetClient(sessionID)
+	if client == nil {
+		return status.Errorf(codes.NotFound, "Client session %q not found", sessionID)
+	}
+	if !managerutil.GetEnv(ctx).AgentExecEnabled {
+		dlog.Infof(ctx, "AUDIT: exec %q in %s.%s by %s denied, exec is not enabled", rq.Command, rq.Name, rq.Namespace, client.Name)
+		return status.Error(codes.PermissionDenied, "exec is not enabled in the traffic-manager, see the agentInjector.execEnabled Helm value")
+	}
+	if !m.namespaceInScope(ctx, sessionID, rq.Namespace) {
+		return status.Errorf(codes.PermissionDenied, "namespace %q is not managed by the traffic-manager or not mapped by this session", rq.Namespace)
+	}
+	if len(rq.Command) == 0 {
+		return status.Error(codes.InvalidArgument, "no command given")
+	}
+	agents := m.state.GetAgentsByName(rq.Name, rq.Namespace)
+	var agentSessionID string
+	var agent *rpc.AgentInfo
+	for agentSessionID, agent = range agents {
+		break
+	}
+	if agent == nil {
+		return status.Errorf(codes.NotFound, "no traffic-agent found for %s.%s", rq.Name, rq.Namespace)
+	}
+
+	rq.Id = uuid.New().String()
+	dlog.Infof(ctx, "AUDIT: exec %q in %s.%s, pod IP %s, by %s", rq.Command, rq.Name, rq.Namespace, agent.PodIp, client.Name)
+	agentStream, release, err := m.state.StartAgentExec(ctx, agentSessionID, rq)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	// Relay standard input from the client to the agent
+	go func() {
+		for {
+			msg, err := stream.Recv()
+			if err != nil {
+				if errors.Is(err, io.EOF) {
+					_ = agentStream.Send(&rpc.ExecMessage{CloseStdin: true})
+				}
+				return
+			}
+			msg.Request = nil
+			if err = agentStream.Send(msg); err != nil {
+				return
+			}
+		}
+	}()
+
+	// Relay standard output, standard error, and the exit status from the agent to the client
+	for {
+		msg, err := agentStream.Recv()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				err = status.Error(codes.Aborted, "the traffic-agent ended the command without an exit status")
+			}
+			return err
+		}
+		if err = stream.Send(msg); err != nil {
+			return err
+		}
+		if msg.Error != "" {
+			dlog.Infof(ctx, "AUDIT: exec %q in %s.%s by %s failed: %s", rq.Command, rq.Name, rq.Namespace, client.Name, msg.Error)
+			return nil
+		}
+		if msg.Exited {
+			dlog.Infof(ctx, "AUDIT: exec %q in %s.%s by %s exited with status %d", rq.Command, rq.Name, rq.Namespace, client.Name, msg.ExitCode)
+			return nil
+		}
+	}
+}
+
+// WatchExecRequests lets an agent receive requests to run commands.
+func (m *Manager) WatchExecRequests(session *rpc.SessionInfo, stream rpc.Manager_WatchExecRequestsServer) error {
+	ctx := managerutil.WithSessionInfo(stream.Context(), session)
+	dlog.Debugf(ctx, "WatchExecRequests called")
+	sessionDone, err := m.state.SessionDone(session.GetSessionId())
+	if err != nil {
+		return err
+	}
+	rqCh := m.state.WatchAgentExecRequests(session.GetSessionId())
+	if rqCh == nil {
+		return status.Errorf(codes.NotFound, "Agent session %q not found", session.GetSessionId())
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-sessionDone:
+			return nil
+		case rq := <-rqCh:
+			if err := stream.Send(rq); err != nil {
+				dlog.Errorf(ctx, "WatchExecRequests.Send() failed: %v", err)
+				return nil
+			}
+		}
+	}
+}
+
+// AgentExec lets an agent hand over the stream of a command that it runs in response to an ExecRequest.
+// The call returns when the Exec call of the client is done with the stream.
+func (m *Manager) AgentExec(stream rpc.Manager_AgentExecServer) error {
+	msg, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	ctx := managerutil.WithSessionInfo(stream.Context(), msg.GetSession())
+	released, err := m.state.AttachAgentExec(msg.GetSession().GetSessionId(), msg.RequestId, stream)
+	if err != nil {
+		return err
+	}
+	select {
+	case <-released:
+	case <-ctx.Done():
+	}
+	return nil
+}
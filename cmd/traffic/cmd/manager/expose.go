@@ -0,0 +1,271 @@
+package manager
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	empty "google.golang.org/protobuf/types/known/emptypb"
+	core "k8s.io/api/core/v1"
+	errors2 "k8s.io/apimachinery/pkg/api/errors"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"github.com/datawire/dlib/dcontext"
+	"github.com/datawire/dlib/dlog"
+	rpc "github.com/telepresenceio/telepresence/rpc/v2/manager"
+	"github.com/telepresenceio/telepresence/v2/cmd/traffic/cmd/manager/managerutil"
+	"github.com/telepresenceio/telepresence/v2/pkg/ipproto"
+	"github.com/telepresenceio/telepresence/v2/pkg/k8sapi"
+	"github.com/telepresenceio/telepresence/v2/pkg/tunnel"
+)
+
+const (
+	exposedByAnnotation = "telepresence.getambassador.io/exposed-by"
+
+	// exposedLabel marks the Services and Endpoints that are created by Expose, so that the ones that
+	// outlive the traffic-manager can be found and removed when it restarts.
+	exposedLabel = "telepresence.getambassador.io/exposed"
+)
+
+// Expose creates a Service that relays the connections that it receives to a port on the workstation of
+// a client. The Service has no selector. Its endpoint is a port that the traffic-manager listens to, and
+// each connection accepted on that port is tunneled to the client. The Service is removed when Unexpose
+// is called or when the client session ends.
+func (m *Manager) Expose(ctx context.Context, rq *rpc.ExposeRequest) (*empty.Empty, error) {
+	ctx = managerutil.WithSessionInfo(ctx, rq.GetSession())
+	sessionID := rq.GetSession().GetSessionId()
+	client := m.state.GetClient(sessionID)
+	if client == nil {
+		return nil, status.Errorf(codes.NotFound, "Client session %q not found", sessionID)
+	}
+	env := managerutil.GetEnv(ctx)
+	if !env.ExposeEnabled {
+		return nil, status.Error(codes.PermissionDenied, "expose is not enabled in the traffic-manager, see the expose.enabled Helm value")
+	}
+	if !m.namespaceInScope(ctx, sessionID, rq.Namespace) {
+		return nil, status.Errorf(codes.PermissionDenied, "namespace %q is not managed by the traffic-manager or not mapped by this session", rq.Namespace)
+	}
+	if rq.Port <= 0 || rq.Port > 0xffff || rq.LocalPort <= 0 || rq.LocalPort > 0xffff {
+		return nil, status.Error(codes.InvalidArgument, "ports must be in the range 1-65535")
+	}
+	podIP := net.ParseIP(env.PodIP)
+	if podIP == nil {
+		return nil, status.Error(codes.FailedPrecondition, "the traffic-manager doesn't know its pod IP")
+	}
+	sessionDone, err := m.state.SessionDone(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	// The relay must outlive this call, so its context is derived from the manager's context.
+	rctx, cancel := context.WithCancel(managerutil.WithSessionInfo(m.ctx, rq.GetSession()))
+	if err = m.state.AddExposure(sessionID, rq.Name, rq.Namespace, cancel); err != nil {
+		cancel()
+		return nil, err
+	}
+	var lc net.ListenConfig
+	l, err := lc.Listen(rctx, "tcp", ":0")
+	if err != nil {
+		m.state.RemoveExposure(sessionID, rq.Name, rq.Namespace)
+		return nil, status.Errorf(codes.Internal, "failed to listen for connections to %s.%s: %v", rq.Name, rq.Namespace, err)
+	}
+	relayPort := int32(l.Addr().(*net.TCPAddr).Port)
+	if err = createExposeService(ctx, rq, podIP, relayPort, client.Name); err != nil {
+		m.state.RemoveExposure(sessionID, rq.Name, rq.Namespace)
+		_ = l.Close()
+		return nil, err
+	}
+	dlog.Infof(ctx, "AUDIT: service %s.%s port %d exposed by %s, relayed to its port %d", rq.Name, rq.Namespace, rq.Port, client.Name, rq.LocalPort)
+
+	go func() {
+		select {
+		case <-sessionDone:
+			m.state.RemoveExposure(sessionID, rq.Name, rq.Namespace)
+		case <-rctx.Done():
+		}
+		_ = l.Close()
+		ctx, cancel := context.WithTimeout(dcontext.WithoutCancel(rctx), 10*time.Second)
+		defer cancel()
+		deleteExposeService(ctx, rq.Name, rq.Namespace)
+		dlog.Infof(ctx, "AUDIT: service %s.%s exposed by %s removed", rq.Name, rq.Namespace, client.Name)
+	}()
+	go relayExposed(rctx, l, func(ctx context.Context, stream tunnel.Stream) error {
+		return m.state.TunnelToClient(ctx, sessionID, stream)
+	}, sessionID, uint16(rq.LocalPort), time.Duration(rq.RoundtripLatency), time.Duration(rq.DialTimeout))
+	return &empty.Empty{}, nil
+}
+
+// Unexpose removes a Service that was created by Expose.
+func (m *Manager) Unexpose(ctx context.Context, rq *rpc.UnexposeRequest) (*empty.Empty, error) {
+	ctx = managerutil.WithSessionInfo(ctx, rq.GetSession())
+	dlog.Debugf(ctx, "Unexpose called %s.%s", rq.Name, rq.Namespace)
+	if !m.state.RemoveExposure(rq.GetSession().GetSessionId(), rq.Name, rq.Namespace) {
+		return nil, status.Errorf(codes.NotFound, "service %s.%s isn't exposed by this client", rq.Name, rq.Namespace)
+	}
+	return &empty.Empty{}, nil
+}
+
+// relayExposed accepts connections on the given listener until it is closed, and relays each one of
+// them to the given port on the client's workstation through a stream that is passed to toClient.
+func relayExposed(
+	ctx context.Context,
+	l net.Listener,
+	toClient func(context.Context, tunnel.Stream) error,
+	sessionID string,
+	localPort uint16,
+	roundtripLatency, dialTimeout time.Duration,
+) {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if ctx.Err() == nil {
+				dlog.Errorf(ctx, "failed to accept exposed connection: %v", err)
+			}
+			return
+		}
+		go func() {
+			src := conn.RemoteAddr().(*net.TCPAddr)
+			id := tunnel.NewConnID(ipproto.TCP, src.IP, net.IPv4(127, 0, 0, 1), uint16(src.Port), localPort)
+			if err := relayExposedConn(ctx, conn, id, toClient, sessionID, roundtripLatency, dialTimeout); err != nil {
+				dlog.Errorf(ctx, "!! CONN %s, %v", id, err)
+				_ = conn.Close()
+			}
+		}()
+	}
+}
+
+func relayExposedConn(
+	ctx context.Context,
+	conn net.Conn,
+	id tunnel.ConnID,
+	toClient func(context.Context, tunnel.Stream) error,
+	sessionID string,
+	roundtripLatency, dialTimeout time.Duration,
+) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// The client side of the pipe is served by a server stream that is extended to the client, just
+	// as if it was created by a call to Tunnel.
+	clientEnd, serverEnd := tunnel.NewPipe(ctx)
+	go func() {
+		defer cancel()
+		stream, err := tunnel.NewServerStream(ctx, serverEnd)
+		if err != nil {
+			dlog.Errorf(ctx, "!! CONN %s, failed to connect stream: %v", id, err)
+			return
+		}
+		if err = toClient(ctx, stream); err != nil {
+			dlog.Errorf(ctx, "!! CONN %s, failed to tunnel to client: %v", id, err)
+		}
+	}()
+	stream, err := tunnel.NewClientStream(ctx, clientEnd, id, sessionID, roundtripLatency, dialTimeout)
+	if err != nil {
+		return err
+	}
+	d := tunnel.NewConnEndpoint(stream, conn)
+	d.Start(ctx)
+	<-d.Done()
+	return nil
+}
+
+func createExposeService(ctx context.Context, rq *rpc.ExposeRequest, podIP net.IP, relayPort int32, clientName string) error {
+	om := meta.ObjectMeta{
+		Name:      rq.Name,
+		Namespace: rq.Namespace,
+		Labels: map[string]string{
+			"app.kubernetes.io/created-by": "traffic-manager",
+			exposedLabel:                   "true",
+		},
+		Annotations: map[string]string{
+			exposedByAnnotation: clientName,
+		},
+	}
+	api := k8sapi.GetK8sInterface(ctx).CoreV1()
+	_, err := api.Services(rq.Namespace).Create(ctx, &core.Service{
+		ObjectMeta: om,
+		Spec: core.ServiceSpec{
+			Ports: []core.ServicePort{{
+				Protocol:   core.ProtocolTCP,
+				Port:       rq.Port,
+				TargetPort: intstr.FromInt(int(relayPort)),
+			}},
+		},
+	}, meta.CreateOptions{})
+	if err != nil {
+		if errors2.IsAlreadyExists(err) {
+			return status.Errorf(codes.AlreadyExists, "service %s.%s already exists", rq.Name, rq.Namespace)
+		}
+		return status.Errorf(codes.Internal, "failed to create service %s.%s: %v", rq.Name, rq.Namespace, err)
+	}
+	_, err = api.Endpoints(rq.Namespace).Create(ctx, &core.Endpoints{
+		ObjectMeta: om,
+		Subsets: []core.EndpointSubset{{
+			Addresses: []core.EndpointAddress{{IP: podIP.String()}},
+			Ports: []core.EndpointPort{{
+				Protocol: core.ProtocolTCP,
+				Port:     relayPort,
+			}},
+		}},
+	}, meta.CreateOptions{})
+	if err != nil {
+		_ = api.Services(rq.Namespace).Delete(ctx, rq.Name, meta.DeleteOptions{})
+		return status.Errorf(codes.Internal, "failed to create endpoints %s.%s: %v", rq.Name, rq.Namespace, err)
+	}
+	return nil
+}
+
+// deleteExposeService deletes the Service with the given name and namespace, and its Endpoints.
+func deleteExposeService(ctx context.Context, name, namespace string) {
+	api := k8sapi.GetK8sInterface(ctx).CoreV1()
+	if err := api.Services(namespace).Delete(ctx, name, meta.DeleteOptions{}); err != nil && !errors2.IsNotFound(err) {
+		dlog.Errorf(ctx, "failed to delete service %s.%s: %v", name, namespace, err)
+	}
+	if err := api.Endpoints(namespace).Delete(ctx, name, meta.DeleteOptions{}); err != nil && !errors2.IsNotFound(err) {
+		dlog.Errorf(ctx, "failed to delete endpoints %s.%s: %v", name, namespace, err)
+	}
+}
+
+// sweepExposed removes the Services and Endpoints that were created by Expose calls that were made to an
+// earlier instance of the traffic-manager. The client sessions that they belonged to ended with it, so
+// nothing relays their connections anymore.
+func sweepExposed(ctx context.Context) {
+	env := managerutil.GetEnv(ctx)
+	if !env.ExposeEnabled {
+		return
+	}
+	namespaces := env.GetManagedNamespaces()
+	if len(namespaces) == 0 {
+		namespaces = []string{""}
+	}
+	api := k8sapi.GetK8sInterface(ctx).CoreV1()
+	opts := meta.ListOptions{LabelSelector: exposedLabel}
+	for _, ns := range namespaces {
+		svcs, err := api.Services(ns).List(ctx, opts)
+		if err != nil {
+			dlog.Errorf(ctx, "failed to list exposed services: %v", err)
+			continue
+		}
+		for i := range svcs.Items {
+			svc := &svcs.Items[i]
+			deleteExposeService(ctx, svc.Name, svc.Namespace)
+			dlog.Infof(ctx, "AUDIT: orphaned service %s.%s exposed by %s removed", svc.Name, svc.Namespace, svc.Annotations[exposedByAnnotation])
+		}
+
+		// Endpoints remain when the deletion of their Service succeeded but their own deletion failed.
+		eps, err := api.Endpoints(ns).List(ctx, opts)
+		if err != nil {
+			dlog.Errorf(ctx, "failed to list exposed endpoints: %v", err)
+			continue
+		}
+		for i := range eps.Items {
+			ep := &eps.Items[i]
+			if err = api.Endpoints(ep.Namespace).Delete(ctx, ep.Name, meta.DeleteOptions{}); err != nil && !errors2.IsNotFound(err) {
+				dlog.Errorf(ctx, "failed to delete endpoints %s.%s: %v", ep.Name, ep.Namespace, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package manager
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	core "k8s.io/api/core/v1"
+	errors2 "k8s.io/apimachinery/pkg/api/errors"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/datawire/dlib/dlog"
+	"github.com/telepresenceio/telepresence/v2/cmd/traffic/cmd/manager/managerutil"
+	"github.com/telepresenceio/telepresence/v2/pkg/k8sapi"
+	"github.com/telepresenceio/telepresence/v2/pkg/tunnel"
+)
+
+func TestRelayExposed(t *testing.T) {
+	ctx, cancel := context.WithTimeout(dlog.NewTestContext(t, false), 10*time.Second)
+	defer cancel()
+
+	// The server on the client's workstation echoes what it receives
+	local, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer local.Close()
+	go func() {
+		for {
+			conn, err := local.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				_, _ = io.Copy(conn, conn)
+			}()
+		}
+	}()
+
+	// The client side of the tunnel dials the destination of the stream, just like the user daemon does
+	// when it receives a DialRequest.
+	toClient := func(ctx context.Context, stream tunnel.Stream) error {
+		d := tunnel.NewDialer(stream)
+		d.Start(ctx)
+		<-d.Done()
+		return nil
+	}
+	relay, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer relay.Close()
+	localPort := uint16(local.Addr().(*net.TCPAddr).Port)
+	go relayExposed(ctx, relay, toClient, "session-id", localPort, time.Second, 5*time.Second)
+
+	conn, err := net.Dial("tcp", relay.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, conn.SetDeadline(time.Now().Add(5*time.Second)))
+	_, err = conn.Write([]byte("hello"))
+	require.NoError(t, err)
+	buf := make([]byte, 5)
+	_, err = io.ReadFull(conn, buf)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(buf))
+}
+
+func TestSweepExposed(t *testing.T) {
+	ctx := dlog.NewTestContext(t, false)
+	exposed := meta.ObjectMeta{
+		Name:        "exposed",
+		Namespace:   "default",
+		Labels:      map[string]string{exposedLabel: "true"},
+		Annotations: map[string]string{exposedByAnnotation: "alice"},
+	}
+	other := meta.ObjectMeta{Name: "other", Namespace: "default"}
+	ki := fake.NewSimpleClientset(
+		&core.Service{ObjectMeta: exposed},
+		&core.Endpoints{ObjectMeta: exposed},
+		&core.Service{ObjectMeta: other},
+		&core.Endpoints{ObjectMeta: other},
+	)
+	ctx = k8sapi.WithK8sInterface(ctx, ki)
+	ctx = managerutil.WithEnv(ctx, &managerutil.Env{ExposeEnabled: true})
+	sweepExposed(ctx)
+
+	api := ki.CoreV1()
+	_, err := api.Services("default").Get(ctx, "exposed", meta.GetOptions{})
+	assert.True(t, errors2.IsNotFound(err))
+	_, err = api.Endpoints("default").Get(ctx, "exposed", meta.GetOptions{})
+	assert.True(t, errors2.IsNotFound(err))
+	_, err = api.Services("default").Get(ctx, "other", meta.GetOptions{})
+	assert.NoError(t, err)
+	_, err = api.Endpoints("default").Get(ctx, "other", meta.GetOptions{})
+	assert.NoError(t, err)
+}
@@ -3,7 +3,6 @@ package cluster
 import (
 	"context"
 	"net"
-	"regexp"
 	"strings"
 	"sync"
 
@@ -32,6 +31,9 @@ type Info interface {
 	// GetClusterID returns the ClusterID
 	GetClusterID() string
 
+	// GetClusterInfo returns a snapshot of the ClusterInfo
+	GetClusterInfo() *rpc.ClusterInfo
+
 	// GetTrafficManagerPods acquires all pods that have `traffic-manager` in
 	// their name
 	GetTrafficManagerPods(context.Context) ([]*corev1.Pod, error)
@@ -50,6 +52,12 @@ type info struct {
 	rpc.ClusterInfo
 	ciSubs *clusterInfoSubscribers
 
+	// subnetsLock protects the pod and service subnets of the ClusterInfo, which are updated by watchers
+	subnetsLock sync.Mutex
+
+	// staticServiceSubnets are the service subnets that were discovered on startup
+	staticServiceSubnets subnet.Set
+
 	// clusterID is the UID of the default namespace
 	clusterID string
 }
@@ -143,36 +151,19 @@ func NewInfo(ctx context.Context) Info {
 	// check the error message for the correct range as suggested tin the second answer here:
 	//   https://stackoverflow.com/questions/44190607/how-do-you-find-the-cluster-service-cidr-of-a-kubernetes-cluster
 	// This requires an additional permission to create a service, which the traffic-manager
-	// should have.
-	svc := corev1.Service{
-		TypeMeta: metav1.TypeMeta{
-			Kind: "Service",
-		},
-		ObjectMeta: metav1.ObjectMeta{
-			Namespace: env.ManagerNamespace,
-			Name:      "t2-tst-dummy",
-		},
-		Spec: corev1.ServiceSpec{
-			Ports:     []corev1.ServicePort{{Port: 443}},
-			ClusterIP: "1.1.1.1",
-		},
-	}
-	if _, err = client.Services(env.ManagerNamespace).Create(ctx, &svc, metav1.CreateOptions{}); err != nil {
-		svcCIDRrx := regexp.MustCompile(`range of valid IPs is (.*)$`)
-		if match := svcCIDRrx.FindStringSubmatch(err.Error()); match != nil {
-			var cidr *net.IPNet
-			if _, cidr, err = net.ParseCIDR(match[1]); err != nil {
-				dlog.Errorf(ctx, "unable to parse service CIDR %q", match[1])
-			} else {
-				dlog.Infof(ctx, "Extracting service subnet %v from create service error message", cidr)
-				oi.ServiceSubnet = iputil.IPNetToRPC(cidr)
-			}
-		} else {
-			dlog.Errorf(ctx, "unable to extract service subnet from error message %q", err.Error())
+	// should have. One attempt is made for each IP family, because a dual-stack cluster
+	// has one service subnet for each.
+	svcSubnets := make(subnet.Set)
+	for _, clusterIP := range []string{"1.1.1.1", "2001:db8::1"} {
+		for _, cidr := range probeServiceSubnets(ctx, client, env.ManagerNamespace, clusterIP) {
+			svcSubnets.Add(cidr)
 		}
 	}
+	if len(svcSubnets) == 0 {
+		dlog.Warn(ctx, "Unable to extract service subnets from create service error messages")
+	}
 
-	if oi.ServiceSubnet == nil && oi.KubeDnsIp != nil {
+	if len(svcSubnets) == 0 && oi.KubeDnsIp != nil {
 		// Using a "kubectl cluster-info dump" or scanning all services generates a lot of unwanted traffic
 		// and would quite possibly also require elevated permissions, so instead, we derive the service subnet
 		// from the kubeDNS IP. This is cheating but a cluster may only have one service subnet and the mask is
@@ -181,7 +172,14 @@ func NewInfo(ctx context.Context) Info {
 		bits := len(oi.KubeDnsIp) * 8
 		ones := bits / 2
 		mask := net.CIDRMask(ones, bits) // will yield a 16 bit mask on IPv4 and 64 bit mask on IPv6.
-		oi.ServiceSubnet = &rpc.IPNet{Ip: net.IP(oi.KubeDnsIp).Mask(mask), Mask: int32(ones)}
+		svcSubnets.Add(&net.IPNet{IP: net.IP(oi.KubeDnsIp).Mask(mask), Mask: mask})
+	}
+	oi.staticServiceSubnets = svcSubnets
+	oi.setServiceSubnets(svcSubnets)
+
+	// Clusters that support ServiceCIDR objects can have their service ranges expanded at any time
+	if gv := serviceCIDRGroupVersion(ctx); gv != "" {
+		go oi.watchServiceCIDRs(ctx, gv)
 	}
 
 	podCIDRStrategy := env.PodCIDRStrategy
@@ -296,7 +294,9 @@ func (oi *info) setSubnetsFromEnv(ctx context.Context) bool {
 	}
 	if allOK {
 		dlog.Infof(ctx, "Using subnets from POD_CIDRS environment variable")
+		oi.subnetsLock.Lock()
 		oi.PodSubnets = toRPCSubnets(subnets)
+		oi.subnetsLock.Unlock()
 		oi.ciSubs.notify(ctx, oi.clusterInfo())
 	} else {
 		dlog.Errorf(ctx, "unable to parse subnets from POD_CIDRS value %q", pcEnv)
@@ -314,25 +314,47 @@ func (oi *info) GetClusterID() string {
 	return oi.clusterID
 }
 
+func (oi *info) GetClusterInfo() *rpc.ClusterInfo {
+	return oi.clusterInfo()
+}
+
 func (oi *info) clusterInfo() *rpc.ClusterInfo {
+	oi.subnetsLock.Lock()
+	defer oi.subnetsLock.Unlock()
 	ci := &rpc.ClusterInfo{
-		KubeDnsIp:     oi.KubeDnsIp,
-		ServiceSubnet: oi.ServiceSubnet,
-		PodSubnets:    make([]*rpc.IPNet, len(oi.PodSubnets)),
-		ClusterDomain: oi.ClusterDomain,
-		ManagerPodIp:  oi.ManagerPodIp,
+		KubeDnsIp:      oi.KubeDnsIp,
+		ServiceSubnet:  oi.ServiceSubnet,
+		ServiceSubnets: make([]*rpc.IPNet, len(oi.ServiceSubnets)),
+		PodSubnets:     make([]*rpc.IPNet, len(oi.PodSubnets)),
+		ClusterDomain:  oi.ClusterDomain,
+		ManagerPodIp:   oi.ManagerPodIp,
 	}
+	copy(ci.ServiceSubnets, oi.ServiceSubnets)
 	copy(ci.PodSubnets, oi.PodSubnets)
 	return ci
 }
 
 func (oi *info) watchSubnets(ctx context.Context, retriever subnetRetriever) {
 	retriever.changeNotifier(ctx, func(subnets subnet.Set) {
+		oi.subnetsLock.Lock()
 		oi.PodSubnets = toRPCSubnets(subnets)
+		oi.subnetsLock.Unlock()
 		oi.ciSubs.notify(ctx, oi.clusterInfo())
 	})
 }
 
+// setServiceSubnets sets the service subnets of the ClusterInfo. The ServiceSubnet is set to the first of them.
+func (oi *info) setServiceSubnets(subnets subnet.Set) {
+	oi.subnetsLock.Lock()
+	defer oi.subnetsLock.Unlock()
+	oi.ServiceSubnets = toRPCSubnets(subnets)
+	if len(oi.ServiceSubnets) > 0 {
+		oi.ServiceSubnet = oi.ServiceSubnets[0]
+	} else {
+		oi.ServiceSubnet = nil
+	}
+}
+
 func toRPCSubnets(cidrMap subnet.Set) []*rpc.IPNet {
 	subnets := cidrMap.AppendSortedTo(nil)
 	rpcSubnets := make([]*rpc.IPNet, len(subnets))
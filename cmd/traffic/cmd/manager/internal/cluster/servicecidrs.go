@@ -0,0 +1,149 @@
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"regexp"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+
+	"github.com/datawire/dlib/dlog"
+	"github.com/datawire/dlib/dtime"
+	"github.com/telepresenceio/telepresence/v2/pkg/k8sapi"
+	"github.com/telepresenceio/telepresence/v2/pkg/subnet"
+)
+
+// serviceCIDRPollInterval is how often the ServiceCIDR objects are listed.
+const serviceCIDRPollInterval = 30 * time.Second
+
+var svcCIDRrx = regexp.MustCompile(`range of valid IPs is (.*)$`) //nolint:gochecknoglobals // constant
+
+// probeServiceSubnets attempts to create a service with the given ClusterIP, which is assumed to be outside
+// the service subnets of its IP family, and returns the subnets that the error message declares as valid.
+func probeServiceSubnets(ctx context.Context, client typedcorev1.CoreV1Interface, namespace, clusterIP string) []*net.IPNet {
+	svc := corev1.Service{
+		TypeMeta: metav1.TypeMeta{
+			Kind: "Service",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      "t2-tst-dummy",
+		},
+		Spec: corev1.ServiceSpec{
+			Ports:     []corev1.ServicePort{{Port: 443}},
+			ClusterIP: clusterIP,
+		},
+	}
+	_, err := client.Services(namespace).Create(ctx, &svc, metav1.CreateOptions{})
+	if err == nil {
+		// Very unlikely, but the IP was in range after all.
+		dlog.Warnf(ctx, "service with ClusterIP %s was unexpectedly created", clusterIP)
+		_ = client.Services(namespace).Delete(ctx, svc.Name, metav1.DeleteOptions{})
+		return nil
+	}
+	match := svcCIDRrx.FindStringSubmatch(err.Error())
+	if match == nil {
+		// An IP family that isn't configured in the cluster yields a different error.
+		dlog.Debugf(ctx, "unable to extract service subnet from error message %q", err.Error())
+		return nil
+	}
+	var cidrs []*net.IPNet
+	for _, s := range strings.FieldsFunc(match[1], func(r rune) bool { return r == ',' || r == ' ' }) {
+		if _, cidr, err := net.ParseCIDR(s); err != nil {
+			dlog.Errorf(ctx, "unable to parse service CIDR %q", s)
+		} else {
+			dlog.Infof(ctx, "Extracting service subnet %v from create service error message", cidr)
+			cidrs = append(cidrs, cidr)
+		}
+	}
+	return cidrs
+}
+
+// serviceCIDRGroupVersion returns the group version of the ServiceCIDR resource, or an empty string
+// when the cluster doesn't have that resource.
+func serviceCIDRGroupVersion(ctx context.Context) string {
+	dc := k8sapi.GetK8sInterface(ctx).Discovery()
+	for _, gv := range []string{"networking.k8s.io/v1", "networking.k8s.io/v1beta1"} {
+		rl, err := dc.ServerResourcesForGroupVersion(gv)
+		if err != nil {
+			continue
+		}
+		for _, r := range rl.APIResources {
+			if r.Name == "servicecidrs" {
+				return gv
+			}
+		}
+	}
+	return ""
+}
+
+// serviceCIDRList is the part of a list of ServiceCIDR objects that the traffic-manager uses. The
+// client-go version that the traffic-manager uses doesn't know about them.
+type serviceCIDRList struct {
+	Items []struct {
+		Spec struct {
+			CIDRs []string `json:"cidrs"`
+		} `json:"spec"`
+	} `json:"items"`
+}
+
+func listServiceCIDRs(ctx context.Context, gv string) (subnet.Set, error) {
+	data, err := k8sapi.GetK8sInterface(ctx).Discovery().RESTClient().Get().AbsPath("/apis", gv, "servicecidrs").DoRaw(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var list serviceCIDRList
+	if err = json.Unmarshal(data, &list); err != nil {
+		return nil, err
+	}
+	subnets := make(subnet.Set)
+	for _, item := range list.Items {
+		for _, s := range item.Spec.CIDRs {
+			if _, cidr, err := net.ParseCIDR(s); err == nil {
+				subnets.Add(cidr)
+			}
+		}
+	}
+	return subnets, nil
+}
+
+// watchServiceCIDRs lists the ServiceCIDR objects periodically and updates the service subnets of the
+// ClusterInfo when their CIDRs change. The service subnets discovered on startup are always retained.
+func (oi *info) watchServiceCIDRs(ctx context.Context, gv string) {
+	dlog.Infof(ctx, "Watching service subnets of ServiceCIDR objects")
+	var current subnet.Set
+	for {
+		cidrs, err := listServiceCIDRs(ctx, gv)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			if k8serrors.IsForbidden(err) {
+				// A namespaced traffic-manager isn't permitted to list cluster scoped resources.
+				dlog.Infof(ctx, "Not permitted to list ServiceCIDR objects: %v", err)
+				return
+			}
+			dlog.Errorf(ctx, "unable to list ServiceCIDR objects: %v", err)
+		} else {
+			for _, sn := range oi.staticServiceSubnets.AppendSortedTo(nil) {
+				cidrs.Add(sn)
+			}
+			if !cidrs.Equals(current) {
+				current = cidrs
+				dlog.Infof(ctx, "Using service subnets %s", cidrs)
+				oi.setServiceSubnets(cidrs)
+				oi.ciSubs.notify(ctx, oi.clusterInfo())
+			}
+		}
+		dtime.SleepWithContext(ctx, serviceCIDRPollInterval)
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/datawire/dlib/dlog"
+	"github.com/telepresenceio/telepresence/v2/cmd/traffic/cmd/manager/managerutil"
+	"github.com/telepresenceio/telepresence/v2/pkg/iputil"
+	"github.com/telepresenceio/telepresence/v2/pkg/k8sapi"
+)
+
+func TestNewInfo_ServiceSubnets(t *testing.T) {
+	env := managerutil.Env{
+		ManagerNamespace: "ambassador",
+		PodCIDRStrategy:  "environment",
+		PodCIDRs:         "10.244.0.0/16",
+	}
+
+	newInfo := func(t *testing.T, ranges map[string]string) *info {
+		cs := fake.NewSimpleClientset()
+		cs.PrependReactor("create", "services", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			svc := action.(k8stesting.CreateAction).GetObject().(*v1.Service)
+			family := "IPv4"
+			if net.ParseIP(svc.Spec.ClusterIP).To4() == nil {
+				family = "IPv6"
+			}
+			if r, ok := ranges[family]; ok {
+				return true, nil, fmt.Errorf(`Service "t2-tst-dummy" is invalid: spec.clusterIPs: Invalid value: []string{%q}: `+
+					`failed to allocate IP %s: provided IP is not in the valid range. The range of valid IPs is %s`,
+					svc.Spec.ClusterIP, svc.Spec.ClusterIP, r)
+			}
+			return true, nil, errors.New(`Service "t2-tst-dummy" is invalid: spec.clusterIPs: Invalid value: not configured on this cluster`)
+		})
+		ctx := k8sapi.WithK8sInterface(dlog.NewTestContext(t, false), cs)
+		ctx = managerutil.WithEnv(ctx, &env)
+		return NewInfo(ctx).(*info)
+	}
+
+	subnetStrings := func(oi *info) []string {
+		ci := oi.clusterInfo()
+		ss := make([]string, len(ci.ServiceSubnets))
+		for i, sn := range ci.ServiceSubnets {
+			ss[i] = iputil.IPNetFromRPC(sn).String()
+		}
+		return ss
+	}
+
+	t.Run("single stack", func(t *testing.T) {
+		oi := newInfo(t, map[string]string{"IPv4": "10.96.0.0/12"})
+		assert.Equal(t, []string{"10.96.0.0/12"}, subnetStrings(oi))
+		assert.Equal(t, "10.96.0.0/12", iputil.IPNetFromRPC(oi.clusterInfo().ServiceSubnet).String())
+	})
+
+	t.Run("dual stack", func(t *testing.T) {
+		oi := newInfo(t, map[string]string{"IPv4": "10.96.0.0/12", "IPv6": "fd00:10:96::/112"})
+		assert.ElementsMatch(t, []string{"10.96.0.0/12", "fd00:10:96::/112"}, subnetStrings(oi))
+		assert.NotNil(t, oi.clusterInfo().ServiceSubnet)
+	})
+
+	t.Run("multiple ranges", func(t *testing.T) {
+		oi := newInfo(t, map[string]string{"IPv4": "10.96.0.0/12, 10.112.0.0/16"})
+		assert.ElementsMatch(t, []string{"10.96.0.0/12", "10.112.0.0/16"}, subnetStrings(oi))
+	})
+}
+
+func TestServiceCIDRGroupVersion(t *testing.T) {
+	cs := fake.NewSimpleClientset()
+	ctx := k8sapi.WithK8sInterface(context.Background(), cs)
+	require.Equal(t, "", serviceCIDRGroupVersion(ctx))
+
+	cs.Discovery().(*fakediscovery.FakeDiscovery).Resources = []*metav1.APIResourceList{
+		{
+			GroupVersion: "networking.k8s.io/v1beta1",
+			APIResources: []metav1.APIResource{{Name: "servicecidrs"}, {Name: "ipaddresses"}},
+		},
+		{
+			GroupVersion: "networking.k8s.io/v1",
+			APIResources: []metav1.APIResource{{Name: "ingresses"}},
+		},
+	}
+	require.Equal(t, "networking.k8s.io/v1beta1", serviceCIDRGroupVersion(ctx))
+}
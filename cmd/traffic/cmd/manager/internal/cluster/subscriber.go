@@ -91,15 +91,19 @@ func (ss *clusterInfoSubscribers) subscriberLoop(ctx context.Context, rec interf
 }
 
 func clusterInfoEqual(a, b *rpc.ClusterInfo) bool {
-	if len(a.PodSubnets) != len(b.PodSubnets) ||
-		a.ServiceSubnet != b.ServiceSubnet ||
-		a.ClusterDomain != b.ClusterDomain ||
-		!net.IP(a.KubeDnsIp).Equal(b.KubeDnsIp) {
+	return a.ClusterDomain == b.ClusterDomain &&
+		net.IP(a.KubeDnsIp).Equal(b.KubeDnsIp) &&
+		subnetsEqual(a.ServiceSubnets, b.ServiceSubnets) &&
+		subnetsEqual(a.PodSubnets, b.PodSubnets)
+}
+
+func subnetsEqual(a, b []*rpc.IPNet) bool {
+	if len(a) != len(b) {
 		return false
 	}
-	for i, aps := range a.PodSubnets {
-		bps := b.PodSubnets[i]
-		if !net.IP(aps.Ip).Equal(bps.Ip) || aps.Mask != bps.Mask {
+	for i, as := range a {
+		bs := b[i]
+		if !net.IP(as.Ip).Equal(bs.Ip) || as.Mask != bs.Mask {
 			return false
 		}
 	}
@@ -255,7 +255,7 @@ func addAgentVolumes(pod *core.Pod, ag *agentconfig.Sidecar, patches patchOps) p
 			return patches
 		}
 	}
-	avs := agentconfig.AgentVolumes(ag.AgentName)
+	avs := agentconfig.AgentVolumes(ag.AgentName, ag.TLSSecret)
 	if len(avs) == 0 {
 		return patches
 	}
@@ -0,0 +1,110 @@
+package state
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	rpc "github.com/telepresenceio/telepresence/rpc/v2/manager"
+)
+
+// agentExecAttachTimeout is the time to wait for an agent to attach the stream of a command that
+// it has accepted to run.
+const agentExecAttachTimeout = 10 * time.Second
+
+// ExecStream is the agent's end of a command that runs in the agent.
+type ExecStream interface {
+	Send(*rpc.ExecMessage) error
+	Recv() (*rpc.ExecMessage, error)
+}
+
+type agentExecAttachment struct {
+	stream   ExecStream
+	released chan struct{}
+}
+
+// StartAgentExec sends the given request to the agent session and waits for the agent to attach the
+// stream of the command. The returned function must be called when the stream is no longer used.
+func (s *State) StartAgentExec(ctx context.Context, agentSessionID string, request *rpc.ExecRequest) (ExecStream, func(), error) {
+	s.mu.Lock()
+	as, ok := s.sessions[agentSessionID].(*agentSessionState)
+	if !ok {
+		s.mu.Unlock()
+		return nil, nil, status.Errorf(codes.NotFound, "agent session %q not found", agentSessionID)
+	}
+	waiter := make(chan agentExecAttachment, 1)
+	as.execWaiters[request.Id] = waiter
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		delete(as.execWaiters, request.Id)
+		s.mu.Unlock()
+		select {
+		case a := <-waiter:
+			// Attached after we gave up
+			close(a.released)
+		default:
+		}
+	}()
+
+	tc, cancel := context.WithTimeout(ctx, agentRequestTimeout)
+	defer cancel()
+	select {
+	case as.execRequests <- request:
+	case <-as.Done():
+		return nil, nil, status.Error(codes.Canceled, "session cancelled")
+	case <-tc.Done():
+		if ctx.Err() != nil {
+			return nil, nil, ctx.Err()
+		}
+		return nil, nil, status.Errorf(codes.Unavailable, "traffic-agent %s.%s doesn't run commands", as.agent.Name, as.agent.Namespace)
+	}
+
+	tc, cancel = context.WithTimeout(ctx, agentExecAttachTimeout)
+	defer cancel()
+	select {
+	case a := <-waiter:
+		return a.stream, func() { close(a.released) }, nil
+	case <-as.Done():
+		return nil, nil, status.Error(codes.Canceled, "session cancelled")
+	case <-tc.Done():
+		if ctx.Err() != nil {
+			return nil, nil, ctx.Err()
+		}
+		return nil, nil, status.Errorf(codes.DeadlineExceeded, "traffic-agent %s.%s didn't start the command", as.agent.Name, as.agent.Namespace)
+	}
+}
+
+// AttachAgentExec hands the agent's stream of a command to the StartAgentExec call that waits for it,
+// and returns a channel that is closed when the stream is no longer used.
+func (s *State) AttachAgentExec(agentSessionID, requestID string, stream ExecStream) (<-chan struct{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var waiter chan agentExecAttachment
+	if as, ok := s.sessions[agentSessionID].(*agentSessionState); ok {
+		waiter = as.execWaiters[requestID]
+		delete(as.execWaiters, requestID)
+	}
+	if waiter == nil {
+		return nil, status.Errorf(codes.NotFound, "no one is waiting for exec request %q", requestID)
+	}
+	// The waiter is buffered and removed, so this never blocks. Sending while the lock is held
+	// guarantees that StartAgentExec finds the attachment when it gives up.
+	released := make(chan struct{})
+	waiter <- agentExecAttachment{stream: stream, released: released}
+	return released, nil
+}
+
+// WatchAgentExecRequests returns the channel that receives the requests to run commands that are
+// sent to the given agent session.
+func (s *State) WatchAgentExecRequests(agentSessionID string) <-chan *rpc.ExecRequest {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if as, ok := s.sessions[agentSessionID].(*agentSessionState); ok {
+		return as.execRequests
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package state_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	rpc "github.com/telepresenceio/telepresence/rpc/v2/manager"
+	manager "github.com/telepresenceio/telepresence/v2/cmd/traffic/cmd/manager/internal/state"
+	testdata "github.com/telepresenceio/telepresence/v2/cmd/traffic/cmd/manager/internal/test"
+)
+
+type fakeExecStream struct {
+	ch chan *rpc.ExecMessage
+}
+
+func (s *fakeExecStream) Send(msg *rpc.ExecMessage) error {
+	s.ch <- msg
+	return nil
+}
+
+func (s *fakeExecStream) Recv() (*rpc.ExecMessage, error) {
+	return <-s.ch, nil
+}
+
+func TestAgentExec(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	clock := &FakeClock{}
+	state := manager.NewState(ctx)
+	agentID := state.AddAgent(testdata.GetTestAgents(t)["hello"], clock.Now())
+
+	// The agent's side: receive the request and attach its stream
+	rqCh := state.WatchAgentExecRequests(agentID)
+	require.NotNil(t, rqCh)
+	agentStream := &fakeExecStream{ch: make(chan *rpc.ExecMessage, 1)}
+	released := make(chan (<-chan struct{}), 1)
+	go func() {
+		rq := <-rqCh
+		r, err := state.AttachAgentExec(agentID, rq.Id, agentStream)
+		assert.NoError(t, err)
+		released <- r
+	}()
+
+	stream, release, err := state.StartAgentExec(ctx, agentID, &rpc.ExecRequest{Id: "rq1", Command: []string{"ls"}})
+	require.NoError(t, err)
+	require.NoError(t, stream.Send(&rpc.ExecMessage{Stdin: []byte("hello")}))
+	msg, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, []byte("hello"), msg.Stdin)
+
+	r := <-released
+	select {
+	case <-r:
+		t.Fatal("stream released before it was done")
+	default:
+	}
+	release()
+	select {
+	case <-r:
+	case <-ctx.Done():
+		t.Fatal("stream was never released")
+	}
+
+	// No one waits for the request anymore
+	_, err = state.AttachAgentExec(agentID, "rq1", agentStream)
+	assert.Error(t, err)
+
+	// Unknown sessions are reported
+	_, _, err = state.StartAgentExec(ctx, "nope", &rpc.ExecRequest{Id: "rq2"})
+	assert.Error(t, err)
+}
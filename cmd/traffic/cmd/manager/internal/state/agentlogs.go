@@ -0,0 +1,114 @@
+package state
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	rpc "github.com/telepresenceio/telepresence/rpc/v2/manager"
+)
+
+// agentRequestTimeout is the time to wait for an agent to accept a request for its log output or
+// for running a command. Agents that are older than the manager never accept them.
+const agentRequestTimeout = 5 * time.Second
+
+// agentLogStream connects the receiver of an agent's log output with the call that the agent
+// sends it through.
+type agentLogStream struct {
+	entries chan []*rpc.LogEntry // closed by the agent's call when it ends
+	done    chan struct{}        // closed when the output is no longer wanted
+}
+
+// StartAgentLogs sends the given request to the agent session and returns a channel that
+// receives the log output that the agent sends in response. The channel is closed when the
+// agent stops sending. EndAgentLogs must be called when the output is no longer wanted.
+func (s *State) StartAgentLogs(ctx context.Context, agentSessionID string, request *rpc.AgentLogsRequest) (<-chan []*rpc.LogEntry, error) {
+	s.mu.Lock()
+	as, ok := s.sessions[agentSessionID].(*agentSessionState)
+	if !ok {
+		s.mu.Unlock()
+		return nil, status.Errorf(codes.NotFound, "agent session %q not found", agentSessionID)
+	}
+	ls := &agentLogStream{
+		entries: make(chan []*rpc.LogEntry),
+		done:    make(chan struct{}),
+	}
+	as.logStreams[request.Id] = ls
+	s.mu.Unlock()
+
+	tc, cancel := context.WithTimeout(ctx, agentRequestTimeout)
+	defer cancel()
+	select {
+	case as.logRequests <- request:
+		return ls.entries, nil
+	case <-as.Done():
+		err := status.Error(codes.Canceled, "session cancelled")
+		s.EndAgentLogs(agentSessionID, request.Id)
+		return nil, err
+	case <-tc.Done():
+		s.EndAgentLogs(agentSessionID, request.Id)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, status.Errorf(codes.Unavailable, "traffic-agent %s.%s doesn't stream its logs", as.agent.Name, as.agent.Namespace)
+	}
+}
+
+// EndAgentLogs tells the agent's call that the log output for the given request is no longer wanted.
+func (s *State) EndAgentLogs(agentSessionID, requestID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if as, ok := s.sessions[agentSessionID].(*agentSessionState); ok {
+		if ls, ok := as.logStreams[requestID]; ok {
+			delete(as.logStreams, requestID)
+			close(ls.done)
+		}
+	}
+}
+
+// PostAgentLogs passes log output from an agent to the receiver of the request that it responds to.
+// An error is returned when the output is no longer wanted.
+func (s *State) PostAgentLogs(ctx context.Context, response *rpc.AgentLogsResponse) error {
+	ls := s.agentLogStream(response.GetSession().GetSessionId(), response.RequestId)
+	if ls == nil {
+		return status.Errorf(codes.NotFound, "no receiver for logs request %q", response.RequestId)
+	}
+	select {
+	case ls.entries <- response.Entries:
+		return nil
+	case <-ls.done:
+		return status.Error(codes.Canceled, "logs are no longer wanted")
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// AgentLogsDone closes the channel returned by StartAgentLogs. It must be called by the agent's call
+// when it ends, and never while a PostAgentLogs with the same request is in progress.
+func (s *State) AgentLogsDone(agentSessionID, requestID string) {
+	if ls := s.agentLogStream(agentSessionID, requestID); ls != nil {
+		close(ls.entries)
+	}
+}
+
+func (s *State) agentLogStream(agentSessionID, requestID string) *agentLogStream {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if as, ok := s.sessions[agentSessionID].(*agentSessionState); ok {
+		return as.logStreams[requestID]
+	}
+	return nil
+}
+
+// WatchAgentLogsRequests returns the channel that receives the requests for log output that are
+// sent to the given agent session.
+func (s *State) WatchAgentLogsRequests(agentSessionID string) <-chan *rpc.AgentLogsRequest {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if as, ok := s.sessions[agentSessionID].(*agentSessionState); ok {
+		return as.logRequests
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package state_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	rpc "github.com/telepresenceio/telepresence/rpc/v2/manager"
+	manager "github.com/telepresenceio/telepresence/v2/cmd/traffic/cmd/manager/internal/state"
+	testdata "github.com/telepresenceio/telepresence/v2/cmd/traffic/cmd/manager/internal/test"
+)
+
+func TestAgentLogs(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	clock := &FakeClock{}
+	state := manager.NewState(ctx)
+	agentID := state.AddAgent(testdata.GetTestAgents(t)["hello"], clock.Now())
+	session := &rpc.SessionInfo{SessionId: agentID}
+
+	// The agent's side: receive the request and respond with two messages
+	rqCh := state.WatchAgentLogsRequests(agentID)
+	require.NotNil(t, rqCh)
+	agentDone := make(chan error, 1)
+	go func() {
+		rq := <-rqCh
+		defer state.AgentLogsDone(agentID, rq.Id)
+		for _, text := range []string{"one", "two"} {
+			if err := state.PostAgentLogs(ctx, &rpc.AgentLogsResponse{
+				Session:   session,
+				RequestId: rq.Id,
+				Entries:   []*rpc.LogEntry{{Text: text}},
+			}); err != nil {
+				agentDone <- err
+				return
+			}
+		}
+		agentDone <- nil
+	}()
+
+	ch, err := state.StartAgentLogs(ctx, agentID, &rpc.AgentLogsRequest{Id: "rq1"})
+	require.NoError(t, err)
+	var texts []string
+	for es := range ch {
+		for _, e := range es {
+			texts = append(texts, e.Text)
+		}
+	}
+	state.EndAgentLogs(agentID, "rq1")
+	require.NoError(t, <-agentDone)
+	assert.Equal(t, []string{"one", "two"}, texts)
+
+	// Output is refused once the receiver is gone
+	assert.Error(t, state.PostAgentLogs(ctx, &rpc.AgentLogsResponse{Session: session, RequestId: "rq1"}))
+
+	// Unknown sessions are reported
+	_, err = state.StartAgentLogs(ctx, "nope", &rpc.AgentLogsRequest{Id: "rq2"})
+	assert.Error(t, err)
+}
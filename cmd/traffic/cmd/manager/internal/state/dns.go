@@ -0,0 +1,144 @@
+package state
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/miekg/dns"
+	"google.golang.org/protobuf/proto"
+
+	rpc "github.com/telepresenceio/telepresence/rpc/v2/manager"
+	"github.com/telepresenceio/telepresence/v2/pkg/dnsproxy"
+)
+
+// AgentsLookupDNS will send the given request to all agents currently intercepted by the client identified with
+// the clientSessionID, it will then wait for results to arrive and return the best of them together with a count
+// of how many agents that replied. An answer that contains records is better than an empty answer, and an empty
+// answer is better than NXDOMAIN.
+func (s *State) AgentsLookupDNS(ctx context.Context, clientSessionID string, request *rpc.DNSRequest) (*rpc.DNSResponse, int, error) {
+	iceptAgentIDs := s.getAgentsInterceptedByClient(clientSessionID)
+	iceptCount := len(iceptAgentIDs)
+	if iceptCount == 0 {
+		return nil, 0, nil
+	}
+
+	rsMu := sync.Mutex{} // prevent concurrent updates of the best response
+	agentTimeout, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+
+	var best *rpc.DNSResponse
+	count := 0
+	wg := sync.WaitGroup{}
+	wg.Add(iceptCount)
+	for _, agentSessionID := range iceptAgentIDs {
+		go func(agentSessionID string) {
+			defer wg.Done()
+			id, rsCh := s.startLookupDNS(agentSessionID, request)
+			if rsCh == nil {
+				return
+			}
+			defer s.endLookupDNS(agentSessionID, id)
+			select {
+			case <-agentTimeout.Done():
+				return
+			case rs := <-rsCh:
+				if rs == nil {
+					// Channel closed
+					return
+				}
+				rsMu.Lock()
+				count++
+				if best == nil || dnsResponseRank(rs) > dnsResponseRank(best) {
+					best = rs
+				}
+				rsMu.Unlock()
+			}
+		}(agentSessionID)
+	}
+	wg.Wait() // wait for timeout or that all agents have responded
+	return best, count, nil
+}
+
+func dnsResponseRank(r *rpc.DNSResponse) int {
+	if r.RCode != dns.RcodeSuccess {
+		return 0
+	}
+	if sections, err := dnsproxy.FromPacked(r.Rrs); err != nil || len(sections.Answer) == 0 {
+		return 1
+	}
+	return 2
+}
+
+// PostLookupDNSResponse receives DNS responses from an agent and places them in the channel
+// that corresponds to the DNS request. Responses to requests that are no longer waited for
+// are discarded.
+func (s *State) PostLookupDNSResponse(response *rpc.DNSAgentResponse) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if as, ok := s.sessions[response.Session.SessionId].(*agentSessionState); ok {
+		if rch, ok := as.dnsResponses[response.Request.GetId()]; ok {
+			// The channel has room for one response and there's one waiter per request ID,
+			// so this never blocks while the lock is held.
+			select {
+			case rch <- response.Response:
+			default:
+			}
+		}
+	}
+}
+
+// startLookupDNS sends the given request to the agent of the given session. It returns the ID that
+// the request was given, and the channel that the response is delivered on, or nil if the session
+// isn't an agent session.
+func (s *State) startLookupDNS(agentSessionID string, request *rpc.DNSRequest) (string, <-chan *rpc.DNSResponse) {
+	// Each waiter has its own ID, so that concurrent requests for the same name don't share a
+	// channel.
+	request = proto.Clone(request).(*rpc.DNSRequest)
+	request.Id = uuid.New().String()
+	rch := make(chan *rpc.DNSResponse, 1)
+	s.mu.Lock()
+	as, ok := s.sessions[agentSessionID].(*agentSessionState)
+	if ok {
+		as.dnsResponses[request.Id] = rch
+	}
+	s.mu.Unlock()
+	if !ok {
+		return "", nil
+	}
+
+	// the as.dnsRequests channel may be closed at this point, so guard for panic
+	func() {
+		defer func() {
+			if r := recover(); r != nil {
+				s.endLookupDNS(agentSessionID, request.Id)
+			}
+		}()
+		as.dnsRequests <- request
+	}()
+	return request.Id, rch
+}
+
+// endLookupDNS ends the wait for the response to the request with the given ID.
+func (s *State) endLookupDNS(agentSessionID, id string) {
+	s.mu.Lock()
+	if as, ok := s.sessions[agentSessionID].(*agentSessionState); ok {
+		if rch, ok := as.dnsResponses[id]; ok {
+			delete(as.dnsResponses, id)
+			close(rch)
+		}
+	}
+	s.mu.Unlock()
+}
+
+// WatchLookupDNS returns the channel that the DNS requests for the given agent are sent to.
+func (s *State) WatchLookupDNS(agentSessionID string) <-chan *rpc.DNSRequest {
+	s.mu.RLock()
+	ss, ok := s.sessions[agentSessionID]
+	s.mu.RUnlock()
+	if !ok {
+		return nil
+	}
+	return ss.(*agentSessionState).dnsRequests
+}
@@ -0,0 +1,68 @@
+package state
+
+import (
+	"context"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/telepresenceio/telepresence/v2/pkg/tunnel"
+)
+
+// exposure is a Service that the traffic-manager maintains on behalf of a client, and that relays
+// the connections that it receives to that client.
+type exposure struct {
+	clientSessionID string
+	cancel          context.CancelFunc
+}
+
+// AddExposure registers that the client with the given session exposes a Service with the given name
+// in the given namespace. The given function is called when the exposure is removed.
+func (s *State) AddExposure(clientSessionID, name, namespace string, cancel context.CancelFunc) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.sessions[clientSessionID].(*clientSessionState); !ok {
+		return status.Errorf(codes.NotFound, "client session %q not found", clientSessionID)
+	}
+	key := name + "." + namespace
+	if _, ok := s.exposures[key]; ok {
+		return status.Errorf(codes.AlreadyExists, "service %s is already exposed", key)
+	}
+	s.exposures[key] = &exposure{clientSessionID: clientSessionID, cancel: cancel}
+	return nil
+}
+
+// RemoveExposure removes the exposure of the Service with the given name in the given namespace. It
+// returns false if the Service isn't exposed by the client with the given session.
+func (s *State) RemoveExposure(clientSessionID, name, namespace string) bool {
+	s.mu.Lock()
+	key := name + "." + namespace
+	e, ok := s.exposures[key]
+	if ok && e.clientSessionID == clientSessionID {
+		delete(s.exposures, key)
+	} else {
+		ok = false
+	}
+	s.mu.Unlock()
+	if ok {
+		e.cancel()
+	}
+	return ok
+}
+
+// TunnelToClient extends the given stream to the client with the given session, and waits until the
+// resulting tunnel is closed. The client is asked to dial the destination of the stream.
+func (s *State) TunnelToClient(ctx context.Context, clientSessionID string, stream tunnel.Stream) error {
+	s.mu.RLock()
+	ss, ok := s.sessions[clientSessionID].(*clientSessionState)
+	s.mu.RUnlock()
+	if !ok {
+		return status.Errorf(codes.NotFound, "client session %q not found", clientSessionID)
+	}
+	endPoint, err := ss.EstablishBidiPipe(ctx, stream)
+	if err != nil {
+		return err
+	}
+	<-endPoint.Done()
+	return nil
+}
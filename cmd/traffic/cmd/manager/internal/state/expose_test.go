@@ -0,0 +1,39 @@
+package state_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	manager "github.com/telepresenceio/telepresence/v2/cmd/traffic/cmd/manager/internal/state"
+	testdata "github.com/telepresenceio/telepresence/v2/cmd/traffic/cmd/manager/internal/test"
+)
+
+func TestExposure(t *testing.T) {
+	ctx := context.Background()
+	clock := &FakeClock{}
+	state := manager.NewState(ctx)
+	testClients := testdata.GetTestClients(t)
+	alice := state.AddClient(testClients["alice"], clock.Now())
+	bob := state.AddClient(testClients["bob"], clock.Now())
+
+	err := state.AddExposure("asdf", "svc", "default", func() {})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	exposeCtx, cancel := context.WithCancel(ctx)
+	require.NoError(t, state.AddExposure(alice, "svc", "default", cancel))
+	err = state.AddExposure(bob, "svc", "default", func() {})
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+	require.NoError(t, state.AddExposure(bob, "svc", "other", func() {}))
+
+	// Only the client that exposed the service can remove it
+	assert.False(t, state.RemoveExposure(bob, "svc", "default"))
+	assert.NoError(t, exposeCtx.Err())
+	assert.True(t, state.RemoveExposure(alice, "svc", "default"))
+	assert.Error(t, exposeCtx.Err())
+	assert.False(t, state.RemoveExposure(alice, "svc", "default"))
+}
@@ -146,6 +146,9 @@ type clientSessionState struct {
 	sessionState
 	name string
 	pool *tunnel.Pool
+
+	// namespaces are the namespaces that the client watches agents in. Guarded by State.mu
+	namespaces []string
 }
 
 type agentSessionState struct {
@@ -153,6 +156,15 @@ type agentSessionState struct {
 	agent           *rpc.AgentInfo
 	lookups         chan *rpc.LookupHostRequest
 	lookupResponses map[string]chan *rpc.LookupHostResponse
+	dnsRequests     chan *rpc.DNSRequest
+	dnsResponses    map[string]chan *rpc.DNSResponse
+	logRequests     chan *rpc.AgentLogsRequest
+	logStreams      map[string]*agentLogStream
+	execRequests    chan *rpc.ExecRequest
+	execWaiters     map[string]chan agentExecAttachment
+
+	// environment is the environment of the agent's app containers. Guarded by State.mu
+	environment []*rpc.ContainerEnvironment
 }
 
 func (ss *agentSessionState) Cancel() {
@@ -160,6 +172,10 @@ func (ss *agentSessionState) Cancel() {
 	for _, lr := range ss.lookupResponses {
 		close(lr)
 	}
+	close(ss.dnsRequests)
+	for _, dr := range ss.dnsResponses {
+		close(dr)
+	}
 	ss.sessionState.Cancel()
 }
 
@@ -194,6 +210,7 @@ type State struct {
 	llSubs           *loglevelSubscribers
 	cfgMapLocks      map[string]*sync.Mutex
 	cachedAgentImage string
+	exposures        map[string]*exposure
 }
 
 func NewState(ctx context.Context) *State {
@@ -204,6 +221,7 @@ func NewState(ctx context.Context) *State {
 		agentsByName:    make(map[string]map[string]*rpc.AgentInfo),
 		cfgMapLocks:     make(map[string]*sync.Mutex),
 		interceptStates: make(map[string]*interceptState),
+		exposures:       make(map[string]*exposure),
 		timedLogLevel:   log.NewTimedLevel(loglevel, log.SetLevel),
 		llSubs:          newLoglevelSubscribers(),
 	}
@@ -425,6 +443,31 @@ func (s *State) GetClient(sessionID string) *rpc.ClientInfo {
 	return ret
 }
 
+// SetClientNamespaces records the namespaces that the client of the given session watches agents in.
+// They are declared by the client, so they limit the scope of the session but don't authorize it.
+func (s *State) SetClientNamespaces(sessionID string, namespaces []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if sess, ok := s.sessions[sessionID].(*clientSessionState); ok {
+		sess.namespaces = append([]string(nil), namespaces...)
+	}
+}
+
+// ClientNamespaceInScope returns true if the client of the given session watches agents in the
+// given namespace.
+func (s *State) ClientNamespaceInScope(sessionID, namespace string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if sess, ok := s.sessions[sessionID].(*clientSessionState); ok {
+		for _, ns := range sess.namespaces {
+			if ns == namespace {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 func (s *State) GetAllClients() map[string]*rpc.ClientInfo {
 	return s.clients.LoadAll()
 }
@@ -460,6 +503,12 @@ func (s *State) AddAgent(agent *rpc.AgentInfo, now time.Time) string {
 		sessionState:    s.newSessionState(now),
 		lookups:         make(chan *rpc.LookupHostRequest),
 		lookupResponses: make(map[string]chan *rpc.LookupHostResponse),
+		dnsRequests:     make(chan *rpc.DNSRequest),
+		dnsResponses:    make(map[string]chan *rpc.DNSResponse),
+		logRequests:     make(chan *rpc.AgentLogsRequest),
+		logStreams:      make(map[string]*agentLogStream),
+		execRequests:    make(chan *rpc.ExecRequest),
+		execWaiters:     make(map[string]chan agentExecAttachment),
 		agent:           agent,
 	}
 
@@ -503,6 +552,34 @@ func (s *State) GetAgentsByName(name, namespace string) map[string]*rpc.AgentInf
 	return ret
 }
 
+// SetAgentEnvironment records the environment of the app containers of the agent of the given
+// session. It returns false if there is no such session.
+func (s *State) SetAgentEnvironment(sessionID string, env []*rpc.ContainerEnvironment) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	as, ok := s.sessions[sessionID].(*agentSessionState)
+	if ok {
+		as.environment = env
+	}
+	return ok
+}
+
+// GetAgentEnvironment returns the environment of the given app container of the agent of the given
+// session, or of its first app container when the name is empty. It returns nil if the agent hasn't
+// reported the environment of such a container.
+func (s *State) GetAgentEnvironment(sessionID, container string) *rpc.ContainerEnvironment {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if as, ok := s.sessions[sessionID].(*agentSessionState); ok {
+		for _, cn := range as.environment {
+			if container == "" || cn.Name == container {
+				return proto.Clone(cn).(*rpc.ContainerEnvironment)
+			}
+		}
+	}
+	return nil
+}
+
 func (s *State) WatchAgents(
 	ctx context.Context,
 	filter func(sessionID string, agent *rpc.AgentInfo) bool,
@@ -5,6 +5,9 @@ import (
 	"testing"
 	"time"
 
+	"github.com/stretchr/testify/assert"
+
+	rpc "github.com/telepresenceio/telepresence/rpc/v2/manager"
 	manager "github.com/telepresenceio/telepresence/v2/cmd/traffic/cmd/manager/internal/state"
 	testdata "github.com/telepresenceio/telepresence/v2/cmd/traffic/cmd/manager/internal/test"
 )
@@ -125,3 +128,44 @@ func TestStateInternal(topT *testing.T) {
 		a.False(state.Mark(c3, clock.Now()))
 	})
 }
+
+func TestClientNamespaces(t *testing.T) {
+	clock := &FakeClock{}
+	state := manager.NewState(context.Background())
+	testClients := testdata.GetTestClients(t)
+	alice := state.AddClient(testClients["alice"], clock.Now())
+	bob := state.AddClient(testClients["bob"], clock.Now())
+
+	assert.False(t, state.ClientNamespaceInScope(alice, "default"), "no namespaces until the client watches agents")
+	state.SetClientNamespaces(alice, []string{"default", "dev"})
+	assert.True(t, state.ClientNamespaceInScope(alice, "dev"))
+	assert.False(t, state.ClientNamespaceInScope(alice, "kube-system"))
+	assert.False(t, state.ClientNamespaceInScope(bob, "dev"), "namespaces are per session")
+
+	state.SetClientNamespaces(alice, []string{"default"})
+	assert.False(t, state.ClientNamespaceInScope(alice, "dev"))
+}
+
+func TestAgentEnvironment(t *testing.T) {
+	clock := &FakeClock{}
+	state := manager.NewState(context.Background())
+	testAgents := testdata.GetTestAgents(t)
+	testClients := testdata.GetTestClients(t)
+	hello := state.AddAgent(testAgents["hello"], clock.Now())
+	alice := state.AddClient(testClients["alice"], clock.Now())
+
+	assert.Nil(t, state.GetAgentEnvironment(hello, ""), "no environment until the agent reports it")
+	assert.False(t, state.SetAgentEnvironment(alice, nil), "clients have no environment")
+	assert.True(t, state.SetAgentEnvironment(hello, []*rpc.ContainerEnvironment{
+		{Name: "app", Environment: map[string]string{"A": "a"}},
+		{Name: "sidecar", Environment: map[string]string{"B": "b"}, RedactedEnvironment: []string{"TOKEN"}},
+	}))
+	assert.Equal(t, "app", state.GetAgentEnvironment(hello, "").GetName())
+	cn := state.GetAgentEnvironment(hello, "sidecar")
+	assert.Equal(t, map[string]string{"B": "b"}, cn.GetEnvironment())
+	assert.Equal(t, []string{"TOKEN"}, cn.GetRedactedEnvironment())
+	assert.Nil(t, state.GetAgentEnvironment(hello, "other"))
+
+	state.RemoveSession(context.Background(), hello)
+	assert.Nil(t, state.GetAgentEnvironment(hello, ""))
+}
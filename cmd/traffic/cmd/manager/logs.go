@@ -0,0 +1,203 @@
+package manager
+
+import (
+	"context"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	empty "google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/datawire/dlib/dlog"
+	rpc "github.com/telepresenceio/telepresence/rpc/v2/manager"
+	"github.com/telepresenceio/telepresence/v2/cmd/traffic/cmd/manager/managerutil"
+	"github.com/telepresenceio/telepresence/v2/pkg/install"
+	"github.com/telepresenceio/telepresence/v2/pkg/log"
+)
+
+// WatchLogs streams the log output of the traffic-manager, or of the traffic-agents of a workload, to
+// a client. Agent output is only streamed when the workload's namespace is one of the namespaces that
+// the client's session watches agents in. Output that the traffic-manager logged on behalf of other
+// client sessions, such as the audit of their exec and expose calls, is never streamed.
+func (m *Manager) WatchLogs(request *rpc.WatchLogsRequest, stream rpc.Manager_WatchLogsServer) error {
+	ctx := managerutil.WithSessionInfo(stream.Context(), request.GetSession())
+	dlog.Debugf(ctx, "WatchLogs called for %s.%s", request.Name, request.Namespace)
+
+	sessionID := request.GetSession().GetSessionId()
+	if m.state.GetClient(sessionID) == nil {
+		return status.Errorf(codes.NotFound, "Client session %q not found", sessionID)
+	}
+	if request.Name == install.ManagerAppName && request.Namespace == managerutil.GetEnv(ctx).ManagerNamespace {
+		return m.watchManagerLogs(ctx, request, stream)
+	}
+
+	if !m.namespaceInScope(ctx, sessionID, request.Namespace) {
+		return status.Errorf(codes.PermissionDenied, "namespace %q is not managed by the traffic-manager or not mapped by this session", request.Namespace)
+	}
+	agents := m.state.GetAgentsByName(request.Name, request.Namespace)
+	if len(agents) == 0 {
+		return status.Errorf(codes.NotFound, "no traffic-agent found for %s.%s", request.Name, request.Namespace)
+	}
+	return m.watchAgentLogs(ctx, request, agents, stream)
+}
+
+func (m *Manager) watchManagerLogs(ctx context.Context, request *rpc.WatchLogsRequest, stream rpc.Manager_WatchLogsServer) error {
+	buffer := log.GetBuffer(m.ctx)
+	if buffer == nil {
+		return status.Error(codes.Unavailable, "the traffic-manager doesn't retain its log output")
+	}
+	var since time.Time
+	if d := request.Since.AsDuration(); d > 0 {
+		since = m.clock.Now().Add(-d)
+	}
+	podName, _ := os.Hostname()
+	sessionID := request.GetSession().GetSessionId()
+	send := func(e log.Entry) error {
+		if e.SessionID != "" && e.SessionID != sessionID {
+			return nil
+		}
+		return stream.Send(&rpc.LogEntry{PodName: podName, Time: timestamppb.New(e.Time), Text: e.Text})
+	}
+
+	var es []log.Entry
+	var ch <-chan log.Entry
+	if request.Follow {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithCancel(ctx)
+		defer cancel()
+		es, ch = buffer.Follow(ctx, since)
+	} else {
+		es = buffer.Since(since)
+	}
+	for _, e := range es {
+		if err := send(e); err != nil {
+			return err
+		}
+	}
+	for ch != nil {
+		select {
+		case <-ctx.Done():
+			return nil
+		case e, ok := <-ch:
+			if !ok {
+				return nil
+			}
+			if err := send(e); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (m *Manager) watchAgentLogs(ctx context.Context, request *rpc.WatchLogsRequest, agents map[string]*rpc.AgentInfo, stream rpc.Manager_WatchLogsServer) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		sendMu  sync.Mutex // stream.Send must not be called concurrently
+		sendErr error
+		started int
+		err     error
+	)
+	wg := sync.WaitGroup{}
+	for agentSessionID := range agents {
+		rq := &rpc.AgentLogsRequest{Id: uuid.New().String(), Since: request.Since, Follow: request.Follow}
+		var ch <-chan []*rpc.LogEntry
+		if ch, err = m.state.StartAgentLogs(ctx, agentSessionID, rq); err != nil {
+			dlog.Errorf(ctx, "unable to get logs from agent session %s: %v", agentSessionID, err)
+			continue
+		}
+		started++
+		wg.Add(1)
+		go func(agentSessionID, requestID string) {
+			defer wg.Done()
+			defer m.state.EndAgentLogs(agentSessionID, requestID)
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case es, ok := <-ch:
+					if !ok {
+						return
+					}
+					sendMu.Lock()
+					for _, e := range es {
+						if sendErr == nil {
+							sendErr = stream.Send(e)
+						}
+					}
+					failed := sendErr != nil
+					sendMu.Unlock()
+					if failed {
+						cancel()
+						return
+					}
+				}
+			}
+		}(agentSessionID, rq.Id)
+	}
+	if started == 0 {
+		return err
+	}
+	wg.Wait()
+	return sendErr
+}
+
+// WatchAgentLogsRequests lets an agent receive requests for its log output.
+func (m *Manager) WatchAgentLogsRequests(session *rpc.SessionInfo, stream rpc.Manager_WatchAgentLogsRequestsServer) error {
+	ctx := managerutil.WithSessionInfo(stream.Context(), session)
+	dlog.Debugf(ctx, "WatchAgentLogsRequests called")
+	sessionDone, err := m.state.SessionDone(session.GetSessionId())
+	if err != nil {
+		return err
+	}
+	rqCh := m.state.WatchAgentLogsRequests(session.GetSessionId())
+	if rqCh == nil {
+		return status.Errorf(codes.NotFound, "Agent session %q not found", session.GetSessionId())
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-sessionDone:
+			return nil
+		case rq := <-rqCh:
+			if err := stream.Send(rq); err != nil {
+				dlog.Errorf(ctx, "WatchAgentLogsRequests.Send() failed: %v", err)
+				return nil
+			}
+		}
+	}
+}
+
+// AgentLogs receives the log output that an agent sends in response to an AgentLogsRequest, and
+// returns when the output is no longer wanted.
+func (m *Manager) AgentLogs(stream rpc.Manager_AgentLogsServer) error {
+	ctx := stream.Context()
+	r, err := stream.Recv()
+	if err != nil {
+		if err == io.EOF {
+			return stream.SendAndClose(&empty.Empty{})
+		}
+		return err
+	}
+	defer m.state.AgentLogsDone(r.GetSession().GetSessionId(), r.RequestId)
+	for {
+		if err = m.state.PostAgentLogs(ctx, r); err != nil {
+			// The output is no longer wanted, so tell the agent to stop sending.
+			return stream.SendAndClose(&empty.Empty{})
+		}
+		if r, err = stream.Recv(); err != nil {
+			if err == io.EOF {
+				return stream.SendAndClose(&empty.Empty{})
+			}
+			return err
+		}
+	}
+}
@@ -46,6 +46,8 @@ func Main(ctx context.Context, _ ...string) error {
 		return fmt.Errorf("unable to initialize traffic manager: %w", err)
 	}
 
+	sweepExposed(ctx)
+
 	g := dgroup.NewGroup(ctx, dgroup.GroupConfig{
 		EnableSignalHandling: true,
 	})
@@ -2,6 +2,8 @@ package managerutil
 
 import (
 	"context"
+	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/sethvargo/go-envconfig"
@@ -30,6 +32,12 @@ type Env struct {
 	MaxReceiveSize      resource.Quantity          `env:"TELEPRESENCE_MAX_RECEIVE_SIZE,default=4Mi"`
 	AppProtocolStrategy k8sapi.AppProtocolStrategy `env:"TELEPRESENCE_APP_PROTO_STRATEGY,default="`
 	AgentInjectPolicy   agentconfig.InjectPolicy   `env:"AGENT_INJECT_POLICY,default="`
+	AgentEnvRedact      string                     `env:"AGENT_ENV_REDACT,default="`
+	AgentEnvWithhold    string                     `env:"AGENT_ENV_WITHHOLD,default="`
+	AgentEnvSecrets     string                     `env:"AGENT_ENV_SECRETS,default="`
+	AgentExecEnabled    bool                       `env:"AGENT_EXEC_ENABLED,default=false"`
+	ExposeEnabled       bool                       `env:"EXPOSE_ENABLED,default=false"`
+	PingProbePorts      string                     `env:"PING_PROBE_PORTS,default="`
 
 	PodCIDRStrategy string `env:"POD_CIDR_STRATEGY,default=auto"`
 	PodCIDRs        string `env:"POD_CIDRS,default="`
@@ -43,15 +51,37 @@ type Env struct {
 type envKey struct{}
 
 func (e *Env) GeneratorConfig(qualifiedAgentImage string) *agentmap.GeneratorConfig {
+	ep, _ := e.EnvPolicy() // validated by LoadEnv
 	return &agentmap.GeneratorConfig{
 		AgentPort:           uint16(e.AgentPort),
 		APIPort:             uint16(e.APIPort),
 		QualifiedAgentImage: qualifiedAgentImage,
 		ManagerNamespace:    e.ManagerNamespace,
 		LogLevel:            e.LogLevel,
+		EnvPolicy:           ep,
 	}
 }
 
+// EnvPolicy returns the policy that controls which environment variables of intercepted
+// containers that are passed on to clients, or nil when all of them are.
+func (e *Env) EnvPolicy() (*agentconfig.EnvPolicy, error) {
+	ep := &agentconfig.EnvPolicy{}
+	var err error
+	if ep.Redact, err = agentconfig.ParseEnvPatterns(e.AgentEnvRedact); err != nil {
+		return nil, fmt.Errorf("AGENT_ENV_REDACT: %w", err)
+	}
+	if ep.Withhold, err = agentconfig.ParseEnvPatterns(e.AgentEnvWithhold); err != nil {
+		return nil, fmt.Errorf("AGENT_ENV_WITHHOLD: %w", err)
+	}
+	if ep.Secrets, err = agentconfig.ParseEnvAction(e.AgentEnvSecrets); err != nil {
+		return nil, fmt.Errorf("AGENT_ENV_SECRETS: %w", err)
+	}
+	if ep.IsEmpty() {
+		return nil, nil
+	}
+	return ep, nil
+}
+
 func (e *Env) QualifiedAgentImage() string {
 	img := e.AgentImage
 	if img == "" {
@@ -67,11 +97,32 @@ func (e *Env) GetManagedNamespaces() []string {
 	return nil
 }
 
+// GetPingProbePorts returns the ports that the traffic-manager connects to in order to check if an IP
+// that doesn't respond to pings is reachable.
+func (e *Env) GetPingProbePorts() ([]uint16, error) {
+	fs := strings.Fields(e.PingProbePorts)
+	ports := make([]uint16, len(fs))
+	for i, f := range fs {
+		p, err := strconv.ParseUint(f, 10, 16)
+		if err != nil || p == 0 {
+			return nil, fmt.Errorf("PING_PROBE_PORTS: invalid port %q", f)
+		}
+		ports[i] = uint16(p)
+	}
+	return ports, nil
+}
+
 func LoadEnv(ctx context.Context) (context.Context, error) {
 	var env Env
 	if err := envconfig.Process(ctx, &env); err != nil {
 		return ctx, err
 	}
+	if _, err := env.EnvPolicy(); err != nil {
+		return ctx, err
+	}
+	if _, err := env.GetPingProbePorts(); err != nil {
+		return ctx, err
+	}
 	return WithEnv(ctx, &env), nil
 }
 
@@ -0,0 +1,289 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/datawire/dlib/dlog"
+	rpc "github.com/telepresenceio/telepresence/rpc/v2/manager"
+	"github.com/telepresenceio/telepresence/v2/cmd/traffic/cmd/manager/managerutil"
+	"github.com/telepresenceio/telepresence/v2/pkg/ipproto"
+	"github.com/telepresenceio/telepresence/v2/pkg/iputil"
+)
+
+const (
+	defaultPingTimeout = 2 * time.Second
+	maxPingTimeout     = 10 * time.Second
+)
+
+// pingSeq is the sequence number of the last echo request that was sent.
+var pingSeq uint32 //nolint:gochecknoglobals // a sequence number
+
+// errNoICMPSocket is returned by echo when neither an unprivileged ICMP socket nor a raw socket can be opened.
+var errNoICMPSocket = errors.New("unable to open an ICMP socket")
+
+// Ping sends an ICMP echo request to an IP in the cluster on behalf of a client, and returns the echo reply or
+// the ICMP error that was received instead. When probe ports are configured and the IP doesn't respond, an echo
+// reply is synthesized if a TCP connection to one of those ports is established or refused.
+func (m *Manager) Ping(ctx context.Context, rq *rpc.PingRequest) (*rpc.PingResponse, error) {
+	ctx = managerutil.WithSessionInfo(ctx, rq.GetSession())
+	sessionID := rq.GetSession().GetSessionId()
+	if m.state.GetClient(sessionID) == nil {
+		return nil, status.Errorf(codes.NotFound, "Client session %q not found", sessionID)
+	}
+	ip := net.IP(rq.Ip)
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	} else if len(ip) != net.IPv6len {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid IP %v", rq.Ip)
+	}
+	if !inClusterSubnets(m.clusterInfo.GetClusterInfo(), ip) {
+		return nil, status.Errorf(codes.InvalidArgument, "IP %s is not in a pod or service subnet of the cluster", ip)
+	}
+	timeout := rq.GetTimeout().AsDuration()
+	if timeout <= 0 {
+		timeout = defaultPingTimeout
+	} else if timeout > maxPingTimeout {
+		timeout = maxPingTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// The ports are validated by LoadEnv
+	ports, _ := managerutil.GetEnv(ctx).GetPingProbePorts()
+	var probeCh chan *rpc.PingResponse
+	if len(ports) > 0 {
+		probeCh = make(chan *rpc.PingResponse, 1)
+		go func() {
+			probeCh <- probe(ctx, ip, ports, rq.Data)
+		}()
+	}
+
+	rs, err := echo(ctx, ip, rq.Data)
+	if err == nil {
+		dlog.Tracef(ctx, "Ping %s -> type %d, code %d from %s", ip, rs.Type, rs.Code, net.IP(rs.Source))
+		return rs, nil
+	}
+	if !(errors.Is(err, os.ErrDeadlineExceeded) || errors.Is(err, errNoICMPSocket)) {
+		dlog.Errorf(ctx, "Ping %s: %v", ip, err)
+		return nil, status.Errorf(codes.Unavailable, "ping %s failed: %v", ip, err)
+	}
+	dlog.Tracef(ctx, "Ping %s: %v", ip, err)
+	if probeCh != nil {
+		select {
+		case <-ctx.Done():
+		case rs = <-probeCh:
+			if rs != nil {
+				dlog.Tracef(ctx, "Ping %s -> type %d, code %d synthesized by TCP probe", ip, rs.Type, rs.Code)
+				return rs, nil
+			}
+		}
+	}
+	return nil, status.Errorf(codes.DeadlineExceeded, "no reply from %s", ip)
+}
+
+// inClusterSubnets returns true if the given IP is in one of the pod or service subnets of the given ClusterInfo.
+func inClusterSubnets(ci *rpc.ClusterInfo, ip net.IP) bool {
+	contains := func(subnets []*rpc.IPNet) bool {
+		for _, s := range subnets {
+			if s != nil && iputil.IPNetFromRPC(s).Contains(ip) {
+				return true
+			}
+		}
+		return false
+	}
+	return contains(ci.GetPodSubnets()) || contains(ci.GetServiceSubnets()) || contains([]*rpc.IPNet{ci.GetServiceSubnet()})
+}
+
+// echo sends an echo request to the given IP and waits for the reply, or for an ICMP error, until the given
+// context is done. An unprivileged ICMP socket is used when possible, and a raw socket otherwise. The errors
+// that an unprivileged socket receives are reported without the IP of the host that sent them.
+func echo(ctx context.Context, ip net.IP, data []byte) (*rpc.PingResponse, error) {
+	v4 := len(ip) == net.IPv4len
+	rawNetwork, laddr := "ip4:icmp", "0.0.0.0"
+	proto := ipproto.ICMP
+	var echoType, replyType icmp.Type = ipv4.ICMPTypeEcho, ipv4.ICMPTypeEchoReply
+	if !v4 {
+		rawNetwork, laddr = "ip6:ipv6-icmp", "::"
+		proto = ipproto.ICMPV6
+		echoType, replyType = ipv6.ICMPTypeEchoRequest, ipv6.ICMPTypeEchoReply
+	}
+
+	privileged := false
+	var dst net.Addr = &net.UDPAddr{IP: ip}
+	c, err := listenUnprivilegedICMP(v4)
+	if err != nil {
+		dlog.Tracef(ctx, "unable to open unprivileged ICMP socket: %v", err)
+		if c, err = icmp.ListenPacket(rawNetwork, laddr); err != nil {
+			dlog.Tracef(ctx, "unable to open raw ICMP socket: %v", err)
+			return nil, errNoICMPSocket
+		}
+		privileged = true
+		dst = &net.IPAddr{IP: ip}
+	}
+	defer c.Close()
+
+	// The kernel replaces the identifier of echo requests that are sent from an unprivileged socket
+	id := rand.Intn(0x10000)
+	seq := int(atomic.AddUint32(&pingSeq, 1) & 0xffff)
+	msg := icmp.Message{Type: echoType, Body: &icmp.Echo{ID: id, Seq: seq, Data: data}}
+	wb, err := msg.Marshal(nil)
+	if err != nil {
+		return nil, err
+	}
+	if dl, ok := ctx.Deadline(); ok {
+		if err = c.SetDeadline(dl); err != nil {
+			return nil, err
+		}
+	}
+	if _, err = c.WriteTo(wb, dst); err != nil {
+		if rs := errnoResponse(err, ip); rs != nil {
+			return rs, nil
+		}
+		return nil, err
+	}
+
+	rb := make([]byte, 0x10000)
+	for {
+		n, peer, err := c.ReadFrom(rb)
+		if err != nil {
+			if rs := errnoResponse(err, ip); rs != nil {
+				return rs, nil
+			}
+			return nil, err
+		}
+		rm, err := icmp.ParseMessage(proto, rb[:n])
+		if err != nil {
+			continue
+		}
+		src := addrIP(peer, v4)
+		switch body := rm.Body.(type) {
+		case *icmp.Echo:
+			if rm.Type == replyType && (body.ID == id || !privileged) && body.Seq == seq && src.Equal(ip) {
+				return &rpc.PingResponse{Type: icmpTypeNumber(rm.Type), Source: ip, Data: body.Data}, nil
+			}
+		case *icmp.DstUnreach:
+			if privileged && quotesEcho(body.Data, v4, ip, id, seq) {
+				return &rpc.PingResponse{Type: icmpTypeNumber(rm.Type), Code: int32(rm.Code), Source: src}, nil
+			}
+		case *icmp.TimeExceeded:
+			if privileged && quotesEcho(body.Data, v4, ip, id, seq) {
+				return &rpc.PingResponse{Type: icmpTypeNumber(rm.Type), Code: int32(rm.Code), Source: src}, nil
+			}
+		}
+	}
+}
+
+// quotesEcho returns true if the given data of an ICMP error is the start of the echo request with the given
+// destination, identifier, and sequence number.
+func quotesEcho(data []byte, v4 bool, ip net.IP, id, seq int) bool {
+	var hl int
+	var dst net.IP
+	if v4 {
+		if len(data) < ipv4.HeaderLen {
+			return false
+		}
+		hl = int(data[0]&0x0f) << 2
+		dst = data[16:20]
+	} else {
+		if len(data) < ipv6.HeaderLen {
+			return false
+		}
+		hl = ipv6.HeaderLen
+		dst = data[24:40]
+	}
+	if len(data) < hl+8 || !dst.Equal(ip) {
+		return false
+	}
+	echo := data[hl:]
+	return int(echo[4])<<8|int(echo[5]) == id && int(echo[6])<<8|int(echo[7]) == seq
+}
+
+// errnoResponse translates an error that an unprivileged ICMP socket reports when it receives an ICMP error
+// into a destination unreachable response. It returns nil when the error isn't such an error.
+func errnoResponse(err error, ip net.IP) *rpc.PingResponse {
+	var v4Code, v6Code int32
+	switch {
+	case errors.Is(err, syscall.ENETUNREACH):
+		v4Code, v6Code = 0, 0 // network unreachable, no route to destination
+	case errors.Is(err, syscall.EHOSTUNREACH):
+		v4Code, v6Code = 1, 3 // host unreachable, address unreachable
+	case errors.Is(err, syscall.ECONNREFUSED):
+		v4Code, v6Code = 3, 4 // port unreachable
+	default:
+		return nil
+	}
+	if len(ip) == net.IPv4len {
+		return &rpc.PingResponse{Type: int32(ipv4.ICMPTypeDestinationUnreachable), Code: v4Code, Source: ip}
+	}
+	return &rpc.PingResponse{Type: int32(ipv6.ICMPTypeDestinationUnreachable), Code: v6Code, Source: ip}
+}
+
+// probe checks if the given IP is reachable by connecting to the given ports. It returns a synthesized echo
+// reply as soon as a connection is established or refused, and nil if no port gives that result before the
+// context is done.
+func probe(ctx context.Context, ip net.IP, ports []uint16, data []byte) *rpc.PingResponse {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	okCh := make(chan bool, len(ports))
+	for _, port := range ports {
+		go func(port uint16) {
+			var d net.Dialer
+			conn, err := d.DialContext(ctx, "tcp", net.JoinHostPort(ip.String(), strconv.Itoa(int(port))))
+			if err == nil {
+				_ = conn.Close()
+			}
+			okCh <- err == nil || errors.Is(err, syscall.ECONNREFUSED)
+		}(port)
+	}
+	for range ports {
+		if <-okCh {
+			var replyType icmp.Type = ipv4.ICMPTypeEchoReply
+			if len(ip) != net.IPv4len {
+				replyType = ipv6.ICMPTypeEchoReply
+			}
+			return &rpc.PingResponse{Type: icmpTypeNumber(replyType), Source: ip, Data: data}
+		}
+	}
+	return nil
+}
+
+func icmpTypeNumber(t icmp.Type) int32 {
+	switch t := t.(type) {
+	case ipv4.ICMPType:
+		return int32(t)
+	case ipv6.ICMPType:
+		return int32(t)
+	}
+	return -1
+}
+
+// addrIP returns the IP of the given address, in its 4-byte form when v4 is true.
+func addrIP(addr net.Addr, v4 bool) net.IP {
+	var ip net.IP
+	switch addr := addr.(type) {
+	case *net.IPAddr:
+		ip = addr.IP
+	case *net.UDPAddr:
+		ip = addr.IP
+	}
+	if v4 {
+		if ip4 := ip.To4(); ip4 != nil {
+			ip = ip4
+		}
+	}
+	return ip
+}
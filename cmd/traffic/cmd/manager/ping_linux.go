@@ -0,0 +1,34 @@
+package manager
+
+import (
+	"net"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// listenUnprivilegedICMP opens an unprivileged ICMP socket. The socket reports the ICMP errors that it receives
+// as errors when it's read. The kernel would just drop them otherwise.
+func listenUnprivilegedICMP(v4 bool) (net.PacketConn, error) {
+	family, proto, level, opt := unix.AF_INET, unix.IPPROTO_ICMP, unix.IPPROTO_IP, unix.IP_RECVERR
+	var sa unix.Sockaddr = &unix.SockaddrInet4{}
+	if !v4 {
+		family, proto, level, opt = unix.AF_INET6, unix.IPPROTO_ICMPV6, unix.IPPROTO_IPV6, unix.IPV6_RECVERR
+		sa = &unix.SockaddrInet6{}
+	}
+	fd, err := unix.Socket(family, unix.SOCK_DGRAM|unix.SOCK_CLOEXEC, proto)
+	if err != nil {
+		return nil, os.NewSyscallError("socket", err)
+	}
+
+	// net.FilePacketConn uses a duplicate of the file descriptor, so the file is always closed.
+	f := os.NewFile(uintptr(fd), "icmp")
+	defer f.Close()
+	if err = unix.SetsockoptInt(fd, level, opt, 1); err != nil {
+		return nil, os.NewSyscallError("setsockopt", err)
+	}
+	if err = unix.Bind(fd, sa); err != nil {
+		return nil, os.NewSyscallError("bind", err)
+	}
+	return net.FilePacketConn(f)
+}
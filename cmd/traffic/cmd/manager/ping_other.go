@@ -0,0 +1,18 @@
+//go:build !linux
+// +build !linux
+
+package manager
+
+import (
+	"net"
+
+	"golang.org/x/net/icmp"
+)
+
+// listenUnprivilegedICMP opens an unprivileged ICMP socket.
+func listenUnprivilegedICMP(v4 bool) (net.PacketConn, error) {
+	if v4 {
+		return icmp.ListenPacket("udp4", "0.0.0.0")
+	}
+	return icmp.ListenPacket("udp6", "::")
+}
@@ -0,0 +1,107 @@
+package manager
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/datawire/dlib/dlog"
+	rpc "github.com/telepresenceio/telepresence/rpc/v2/manager"
+	"github.com/telepresenceio/telepresence/v2/cmd/traffic/cmd/manager/internal/cluster"
+	"github.com/telepresenceio/telepresence/v2/cmd/traffic/cmd/manager/internal/state"
+	"github.com/telepresenceio/telepresence/v2/pkg/iputil"
+)
+
+func TestQuotesEcho(t *testing.T) {
+	dst := net.IP{10, 0, 0, 1}
+	echo, err := (&icmp.Message{Type: ipv4.ICMPTypeEcho, Body: &icmp.Echo{ID: 0x1234, Seq: 7, Data: []byte("data")}}).Marshal(nil)
+	require.NoError(t, err)
+	hdr, err := (&ipv4.Header{Version: ipv4.Version, Len: ipv4.HeaderLen, TotalLen: ipv4.HeaderLen + len(echo), Protocol: 1, Dst: dst}).Marshal()
+	require.NoError(t, err)
+	quote := append(hdr, echo[:8]...)
+
+	assert.True(t, quotesEcho(quote, true, dst, 0x1234, 7))
+	assert.False(t, quotesEcho(quote, true, dst, 0x1234, 8))
+	assert.False(t, quotesEcho(quote, true, net.IP{10, 0, 0, 2}, 0x1234, 7))
+	assert.False(t, quotesEcho(quote[:ipv4.HeaderLen+4], true, dst, 0x1234, 7))
+}
+
+func TestProbe(t *testing.T) {
+	ctx, cancel := context.WithTimeout(dlog.NewTestContext(t, false), 5*time.Second)
+	defer cancel()
+	ip := net.IP{127, 0, 0, 1}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := uint16(l.Addr().(*net.TCPAddr).Port)
+
+	// An established connection proves that the IP is reachable
+	rs := probe(ctx, ip, []uint16{port}, []byte("data"))
+	require.NotNil(t, rs)
+	assert.Equal(t, int32(ipv4.ICMPTypeEchoReply), rs.Type)
+	assert.Equal(t, []byte("data"), rs.Data)
+
+	// So does a refused connection
+	require.NoError(t, l.Close())
+	rs = probe(ctx, ip, []uint16{port}, nil)
+	require.NotNil(t, rs)
+	assert.Equal(t, int32(ipv4.ICMPTypeEchoReply), rs.Type)
+
+	// But a dial that doesn't complete before the context is done doesn't
+	ctx, cancel = context.WithCancel(ctx)
+	cancel()
+	assert.Nil(t, probe(ctx, ip, []uint16{port}, nil))
+}
+
+func TestInClusterSubnets(t *testing.T) {
+	subnet := func(cidr string) *rpc.IPNet {
+		_, n, err := net.ParseCIDR(cidr)
+		require.NoError(t, err)
+		return iputil.IPNetToRPC(n)
+	}
+	ci := &rpc.ClusterInfo{
+		PodSubnets:     []*rpc.IPNet{subnet("10.244.0.0/16"), subnet("fd00:10:244::/56")},
+		ServiceSubnets: []*rpc.IPNet{subnet("10.96.0.0/12")},
+	}
+	assert.True(t, inClusterSubnets(ci, net.IP{10, 244, 1, 5}))
+	assert.True(t, inClusterSubnets(ci, net.IP{10, 96, 0, 10}))
+	assert.True(t, inClusterSubnets(ci, net.ParseIP("fd00:10:244::5")))
+	assert.False(t, inClusterSubnets(ci, net.IP{192, 168, 1, 1}))
+	assert.False(t, inClusterSubnets(ci, net.IP{127, 0, 0, 1}))
+	assert.False(t, inClusterSubnets(ci, net.ParseIP("fd00:10:245::5")))
+	assert.False(t, inClusterSubnets(&rpc.ClusterInfo{}, net.IP{10, 244, 1, 5}))
+
+	// Clients that don't know about service_subnets only get the service_subnet
+	assert.True(t, inClusterSubnets(&rpc.ClusterInfo{ServiceSubnet: subnet("10.96.0.0/12")}, net.IP{10, 96, 0, 10}))
+}
+
+type staticClusterInfo struct {
+	cluster.Info
+	ci *rpc.ClusterInfo
+}
+
+func (s *staticClusterInfo) GetClusterInfo() *rpc.ClusterInfo {
+	return s.ci
+}
+
+func TestPing_OutsideClusterSubnets(t *testing.T) {
+	ctx := dlog.NewTestContext(t, false)
+	_, podSubnet, err := net.ParseCIDR("10.244.0.0/16")
+	require.NoError(t, err)
+	m := &Manager{
+		state:       state.NewState(ctx),
+		clusterInfo: &staticClusterInfo{ci: &rpc.ClusterInfo{PodSubnets: []*rpc.IPNet{iputil.IPNetToRPC(podSubnet)}}},
+	}
+	sessionID := m.state.AddClient(&rpc.ClientInfo{Name: "client"}, time.Now())
+
+	_, err = m.Ping(ctx, &rpc.PingRequest{Session: &rpc.SessionInfo{SessionId: sessionID}, Ip: net.IP{169, 254, 169, 254}})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/miekg/dns"
 	"github.com/pkg/errors"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -25,6 +26,7 @@ import (
 	"github.com/telepresenceio/telepresence/v2/cmd/traffic/cmd/manager/license"
 	"github.com/telepresenceio/telepresence/v2/cmd/traffic/cmd/manager/managerutil"
 	"github.com/telepresenceio/telepresence/v2/pkg/a8rcloud"
+	"github.com/telepresenceio/telepresence/v2/pkg/dnsproxy"
 	"github.com/telepresenceio/telepresence/v2/pkg/iputil"
 	"github.com/telepresenceio/telepresence/v2/pkg/tunnel"
 	"github.com/telepresenceio/telepresence/v2/pkg/version"
@@ -269,6 +271,28 @@ func infosEqual(a, b *rpc.AgentInfo) bool {
 	return true
 }
 
+// namespaceInScope returns true if the client of the given session may act on workloads in the
+// given namespace. The namespace must be managed by this traffic-manager, and be one of the
+// namespaces that the session watches agents in. Those namespaces are declared by the client and
+// cannot be verified by the traffic-manager, so this limits the scope of a session to what its
+// client has mapped. It is not an authorization; any client that can reach the traffic-manager can
+// map any namespace that it manages.
+func (m *Manager) namespaceInScope(ctx context.Context, sessionID, namespace string) bool {
+	if mns := managerutil.GetEnv(ctx).GetManagedNamespaces(); len(mns) > 0 {
+		managed := false
+		for _, ns := range mns {
+			if ns == namespace {
+				managed = true
+				break
+			}
+		}
+		if !managed {
+			return false
+		}
+	}
+	return m.state.ClientNamespaceInScope(sessionID, namespace)
+}
+
 // WatchAgentsNS notifies a client of the set of known Agents.
 func (m *Manager) WatchAgentsNS(request *rpc.AgentsRequest, stream rpc.Manager_WatchAgentsNSServer) error {
 	ctx := managerutil.WithSessionInfo(stream.Context(), request.Session)
@@ -280,6 +304,7 @@ func (m *Manager) WatchAgentsNS(request *rpc.AgentsRequest, stream rpc.Manager_W
 	if err != nil {
 		return err
 	}
+	m.state.SetClientNamespaces(request.Session.GetSessionId(), request.Namespaces)
 
 	// Ensure that initial snapshot is not equal to lastSnap even if it is empty so
 	// that an initial snapshot is sent even when it's empty.
@@ -513,6 +538,18 @@ func (m *Manager) UpdateIntercept(ctx context.Context, req *rpc.UpdateInterceptR
 
 	dlog.Debugf(ctx, "UpdateIntercept called: %s", interceptID)
 
+	if lpr := req.LocalProcessRestarting; lpr != nil {
+		intercept := m.state.UpdateIntercept(interceptID, func(intercept *rpc.InterceptInfo) {
+			intercept.LocalProcessRestarting = *lpr
+		})
+		if intercept == nil {
+			return nil, status.Errorf(codes.NotFound, "Intercept with ID %q not found for this session", interceptID)
+		}
+		if req.PreviewDomainAction == nil {
+			return intercept, nil
+		}
+	}
+
 	switch action := req.PreviewDomainAction.(type) {
 	case *rpc.UpdateInterceptRequest_AddPreviewDomain:
 		// Check if this is already done.
@@ -725,6 +762,9 @@ func (m *Manager) ReviewIntercept(ctx context.Context, rIReq *rpc.ReviewIntercep
 			intercept.Message = rIReq.Message
 			intercept.PodIp = rIReq.PodIp
 			intercept.SftpPort = rIReq.SftpPort
+			intercept.NfsPort = rIReq.NfsPort
+			intercept.MountPolicy = rIReq.MountPolicy
+			intercept.RedactedEnvironment = rIReq.RedactedEnvironment
 			intercept.MountPoint = rIReq.MountPoint
 			intercept.MechanismArgsDesc = rIReq.MechanismArgsDesc
 			intercept.Headers = rIReq.Headers
@@ -833,6 +873,63 @@ func (m *Manager) WatchLookupHost(session *rpc.SessionInfo, stream rpc.Manager_W
 	}
 }
 
+func (m *Manager) LookupDNS(ctx context.Context, request *rpc.DNSRequest) (*rpc.DNSResponse, error) {
+	ctx = managerutil.WithSessionInfo(ctx, request.GetSession())
+	qts := dns.TypeToString[uint16(request.Type)]
+	dlog.Debugf(ctx, "LookupDNS called %s %s", qts, request.Name)
+	sessionID := request.GetSession().GetSessionId()
+
+	rs, count, err := m.state.AgentsLookupDNS(ctx, sessionID, request)
+	if err != nil {
+		dlog.Errorf(ctx, "AgentsLookupDNS: %v", err)
+	} else if count > 0 {
+		dlog.Debugf(ctx, "LookupDNS on agents: %s %s -> %s", qts, request.Name, dns.RcodeToString[int(rs.RCode)])
+		return rs, nil
+	}
+
+	sections, rCode, err := dnsproxy.Lookup(ctx, uint16(request.Type), request.Name)
+	if err != nil {
+		dlog.Errorf(ctx, "LookupDNS on traffic-manager: %v", err)
+		return nil, status.Errorf(codes.Unavailable, "lookup of %s %s failed: %v", qts, request.Name, err)
+	}
+	dlog.Debugf(ctx, "LookupDNS on traffic-manager: %s %s -> %s %v", qts, request.Name, dns.RcodeToString[rCode], sections.Answer)
+	bs, err := sections.ToPacked()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to pack DNS records: %v", err)
+	}
+	return &rpc.DNSResponse{Rrs: bs, RCode: int32(rCode)}, nil
+}
+
+func (m *Manager) AgentLookupDNSResponse(ctx context.Context, response *rpc.DNSAgentResponse) (*empty.Empty, error) {
+	ctx = managerutil.WithSessionInfo(ctx, response.GetSession())
+	dlog.Debugf(ctx, "AgentLookupDNSResponse called %s -> %s", response.Request.Name, dns.RcodeToString[int(response.Response.RCode)])
+	m.state.PostLookupDNSResponse(response)
+	return &empty.Empty{}, nil
+}
+
+func (m *Manager) WatchLookupDNS(session *rpc.SessionInfo, stream rpc.Manager_WatchLookupDNSServer) error {
+	ctx := managerutil.WithSessionInfo(stream.Context(), session)
+	dlog.Debugf(ctx, "WatchLookupDNS called")
+	rqCh := m.state.WatchLookupDNS(session.SessionId)
+	if rqCh == nil {
+		return status.Errorf(codes.NotFound, "Agent session %q not found", session.SessionId)
+	}
+	for {
+		select {
+		case <-m.ctx.Done():
+			return nil
+		case rq := <-rqCh:
+			if rq == nil {
+				return nil
+			}
+			if err := stream.Send(rq); err != nil {
+				dlog.Errorf(ctx, "WatchLookupDNS.Send() failed: %v", err)
+				return nil
+			}
+		}
+	}
+}
+
 // GetLogs acquires the logs for the traffic-manager and/or traffic-agents specified by the
 // GetLogsRequest and returns them to the caller
 // Deprecated: Clients should use the user daemon's GatherLogs method
@@ -81,6 +81,13 @@ func AgentContainer(
 			MountPath: TempMountPoint,
 		},
 	)
+	if config.TLSSecret != "" {
+		mounts = append(mounts, core.VolumeMount{
+			Name:      TLSVolumeName,
+			MountPath: TLSMountPoint,
+			ReadOnly:  true,
+		})
+	}
 
 	if len(efs) == 0 {
 		efs = nil
@@ -120,7 +127,7 @@ func InitContainer(qualifiedAgentImage string) *core.Container {
 	}
 }
 
-func AgentVolumes(agentName string) []core.Volume {
+func AgentVolumes(agentName, tlsSecret string) []core.Volume {
 	var items []core.KeyToPath
 	if agentName != "" {
 		items = []core.KeyToPath{{
@@ -128,7 +135,7 @@ func AgentVolumes(agentName string) []core.Volume {
 			Path: ConfigFile,
 		}}
 	}
-	return []core.Volume{
+	vols := []core.Volume{
 		{
 			Name: AnnotationVolumeName,
 			VolumeSource: core.VolumeSource{
@@ -167,6 +174,21 @@ func AgentVolumes(agentName string) []core.Volume {
 			},
 		},
 	}
+	if tlsSecret != "" {
+		vols = append(vols, core.Volume{
+			Name: TLSVolumeName,
+			VolumeSource: core.VolumeSource{
+				Secret: &core.SecretVolumeSource{
+					SecretName: tlsSecret,
+					Items: []core.KeyToPath{
+						{Key: core.TLSCertKey, Path: core.TLSCertKey},
+						{Key: core.TLSPrivateKeyKey, Path: core.TLSPrivateKeyKey},
+					},
+				},
+			},
+		})
+	}
+	return vols
 }
 
 // EachContainer will find each container in the given config and match it against a container
@@ -226,7 +248,11 @@ func appendAppContainerEnv(app *core.Container, cc *Container, es []core.EnvVar)
 
 func appendAppContainerEnvFrom(app *core.Container, cc *Container, es []core.EnvFromSource) []core.EnvFromSource {
 	for _, e := range app.EnvFrom {
-		e.Prefix = EnvPrefixApp + cc.EnvPrefix + e.Prefix
+		if e.SecretRef != nil {
+			e.Prefix = EnvPrefixAppSecret + cc.EnvPrefix + e.Prefix
+		} else {
+			e.Prefix = EnvPrefixApp + cc.EnvPrefix + e.Prefix
+		}
 		es = append(es, e)
 	}
 	return es
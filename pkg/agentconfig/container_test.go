@@ -0,0 +1,66 @@
+package agentconfig
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	core "k8s.io/api/core/v1"
+)
+
+func findVolume(vols []core.Volume, name string) *core.Volume {
+	for i := range vols {
+		if vols[i].Name == name {
+			return &vols[i]
+		}
+	}
+	return nil
+}
+
+func findMount(mounts []core.VolumeMount, name string) *core.VolumeMount {
+	for i := range mounts {
+		if mounts[i].Name == name {
+			return &mounts[i]
+		}
+	}
+	return nil
+}
+
+func TestAgentVolumes_TLSSecret(t *testing.T) {
+	assert.Nil(t, findVolume(AgentVolumes("echo", ""), TLSVolumeName))
+
+	vol := findVolume(AgentVolumes("echo", "echo-tls"), TLSVolumeName)
+	require.NotNil(t, vol)
+	require.NotNil(t, vol.Secret)
+	assert.Equal(t, "echo-tls", vol.Secret.SecretName)
+	assert.Equal(t, []core.KeyToPath{
+		{Key: core.TLSCertKey, Path: core.TLSCertKey},
+		{Key: core.TLSPrivateKeyKey, Path: core.TLSPrivateKeyKey},
+	}, vol.Secret.Items)
+}
+
+func TestAgentContainer_TLSSecret(t *testing.T) {
+	pod := &core.Pod{Spec: core.PodSpec{Containers: []core.Container{{Name: "echo"}}}}
+	config := &Sidecar{
+		AgentImage: "tel2",
+		Containers: []*Container{{
+			Name: "echo",
+			Intercepts: []*Intercept{{
+				ContainerPortName: "https",
+				Protocol:          core.ProtocolTCP,
+				AgentPort:         9900,
+			}},
+		}},
+	}
+	ac := AgentContainer(pod, config)
+	require.NotNil(t, ac)
+	assert.Nil(t, findMount(ac.VolumeMounts, TLSVolumeName))
+
+	config.TLSSecret = "echo-tls"
+	ac = AgentContainer(pod, config)
+	require.NotNil(t, ac)
+	mount := findMount(ac.VolumeMounts, TLSVolumeName)
+	require.NotNil(t, mount)
+	assert.Equal(t, TLSMountPoint, mount.MountPath)
+	assert.True(t, mount.ReadOnly)
+}
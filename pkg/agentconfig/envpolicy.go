@@ -0,0 +1,140 @@
+package agentconfig
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+const (
+	// EnvRedactAnnotation is a comma separated list of glob patterns. The values of the app
+	// container's environment variables that match a pattern are redacted before they are sent
+	// to intercepting clients.
+	EnvRedactAnnotation = DomainPrefix + "inject-env-redact"
+
+	// EnvWithholdAnnotation is a comma separated list of glob patterns. The app container's
+	// environment variables that match a pattern are never sent to intercepting clients.
+	EnvWithholdAnnotation = DomainPrefix + "inject-env-withhold"
+
+	// EnvSecretsAnnotation is either "expose", "redact", or "withhold", and controls what
+	// happens to environment variables that are sourced from Secrets.
+	EnvSecretsAnnotation = DomainPrefix + "inject-env-secrets"
+
+	// RedactedValue replaces the value of a redacted environment variable.
+	RedactedValue = "<redacted>"
+)
+
+// EnvAction is what an EnvPolicy does with a secret-sourced environment variable.
+type EnvAction string
+
+const (
+	EnvExpose   EnvAction = "expose"
+	EnvRedact   EnvAction = "redact"
+	EnvWithhold EnvAction = "withhold"
+)
+
+// ParseEnvAction returns the EnvAction with the given name. The empty string yields EnvExpose.
+func ParseEnvAction(s string) (EnvAction, error) {
+	switch a := EnvAction(strings.TrimSpace(s)); a {
+	case "", EnvExpose:
+		return EnvExpose, nil
+	case EnvRedact, EnvWithhold:
+		return a, nil
+	}
+	return "", fmt.Errorf("invalid env action %q, must be one of %q, %q, or %q", s, EnvExpose, EnvRedact, EnvWithhold)
+}
+
+// EnvPolicy controls which of the app container's environment variables that the traffic-agent
+// passes on to intercepting clients. A nil EnvPolicy passes on all variables.
+type EnvPolicy struct {
+	// Redact lists glob patterns of variables whose values are replaced with RedactedValue
+	Redact []string `json:"redact,omitempty" yaml:"redact,omitempty"`
+
+	// Withhold lists glob patterns of variables that are omitted
+	Withhold []string `json:"withhold,omitempty" yaml:"withhold,omitempty"`
+
+	// Secrets is what happens to variables that are sourced from Secrets
+	Secrets EnvAction `json:"secrets,omitempty" yaml:"secrets,omitempty"`
+}
+
+// IsEmpty returns true if the policy passes on all variables.
+func (ep *EnvPolicy) IsEmpty() bool {
+	return ep == nil || len(ep.Redact) == 0 && len(ep.Withhold) == 0 && (ep.Secrets == "" || ep.Secrets == EnvExpose)
+}
+
+// Override returns a copy of this policy where each setting that is declared by the given
+// annotations is replaced. It returns nil if the resulting policy is empty.
+func (ep *EnvPolicy) Override(annotations map[string]string) (*EnvPolicy, error) {
+	o := EnvPolicy{}
+	if ep != nil {
+		o = *ep
+	}
+	var err error
+	if s, ok := annotations[EnvRedactAnnotation]; ok {
+		if o.Redact, err = ParseEnvPatterns(s); err != nil {
+			return nil, fmt.Errorf("invalid annotation %s: %w", EnvRedactAnnotation, err)
+		}
+	}
+	if s, ok := annotations[EnvWithholdAnnotation]; ok {
+		if o.Withhold, err = ParseEnvPatterns(s); err != nil {
+			return nil, fmt.Errorf("invalid annotation %s: %w", EnvWithholdAnnotation, err)
+		}
+	}
+	if s, ok := annotations[EnvSecretsAnnotation]; ok {
+		if o.Secrets, err = ParseEnvAction(s); err != nil {
+			return nil, fmt.Errorf("invalid annotation %s: %w", EnvSecretsAnnotation, err)
+		}
+	}
+	if o.IsEmpty() {
+		return nil, nil
+	}
+	return &o, nil
+}
+
+// ParseEnvPatterns parses a list of glob patterns separated by commas or spaces.
+func ParseEnvPatterns(s string) ([]string, error) {
+	ps := strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' })
+	for _, p := range ps {
+		if _, err := path.Match(p, ""); err != nil {
+			return nil, fmt.Errorf("%q is not a valid glob pattern", p)
+		}
+	}
+	if len(ps) == 0 {
+		return nil, nil
+	}
+	return ps, nil
+}
+
+// Apply redacts or deletes the variables in env that the policy doesn't pass on. Variables
+// present in secretKeys are sourced from Secrets. The names of the affected variables are
+// returned in sorted order.
+func (ep *EnvPolicy) Apply(env map[string]string, secretKeys map[string]struct{}) []string {
+	if ep.IsEmpty() {
+		return nil
+	}
+	var affected []string
+	for k := range env {
+		_, secret := secretKeys[k]
+		switch {
+		case matchesAny(ep.Withhold, k) || secret && ep.Secrets == EnvWithhold:
+			delete(env, k)
+		case matchesAny(ep.Redact, k) || secret && ep.Secrets == EnvRedact:
+			env[k] = RedactedValue
+		default:
+			continue
+		}
+		affected = append(affected, k)
+	}
+	sort.Strings(affected)
+	return affected
+}
+
+func matchesAny(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,67 @@
+package agentconfig
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnvPolicy_Override(t *testing.T) {
+	var ep *EnvPolicy
+	o, err := ep.Override(map[string]string{})
+	require.NoError(t, err)
+	assert.Nil(t, o)
+
+	ep = &EnvPolicy{Redact: []string{"*_TOKEN"}, Secrets: EnvRedact}
+	o, err = ep.Override(map[string]string{
+		EnvWithholdAnnotation: "AWS_*, DB_PASSWORD",
+		EnvSecretsAnnotation:  "withhold",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, &EnvPolicy{Redact: []string{"*_TOKEN"}, Withhold: []string{"AWS_*", "DB_PASSWORD"}, Secrets: EnvWithhold}, o)
+	assert.Equal(t, &EnvPolicy{Redact: []string{"*_TOKEN"}, Secrets: EnvRedact}, ep, "original was modified")
+
+	// An empty annotation value clears the setting
+	o, err = ep.Override(map[string]string{EnvRedactAnnotation: "", EnvSecretsAnnotation: "expose"})
+	require.NoError(t, err)
+	assert.Nil(t, o)
+
+	_, err = ep.Override(map[string]string{EnvRedactAnnotation: "[A-"})
+	assert.Error(t, err)
+	_, err = ep.Override(map[string]string{EnvSecretsAnnotation: "hide"})
+	assert.Error(t, err)
+}
+
+func TestEnvPolicy_Apply(t *testing.T) {
+	env := func() map[string]string {
+		return map[string]string{
+			"API_TOKEN":   "abc",
+			"AWS_KEY":     "def",
+			"DB_PASSWORD": "ghi",
+			"HOST":        "example.com",
+		}
+	}
+	secrets := map[string]struct{}{"DB_PASSWORD": {}}
+
+	var ep *EnvPolicy
+	e := env()
+	assert.Nil(t, ep.Apply(e, secrets))
+	assert.Equal(t, env(), e)
+
+	ep = &EnvPolicy{Redact: []string{"*_TOKEN"}, Withhold: []string{"AWS_*"}, Secrets: EnvRedact}
+	e = env()
+	assert.Equal(t, []string{"API_TOKEN", "AWS_KEY", "DB_PASSWORD"}, ep.Apply(e, secrets))
+	assert.Equal(t, map[string]string{
+		"API_TOKEN":   RedactedValue,
+		"DB_PASSWORD": RedactedValue,
+		"HOST":        "example.com",
+	}, e)
+
+	// Withhold has precedence over redact
+	ep = &EnvPolicy{Redact: []string{"*"}, Secrets: EnvWithhold}
+	e = env()
+	assert.Equal(t, []string{"API_TOKEN", "AWS_KEY", "DB_PASSWORD", "HOST"}, ep.Apply(e, secrets))
+	assert.NotContains(t, e, "DB_PASSWORD")
+	assert.Equal(t, RedactedValue, e["HOST"])
+}
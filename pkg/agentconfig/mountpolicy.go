@@ -0,0 +1,231 @@
+package agentconfig
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/telepresenceio/telepresence/rpc/v2/manager"
+)
+
+const (
+	// MountReadOnlyAnnotation, when set to "true", prevents intercepting clients from modifying the
+	// volumes of the workload's containers.
+	MountReadOnlyAnnotation = DomainPrefix + "inject-mount-read-only"
+
+	// MountAllowAnnotation is a comma separated list of container paths. When present, only those
+	// paths, and what's below them, are exposed to intercepting clients.
+	MountAllowAnnotation = DomainPrefix + "inject-mount-allow"
+
+	// MountDenyAnnotation is a comma separated list of container paths that are never exposed to
+	// intercepting clients, e.g. "/var/run/secrets".
+	MountDenyAnnotation = DomainPrefix + "inject-mount-deny"
+)
+
+// MountPolicy restricts what the traffic-agent exposes of the volumes of an intercepted container.
+// All paths are absolute paths in the intercepted container. A nil MountPolicy exposes everything.
+type MountPolicy struct {
+	// ReadOnly prevents all modifications
+	ReadOnly bool `json:"readOnly,omitempty" yaml:"readOnly,omitempty"`
+
+	// Allow, when not empty, is the list of paths that are exposed
+	Allow []string `json:"allow,omitempty" yaml:"allow,omitempty"`
+
+	// Deny is a list of paths that are never exposed
+	Deny []string `json:"deny,omitempty" yaml:"deny,omitempty"`
+}
+
+// MountPolicyFromAnnotations returns the MountPolicy declared by the given annotations, or nil
+// when no policy is declared.
+func MountPolicyFromAnnotations(annotations map[string]string) (*MountPolicy, error) {
+	mp := &MountPolicy{}
+	if s, ok := annotations[MountReadOnlyAnnotation]; ok {
+		ro, err := strconv.ParseBool(strings.TrimSpace(s))
+		if err != nil {
+			return nil, fmt.Errorf("invalid value %q for annotation %s", s, MountReadOnlyAnnotation)
+		}
+		mp.ReadOnly = ro
+	}
+	var err error
+	if mp.Allow, err = annotationPaths(annotations, MountAllowAnnotation); err != nil {
+		return nil, err
+	}
+	if mp.Deny, err = annotationPaths(annotations, MountDenyAnnotation); err != nil {
+		return nil, err
+	}
+	if mp.IsEmpty() {
+		return nil, nil
+	}
+	return mp, nil
+}
+
+func annotationPaths(annotations map[string]string, key string) ([]string, error) {
+	s := strings.TrimSpace(annotations[key])
+	if s == "" {
+		return nil, nil
+	}
+	ps, err := CleanMountPaths(strings.Split(s, ","))
+	if err != nil {
+		return nil, fmt.Errorf("invalid annotation %s: %w", key, err)
+	}
+	return ps, nil
+}
+
+// CleanMountPaths trims and cleans the given paths, and returns an error unless all of them are absolute.
+func CleanMountPaths(ps []string) ([]string, error) {
+	cps := make([]string, 0, len(ps))
+	for _, p := range ps {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		if !path.IsAbs(p) {
+			return nil, fmt.Errorf("mount path %q is not absolute", p)
+		}
+		cps = append(cps, path.Clean(p))
+	}
+	if len(cps) == 0 {
+		cps = nil
+	}
+	return cps, nil
+}
+
+// IsEmpty returns true if the policy doesn't restrict anything.
+func (mp *MountPolicy) IsEmpty() bool {
+	return mp == nil || !mp.ReadOnly && len(mp.Allow) == 0 && len(mp.Deny) == 0
+}
+
+// Merge returns a policy that is at least as restrictive as both this policy and the given policy.
+func (mp *MountPolicy) Merge(o *MountPolicy) *MountPolicy {
+	if mp.IsEmpty() {
+		return o
+	}
+	if o.IsEmpty() {
+		return mp
+	}
+	m := &MountPolicy{ReadOnly: mp.ReadOnly || o.ReadOnly}
+	m.Deny = appendUnique(append([]string(nil), mp.Deny...), o.Deny...)
+	switch {
+	case len(mp.Allow) == 0:
+		m.Allow = o.Allow
+	case len(o.Allow) == 0:
+		m.Allow = mp.Allow
+	default:
+		// A path is allowed when both policies allow it, so the result is the intersection
+		for _, a := range mp.Allow {
+			for _, b := range o.Allow {
+				switch {
+				case isBelow(a, b):
+					m.Allow = appendUnique(m.Allow, a)
+				case isBelow(b, a):
+					m.Allow = appendUnique(m.Allow, b)
+				}
+			}
+		}
+		if len(m.Allow) == 0 {
+			// Nothing is allowed
+			m.Allow = nil
+			m.Deny = []string{"/"}
+		}
+	}
+	return m
+}
+
+// Permits returns true if the given path, and everything below it that isn't explicitly denied,
+// is exposed by the policy.
+func (mp *MountPolicy) Permits(p string) bool {
+	if mp == nil {
+		return true
+	}
+	for _, d := range mp.Deny {
+		if isBelow(p, d) {
+			return false
+		}
+	}
+	if len(mp.Allow) == 0 {
+		return true
+	}
+	for _, a := range mp.Allow {
+		if isBelow(p, a) {
+			return true
+		}
+	}
+	return false
+}
+
+// Visible returns true if the given path is permitted, or if it is a directory that must be
+// traversed to reach a permitted path. Visible directories that aren't permitted can be listed,
+// but only the entries that are visible themselves should be shown.
+func (mp *MountPolicy) Visible(p string) bool {
+	if mp.Permits(p) {
+		return true
+	}
+	for _, d := range mp.Deny {
+		if isBelow(p, d) {
+			return false
+		}
+	}
+	for _, a := range mp.Allow {
+		if isBelow(a, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns a human-readable description of the policy.
+func (mp *MountPolicy) String() string {
+	if mp.IsEmpty() {
+		return "read-write"
+	}
+	sb := strings.Builder{}
+	if mp.ReadOnly {
+		sb.WriteString("read-only")
+	} else {
+		sb.WriteString("read-write")
+	}
+	if len(mp.Allow) > 0 {
+		sb.WriteString(", allow ")
+		sb.WriteString(strings.Join(mp.Allow, " "))
+	}
+	if len(mp.Deny) > 0 {
+		sb.WriteString(", deny ")
+		sb.WriteString(strings.Join(mp.Deny, " "))
+	}
+	return sb.String()
+}
+
+// MountPolicyFromRPC returns the MountPolicy that corresponds to the given gRPC message.
+func MountPolicyFromRPC(rp *manager.MountPolicy) *MountPolicy {
+	if rp == nil {
+		return nil
+	}
+	return &MountPolicy{ReadOnly: rp.ReadOnly, Allow: rp.Allow, Deny: rp.Deny}
+}
+
+// RPC returns the gRPC message that corresponds to this MountPolicy.
+func (mp *MountPolicy) RPC() *manager.MountPolicy {
+	if mp == nil {
+		return nil
+	}
+	return &manager.MountPolicy{ReadOnly: mp.ReadOnly, Allow: mp.Allow, Deny: mp.Deny}
+}
+
+// isBelow returns true if p is equal to dir or is a path below dir.
+func isBelow(p, dir string) bool {
+	return p == dir || dir == "/" || strings.HasPrefix(p, dir+"/")
+}
+
+func appendUnique(ps []string, as ...string) []string {
+nextA:
+	for _, a := range as {
+		for _, p := range ps {
+			if p == a {
+				continue nextA
+			}
+		}
+		ps = append(ps, a)
+	}
+	return ps
+}
@@ -0,0 +1,70 @@
+package agentconfig
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMountPolicyFromAnnotations(t *testing.T) {
+	mp, err := MountPolicyFromAnnotations(map[string]string{})
+	require.NoError(t, err)
+	assert.Nil(t, mp)
+
+	mp, err = MountPolicyFromAnnotations(map[string]string{
+		MountReadOnlyAnnotation: "true",
+		MountAllowAnnotation:    "/etc/config/, /data",
+		MountDenyAnnotation:     "/var/run/secrets",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, &MountPolicy{ReadOnly: true, Allow: []string{"/etc/config", "/data"}, Deny: []string{"/var/run/secrets"}}, mp)
+	assert.Equal(t, "read-only, allow /etc/config /data, deny /var/run/secrets", mp.String())
+
+	_, err = MountPolicyFromAnnotations(map[string]string{MountDenyAnnotation: "var/run"})
+	assert.Error(t, err)
+	_, err = MountPolicyFromAnnotations(map[string]string{MountReadOnlyAnnotation: "maybe"})
+	assert.Error(t, err)
+}
+
+func TestMountPolicy_Visible(t *testing.T) {
+	mp := &MountPolicy{Allow: []string{"/etc/config", "/var/run"}, Deny: []string{"/var/run/secrets"}}
+	tests := []struct {
+		path    string
+		permits bool
+		visible bool
+	}{
+		{"/", false, true},
+		{"/etc", false, true},
+		{"/etc/config", true, true},
+		{"/etc/config/app.yaml", true, true},
+		{"/etc/configs", false, false},
+		{"/etc/passwd", false, false},
+		{"/var/run/lock", true, true},
+		{"/var/run/secrets", false, false},
+		{"/var/run/secrets/token", false, false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.permits, mp.Permits(tt.path), "Permits(%q)", tt.path)
+		assert.Equal(t, tt.visible, mp.Visible(tt.path), "Visible(%q)", tt.path)
+	}
+	var nilPolicy *MountPolicy
+	assert.True(t, nilPolicy.Permits("/var/run/secrets"))
+}
+
+func TestMountPolicy_Merge(t *testing.T) {
+	wl := &MountPolicy{Allow: []string{"/etc", "/data"}, Deny: []string{"/etc/secret"}}
+	assert.Same(t, wl, wl.Merge(nil))
+
+	m := wl.Merge(&MountPolicy{ReadOnly: true, Allow: []string{"/etc/config", "/tmp"}, Deny: []string{"/data/cache"}})
+	assert.Equal(t, &MountPolicy{
+		ReadOnly: true,
+		Allow:    []string{"/etc/config"},
+		Deny:     []string{"/etc/secret", "/data/cache"},
+	}, m)
+
+	// Nothing is permitted when the allow-lists don't overlap
+	m = wl.Merge(&MountPolicy{Allow: []string{"/tmp"}})
+	assert.False(t, m.Visible("/etc"))
+	assert.False(t, m.Visible("/tmp"))
+}
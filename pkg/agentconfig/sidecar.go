@@ -21,10 +21,16 @@ const (
 	ExportsMountPoint    = "/tel_app_exports"
 	TempVolumeName       = "tel-agent-tmp"
 	TempMountPoint       = "/tmp"
+	TLSVolumeName        = "traffic-tls"
+	TLSMountPoint        = "/tel_agent_tls"
 	EnvPrefix            = "_TEL_"
 	EnvPrefixAgent       = EnvPrefix + "AGENT_"
 	EnvPrefixApp         = EnvPrefix + "APP_"
 
+	// EnvPrefixAppSecret is used instead of EnvPrefixApp for variables that the app container
+	// gets from a Secret using envFrom, so that the agent can tell that they are secret.
+	EnvPrefixAppSecret = EnvPrefix + "SECRET_"
+
 	// EnvInterceptContainer intercepted container propagated to client during intercept
 	EnvInterceptContainer = "TELEPRESENCE_CONTAINER"
 
@@ -36,6 +42,10 @@ const (
 
 	DomainPrefix     = "telepresence.getambassador.io/"
 	InjectAnnotation = DomainPrefix + "inject-" + ContainerName
+
+	// TLSSecretAnnotation names a Secret of type kubernetes.io/tls that the traffic-agent will use
+	// when it terminates TLS for intercepted connections.
+	TLSSecretAnnotation = DomainPrefix + "inject-tls-secret"
 )
 
 // Intercept describes the mapping between a service port and an intercepted container port
@@ -90,6 +100,10 @@ type Container struct {
 
 	// Mounts are the actual mount points that are mounted by this container
 	Mounts []string
+
+	// SecretEnv are the names of environment variables that the container gets from Secrets
+	// using valueFrom.secretKeyRef
+	SecretEnv []string `json:"secretEnv,omitempty" yaml:"secretEnv,omitempty"`
 }
 
 // The Sidecar configures the traffic-agent sidecar
@@ -127,6 +141,19 @@ type Sidecar struct {
 	// The port used by the agents restFUL API server
 	APIPort uint16 `json:"apiPort,omitempty" yaml:"apiPort,omitempty"`
 
+	// Name of a kubernetes.io/tls Secret containing the workload's certificate and key. The
+	// Secret is mounted into the agent which then can terminate TLS for intercepted connections.
+	TLSSecret string `json:"tlsSecret,omitempty" yaml:"tlsSecret,omitempty"`
+
+	// Restrictions that apply to what the agent exposes of the app containers' volumes. Declared
+	// using the MountReadOnlyAnnotation, MountAllowAnnotation, and MountDenyAnnotation.
+	MountPolicy *MountPolicy `json:"mountPolicy,omitempty" yaml:"mountPolicy,omitempty"`
+
+	// Controls which environment variables of the app containers that are passed on to
+	// intercepting clients. Configured for the traffic-manager and overridden by annotations
+	// on the namespace and the workload.
+	EnvPolicy *EnvPolicy `json:"envPolicy,omitempty" yaml:"envPolicy,omitempty"`
+
 	// The intercepts managed by the agent
 	Containers []*Container `json:"containers,omitempty" yaml:"containers,omitempty"`
 }
@@ -5,8 +5,10 @@ import (
 	"fmt"
 
 	core "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 
+	"github.com/datawire/dlib/dlog"
 	"github.com/telepresenceio/telepresence/v2/pkg/agentconfig"
 	"github.com/telepresenceio/telepresence/v2/pkg/install"
 	"github.com/telepresenceio/telepresence/v2/pkg/k8sapi"
@@ -26,6 +28,7 @@ type GeneratorConfig struct {
 	QualifiedAgentImage string
 	ManagerNamespace    string
 	LogLevel            string
+	EnvPolicy           *agentconfig.EnvPolicy
 }
 
 func GenerateForPod(ctx context.Context, pod *core.Pod, env *GeneratorConfig) (*agentconfig.Sidecar, error) {
@@ -82,6 +85,15 @@ func Generate(ctx context.Context, wl k8sapi.Workload, cfg *GeneratorConfig) (*a
 		return nil, fmt.Errorf("found no service with a port that matches a container in pod %s.%s", pod.Name, pod.Namespace)
 	}
 
+	mp, err := agentconfig.MountPolicyFromAnnotations(pod.Annotations)
+	if err != nil {
+		return nil, err
+	}
+	ep, err := EnvPolicy(ctx, wl.GetNamespace(), pod.Annotations, cfg.EnvPolicy)
+	if err != nil {
+		return nil, err
+	}
+
 	ag := &agentconfig.Sidecar{
 		AgentImage:   cfg.QualifiedAgentImage,
 		AgentName:    wl.GetName(),
@@ -92,11 +104,29 @@ func Generate(ctx context.Context, wl k8sapi.Workload, cfg *GeneratorConfig) (*a
 		ManagerHost:  ManagerAppName + "." + cfg.ManagerNamespace,
 		ManagerPort:  ManagerPortHTTP,
 		APIPort:      cfg.APIPort,
+		TLSSecret:    pod.Annotations[agentconfig.TLSSecretAnnotation],
+		MountPolicy:  mp,
+		EnvPolicy:    ep,
 		Containers:   ccs,
 	}
 	return ag, nil
 }
 
+// EnvPolicy returns the given policy overridden by the annotations of the namespace, if they can be
+// read, and then by the annotations of the workload.
+func EnvPolicy(ctx context.Context, namespace string, annotations map[string]string, ep *agentconfig.EnvPolicy) (*agentconfig.EnvPolicy, error) {
+	if ki := k8sapi.GetK8sInterface(ctx); ki != nil {
+		ns, err := ki.CoreV1().Namespaces().Get(ctx, namespace, meta.GetOptions{})
+		if err != nil {
+			// The traffic-manager isn't allowed to read namespaces when its RBAC is namespaced
+			dlog.Debugf(ctx, "unable to get annotations of namespace %s: %v", namespace, err)
+		} else if ep, err = ep.Override(ns.Annotations); err != nil {
+			return nil, fmt.Errorf("namespace %s: %w", namespace, err)
+		}
+	}
+	return ep.Override(annotations)
+}
+
 func appendAgentContainerConfigs(svc *core.Service, pod *core.PodTemplateSpec, portNumber func(int32) uint16, ccs []*agentconfig.Container) ([]*agentconfig.Container, error) {
 	portNameOrNumber := pod.Annotations[ServicePortAnnotation]
 	ports, err := install.FilterServicePorts(svc, portNameOrNumber)
@@ -144,6 +174,12 @@ nextSvcPort:
 				continue nextSvcPort
 			}
 		}
+		var secretEnv []string
+		for _, e := range cn.Env {
+			if e.ValueFrom != nil && e.ValueFrom.SecretKeyRef != nil {
+				secretEnv = append(secretEnv, e.Name)
+			}
+		}
 		var mounts []string
 		if l := len(cn.VolumeMounts); l > 0 {
 			mounts = make([]string, l)
@@ -156,6 +192,7 @@ nextSvcPort:
 			EnvPrefix:  CapsBase26(uint64(len(ccs))) + "_",
 			MountPoint: agentconfig.MountPrefixApp + "/" + cn.Name,
 			Mounts:     mounts,
+			SecretEnv:  secretEnv,
 			Intercepts: []*agentconfig.Intercept{ic},
 		})
 	}
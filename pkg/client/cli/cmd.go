@@ -119,8 +119,8 @@ func Command(ctx context.Context) *cobra.Command {
 	rootCmd.InitDefaultHelpCmd()
 	static := cliutil.CommandGroups{
 		"Session Commands": []*cobra.Command{connectCommand(), LoginCommand(), LogoutCommand(), LicenseCommand(), statusCommand(), quitCommand()},
-		"Traffic Commands": []*cobra.Command{listCommand(), interceptCommand(ctx), leaveCommand(), previewCommand()},
-		"Debug Commands":   []*cobra.Command{loglevelCommand(), gatherLogsCommand()},
+		"Traffic Commands": []*cobra.Command{listCommand(), interceptCommand(ctx), interceptAllCommand(), leaveCommand(), previewCommand(), upCommand(), downCommand(), execCommand(), cpCommand(), envCommand(), exposeCommand()},
+		"Debug Commands":   []*cobra.Command{loglevelCommand(), logsCommand(), gatherLogsCommand(), dnsCommand()},
 		"Other Commands":   []*cobra.Command{versionCommand(), uninstallCommand(), dashboardCommand(), ClusterIdCommand(), genYAMLCommand(), vpnDiagCommand()},
 	}
 	for name, cmds := range static {
@@ -0,0 +1,137 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/moby/term"
+	"github.com/spf13/cobra"
+
+	"github.com/telepresenceio/telepresence/rpc/v2/connector"
+	"github.com/telepresenceio/telepresence/v2/pkg/client/errcat"
+)
+
+type cpArgs struct {
+	namespace string
+	container string
+	recursive bool
+	quiet     bool
+}
+
+func cpCommand() *cobra.Command {
+	ca := &cpArgs{}
+	cmd := &cobra.Command{
+		Use:   "cp [flags] <workload>:<path> <local path> | <local path> <workload>:<path>",
+		Args:  cobra.ExactArgs(2),
+		Short: "Copy files between the volumes of a workload and the local filesystem",
+		Long: `Copy files between the volumes of a workload and the local filesystem.
+
+The files are copied using the sftp-server of the workload's traffic-agent, so the app
+container doesn't need tar, and only paths in the volumes of the app container can be
+copied. Paths that the workload's mount policy hides can't be copied, and nothing can be
+copied to a volume that the policy makes read-only. Only workloads in namespaces that are
+mapped by the current connection can be used.`,
+		Example: `# Fetch a generated report from the echo-easy deployment
+telepresence cp echo-easy:/var/reports/latest.html .
+
+# Push a directory of fixtures
+telepresence cp -r ./fixtures echo-easy:/data
+`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return ca.cp(cmd, args[0], args[1])
+		},
+	}
+	flags := cmd.Flags()
+	flags.StringVarP(&ca.namespace, "namespace", "n", "", "If present, the namespace scope for this CLI request")
+	flags.StringVarP(&ca.container, "container", "c", "", "Name of the app container. Defaults to the first container that the traffic-agent serves")
+	flags.BoolVarP(&ca.recursive, "recursive", "r", false, "Copy directories and everything below them")
+	flags.BoolVarP(&ca.quiet, "quiet", "q", false, "Don't show the progress of the copy")
+	return cmd
+}
+
+// splitRemotePath splits an argument of the form <workload>:<path>. Arguments that are paths
+// of the local filesystem, including Windows paths with a drive letter, are not remote.
+func splitRemotePath(arg string) (name, path string, ok bool) {
+	i := strings.IndexByte(arg, ':')
+	if i <= 0 || strings.ContainsAny(arg[:i], `/\`) || i == 1 && filepath.VolumeName(arg) != "" {
+		return "", "", false
+	}
+	return arg[:i], arg[i+1:], true
+}
+
+func (ca *cpArgs) cp(cmd *cobra.Command, src, dst string) error {
+	rq := &connector.CopyRequest{
+		Namespace: ca.namespace,
+		Container: ca.container,
+		Recursive: ca.recursive,
+	}
+	var local string
+	if name, path, ok := splitRemotePath(src); ok {
+		if _, _, ok = splitRemotePath(dst); ok {
+			return errcat.User.New("one of the paths must be a local path")
+		}
+		rq.Name, rq.RemotePath, local = name, path, dst
+	} else if name, path, ok = splitRemotePath(dst); ok {
+		rq.Name, rq.RemotePath, local = name, path, src
+		rq.ToRemote = true
+	} else {
+		return errcat.User.New("one of the paths must be a <workload>:<path>")
+	}
+	if !strings.HasPrefix(rq.RemotePath, "/") {
+		return errcat.User.Newf("remote path %q must be absolute", rq.RemotePath)
+	}
+	var err error
+	if rq.LocalPath, err = filepath.Abs(local); err != nil {
+		return errcat.User.New(err)
+	}
+
+	return withConnector(cmd, true, nil, func(ctx context.Context, cs *connectorState) error {
+		stream, err := cs.userD.Copy(ctx, rq)
+		if err != nil {
+			return err
+		}
+		out := cmd.ErrOrStderr()
+		tty := false
+		if f, ok := out.(*os.File); ok {
+			tty = term.IsTerminal(f.Fd())
+		}
+		cr := ""
+		if tty {
+			cr = "\r"
+		}
+		for {
+			p, err := stream.Recv()
+			if err != nil {
+				if errors.Is(err, io.EOF) {
+					return nil
+				}
+				return err
+			}
+			switch {
+			case ca.quiet:
+			case p.Copied == p.Size:
+				fmt.Fprintf(out, "%s%s %s\n", cr, p.Path, formatSize(p.Size))
+			case tty:
+				fmt.Fprintf(out, "\r%s %s/%s", p.Path, formatSize(p.Copied), formatSize(p.Size))
+			}
+		}
+	})
+}
+
+func formatSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%dB", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
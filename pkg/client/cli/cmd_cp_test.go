@@ -0,0 +1,35 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitRemotePath(t *testing.T) {
+	tests := []struct {
+		arg  string
+		name string
+		path string
+		ok   bool
+	}{
+		{"echo-easy:/data", "echo-easy", "/data", true},
+		{"echo-easy:", "echo-easy", "", true},
+		{"./echo-easy:/data", "", "", false},
+		{"/tmp/a:b", "", "", false},
+		{":/data", "", "", false},
+		{"data", "", "", false},
+	}
+	for _, tt := range tests {
+		name, path, ok := splitRemotePath(tt.arg)
+		assert.Equal(t, tt.ok, ok, tt.arg)
+		assert.Equal(t, tt.name, name, tt.arg)
+		assert.Equal(t, tt.path, path, tt.arg)
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	assert.Equal(t, "12B", formatSize(12))
+	assert.Equal(t, "1.5KiB", formatSize(1536))
+	assert.Equal(t, "2.0MiB", formatSize(2*1024*1024))
+}
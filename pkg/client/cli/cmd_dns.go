@@ -0,0 +1,208 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/spf13/cobra"
+	empty "google.golang.org/protobuf/types/known/emptypb"
+
+	"github.com/telepresenceio/telepresence/rpc/v2/daemon"
+	"github.com/telepresenceio/telepresence/v2/pkg/client/cli/cliutil"
+	"github.com/telepresenceio/telepresence/v2/pkg/client/cli/output"
+	"github.com/telepresenceio/telepresence/v2/pkg/client/errcat"
+)
+
+type dnsQuery struct {
+	Time    time.Time     `json:"time"`
+	Name    string        `json:"name"`
+	Type    string        `json:"type"`
+	Path    string        `json:"path"`
+	RCode   string        `json:"rcode"`
+	Answers int32         `json:"answers"`
+	Latency time.Duration `json:"latency_in_nanos"`
+}
+
+type dnsStats struct {
+	RequestCount int64            `json:"request_count"`
+	CacheHits    int64            `json:"cache_hits"`
+	CacheMisses  int64            `json:"cache_misses"`
+	CacheEntries int64            `json:"cache_entries"`
+	SuffixCounts map[string]int64 `json:"suffix_counts,omitempty"`
+}
+
+func dnsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:  "dns",
+		Args: OnlySubcommands,
+
+		Short: "Show the queries and statistics of the DNS resolver of the root daemon, or flush its cache",
+		RunE:  RunSubcommands,
+	}
+
+	follow := false
+	logCmd := &cobra.Command{
+		Use:  "log",
+		Args: cobra.NoArgs,
+
+		Short: "Show the most recent queries handled by the DNS resolver",
+		Long: `Show the most recent queries handled by the DNS resolver.
+
+Each query is shown with its type, name, response code, the number of records in the answer,
+the path that produced the answer, and the time it took. The path is "cluster" when the query
+was resolved in the cluster (or found in the cache), "fallback" when it was sent to the DNS
+server of the workstation, "local" when it's the name of a service that is intercepted and served
+on the workstation, and "rewrite" when it matched a DNS rewrite rule.`,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return withDNS(cmd, func(ctx context.Context, daemonClient daemon.DaemonClient) error {
+				return dnsLog(ctx, cmd, daemonClient, follow)
+			})
+		},
+	}
+	logCmd.Flags().BoolVarP(&follow, "follow", "f", false, "Continue to show queries as they are handled")
+
+	statsCmd := &cobra.Command{
+		Use:  "stats",
+		Args: cobra.NoArgs,
+
+		Short: "Show the request and cache statistics of the DNS resolver",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return withDNS(cmd, func(ctx context.Context, daemonClient daemon.DaemonClient) error {
+				return dnsStatsShow(ctx, cmd, daemonClient)
+			})
+		},
+	}
+
+	flushCmd := &cobra.Command{
+		Use:  "flush",
+		Args: cobra.NoArgs,
+
+		Short: "Remove all cached answers from the DNS resolver",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return withDNS(cmd, func(ctx context.Context, daemonClient daemon.DaemonClient) error {
+				if _, err := daemonClient.FlushDNS(ctx, &empty.Empty{}); err != nil {
+					return err
+				}
+				fmt.Fprintln(cmd.OutOrStdout(), "DNS cache flushed")
+				return nil
+			})
+		},
+	}
+
+	cmd.AddCommand(logCmd, statsCmd, flushCmd)
+	return cmd
+}
+
+func withDNS(cmd *cobra.Command, f func(context.Context, daemon.DaemonClient) error) error {
+	err := cliutil.WithStartedNetwork(cmd.Context(), f)
+	if errors.Is(err, cliutil.ErrNoNetwork) {
+		err = errcat.User.New("the root daemon is not running")
+	}
+	return err
+}
+
+// structuredStreamer returns the streamer of the command's output when JSON output is requested, or nil.
+func structuredStreamer(cmd *cobra.Command) output.StructuredStreamer {
+	if !output.WantsJSONOutput(cmd.Flags()) {
+		return nil
+	}
+	streamer, _ := cmd.OutOrStdout().(output.StructuredStreamer)
+	if streamer == nil {
+		panic("writer not output.StructuredStreamer")
+	}
+	return streamer
+}
+
+func dnsLog(ctx context.Context, cmd *cobra.Command, daemonClient daemon.DaemonClient, follow bool) error {
+	stream, err := daemonClient.WatchDNSQueries(ctx, &daemon.WatchDNSQueriesRequest{Follow: follow})
+	if err != nil {
+		return err
+	}
+	streamer := structuredStreamer(cmd)
+	var queries []*dnsQuery
+	for {
+		q, err := stream.Recv()
+		if err != nil {
+			if errors.Is(err, io.EOF) || ctx.Err() != nil {
+				break
+			}
+			return err
+		}
+		dq := &dnsQuery{
+			Time:    q.Time.AsTime().Local(),
+			Name:    q.Name,
+			Type:    q.Type,
+			Path:    q.Path,
+			RCode:   q.RCode,
+			Answers: q.Answers,
+			Latency: q.Latency.AsDuration(),
+		}
+		switch {
+		case streamer == nil:
+			fmt.Fprintf(cmd.OutOrStdout(), "%s %-6s %s -> %s, %d answers (%s, %s)\n",
+				dq.Time.Format("15:04:05.000"), dq.Type, dq.Name, dq.RCode, dq.Answers, dq.Path, dq.Latency.Round(time.Microsecond))
+		case follow:
+			streamer.StructuredStream(dq, nil)
+		default:
+			queries = append(queries, dq)
+		}
+	}
+	if streamer != nil && !follow {
+		if queries == nil {
+			queries = []*dnsQuery{}
+		}
+		streamer.StructuredStream(queries, nil)
+	}
+	return nil
+}
+
+func dnsStatsShow(ctx context.Context, cmd *cobra.Command, daemonClient daemon.DaemonClient) error {
+	rs, err := daemonClient.GetDNSStats(ctx, &empty.Empty{})
+	if err != nil {
+		return err
+	}
+	ds := &dnsStats{
+		RequestCount: rs.RequestCount,
+		CacheHits:    rs.CacheHits,
+		CacheMisses:  rs.CacheMisses,
+		CacheEntries: rs.CacheEntries,
+		SuffixCounts: rs.SuffixCounts,
+	}
+	if streamer := structuredStreamer(cmd); streamer != nil {
+		streamer.StructuredStream(ds, nil)
+		return nil
+	}
+
+	out := cmd.OutOrStdout()
+	fmt.Fprintf(out, "Requests     : %d\n", ds.RequestCount)
+	fmt.Fprintf(out, "Cache hits   : %d\n", ds.CacheHits)
+	fmt.Fprintf(out, "Cache misses : %d\n", ds.CacheMisses)
+	fmt.Fprintf(out, "Cache entries: %d\n", ds.CacheEntries)
+	if len(ds.SuffixCounts) == 0 {
+		return nil
+	}
+	sfxs := make([]string, 0, len(ds.SuffixCounts))
+	sfxLen := 0
+	for sfx := range ds.SuffixCounts {
+		sfxs = append(sfxs, sfx)
+		if len(sfx) > sfxLen {
+			sfxLen = len(sfx)
+		}
+	}
+	sort.Slice(sfxs, func(i, j int) bool {
+		ci, cj := ds.SuffixCounts[sfxs[i]], ds.SuffixCounts[sfxs[j]]
+		if ci != cj {
+			return ci > cj
+		}
+		return sfxs[i] < sfxs[j]
+	})
+	fmt.Fprintln(out, "Requests per suffix:")
+	for _, sfx := range sfxs {
+		fmt.Fprintf(out, "  %-*s: %d\n", sfxLen, sfx, ds.SuffixCounts[sfx])
+	}
+	return nil
+}
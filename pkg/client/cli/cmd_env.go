@@ -0,0 +1,70 @@
+package cli
+
+import (
+	"context"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/telepresenceio/telepresence/rpc/v2/connector"
+	"github.com/telepresenceio/telepresence/v2/pkg/client"
+	"github.com/telepresenceio/telepresence/v2/pkg/client/cli/envfile"
+)
+
+type envArgs struct {
+	namespace string
+	container string
+	envSyntax envfile.Syntax
+}
+
+func envCommand() *cobra.Command {
+	ea := &envArgs{}
+	cmd := &cobra.Command{
+		Use:   "env [flags] <workload>",
+		Args:  cobra.ExactArgs(1),
+		Short: "Show the environment of a workload's app container without intercepting it",
+		Long: `Show the environment of a workload's app container without intercepting it.
+
+The environment is obtained from the workload's traffic-agent when it has one. Otherwise,
+it's computed from the workload's pod template, with values from ConfigMaps and Secrets
+resolved, which requires permission to read them. No traffic is diverted. The names of the
+variables that the environment policy redacted or withheld are listed in the variable
+TELEPRESENCE_REDACTED_ENV.`,
+		Example: `# Load the environment of the echo-easy deployment into the current shell
+eval "$(telepresence env --env-syntax sh echo-easy)"
+`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return ea.env(cmd, args[0])
+		},
+	}
+	flags := cmd.Flags()
+	flags.StringVarP(&ea.namespace, "namespace", "n", "", "If present, the namespace scope for this CLI request")
+	flags.StringVarP(&ea.container, "container", "c", "", "Name of the app container. Defaults to the first container that the traffic-agent serves")
+	flags.StringVar((*string)(&ea.envSyntax), "env-syntax", string(envfile.Docker), ``+
+		`Syntax of the output. One of "docker" (the format used by docker run --env-file), `+
+		`"compose", "sh", "powershell", "systemd", "direnv", "json", "vscode" (a launch.json env block), `+
+		`or "intellij" (a .run.xml run configuration)`)
+	return cmd
+}
+
+func (ea *envArgs) env(cmd *cobra.Command, name string) error {
+	var err error
+	if ea.envSyntax, err = envfile.ParseSyntax(string(ea.envSyntax)); err != nil {
+		return err
+	}
+	return withConnector(cmd, true, nil, func(ctx context.Context, cs *connectorState) error {
+		r, err := cs.userD.GetEnvironment(ctx, &connector.EnvironmentRequest{
+			Name:      name,
+			Namespace: ea.namespace,
+			Container: ea.container,
+		})
+		if err != nil {
+			return err
+		}
+		env := client.GetConfig(ctx).Intercept.Env.Apply(r.Environment)
+		if len(r.RedactedEnvironment) > 0 {
+			env["TELEPRESENCE_REDACTED_ENV"] = strings.Join(r.RedactedEnvironment, ",")
+		}
+		return envfile.Write(cmd.OutOrStdout(), env, ea.envSyntax, name)
+	})
+}
@@ -0,0 +1,116 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/telepresenceio/telepresence/rpc/v2/manager"
+	"github.com/telepresenceio/telepresence/v2/pkg/client/errcat"
+)
+
+type execArgs struct {
+	namespace string
+	container string
+}
+
+func execCommand() *cobra.Command {
+	ea := &execArgs{}
+	cmd := &cobra.Command{
+		Use:   "exec [flags] <workload> -- <command> [args...]",
+		Args:  cobra.MinimumNArgs(2),
+		Short: "Run a command in the traffic-agent of a workload, with the environment and mounts of its app container",
+		Long: `Run a command in the traffic-agent of a workload, with the environment and mounts of its app container.
+
+The command's standard input, output, and error are streamed through the traffic-manager.
+The mounts of the app container are found below the directory in the TELEPRESENCE_ROOT
+environment variable, which is also the command's working directory. Only workloads in
+namespaces that are mapped by the current connection can be used, and the traffic-manager
+refuses to run commands unless its agentInjector.execEnabled Helm value is true.`,
+		Example: `# List the mounts of the echo-easy deployment's app container
+telepresence exec echo-easy -- ls -R .
+
+# Run a shell script with the app's environment
+telepresence exec echo-easy -- sh -c 'echo $DATABASE_HOST'
+`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if cmd.ArgsLenAtDash() != 1 {
+				return errcat.User.New("the command must be separated from the workload name by --")
+			}
+			exitCode, err := ea.exec(cmd, args[0], args[1:])
+			if err == nil && exitCode != 0 {
+				os.Exit(exitCode)
+			}
+			return err
+		},
+	}
+	flags := cmd.Flags()
+	flags.StringVarP(&ea.namespace, "namespace", "n", "", "If present, the namespace scope for this CLI request")
+	flags.StringVarP(&ea.container, "container", "c", "", "Name of the app container. Defaults to the first container that the traffic-agent serves")
+	return cmd
+}
+
+func (ea *execArgs) exec(cmd *cobra.Command, name string, command []string) (exitCode int, err error) {
+	err = withConnector(cmd, true, nil, func(ctx context.Context, cs *connectorState) error {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		stream, err := cs.userD.Exec(ctx)
+		if err != nil {
+			return err
+		}
+		err = stream.Send(&manager.ExecMessage{Request: &manager.ExecRequest{
+			Name:      name,
+			Namespace: ea.namespace,
+			Container: ea.container,
+			Command:   command,
+		}})
+		if err != nil {
+			return err
+		}
+
+		// Relay standard input until it's closed
+		go func() {
+			stdin := cmd.InOrStdin()
+			buf := make([]byte, 0x8000)
+			for {
+				n, err := stdin.Read(buf)
+				if n > 0 {
+					if stream.Send(&manager.ExecMessage{Stdin: append([]byte(nil), buf[:n]...)}) != nil {
+						return
+					}
+				}
+				if err != nil {
+					_ = stream.CloseSend()
+					return
+				}
+			}
+		}()
+
+		stdout := cmd.OutOrStdout()
+		stderr := cmd.ErrOrStderr()
+		for {
+			msg, err := stream.Recv()
+			if err != nil {
+				if errors.Is(err, io.EOF) {
+					return errcat.Unknown.New("the command ended without an exit status")
+				}
+				return err
+			}
+			switch {
+			case msg.Error != "":
+				return errcat.User.New(msg.Error)
+			case msg.Exited:
+				exitCode = int(msg.ExitCode)
+				return nil
+			case len(msg.Stdout) > 0:
+				_, _ = stdout.Write(msg.Stdout)
+			case len(msg.Stderr) > 0:
+				_, _ = stderr.Write(msg.Stderr)
+			}
+		}
+	})
+	return exitCode, err
+}
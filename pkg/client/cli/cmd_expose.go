@@ -0,0 +1,114 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/telepresenceio/telepresence/rpc/v2/manager"
+	"github.com/telepresenceio/telepresence/v2/pkg/client/errcat"
+)
+
+type exposeArgs struct {
+	namespace string
+	port      string
+	remove    bool
+}
+
+func exposeCommand() *cobra.Command {
+	ea := &exposeArgs{}
+	cmd := &cobra.Command{
+		Use:   "expose [flags] <service>",
+		Args:  cobra.ExactArgs(1),
+		Short: "Expose a local port in the cluster as a Service",
+		Long: `Expose a local port in the cluster as a Service.
+
+The traffic-manager creates a Service with the given name, and relays the connections that
+it receives to the local port. No workload needs to exist. The Service is removed when
+telepresence expose --remove is used, or when the session ends. The namespace must be mapped
+by the current connection, and the traffic-manager refuses to create Services unless its
+expose.enabled Helm value is true.`,
+		Example: `# Make the server on local port 8080 available as my-new-svc:8080 in the cluster
+telepresence expose my-new-svc --port 8080
+
+# Make the server on local port 8080 available as my-new-svc:80
+telepresence expose my-new-svc --port 8080:80
+
+# Remove the Service again
+telepresence expose my-new-svc --remove
+`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return ea.expose(cmd, args[0])
+		},
+	}
+	flags := cmd.Flags()
+	flags.StringVarP(&ea.namespace, "namespace", "n", "", "If present, the namespace scope for this CLI request")
+	flags.StringVarP(&ea.port, "port", "p", "", "Local port to expose, optionally followed by a colon and the port of the Service. "+
+		"The Service uses the local port when no port is given")
+	flags.BoolVar(&ea.remove, "remove", false, "Remove a Service that was created by a previous expose")
+	return cmd
+}
+
+// parseExposePort parses a port specification on the form <local port>[:<service port>].
+func parseExposePort(s string) (localPort, svcPort int32, err error) {
+	parse := func(s string) (int32, error) {
+		p, err := strconv.ParseUint(s, 10, 16)
+		if err != nil || p == 0 {
+			return 0, errcat.User.Newf("invalid port number %q", s)
+		}
+		return int32(p), nil
+	}
+	local, svc, found := strings.Cut(s, ":")
+	if localPort, err = parse(local); err != nil {
+		return 0, 0, err
+	}
+	if !found {
+		return localPort, localPort, nil
+	}
+	if svcPort, err = parse(svc); err != nil {
+		return 0, 0, err
+	}
+	return localPort, svcPort, nil
+}
+
+func (ea *exposeArgs) expose(cmd *cobra.Command, name string) error {
+	var localPort, svcPort int32
+	if ea.remove {
+		if ea.port != "" {
+			return errcat.User.New("--port cannot be used with --remove")
+		}
+	} else {
+		if ea.port == "" {
+			return errcat.User.New("--port is required")
+		}
+		var err error
+		if localPort, svcPort, err = parseExposePort(ea.port); err != nil {
+			return err
+		}
+	}
+	return withConnector(cmd, true, nil, func(ctx context.Context, cs *connectorState) error {
+		if ea.remove {
+			_, err := cs.userD.Unexpose(ctx, &manager.UnexposeRequest{
+				Name:      name,
+				Namespace: ea.namespace,
+			})
+			if err == nil {
+				fmt.Fprintf(cmd.OutOrStdout(), "Removed service %s\n", name)
+			}
+			return err
+		}
+		_, err := cs.userD.Expose(ctx, &manager.ExposeRequest{
+			Name:      name,
+			Namespace: ea.namespace,
+			Port:      svcPort,
+			LocalPort: localPort,
+		})
+		if err == nil {
+			fmt.Fprintf(cmd.OutOrStdout(), "Service %s port %d relays to local port %d\n", name, svcPort, localPort)
+		}
+		return err
+	})
+}
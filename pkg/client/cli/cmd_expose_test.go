@@ -0,0 +1,29 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseExposePort(t *testing.T) {
+	tests := []struct {
+		spec      string
+		localPort int32
+		svcPort   int32
+		ok        bool
+	}{
+		{"8080", 8080, 8080, true},
+		{"8080:80", 8080, 80, true},
+		{"0", 0, 0, false},
+		{"8080:", 0, 0, false},
+		{"65536", 0, 0, false},
+		{"http", 0, 0, false},
+	}
+	for _, tt := range tests {
+		localPort, svcPort, err := parseExposePort(tt.spec)
+		assert.Equal(t, tt.ok, err == nil, tt.spec)
+		assert.Equal(t, tt.localPort, localPort, tt.spec)
+		assert.Equal(t, tt.svcPort, svcPort, tt.spec)
+	}
+}
@@ -12,6 +12,7 @@ import (
 
 	"github.com/telepresenceio/telepresence/rpc/v2/connector"
 	"github.com/telepresenceio/telepresence/rpc/v2/manager"
+	"github.com/telepresenceio/telepresence/v2/pkg/agentconfig"
 	"github.com/telepresenceio/telepresence/v2/pkg/client"
 	"github.com/telepresenceio/telepresence/v2/pkg/client/cli/output"
 )
@@ -244,8 +245,13 @@ func describeIntercept(ii *manager.InterceptInfo, volumeMountsPrevented error, d
 		fields = append(fields, kv{"Metadata", fmt.Sprintf("%q", ii.Metadata)})
 	}
 
+	if len(ii.RedactedEnvironment) > 0 {
+		fields = append(fields, kv{"Redacted Environment", strings.Join(ii.RedactedEnvironment, ", ")})
+	}
+
 	if ii.ClientMountPoint != "" {
 		fields = append(fields, kv{"Volume Mount Point", ii.ClientMountPoint})
+		fields = append(fields, kv{"Volume Mount Policy", agentconfig.MountPolicyFromRPC(ii.MountPolicy).String()})
 	} else if volumeMountsPrevented != nil {
 		fields = append(fields, kv{"Volume Mount Error", volumeMountsPrevented.Error()})
 	}
@@ -272,6 +278,24 @@ func describeIntercept(ii *manager.InterceptInfo, volumeMountsPrevented error, d
 	if l5Hostname := ii.GetPreviewSpec().GetIngress().GetL5Host(); l5Hostname != "" {
 		fields = append(fields, kv{"Layer 5 Hostname", l5Hostname})
 	}
+	if len(ii.HookResults) > 0 {
+		fields = append(fields, kv{"Hooks", func() string {
+			hb := strings.Builder{}
+			for _, hr := range ii.HookResults {
+				fmt.Fprintf(&hb, "%s %s: ", hr.Phase, hr.Command)
+				switch {
+				case hr.Error == "":
+					hb.WriteString("ok")
+				case hr.Ignored:
+					fmt.Fprintf(&hb, "failed (ignored): %s", hr.Error)
+				default:
+					fmt.Fprintf(&hb, "failed: %s", hr.Error)
+				}
+				hb.WriteByte('\n')
+			}
+			return hb.String()
+		}()})
+	}
 
 	klen := 0
 	for _, kv := range fields {
@@ -0,0 +1,86 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/spf13/cobra"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"github.com/telepresenceio/telepresence/rpc/v2/manager"
+	"github.com/telepresenceio/telepresence/v2/pkg/client/errcat"
+)
+
+type logsArgs struct {
+	follow    bool
+	since     time.Duration
+	namespace string
+	prefix    bool
+}
+
+func logsCommand() *cobra.Command {
+	la := &logsArgs{}
+	cmd := &cobra.Command{
+		Use:   "logs [flags] <workload|traffic-manager>",
+		Args:  cobra.ExactArgs(1),
+		Short: "Show the log output of the traffic-agents of a workload, or of the traffic-manager",
+		Long: `Show the log output of the traffic-agents of a workload, or of the traffic-manager.
+
+The output is streamed through the traffic-manager, so no permission to read pod logs is
+needed, but only workloads in namespaces that are mapped by the current connection can be
+used. The traffic-manager and its traffic-agents retain the most recent part of their output.`,
+		Example: `# Follow the output of the traffic-agents of the echo-easy deployment
+telepresence logs -f echo-easy
+
+# Show what the traffic-manager logged during the last five minutes
+telepresence logs --since 5m traffic-manager
+`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return la.logs(cmd, args[0])
+		},
+	}
+	flags := cmd.Flags()
+	flags.BoolVarP(&la.follow, "follow", "f", false, "Keep streaming new output until interrupted")
+	flags.DurationVar(&la.since, "since", 0, "Only show output that is newer than this, e.g. 10s or 5m. Defaults to all retained output")
+	flags.StringVarP(&la.namespace, "namespace", "n", "", "If present, the namespace scope for this CLI request")
+	flags.BoolVar(&la.prefix, "prefix", false, "Prefix each line with the name of the pod that produced it")
+	return cmd
+}
+
+func (la *logsArgs) logs(cmd *cobra.Command, name string) error {
+	if la.since < 0 {
+		return errcat.User.New("--since must not be negative")
+	}
+	return withConnector(cmd, true, nil, func(ctx context.Context, cs *connectorState) error {
+		rq := &manager.WatchLogsRequest{
+			Name:      name,
+			Namespace: la.namespace,
+			Follow:    la.follow,
+		}
+		if la.since > 0 {
+			rq.Since = durationpb.New(la.since)
+		}
+		stream, err := cs.userD.WatchLogs(ctx, rq)
+		if err != nil {
+			return err
+		}
+		stdout := cmd.OutOrStdout()
+		for {
+			entry, err := stream.Recv()
+			if err != nil {
+				if errors.Is(err, io.EOF) || ctx.Err() != nil {
+					return nil
+				}
+				return err
+			}
+			if la.prefix {
+				fmt.Fprintf(stdout, "[%s] %s\n", entry.PodName, entry.Text)
+			} else {
+				fmt.Fprintln(stdout, entry.Text)
+			}
+		}
+	})
+}
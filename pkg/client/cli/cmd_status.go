@@ -57,6 +57,8 @@ type connectorStatus struct {
 	Error             string                         `json:"error,omitempty"`
 	KubernetesServer  string                         `json:"kubernetes_server,omitempty"`
 	KubernetesContext string                         `json:"kubernetes_context,omitempty"`
+	SOCKSProxy        string                         `json:"socks_proxy,omitempty"`
+	HTTPProxy         string                         `json:"http_proxy,omitempty"`
 	Intercepts        []connectStatusIntercept       `json:"intercepts,omitempty"`
 }
 
@@ -206,6 +208,8 @@ func (s *statusInfo) connectorStatus(ctx context.Context) (*connectorStatus, err
 		}
 		cs.KubernetesServer = status.ClusterServer
 		cs.KubernetesContext = status.ClusterContext
+		cs.SOCKSProxy = status.SocksProxy
+		cs.HTTPProxy = status.HttpProxy
 		for _, icept := range status.GetIntercepts().GetIntercepts() {
 			cs.Intercepts = append(cs.Intercepts, connectStatusIntercept{
 				Name:   icept.Spec.Name,
@@ -285,6 +289,12 @@ func (s *statusInfo) printConnectorText(cs *connectorStatus) {
 		}
 		s.printf("  Kubernetes server : %s\n", cs.KubernetesServer)
 		s.printf("  Kubernetes context: %s\n", cs.KubernetesContext)
+		if cs.SOCKSProxy != "" {
+			s.printf("  SOCKS5 proxy      : %s\n", cs.SOCKSProxy)
+		}
+		if cs.HTTPProxy != "" {
+			s.printf("  HTTP proxy        : %s\n", cs.HTTPProxy)
+		}
 		s.printf("  Intercepts        : %d total\n", len(cs.Intercepts))
 		for _, intercept := range cs.Intercepts {
 			s.printf("    %s: %s\n", intercept.Name, intercept.Client)
@@ -413,6 +413,7 @@ func (g *genInitContainerInfo) run(cmd *cobra.Command, kubeFlags map[string]stri
 
 type genVolumeInfo struct {
 	*genYAMLInfo
+	tlsSecret string
 }
 
 func genVolumeSubCommand(yamlInfo *genYAMLInfo) *cobra.Command {
@@ -426,6 +427,8 @@ func genVolumeSubCommand(yamlInfo *genYAMLInfo) *cobra.Command {
 	}
 	flags := cmd.Flags()
 	flags.StringVarP(&info.workloadName, "workload", "w", "", "Name of the workload.")
+	flags.StringVar(&info.tlsSecret, "tls-secret", "",
+		"Name of a kubernetes.io/tls Secret that the traffic-agent should use when terminating TLS.")
 	return cmd
 }
 
@@ -433,6 +436,6 @@ func (g *genVolumeInfo) run(*cobra.Command, []string) error {
 	if g.workloadName == "" {
 		return errcat.User.New("missing required flag --workload")
 	}
-	volumes := agentconfig.AgentVolumes(g.workloadName)
+	volumes := agentconfig.AgentVolumes(g.workloadName, g.tlsSecret)
 	return g.writeObjToOutput(&volumes)
 }
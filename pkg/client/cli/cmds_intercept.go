@@ -9,10 +9,10 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"reflect"
 	"regexp"
 	"runtime"
-	"sort"
 	"strconv"
 	"strings"
 
@@ -20,6 +20,7 @@ import (
 	"github.com/spf13/cobra"
 	empty "google.golang.org/protobuf/types/known/emptypb"
 	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 
 	"github.com/datawire/dlib/dcontext"
 	"github.com/datawire/dlib/dexec"
@@ -30,9 +31,11 @@ import (
 	"github.com/telepresenceio/telepresence/v2/pkg/client"
 	"github.com/telepresenceio/telepresence/v2/pkg/client/cache"
 	"github.com/telepresenceio/telepresence/v2/pkg/client/cli/cliutil"
+	"github.com/telepresenceio/telepresence/v2/pkg/client/cli/envfile"
 	"github.com/telepresenceio/telepresence/v2/pkg/client/cli/extensions"
 	"github.com/telepresenceio/telepresence/v2/pkg/client/errcat"
 	"github.com/telepresenceio/telepresence/v2/pkg/client/scout"
+	"github.com/telepresenceio/telepresence/v2/pkg/glob"
 	"github.com/telepresenceio/telepresence/v2/pkg/proc"
 )
 
@@ -47,11 +50,22 @@ type interceptArgs struct {
 	previewEnabled bool                 // --preview-url // only valid if !localOnly
 	previewSpec    *manager.PreviewSpec // --preview-url-* // only valid if !localOnly
 
-	envFile  string   // --env-file
-	envJSON  string   // --env-json
-	mount    string   // --mount // "true", "false", or desired mount point // only valid if !localOnly
-	mountSet bool     // whether --mount was passed
-	toPod    []string // --to-pod
+	envFile   string         // --env-file
+	envSyntax envfile.Syntax // --env-syntax
+	envJSON   string         // --env-json
+	mount     string         // --mount // "true", "false", or desired mount point // only valid if !localOnly
+	mountSet  bool           // whether --mount was passed
+	toPod     []string       // --to-pod
+
+	mountMode        string   // --mount-mode // only valid if !localOnly
+	mountInclude     []string // --mount-include // only valid with mountMode copy or sync
+	mountExclude     []string // --mount-exclude // only valid with mountMode copy or sync
+	mountMaxFileSize string   // --mount-max-file-size // only valid with mountMode copy or sync
+	mountReadOnly    bool     // --mount-read-only
+	mountAllow       []string // --mount-allow
+	mountDeny        []string // --mount-deny
+
+	terminateTLS bool // --terminate-tls // only valid if !localOnly
 
 	dockerRun   bool   // --docker-run
 	dockerMount string // --docker-mount // where to mount in a docker container. Defaults to mount unless mount is "true" or "false".
@@ -61,6 +75,9 @@ type interceptArgs struct {
 
 	cmdline []string // Args[1:]
 
+	restart restartPolicy // --restart // only valid with a cmdline
+	watch   []string      // --watch // only valid with a cmdline
+
 	// ingress cmd inputs
 	ingressHost string
 	ingressPort int32
@@ -138,8 +155,12 @@ func interceptCommand(ctx context.Context) *cobra.Command {
 	addPreviewFlags("preview-url-", flags, args.previewSpec)
 
 	flags.StringVarP(&args.envFile, "env-file", "e", "", ``+
-		`Also emit the remote environment to an env file in Docker Compose format. `+
-		`See https://docs.docker.com/compose/env-file/ for more information on the limitations of this format.`)
+		`Also emit the remote environment to an env file. The syntax of the file is controlled by --env-syntax.`)
+
+	flags.StringVar((*string)(&args.envSyntax), "env-syntax", string(envfile.Docker), ``+
+		`Syntax of the file given by --env-file. One of "docker" (the format used by docker run --env-file), `+
+		`"compose", "sh", "powershell", "systemd", "direnv", "json", "vscode" (a launch.json env block), `+
+		`or "intellij" (a .run.xml run configuration)`)
 
 	flags.StringVarP(&args.envJSON, "env-json", "j", "", `Also emit the remote environment to a file as a JSON blob.`)
 
@@ -147,11 +168,42 @@ func interceptCommand(ctx context.Context) *cobra.Command {
 		`The absolute path for the root directory where volumes will be mounted, $TELEPRESENCE_ROOT. Use "true" to `+
 		`have Telepresence pick a random mount point (default). Use "false" to disable filesystem mounting entirely.`)
 
+	flags.StringVar(&args.mountMode, "mount-mode", client.MountModeSSHFS, ``+
+		`How remote volumes are made available at the mount point. Use "sshfs" to mount them (default), "nfs" to `+
+		`mount them using the kernel's NFS client, "copy" to copy them once, or "sync" to copy them and then keep `+
+		`polling for remote changes. The "nfs", "copy", and "sync" modes don't require sshfs, and local changes to `+
+		`the copies are never sent back to the cluster`)
+
+	flags.StringSliceVar(&args.mountInclude, "mount-include", nil, ``+
+		`Only copy the remote files that match this glob pattern when --mount-mode is "copy" or "sync". Patterns are `+
+		`relative to the mount root and "**" matches any number of directories, e.g. "app/**/*.json"`)
+
+	flags.StringSliceVar(&args.mountExclude, "mount-exclude", nil, ``+
+		`Never copy the remote files or directories that match this glob pattern when --mount-mode is "copy" or "sync"`)
+
+	flags.StringVar(&args.mountMaxFileSize, "mount-max-file-size", "", ``+
+		`Don't copy remote files larger than this size, e.g. "10Mi", when --mount-mode is "copy" or "sync"`)
+
+	flags.BoolVar(&args.mountReadOnly, "mount-read-only", false, ``+
+		`Ask the traffic-agent to reject all modifications of the remote volumes`)
+
+	flags.StringSliceVar(&args.mountAllow, "mount-allow", nil, ``+
+		`Ask the traffic-agent to only expose this path, and what's below it, of the intercepted container's volumes. `+
+		`Can be repeated. Restrictions declared by the workload's `+agentconfig.MountAllowAnnotation+` annotation still apply`)
+
+	flags.StringSliceVar(&args.mountDeny, "mount-deny", nil, ``+
+		`Ask the traffic-agent to never expose this path, and what's below it, of the intercepted container's volumes, `+
+		`e.g. "/var/run/secrets". Can be repeated`)
+
 	flags.StringSliceVar(&args.toPod, "to-pod", []string{}, ``+
 		`An additional port to forward from the intercepted pod, will be made available at localhost:PORT `+
 		`Use this to, for example, access proxy/helper sidecars in the intercepted pod. The default protocol is TCP. `+
 		`Use <port>/UDP for UDP ports`)
 
+	flags.BoolVar(&args.terminateTLS, "terminate-tls", false, ``+
+		`Let the traffic-agent terminate TLS so that the intercepted traffic arrives unencrypted. `+
+		`Requires that the workload is annotated with `+agentconfig.TLSSecretAnnotation+` naming a kubernetes.io/tls Secret`)
+
 	flags.BoolVarP(&args.dockerRun, "docker-run", "", false, ``+
 		`Run a Docker container with intercepted environment, volume mount, by passing arguments after -- to 'docker run', `+
 		`e.g. '--docker-run -- -it --rm ubuntu:20.04 /bin/bash'`)
@@ -159,6 +211,14 @@ func interceptCommand(ctx context.Context) *cobra.Command {
 	flags.StringVarP(&args.dockerMount, "docker-mount", "", "", ``+
 		`The volume mount point in docker. Defaults to same as "--mount"`)
 
+	flags.StringVar((*string)(&args.restart), "restart", string(restartNo), ``+
+		`Restart policy for the command that serves the intercept. Use "on-failure" to restart it when it exits `+
+		`with an error, or "always" to restart it whenever it exits. The intercept remains active during restarts`)
+
+	flags.StringSliceVar(&args.watch, "watch", nil, ``+
+		`Restart the command that serves the intercept when a file matching this glob pattern changes. `+
+		`Patterns are relative to the current directory and "**" matches any number of directories, e.g. "**/*.go"`)
+
 	flags.StringVarP(&args.namespace, "namespace", "n", "", "If present, the namespace scope for this CLI request")
 
 	flags.StringVar(&args.ingressHost, "ingress-host", "", "If this flag is set, the ingress dialogue will be skipped,"+
@@ -197,12 +257,15 @@ func interceptCommand(ctx context.Context) *cobra.Command {
 			if cmd.Flag("port").Changed {
 				return errcat.User.New("a local-only intercept cannot have a port")
 			}
-			if cmd.Flag("mount").Changed {
+			if cmd.Flag("mount").Changed || cmd.Flag("mount-mode").Changed {
 				return errcat.User.New("a local-only intercept cannot have mounts")
 			}
 			if cmd.Flag("preview-url").Changed && args.previewEnabled {
 				return errcat.User.New("a local-only intercept cannot be previewed")
 			}
+			if args.terminateTLS {
+				return errcat.User.New("a local-only intercept cannot terminate TLS")
+			}
 		case false:
 			// Actually intercepting something
 			if args.agentName == "" {
@@ -213,6 +276,27 @@ func interceptCommand(ctx context.Context) *cobra.Command {
 			}
 		}
 		args.mountSet = cmd.Flag("mount").Changed
+		if args.envSyntax, err = envfile.ParseSyntax(string(args.envSyntax)); err != nil {
+			return err
+		}
+		if cmd.Flag("env-syntax").Changed && args.envFile == "" {
+			return errcat.User.New("--env-syntax requires --env-file")
+		}
+		if err = validateMountMode(&args); err != nil {
+			return err
+		}
+		if args.mountAllow, err = agentconfig.CleanMountPaths(args.mountAllow); err != nil {
+			return errcat.User.Newf("invalid --mount-allow: %w", err)
+		}
+		if args.mountDeny, err = agentconfig.CleanMountPaths(args.mountDeny); err != nil {
+			return errcat.User.Newf("invalid --mount-deny: %w", err)
+		}
+		if args.restart, err = parseRestartPolicy(string(args.restart)); err != nil {
+			return err
+		}
+		if len(args.cmdline) == 0 && !args.dockerRun && (args.restart != restartNo || len(args.watch) > 0) {
+			return errcat.User.New("--restart and --watch require a command to run")
+		}
 		if args.dockerRun {
 			if err := validateDockerArgs(args.cmdline); err != nil {
 				return err
@@ -257,7 +341,7 @@ func intercept(cmd *cobra.Command, args interceptArgs) error {
 			return client.WithEnsuredState(ctx, is, false, func() (err error) {
 				ctx, cancel := context.WithCancel(dcontext.WithSoftness(ctx))
 				defer cancel()
-				var cmd *dexec.Cmd
+				var start func(context.Context) (*dexec.Cmd, error)
 				if args.dockerRun {
 					envFile := is.args.envFile
 					if envFile == "" {
@@ -272,29 +356,53 @@ func intercept(cmd *cobra.Command, args interceptArgs) error {
 						}
 						envFile = file.Name()
 					}
-					cmd, err = is.startInDocker(ctx, envFile, args.cmdline)
+					start = func(ctx context.Context) (*dexec.Cmd, error) {
+						return is.startInDocker(ctx, envFile, args.cmdline)
+					}
 				} else {
-					cmd, err = proc.Start(ctx, is.env, args.cmdline[0], args.cmdline[1:]...)
+					start = func(ctx context.Context) (*dexec.Cmd, error) {
+						return proc.Start(ctx, is.env, args.cmdline[0], args.cmdline[1:]...)
+					}
+				}
+
+				// Send info about the pid and intercept id to the traffic-manager so that it kills
+				// the process if it receives a leave of quit call. The pid is the pid of this
+				// process, so it remains valid when the command is restarted.
+				cc := is.connectorClient
+				ior := &connector.Interceptor{
+					InterceptId: is.env["TELEPRESENCE_INTERCEPT_ID"],
+					Pid:         int32(os.Getpid()),
+				}
+				if _, err = cc.AddInterceptor(ctx, ior); err != nil {
+					return err
 				}
-				if err == nil {
-					// Send info about the pid and intercept id to the traffic-manager so that it kills
-					// the process if it receives a leave of quit call.
-					cc := is.connectorClient
-					ior := &connector.Interceptor{
-						InterceptId: is.env["TELEPRESENCE_INTERCEPT_ID"],
-						Pid:         int32(os.Getpid()),
+				defer func() {
+					if _, err := cc.RemoveInterceptor(ctx, ior); err != nil {
+						dlog.Error(ctx, err)
 					}
-					if _, err = cc.AddInterceptor(ctx, ior); err != nil {
-						_ = cmd.Process.Kill()
+				}()
+
+				sv := &supervisor{
+					policy: args.restart,
+					watch:  args.watch,
+					out:    is.cmd.OutOrStdout(),
+					start:  start,
+					setRestarting: func(ctx context.Context, restarting bool) error {
+						_, err := is.managerClient.UpdateIntercept(ctx, &manager.UpdateInterceptRequest{
+							Session:                is.connInfo.SessionInfo,
+							Name:                   args.name,
+							LocalProcessRestarting: &restarting,
+						})
 						return err
-					}
-					defer func() {
-						if _, err := cc.RemoveInterceptor(ctx, ior); err != nil {
-							dlog.Error(ctx, err)
-						}
-					}()
-					err = proc.Wait(ctx, cancel, cmd)
+					},
 				}
+				if args.localOnly {
+					// There's no agent that can forward to the intercepted container
+					sv.setRestarting = func(context.Context, bool) error { return nil }
+				} else {
+					sv.readyAddr = fmt.Sprintf("127.0.0.1:%d", is.localPort)
+				}
+				err = sv.run(ctx, cancel)
 				// The external command will not output anything to the logs. An error here
 				// is likely caused by the user hitting <ctrl>-C to terminate the process.
 				if err != nil {
@@ -481,6 +589,11 @@ func (is *interceptState) createRequest(ctx context.Context) (*connector.CreateI
 
 	spec.Agent = is.args.agentName
 	spec.TargetHost = "127.0.0.1"
+	spec.TerminateTls = is.args.terminateTLS
+	mp := &agentconfig.MountPolicy{ReadOnly: is.args.mountReadOnly, Allow: is.args.mountAllow, Deny: is.args.mountDeny}
+	if !mp.IsEmpty() {
+		spec.MountPolicy = mp.RPC()
+	}
 
 	// Parse port into spec based on how it's formatted
 	var err error
@@ -491,7 +604,29 @@ func (is *interceptState) createRequest(ctx context.Context) (*connector.CreateI
 	spec.TargetPort = int32(is.localPort)
 
 	doMount := false
-	if err = checkMountCapability(ctx); err == nil {
+	if is.args.mountMode != client.MountModeSSHFS {
+		if ir.MountPoint, doMount, err = is.getMountPoint(); err != nil {
+			return nil, err
+		}
+		if !doMount {
+			return nil, errcat.User.New("--mount-mode cannot be used with --mount=false")
+		}
+		ir.MountMode = is.args.mountMode
+		if ir.MountMode != client.MountModeNFS {
+			// getMountPoint creates a temporary directory unless an explicit mount point was given.
+			_, boolErr := strconv.ParseBool(is.args.mount)
+			ir.MountPointTemporary = boolErr == nil
+			ir.MountCopy = &connector.MountCopyOptions{
+				Include: is.args.mountInclude,
+				Exclude: is.args.mountExclude,
+			}
+			if is.args.mountMaxFileSize != "" {
+				// Validated when the flags were parsed
+				q := resource.MustParse(is.args.mountMaxFileSize)
+				ir.MountCopy.MaxFileSize = q.Value()
+			}
+		}
+	} else if err = checkMountCapability(ctx); err == nil {
 		if ir.MountPoint, doMount, err = is.getMountPoint(); err != nil {
 			return nil, err
 		}
@@ -543,11 +678,57 @@ func (is *interceptState) getMountPoint() (string, bool, error) {
 		err = nil
 	}
 	if doMount {
-		mountPoint, err = prepareMount(mountPoint)
+		switch is.args.mountMode {
+		case client.MountModeSSHFS, client.MountModeNFS:
+			mountPoint, err = prepareMount(mountPoint)
+		default:
+			// Copies end up in an ordinary directory, also on Windows
+			mountPoint, err = prepareCopyDir(mountPoint)
+		}
 	}
 	return mountPoint, doMount, err
 }
 
+func prepareCopyDir(dir string) (string, error) {
+	if dir == "" {
+		return os.MkdirTemp("", "telfs-")
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	return dir, os.MkdirAll(dir, 0o700)
+}
+
+// validateMountMode validates the --mount-mode flag and the flags that control what's copied.
+func validateMountMode(args *interceptArgs) error {
+	switch args.mountMode {
+	case client.MountModeSSHFS, client.MountModeNFS:
+		if args.mountMode == client.MountModeNFS && runtime.GOOS == "windows" {
+			return errcat.User.New(`--mount-mode "nfs" is not supported on Windows`)
+		}
+		if len(args.mountInclude) > 0 || len(args.mountExclude) > 0 || args.mountMaxFileSize != "" {
+			return errcat.User.New(`--mount-include, --mount-exclude, and --mount-max-file-size require --mount-mode "copy" or "sync"`)
+		}
+		return nil
+	case client.MountModeCopy, client.MountModeSync:
+	default:
+		return errcat.User.Newf("invalid --mount-mode %q, must be one of %s", args.mountMode, client.MountModesString())
+	}
+	for _, p := range append(append([]string{}, args.mountInclude...), args.mountExclude...) {
+		if !glob.Valid(p) {
+			return errcat.User.Newf("%q is not a valid glob pattern", p)
+		}
+	}
+	if args.mountMaxFileSize != "" {
+		q, err := resource.ParseQuantity(args.mountMaxFileSize)
+		if err != nil || q.Sign() < 0 {
+			return errcat.User.Newf("invalid --mount-max-file-size %q", args.mountMaxFileSize)
+		}
+	}
+	return nil
+}
+
 func makeIngressInfo(ingressHost string, ingressPort int32, ingressTLS bool, ingressL5 string) (*manager.IngressInfo, error) {
 	ingress := &manager.IngressInfo{}
 	if hostRx.MatchString(ingressHost) {
@@ -757,9 +938,13 @@ func (is *interceptState) EnsureState(ctx context.Context) (acquired bool, err e
 	}
 	is.scout.SetMetadatum(ctx, "intercept_id", intercept.Id)
 
-	is.env = intercept.Environment
+	is.env = client.GetConfig(ctx).Intercept.Env.Apply(intercept.Environment)
 	is.env["TELEPRESENCE_INTERCEPT_ID"] = intercept.Id
 	is.env["TELEPRESENCE_ROOT"] = intercept.ClientMountPoint
+	if len(intercept.RedactedEnvironment) > 0 {
+		// Let the local process know which values it must provide itself
+		is.env["TELEPRESENCE_REDACTED_ENV"] = strings.Join(intercept.RedactedEnvironment, ",")
+	}
 	if args.envFile != "" {
 		if err = is.writeEnvFile(); err != nil {
 			return true, err
@@ -773,7 +958,7 @@ func (is *interceptState) EnsureState(ctx context.Context) (acquired bool, err e
 
 	var volumeMountProblem error
 	doMount, err := strconv.ParseBool(args.mount)
-	if doMount || err != nil {
+	if (doMount || err != nil) && args.mountMode == client.MountModeSSHFS {
 		volumeMountProblem = checkMountCapability(ctx)
 	}
 	fmt.Fprintln(is.cmd.OutOrStdout(), DescribeIntercepts([]*manager.InterceptInfo{intercept}, volumeMountProblem, false))
@@ -825,6 +1010,11 @@ func (is *interceptState) startInDocker(ctx context.Context, envFile string, arg
 	if !hasArg("--name") {
 		ourArgs = append(ourArgs, "--name", fmt.Sprintf("intercept-%s-%d", is.args.name, is.localPort))
 	}
+	if (is.args.restart != restartNo || len(is.args.watch) > 0) && !hasArg("--rm") {
+		// The container is started again on restart, and a named container must be removed
+		// before its name can be reused.
+		ourArgs = append(ourArgs, "--rm")
+	}
 
 	if is.dockerPort != 0 {
 		ourArgs = append(ourArgs, "-p", fmt.Sprintf("%d:%d", is.localPort, is.dockerPort))
@@ -847,45 +1037,28 @@ func (is *interceptState) writeEnvFile() error {
 	if err != nil {
 		return errcat.NoDaemonLogs.Newf("failed to create environment file %q: %w", is.args.envFile, err)
 	}
-	return is.writeEnvToFileAndClose(file)
+	return is.writeEnvAndClose(file, is.args.envSyntax)
 }
 
-func (is *interceptState) writeEnvToFileAndClose(file *os.File) (err error) {
-	defer file.Close()
-	w := bufio.NewWriter(file)
-
-	keys := make([]string, len(is.env))
-	i := 0
-	for k := range is.env {
-		keys[i] = k
-		i++
-	}
-	sort.Strings(keys)
+// writeEnvToFileAndClose writes the environment in the format used by "docker run --env-file".
+func (is *interceptState) writeEnvToFileAndClose(file *os.File) error {
+	return is.writeEnvAndClose(file, envfile.Docker)
+}
 
-	for _, k := range keys {
-		if _, err = w.WriteString(k); err != nil {
-			return err
-		}
-		if err = w.WriteByte('='); err != nil {
-			return err
-		}
-		if _, err = w.WriteString(is.env[k]); err != nil {
-			return err
-		}
-		if err = w.WriteByte('\n'); err != nil {
-			return err
-		}
+func (is *interceptState) writeEnvAndClose(file *os.File, syntax envfile.Syntax) error {
+	err := envfile.Write(file, is.env, syntax, is.args.name)
+	if cerr := file.Close(); err == nil {
+		err = cerr
 	}
-	return w.Flush()
+	return err
 }
 
 func (is *interceptState) writeEnvJSON() error {
-	data, err := json.MarshalIndent(is.env, "", "  ")
+	file, err := os.OpenFile(is.args.envJSON, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
 	if err != nil {
-		// Creating JSON from a map[string]string should never fail
-		panic(err)
+		return errcat.NoDaemonLogs.Newf("failed to create environment file %q: %w", is.args.envJSON, err)
 	}
-	return os.WriteFile(is.args.envJSON, data, 0644)
+	return is.writeEnvAndClose(file, envfile.JSON)
 }
 
 var hostRx = regexp.MustCompile(`^[a-zA-Z0-9](?:[a-zA-Z0-9\-]*[a-zA-Z0-9])?(?:\.[a-zA-Z0-9](?:[a-zA-Z0-9\-]*[a-zA-Z0-9])?)*$`)
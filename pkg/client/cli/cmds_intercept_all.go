@@ -0,0 +1,225 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"syscall"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/datawire/dlib/dcontext"
+	"github.com/telepresenceio/telepresence/rpc/v2/connector"
+	"github.com/telepresenceio/telepresence/rpc/v2/manager"
+	"github.com/telepresenceio/telepresence/v2/pkg/client/errcat"
+	"github.com/telepresenceio/telepresence/v2/pkg/proc"
+)
+
+type interceptAllInfo struct {
+	namespace string // --namespace
+	selector  string // --selector
+	mapFile   string // --map
+}
+
+// portMap maps the name of a workload to a map of service port identifiers (a port name or
+// number) and the local port that the traffic to that service port should be directed to.
+type portMap map[string]map[string]uint16
+
+func interceptAllCommand() *cobra.Command {
+	ia := &interceptAllInfo{}
+	cmd := &cobra.Command{
+		Use:  "intercept-all [flags] [-- <command with arguments...>]",
+		Args: cobra.ArbitraryArgs,
+
+		Short: "Intercept all workloads in a namespace that match a selector",
+		Long: `Intercept all workloads in a namespace that match a selector.
+
+One intercept is created for each service port listed for a workload in the file given by --map. The file
+is a YAML map of workload names, each containing a map of service port names or numbers to local ports, e.g.
+
+  echo-server:
+    http: 8080
+  orders:
+    80: 8081
+    grpc: 9090
+
+All intercepts share the lifetime of this command. If one of them cannot be created, then the ones that
+were already created are removed. The intercepts are removed when the given command exits, or, when no
+command is given, when the intercept-all command is interrupted.`,
+		RunE: ia.run,
+	}
+	flags := cmd.Flags()
+	flags.StringVarP(&ia.namespace, "namespace", "n", "", "If present, the namespace scope for this CLI request")
+	flags.StringVarP(&ia.selector, "selector", "l", "", "Only intercept workloads with labels matching this selector, e.g. tier=backend")
+	flags.StringVar(&ia.mapFile, "map", "", "YAML file that maps the service ports of each workload to local ports")
+	_ = cmd.MarkFlagRequired("map")
+	return cmd
+}
+
+func (ia *interceptAllInfo) run(cmd *cobra.Command, args []string) error {
+	if ia.selector != "" {
+		if _, err := labels.Parse(ia.selector); err != nil {
+			return errcat.User.Newf("invalid --selector %q: %w", ia.selector, err)
+		}
+	}
+	pm, err := loadPortMap(ia.mapFile)
+	if err != nil {
+		return err
+	}
+	return withConnector(cmd, false, nil, func(ctx context.Context, cs *connectorState) error {
+		r, err := cs.userD.List(ctx, &connector.ListRequest{
+			Filter:        connector.ListRequest_INTERCEPTABLE,
+			Namespace:     ia.namespace,
+			LabelSelector: ia.selector,
+		})
+		if err != nil {
+			return err
+		}
+		irs, err := ia.createRequests(r.Workloads, pm)
+		if err != nil {
+			return err
+		}
+
+		// The intercepts share one lifetime, so all that were created are removed when
+		// this function returns, regardless of how it returns.
+		var created []string
+		defer func() {
+			ctx := dcontext.WithoutCancel(ctx)
+			for i := len(created) - 1; i >= 0; i-- {
+				if err := removeIntercept(ctx, created[i]); err != nil {
+					fmt.Fprintf(cmd.ErrOrStderr(), "failed to remove intercept %s: %v\n", created[i], err)
+				}
+			}
+		}()
+
+		stdout := cmd.OutOrStdout()
+		for _, ir := range irs {
+			spec := ir.Spec
+			r, err := cs.userD.CreateIntercept(ctx, ir)
+			if err != nil {
+				return fmt.Errorf("connector.CreateIntercept: %w", err)
+			}
+			if r.Error != connector.InterceptError_UNSPECIFIED {
+				return fmt.Errorf("intercept %s: %w", spec.Name, interceptMessage(r))
+			}
+			created = append(created, spec.Name)
+			fmt.Fprintf(stdout, "Intercepting %s %s.%s port %s on local port %d as %q\n",
+				r.WorkloadKind, spec.Agent, spec.Namespace, spec.ServicePortIdentifier, spec.TargetPort, spec.Name)
+		}
+
+		if len(args) > 0 {
+			ctx, cancel := context.WithCancel(dcontext.WithSoftness(ctx))
+			defer cancel()
+			pc, err := proc.Start(ctx, nil, args[0], args[1:]...)
+			if err == nil {
+				err = proc.Wait(ctx, cancel, pc)
+			}
+			if err != nil {
+				err = errcat.NoDaemonLogs.New(err)
+			}
+			return err
+		}
+
+		ctx, cancel := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+		defer cancel()
+		fmt.Fprintln(stdout, "Press <ctrl>-C to end all intercepts")
+		<-ctx.Done()
+		return nil
+	})
+}
+
+// createRequests creates one CreateInterceptRequest for each service port that is mapped by the given
+// portMap. An error is returned if a workload is found that isn't mapped, or if the map references
+// a service port that the workload's service doesn't have.
+func (ia *interceptAllInfo) createRequests(wis []*connector.WorkloadInfo, pm portMap) ([]*connector.CreateInterceptRequest, error) {
+	var irs []*connector.CreateInterceptRequest
+	for _, wi := range wis {
+		svc := wi.Service
+		if svc == nil {
+			continue
+		}
+		ports, ok := pm[wi.Name]
+		if !ok {
+			return nil, errcat.User.Newf("workload %s.%s matches the selector but has no entry in %s", wi.Name, wi.Namespace, ia.mapFile)
+		}
+		pids := make([]string, 0, len(ports))
+		for pid := range ports {
+			if !hasServicePort(svc, pid) {
+				return nil, errcat.User.Newf("%s: service %s.%s has no port %s", ia.mapFile, svc.Name, svc.Namespace, pid)
+			}
+			pids = append(pids, pid)
+		}
+		sort.Strings(pids)
+
+		baseName := wi.Name
+		if ia.namespace != "" {
+			baseName += "-" + ia.namespace
+		}
+		for _, pid := range pids {
+			name := baseName
+			if len(pids) > 1 {
+				name += "-" + pid
+			}
+			irs = append(irs, &connector.CreateInterceptRequest{Spec: &manager.InterceptSpec{
+				Name:                  name,
+				Namespace:             wi.Namespace,
+				Agent:                 wi.Name,
+				WorkloadKind:          wi.WorkloadResourceType,
+				ServiceName:           svc.Name,
+				ServicePortIdentifier: pid,
+				Mechanism:             "tcp",
+				TargetHost:            "127.0.0.1",
+				TargetPort:            int32(ports[pid]),
+			}})
+		}
+	}
+	if len(irs) == 0 {
+		return nil, errcat.User.New("found no workloads to intercept")
+	}
+	return irs, nil
+}
+
+func hasServicePort(svc *connector.WorkloadInfo_ServiceReference, pid string) bool {
+	pn, err := strconv.Atoi(pid)
+	for _, p := range svc.Ports {
+		if p.Name == pid || err == nil && int(p.Port) == pn {
+			return true
+		}
+	}
+	return false
+}
+
+func loadPortMap(file string) (portMap, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, errcat.User.New(err)
+	}
+	defer f.Close()
+	var pm portMap
+	dec := yaml.NewDecoder(f)
+	dec.KnownFields(true)
+	if err = dec.Decode(&pm); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errcat.User.Newf("%s is empty", file)
+		}
+		return nil, errcat.User.Newf("unable to parse %s: %w", file, err)
+	}
+	for wl, ports := range pm {
+		if len(ports) == 0 {
+			return nil, errcat.User.Newf("%s: workload %s has no ports", file, wl)
+		}
+		for pid, lp := range ports {
+			if lp == 0 {
+				return nil, errcat.User.Newf("%s: workload %s, port %s: local port must be greater than zero", file, wl, pid)
+			}
+		}
+	}
+	return pm, nil
+}
@@ -0,0 +1,93 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/telepresenceio/telepresence/rpc/v2/connector"
+)
+
+func writePortMap(t *testing.T, content string) string {
+	file := filepath.Join(t.TempDir(), "ports.yaml")
+	require.NoError(t, os.WriteFile(file, []byte(content), 0o644))
+	return file
+}
+
+func TestLoadPortMap(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		pm, err := loadPortMap(writePortMap(t, "echo:\n  http: 8080\norders:\n  80: 8081\n  grpc: 9090\n"))
+		require.NoError(t, err)
+		assert.Equal(t, portMap{
+			"echo":   {"http": 8080},
+			"orders": {"80": 8081, "grpc": 9090},
+		}, pm)
+	})
+	t.Run("empty", func(t *testing.T) {
+		_, err := loadPortMap(writePortMap(t, ""))
+		assert.ErrorContains(t, err, "is empty")
+	})
+	t.Run("no ports", func(t *testing.T) {
+		_, err := loadPortMap(writePortMap(t, "echo: {}\n"))
+		assert.ErrorContains(t, err, "workload echo has no ports")
+	})
+	t.Run("zero port", func(t *testing.T) {
+		_, err := loadPortMap(writePortMap(t, "echo:\n  http: 0\n"))
+		assert.ErrorContains(t, err, "must be greater than zero")
+	})
+	t.Run("port out of range", func(t *testing.T) {
+		_, err := loadPortMap(writePortMap(t, "echo:\n  http: 70000\n"))
+		assert.ErrorContains(t, err, "line 2")
+	})
+}
+
+func TestInterceptAllCreateRequests(t *testing.T) {
+	workload := func(name string, ports ...*connector.WorkloadInfo_ServiceReference_Port) *connector.WorkloadInfo {
+		return &connector.WorkloadInfo{
+			Name:                 name,
+			Namespace:            "team-a",
+			WorkloadResourceType: "Deployment",
+			Service: &connector.WorkloadInfo_ServiceReference{
+				Name:      name,
+				Namespace: "team-a",
+				Ports:     ports,
+			},
+		}
+	}
+	wis := []*connector.WorkloadInfo{
+		workload("echo", &connector.WorkloadInfo_ServiceReference_Port{Name: "http", Port: 80}),
+		workload("orders",
+			&connector.WorkloadInfo_ServiceReference_Port{Name: "http", Port: 80},
+			&connector.WorkloadInfo_ServiceReference_Port{Name: "grpc", Port: 9000}),
+	}
+	ia := &interceptAllInfo{namespace: "team-a", mapFile: "ports.yaml"}
+
+	t.Run("all mapped", func(t *testing.T) {
+		irs, err := ia.createRequests(wis, portMap{
+			"echo":   {"http": 8080},
+			"orders": {"80": 8081, "grpc": 9090},
+		})
+		require.NoError(t, err)
+		require.Len(t, irs, 3)
+		names := make([]string, len(irs))
+		for i, ir := range irs {
+			names[i] = ir.Spec.Name
+		}
+		assert.Equal(t, []string{"echo-team-a", "orders-team-a-80", "orders-team-a-grpc"}, names)
+		assert.Equal(t, int32(9090), irs[2].Spec.TargetPort)
+		assert.Equal(t, "grpc", irs[2].Spec.ServicePortIdentifier)
+	})
+
+	t.Run("unmapped workload", func(t *testing.T) {
+		_, err := ia.createRequests(wis, portMap{"echo": {"http": 8080}})
+		assert.ErrorContains(t, err, "workload orders.team-a matches the selector but has no entry in ports.yaml")
+	})
+
+	t.Run("unknown port", func(t *testing.T) {
+		_, err := ia.createRequests(wis[:1], portMap{"echo": {"https": 8443}})
+		assert.ErrorContains(t, err, "service echo.team-a has no port https")
+	})
+}
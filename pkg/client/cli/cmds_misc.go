@@ -55,6 +55,8 @@ func ClusterIdCommand() *cobra.Command {
 func connectCommand() *cobra.Command {
 	var dnsIP string
 	var mappedNamespaces []string
+	var socksProxy, httpProxy string
+	var proxyAllowRemote bool
 
 	kubeFlags := pflag.NewFlagSet("Kubernetes flags", 0)
 	cmd := &cobra.Command{
@@ -65,6 +67,9 @@ func connectCommand() *cobra.Command {
 			request := &connector.ConnectRequest{
 				KubeFlags:        kubeFlagMap(kubeFlags),
 				MappedNamespaces: mappedNamespaces,
+				SocksProxy:       socksProxy,
+				HttpProxy:        httpProxy,
+				ProxyAllowRemote: proxyAllowRemote,
 			}
 
 			if len(args) == 0 {
@@ -94,6 +99,18 @@ func connectCommand() *cobra.Command {
 		"mapped-namespaces", nil, ``+
 			`Comma separated list of namespaces considered by DNS resolver and NAT for outbound connections. `+
 			`Defaults to all namespaces`)
+	nwFlags.StringVar(&socksProxy,
+		"socks-proxy", "", ``+
+			`Address ([host]:port) of a SOCKS5 proxy that gives proxy-aware tools access to the cluster. `+
+			`The host defaults to 127.0.0.1. The root daemon is not used when this flag or --http-proxy is given`)
+	nwFlags.StringVar(&httpProxy,
+		"http-proxy", "", ``+
+			`Address ([host]:port) of an HTTP CONNECT proxy that gives proxy-aware tools access to the cluster. `+
+			`The host defaults to 127.0.0.1. The root daemon is not used when this flag or --socks-proxy is given`)
+	nwFlags.BoolVar(&proxyAllowRemote,
+		"proxy-allow-remote", false, ``+
+			`Allow the proxies to listen on addresses that aren't loopback addresses, giving other hosts `+
+			`access to the cluster through them`)
 	flags.AddFlagSet(nwFlags)
 
 	kubeConfig := genericclioptions.NewConfigFlags(false)
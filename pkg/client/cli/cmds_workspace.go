@@ -0,0 +1,214 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/datawire/dlib/dcontext"
+	"github.com/datawire/dlib/dgroup"
+	"github.com/telepresenceio/telepresence/rpc/v2/connector"
+	"github.com/telepresenceio/telepresence/rpc/v2/manager"
+	"github.com/telepresenceio/telepresence/v2/pkg/client"
+	"github.com/telepresenceio/telepresence/v2/pkg/client/cli/cliutil"
+	"github.com/telepresenceio/telepresence/v2/pkg/client/cli/workspace"
+	"github.com/telepresenceio/telepresence/v2/pkg/client/errcat"
+	"github.com/telepresenceio/telepresence/v2/pkg/iputil"
+	"github.com/telepresenceio/telepresence/v2/pkg/proc"
+)
+
+type workspaceInfo struct {
+	file string // --file
+}
+
+func (w *workspaceInfo) addFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&w.file, "file", "f", "", "Path to the workspace file. Defaults to "+workspace.FileName+" in the current directory")
+}
+
+func (w *workspaceInfo) load() (*workspace.Workspace, error) {
+	path := w.file
+	if path == "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			return nil, err
+		}
+		if path, err = workspace.Find(wd); err != nil {
+			return nil, err
+		}
+	}
+	return workspace.Load(path)
+}
+
+func upCommand() *cobra.Command {
+	w := &workspaceInfo{}
+	cmd := &cobra.Command{
+		Use:  "up",
+		Args: cobra.NoArgs,
+
+		Short: "Connect and start the intercepts declared in the workspace file",
+		Long: `Connect to the cluster and start the intercepts declared in the workspace file.
+
+Intercepts that declare a handler are kept alive for as long as their handler runs, and this command
+will not return until all handlers have exited. A handler is restarted according to the "restart" and
+"watch" settings of its intercept. Intercepts without a handler remain active until "telepresence down"
+is called.`,
+		RunE: w.up,
+	}
+	w.addFlags(cmd)
+	return cmd
+}
+
+func downCommand() *cobra.Command {
+	w := &workspaceInfo{}
+	cmd := &cobra.Command{
+		Use:  "down",
+		Args: cobra.NoArgs,
+
+		Short: "Remove the intercepts declared in the workspace file and disconnect",
+		RunE:  w.down,
+	}
+	w.addFlags(cmd)
+	return cmd
+}
+
+func connectRequest(ws *workspace.Workspace) *connector.ConnectRequest {
+	cn := &ws.Connection
+	cr := &connector.ConnectRequest{
+		KubeFlags:        map[string]string{},
+		MappedNamespaces: cn.MappedNamespaces,
+	}
+	if cn.Context != "" {
+		cr.KubeFlags["context"] = cn.Context
+	}
+	if len(cn.AlsoProxy) > 0 {
+		cr.AlsoProxy = make([]*manager.IPNet, len(cn.AlsoProxy))
+		for i, ap := range cn.AlsoProxy {
+			cr.AlsoProxy[i] = iputil.IPNetToRPC(ap)
+		}
+	}
+	return cr
+}
+
+// workspaceInterceptArgs returns the arguments for the "telepresence intercept" command that
+// corresponds to the given workspace intercept.
+func workspaceInterceptArgs(ws *workspace.Workspace, ic *workspace.Intercept) []string {
+	args := []string{"intercept", ic.Name, "--workload", ic.Workload}
+	addFlag := func(flag, value string) {
+		if value != "" {
+			args = append(args, "--"+flag, value)
+		}
+	}
+	addFlag("namespace", ws.Namespace(ic))
+	addFlag("service", ic.Service)
+	addFlag("port", ic.Port)
+	addFlag("env-file", ic.EnvFile)
+	addFlag("env-syntax", ic.EnvSyntax)
+	addFlag("env-json", ic.EnvJSON)
+	addFlag("mount", ic.Mount)
+	addFlag("mount-mode", ic.MountMode)
+	for _, tp := range ic.ToPod {
+		addFlag("to-pod", tp)
+	}
+	if ic.DockerRun {
+		args = append(args, "--docker-run")
+	}
+	addFlag("docker-mount", ic.DockerMount)
+	addFlag("restart", ic.Restart)
+	for _, w := range ic.Watch {
+		addFlag("watch", w)
+	}
+	if len(ic.Handler) > 0 {
+		args = append(args, "--")
+		args = append(args, ic.Handler...)
+	}
+	return args
+}
+
+func (w *workspaceInfo) up(cmd *cobra.Command, _ []string) error {
+	ws, err := w.load()
+	if err != nil {
+		return err
+	}
+	exe, err := client.Executable()
+	if err != nil {
+		return err
+	}
+	return withConnector(cmd, true, connectRequest(ws), func(ctx context.Context, cs *connectorState) (err error) {
+		// Each intercept is created by a "telepresence intercept" subprocess. Intercepts without a
+		// handler are created first, and in order, so that the handlers can rely on them.
+		var created, handled []*workspace.Intercept
+		defer func() {
+			// Don't leave a partially started workspace behind.
+			if err != nil {
+				removeWorkspaceIntercepts(ctx, cs.userD, created)
+			}
+		}()
+		for _, ic := range ws.Intercepts {
+			if len(ic.Handler) > 0 {
+				handled = append(handled, ic)
+				continue
+			}
+			if err := proc.Run(ctx, nil, exe, workspaceInterceptArgs(ws, ic)...); err != nil {
+				return errcat.NoDaemonLogs.Newf("intercept %s: %w", ic.Name, err)
+			}
+			created = append(created, ic)
+		}
+		if len(handled) == 0 {
+			return nil
+		}
+
+		// Ensure that no intercept survives its handler, even if the handler was killed before the
+		// intercept subprocess could clean up.
+		defer removeWorkspaceIntercepts(ctx, cs.userD, handled)
+		g := dgroup.NewGroup(ctx, dgroup.GroupConfig{})
+		for _, ic := range handled {
+			ic := ic
+			g.Go(ic.Name, func(ctx context.Context) error {
+				if err := proc.Run(ctx, nil, exe, workspaceInterceptArgs(ws, ic)...); err != nil {
+					return errcat.NoDaemonLogs.Newf("intercept %s: %w", ic.Name, err)
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "Handler for intercept %s exited\n", ic.Name)
+				return nil
+			})
+		}
+		return g.Wait()
+	})
+}
+
+// removeWorkspaceIntercepts removes the given intercepts, ignoring those that no longer exist.
+func removeWorkspaceIntercepts(ctx context.Context, cc connector.ConnectorClient, ics []*workspace.Intercept) {
+	ctx = dcontext.WithoutCancel(ctx)
+	for _, ic := range ics {
+		_, _ = cc.RemoveIntercept(ctx, &manager.RemoveInterceptRequest2{Name: ic.Name})
+	}
+}
+
+func (w *workspaceInfo) down(cmd *cobra.Command, _ []string) error {
+	ws, err := w.load()
+	if err != nil {
+		return err
+	}
+	err = cliutil.WithStartedConnector(cmd.Context(), false, func(ctx context.Context, cc connector.ConnectorClient) error {
+		for _, ic := range ws.Intercepts {
+			r, err := cc.RemoveIntercept(ctx, &manager.RemoveInterceptRequest2{Name: ic.Name})
+			if err != nil {
+				return err
+			}
+			switch r.Error {
+			case connector.InterceptError_UNSPECIFIED:
+				fmt.Fprintf(cmd.OutOrStdout(), "Removed intercept %s\n", ic.Name)
+			case connector.InterceptError_NOT_FOUND, connector.InterceptError_NO_CONNECTION:
+			default:
+				return interceptMessage(r)
+			}
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, cliutil.ErrNoUserDaemon) {
+		return err
+	}
+	return cliutil.Disconnect(cmd.Context(), false, false)
+}
@@ -0,0 +1,33 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/telepresenceio/telepresence/v2/pkg/client/cli/workspace"
+)
+
+func TestWorkspaceInterceptArgs(t *testing.T) {
+	ws := &workspace.Workspace{Connection: workspace.Connection{Namespace: "team-a"}}
+	ic := &workspace.Intercept{
+		Name:     "orders",
+		Workload: "orders",
+		Port:     "8080",
+		Restart:  "on-failure",
+		Watch:    []string{"**/*.go", "go.mod"},
+		Handler:  []string{"go", "run", "."},
+	}
+	assert.Equal(t, []string{
+		"intercept", "orders", "--workload", "orders",
+		"--namespace", "team-a",
+		"--port", "8080",
+		"--restart", "on-failure",
+		"--watch", "**/*.go",
+		"--watch", "go.mod",
+		"--", "go", "run", ".",
+	}, workspaceInterceptArgs(ws, ic))
+
+	ic = &workspace.Intercept{Name: "web", Workload: "web"}
+	assert.Equal(t, []string{"intercept", "web", "--workload", "web", "--namespace", "team-a"}, workspaceInterceptArgs(ws, ic))
+}
@@ -0,0 +1,212 @@
+// Package envfile writes the environment of an intercepted container in formats that can be consumed by
+// shells, container runtimes, service managers, and IDEs.
+package envfile
+
+import (
+	"bufio"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/telepresenceio/telepresence/v2/pkg/client/errcat"
+	"github.com/telepresenceio/telepresence/v2/pkg/shellquote"
+)
+
+// Syntax is the syntax of an environment file.
+type Syntax string
+
+const (
+	// Docker is the format used by "docker run --env-file". Values are written verbatim.
+	Docker = Syntax("docker")
+
+	// Compose is the .env format used by Docker Compose, with quoted values.
+	Compose = Syntax("compose")
+
+	// Sh is a POSIX shell script that exports each variable.
+	Sh = Syntax("sh")
+
+	// PowerShell is a PowerShell script that assigns each variable.
+	PowerShell = Syntax("powershell")
+
+	// Systemd is the format used by the EnvironmentFile directive of systemd units.
+	Systemd = Syntax("systemd")
+
+	// Direnv is an .envrc file for direnv, which is a shell script that exports each variable.
+	Direnv = Syntax("direnv")
+
+	// JSON is a flat JSON object.
+	JSON = Syntax("json")
+
+	// VSCode is a JSON object with an "env" block that can be merged into a launch.json configuration.
+	VSCode = Syntax("vscode")
+
+	// IntelliJ is an IntelliJ run configuration (a .run.xml file).
+	IntelliJ = Syntax("intellij")
+)
+
+// Syntaxes are all syntaxes that Write can produce.
+var Syntaxes = []Syntax{Docker, Compose, Sh, PowerShell, Systemd, Direnv, JSON, VSCode, IntelliJ}
+
+// ParseSyntax returns the Syntax with the given name.
+func ParseSyntax(s string) (Syntax, error) {
+	for _, sx := range Syntaxes {
+		if string(sx) == s {
+			return sx, nil
+		}
+	}
+	names := make([]string, len(Syntaxes))
+	for i, sx := range Syntaxes {
+		names[i] = string(sx)
+	}
+	return "", errcat.User.Newf("invalid env syntax %q, must be one of %s", s, strings.Join(names, ", "))
+}
+
+var identifierRx = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Write writes the given environment to the given writer using the given syntax. The name is used
+// as the name of run configurations. Variables with names that the syntax cannot express are
+// omitted.
+func Write(w io.Writer, env map[string]string, syntax Syntax, name string) error {
+	switch syntax {
+	case JSON:
+		return writeJSON(w, env)
+	case VSCode:
+		return writeJSON(w, map[string]any{"env": env})
+	case IntelliJ:
+		return writeIntelliJ(w, env, name)
+	}
+
+	var line func(k, v string) string
+	switch syntax {
+	case Docker:
+		line = func(k, v string) string {
+			return k + "=" + v
+		}
+	case Compose:
+		line = func(k, v string) string {
+			return k + "=" + doubleQuote(v, `\"$`, "$$")
+		}
+	case Sh, Direnv:
+		line = func(k, v string) string {
+			if !identifierRx.MatchString(k) {
+				return ""
+			}
+			return "export " + k + "=" + shellquote.Unix(v)
+		}
+	case PowerShell:
+		line = func(k, v string) string {
+			if !identifierRx.MatchString(k) {
+				k = "{Env:" + strings.ReplaceAll(k, "}", "`}") + "}"
+			} else {
+				k = "Env:" + k
+			}
+			return "$" + k + " = '" + strings.ReplaceAll(v, "'", "''") + "'"
+		}
+	case Systemd:
+		line = func(k, v string) string {
+			if !identifierRx.MatchString(k) {
+				return ""
+			}
+			return k + "=" + doubleQuote(v, "\\\"$`", `\$`)
+		}
+	default:
+		return fmt.Errorf("unsupported env syntax %q", syntax)
+	}
+
+	bw := bufio.NewWriter(w)
+	for _, k := range sortedKeys(env) {
+		if l := line(k, env[k]); l != "" {
+			if _, err := bw.WriteString(l); err != nil {
+				return err
+			}
+			if err := bw.WriteByte('\n'); err != nil {
+				return err
+			}
+		}
+	}
+	return bw.Flush()
+}
+
+// doubleQuote quotes s using double quotes, escaping the characters in special with a backslash, and
+// newlines as \n. Dollar signs are replaced with the given dollar string to prevent interpolation.
+func doubleQuote(s, special, dollar string) string {
+	b := strings.Builder{}
+	b.WriteByte('"')
+	for _, c := range s {
+		switch {
+		case c == '$':
+			b.WriteString(dollar)
+		case c == '\n':
+			b.WriteString(`\n`)
+		case strings.ContainsRune(special, c):
+			b.WriteByte('\\')
+			b.WriteRune(c)
+		default:
+			b.WriteRune(c)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+func sortedKeys(env map[string]string) []string {
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func writeIntelliJ(w io.Writer, env map[string]string, name string) error {
+	type envEntry struct {
+		Name  string `xml:"name,attr"`
+		Value string `xml:"value,attr"`
+	}
+	type method struct {
+		V string `xml:"v,attr"`
+	}
+	type configuration struct {
+		Default     bool       `xml:"default,attr"`
+		Name        string     `xml:"name,attr"`
+		Type        string     `xml:"type,attr"`
+		FactoryName string     `xml:"factoryName,attr"`
+		Envs        []envEntry `xml:"envs>env"`
+		Method      method     `xml:"method"`
+	}
+	type component struct {
+		XMLName       xml.Name      `xml:"component"`
+		Name          string        `xml:"name,attr"`
+		Configuration configuration `xml:"configuration"`
+	}
+	c := component{
+		Name: "ProjectRunConfigurationManager",
+		Configuration: configuration{
+			Name:        name,
+			Type:        "Application",
+			FactoryName: "Application",
+			Method:      method{V: "2"},
+		},
+	}
+	for _, k := range sortedKeys(env) {
+		c.Configuration.Envs = append(c.Configuration.Envs, envEntry{Name: k, Value: env[k]})
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(&c); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,66 @@
+package envfile
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWrite(t *testing.T) {
+	env := map[string]string{
+		"PLAIN":   "value",
+		"QUOTED":  `it's "$HOME"`,
+		"LINES":   "a\nb",
+		"my.name": "dotted",
+	}
+	tests := []struct {
+		syntax Syntax
+		want   string
+	}{
+		{Docker, "LINES=a\nb\nPLAIN=value\nQUOTED=it's \"$HOME\"\nmy.name=dotted\n"},
+		{Compose, `LINES="a\nb"` + "\n" + `PLAIN="value"` + "\n" + `QUOTED="it's \"$$HOME\""` + "\n" + `my.name="dotted"` + "\n"},
+		{Sh, "export LINES='a\nb'\nexport PLAIN=value\nexport QUOTED=it\\''s \"$HOME\"'\n"},
+		{PowerShell, "$Env:LINES = 'a\nb'\n$Env:PLAIN = 'value'\n$Env:QUOTED = 'it''s \"$HOME\"'\n${Env:my.name} = 'dotted'\n"},
+		{Systemd, `LINES="a\nb"` + "\n" + `PLAIN="value"` + "\n" + `QUOTED="it's \"\$HOME\""` + "\n"},
+		{JSON, "{\n  \"LINES\": \"a\\nb\",\n  \"PLAIN\": \"value\",\n  \"QUOTED\": \"it's \\\"$HOME\\\"\",\n  \"my.name\": \"dotted\"\n}\n"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(string(tt.syntax), func(t *testing.T) {
+			sb := strings.Builder{}
+			require.NoError(t, Write(&sb, env, tt.syntax, "echo"))
+			assert.Equal(t, tt.want, sb.String())
+		})
+	}
+}
+
+func TestWriteIDE(t *testing.T) {
+	env := map[string]string{"A": "1", "B": `<&">`}
+
+	sb := strings.Builder{}
+	require.NoError(t, Write(&sb, env, VSCode, "echo"))
+	assert.Equal(t, "{\n  \"env\": {\n    \"A\": \"1\",\n    \"B\": \"<&\\\">\"\n  }\n}\n", sb.String())
+
+	sb.Reset()
+	require.NoError(t, Write(&sb, env, IntelliJ, "echo"))
+	assert.Equal(t, `<component name="ProjectRunConfigurationManager">
+  <configuration default="false" name="echo" type="Application" factoryName="Application">
+    <envs>
+      <env name="A" value="1"></env>
+      <env name="B" value="&lt;&amp;&#34;&gt;"></env>
+    </envs>
+    <method v="2"></method>
+  </configuration>
+</component>
+`, sb.String())
+}
+
+func TestParseSyntax(t *testing.T) {
+	sx, err := ParseSyntax("direnv")
+	require.NoError(t, err)
+	assert.Equal(t, Direnv, sx)
+	_, err = ParseSyntax("csh")
+	assert.ErrorContains(t, err, `invalid env syntax "csh", must be one of docker, compose, sh`)
+}
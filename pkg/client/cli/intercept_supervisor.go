@@ -0,0 +1,294 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/datawire/dlib/dexec"
+	"github.com/datawire/dlib/dlog"
+	"github.com/telepresenceio/telepresence/v2/pkg/client"
+	"github.com/telepresenceio/telepresence/v2/pkg/client/errcat"
+	"github.com/telepresenceio/telepresence/v2/pkg/glob"
+	"github.com/telepresenceio/telepresence/v2/pkg/proc"
+)
+
+// restartPolicy controls if the process that serves an intercept is restarted when it exits.
+type restartPolicy string
+
+const (
+	restartNo        = restartPolicy(client.RestartNo)
+	restartOnFailure = restartPolicy(client.RestartOnFailure)
+	restartAlways    = restartPolicy(client.RestartAlways)
+)
+
+const (
+	minRestartDelay = time.Second
+	maxRestartDelay = 30 * time.Second
+
+	// A process that has been running for stableRunDuration is considered healthy, and the
+	// restart delay is reset when it exits.
+	stableRunDuration = time.Minute
+
+	// watchDelay is how long the supervisor waits for more file system events before it
+	// restarts the process. Saving a file will often produce a burst of events.
+	watchDelay = 200 * time.Millisecond
+
+	// readyTimeout is how long the supervisor waits for a restarted process to accept
+	// connections before it tells the agent to send traffic to it anyway.
+	readyTimeout = 30 * time.Second
+)
+
+func parseRestartPolicy(s string) (restartPolicy, error) {
+	if !client.IsRestartPolicy(s) {
+		return "", errcat.User.Newf("invalid --restart %q, must be one of %s", s, client.RestartPoliciesString())
+	}
+	return restartPolicy(s), nil
+}
+
+// backoff computes an exponentially increasing delay between restarts.
+type backoff struct {
+	min time.Duration
+	max time.Duration
+	cur time.Duration
+}
+
+func (b *backoff) next() time.Duration {
+	if b.cur == 0 {
+		b.cur = b.min
+	} else if b.cur *= 2; b.cur > b.max {
+		b.cur = b.max
+	}
+	return b.cur
+}
+
+func (b *backoff) reset() {
+	b.cur = 0
+}
+
+// supervisor runs the process that serves an intercept and restarts it according to a
+// restart policy, or when files that match the watch patterns change.
+type supervisor struct {
+	policy restartPolicy
+	watch  []string
+	out    io.Writer
+
+	// start starts the process.
+	start func(ctx context.Context) (*dexec.Cmd, error)
+
+	// readyAddr, if set, is the address that a restarted process must accept connections on
+	// before it's considered ready.
+	readyAddr string
+
+	// setRestarting is called with true before the process is restarted, and with false
+	// once the restarted process is ready.
+	setRestarting func(ctx context.Context, restarting bool) error
+
+	// restarting is 1 when the traffic-manager has been told that the process is restarting
+	// and not yet that it's ready again. It's accessed atomically.
+	restarting int32
+}
+
+// run starts the process and supervises it until the context is cancelled or until the
+// restart policy says that the process shouldn't be restarted. The given cancel function is
+// called when the user interrupts the process.
+func (s *supervisor) run(ctx context.Context, cancel context.CancelFunc) error {
+	var changes <-chan struct{}
+	if len(s.watch) > 0 {
+		wd, err := os.Getwd()
+		if err != nil {
+			return err
+		}
+		ch := make(chan struct{}, 1)
+		if err = s.startWatcher(ctx, wd, ch); err != nil {
+			return err
+		}
+		changes = ch
+	}
+
+	bo := backoff{min: minRestartDelay, max: maxRestartDelay}
+	for {
+		pCtx, pCancel := context.WithCancel(ctx)
+		cmd, err := s.start(pCtx)
+		if err != nil {
+			pCancel()
+			return err
+		}
+		started := time.Now()
+		if atomic.LoadInt32(&s.restarting) == 1 {
+			go s.awaitReady(pCtx)
+		}
+
+		exited := make(chan error, 1)
+		go func() {
+			exited <- proc.Wait(pCtx, cancel, cmd)
+		}()
+
+		changed := false
+		select {
+		case err = <-exited:
+		case <-changes:
+			changed = true
+			fmt.Fprintln(s.out, "Files changed, restarting process")
+			pCancel()
+			err = <-exited
+		}
+		pCancel()
+		if ctx.Err() != nil {
+			return err
+		}
+
+		var delay time.Duration
+		if !changed {
+			switch {
+			case s.policy == restartNo, s.policy == restartOnFailure && err == nil:
+				return err
+			case err != nil:
+				fmt.Fprintf(s.out, "Process failed: %v\n", err)
+			}
+			if time.Since(started) >= stableRunDuration {
+				bo.reset()
+			}
+			delay = bo.next()
+		}
+
+		s.markRestarting(ctx)
+		if delay > 0 {
+			fmt.Fprintf(s.out, "Restarting process in %s\n", delay)
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(delay):
+			case <-changes:
+			}
+		}
+	}
+}
+
+// markRestarting tells the traffic-manager that the process is restarting, unless it has already
+// been told so and not yet been told that the process is ready again.
+func (s *supervisor) markRestarting(ctx context.Context) {
+	if !atomic.CompareAndSwapInt32(&s.restarting, 0, 1) {
+		return
+	}
+	if err := s.setRestarting(ctx, true); err != nil {
+		dlog.Errorf(ctx, "unable to tell the traffic-manager that the process is restarting: %v", err)
+	}
+}
+
+// awaitReady waits for the process to accept connections on the readyAddr and then tells the
+// traffic-manager that it's no longer restarting.
+func (s *supervisor) awaitReady(ctx context.Context) {
+	if s.readyAddr != "" {
+		tc, cancel := context.WithTimeout(ctx, readyTimeout)
+		defer cancel()
+		d := net.Dialer{Timeout: time.Second}
+	dialLoop:
+		for {
+			conn, err := d.DialContext(tc, "tcp", s.readyAddr)
+			if err == nil {
+				conn.Close()
+				break
+			}
+			select {
+			case <-tc.Done():
+				break dialLoop
+			case <-time.After(100 * time.Millisecond):
+			}
+		}
+		if ctx.Err() != nil {
+			// The process exited or was restarted before it became ready
+			return
+		}
+		if tc.Err() != nil {
+			dlog.Warnf(ctx, "process is not accepting connections on %s after %s", s.readyAddr, readyTimeout)
+		}
+	}
+	if err := s.setRestarting(ctx, false); err != nil {
+		dlog.Errorf(ctx, "unable to tell the traffic-manager that the process has restarted: %v", err)
+		return
+	}
+	atomic.StoreInt32(&s.restarting, 0)
+}
+
+// startWatcher starts a goroutine that sends to the changes channel whenever a file below dir
+// that matches one of the watch patterns is created, written, removed, or renamed.
+func (s *supervisor) startWatcher(ctx context.Context, dir string, changes chan<- struct{}) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+
+	// The fsnotify watcher isn't recursive, so each directory must be added.
+	addDirs := func(root string) error {
+		return filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+			if err != nil || !d.IsDir() {
+				return err
+			}
+			if path != root && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return watcher.Add(path)
+		})
+	}
+	if err = addDirs(dir); err != nil {
+		watcher.Close()
+		return err
+	}
+
+	// The delay timer will initially sleep forever. It's reset to a short delay
+	// when a matching file changes.
+	delay := time.AfterFunc(time.Duration(math.MaxInt64), func() {
+		select {
+		case changes <- struct{}{}:
+		default:
+		}
+	})
+	go func() {
+		defer func() {
+			delay.Stop()
+			watcher.Close()
+		}()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case err := <-watcher.Errors:
+				dlog.Error(ctx, err)
+			case event := <-watcher.Events:
+				if event.Op&fsnotify.Create != 0 {
+					if fi, err := os.Stat(event.Name); err == nil && fi.IsDir() {
+						if err = addDirs(event.Name); err != nil {
+							dlog.Error(ctx, err)
+						}
+						continue
+					}
+				}
+				if event.Op&(fsnotify.Create|fsnotify.Write|fsnotify.Remove|fsnotify.Rename) == 0 {
+					continue
+				}
+				rel, err := filepath.Rel(dir, event.Name)
+				if err != nil {
+					continue
+				}
+				rel = filepath.ToSlash(rel)
+				for _, pattern := range s.watch {
+					if glob.Match(pattern, rel) {
+						delay.Reset(watchDelay)
+						break
+					}
+				}
+			}
+		}
+	}()
+	return nil
+}
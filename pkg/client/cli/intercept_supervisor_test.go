@@ -0,0 +1,55 @@
+package cli
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRestartPolicy(t *testing.T) {
+	for _, s := range []string{"no", "on-failure", "always"} {
+		p, err := parseRestartPolicy(s)
+		require.NoError(t, err)
+		assert.Equal(t, restartPolicy(s), p)
+	}
+	_, err := parseRestartPolicy("sometimes")
+	assert.ErrorContains(t, err, `invalid --restart "sometimes"`)
+}
+
+func TestBackoff(t *testing.T) {
+	bo := backoff{min: time.Second, max: 5 * time.Second}
+	var delays []time.Duration
+	for i := 0; i < 5; i++ {
+		delays = append(delays, bo.next())
+	}
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}, delays)
+	bo.reset()
+	assert.Equal(t, time.Second, bo.next())
+}
+
+func TestSupervisor_RestartingReportedOnEveryRestart(t *testing.T) {
+	var reported []bool
+	s := &supervisor{
+		setRestarting: func(_ context.Context, restarting bool) error {
+			reported = append(reported, restarting)
+			return nil
+		},
+	}
+	ctx := context.Background()
+
+	// The first exit is reported, but consecutive exits before the process is ready are not.
+	s.markRestarting(ctx)
+	s.markRestarting(ctx)
+	assert.Equal(t, []bool{true}, reported)
+
+	// Without a ready address, the process is ready as soon as it has started.
+	s.awaitReady(ctx)
+	assert.Equal(t, []bool{true, false}, reported)
+
+	// A later exit must be reported again.
+	s.markRestarting(ctx)
+	assert.Equal(t, []bool{true, false, true}, reported)
+}
@@ -35,14 +35,14 @@ type connectorState struct {
 
 // withConnector is like cliutil.WithConnector, but also
 //
-//  - Ensures that the damon is running too
+//  - Ensures that the damon is running too, unless the session uses proxies instead
 //
 //  - Cleans up after itself unless retain is true (If it launches the daemon or connector, then it will shut
 //    them down when it's done.  If they were already running, it will leave them running.)
 //
 //  - Makes the connector.Connect gRPC call to set up networking
 func withConnector(cmd *cobra.Command, retain bool, request *connector.ConnectRequest, f func(context.Context, *connectorState) error) error {
-	return cliutil.WithNetwork(cmd.Context(), func(ctx context.Context, daemonClient daemon.DaemonClient) error {
+	withUserDaemon := func(ctx context.Context, daemonClient daemon.DaemonClient) error {
 		return cliutil.WithConnector(ctx, func(ctx context.Context, connectorClient connector.ConnectorClient) error {
 			didConnect, connInfo, err := connect(ctx, connectorClient, cmd.OutOrStdout(), request)
 			if err != nil {
@@ -58,7 +58,30 @@ func withConnector(cmd *cobra.Command, retain bool, request *connector.ConnectRe
 			}
 			return f(ctx, &connectorState{ConnectInfo: connInfo, userD: connectorClient, rootD: daemonClient})
 		})
+	}
+	ctx := cmd.Context()
+	if usesProxies(ctx, request) {
+		// The root daemon isn't needed, so it's neither started nor connected to.
+		return withUserDaemon(ctx, nil)
+	}
+	return cliutil.WithNetwork(ctx, withUserDaemon)
+}
+
+// usesProxies returns true if the given request asks for a session that uses proxies instead of the root
+// daemon or, when the request is nil, if the running user daemon is connected using such a session.
+func usesProxies(ctx context.Context, request *connector.ConnectRequest) bool {
+	if request != nil {
+		return request.SocksProxy != "" || request.HttpProxy != ""
+	}
+	proxies := false
+	_ = cliutil.WithStartedConnector(ctx, false, func(ctx context.Context, connectorClient connector.ConnectorClient) error {
+		ci, err := connectorClient.Status(ctx, &empty.Empty{})
+		if err == nil {
+			proxies = ci.SocksProxy != "" || ci.HttpProxy != ""
+		}
+		return err
 	})
+	return proxies
 }
 
 func addKubeconfigEnv(cr *connector.ConnectRequest) {
@@ -93,6 +116,12 @@ func connect(ctx context.Context, connectorClient connector.ConnectorClient, std
 	switch ci.Error {
 	case connector.ConnectInfo_UNSPECIFIED:
 		fmt.Fprintf(stdout, "Connected to context %s (%s)\n", ci.ClusterContext, ci.ClusterServer)
+		if ci.SocksProxy != "" {
+			fmt.Fprintf(stdout, "SOCKS5 proxy listening on %s\n", ci.SocksProxy)
+		}
+		if ci.HttpProxy != "" {
+			fmt.Fprintf(stdout, "HTTP CONNECT proxy listening on %s\n", ci.HttpProxy)
+		}
 		return true, ci, nil
 	case connector.ConnectInfo_ALREADY_CONNECTED:
 		return false, ci, nil
@@ -0,0 +1,368 @@
+// Package workspace contains the parser for the telepresence.yaml workspace file that declares the
+// connection settings and intercepts used by the "telepresence up" and "telepresence down" commands.
+package workspace
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/telepresenceio/telepresence/v2/pkg/agentconfig"
+	"github.com/telepresenceio/telepresence/v2/pkg/client"
+	"github.com/telepresenceio/telepresence/v2/pkg/client/cli/envfile"
+	"github.com/telepresenceio/telepresence/v2/pkg/client/errcat"
+	"github.com/telepresenceio/telepresence/v2/pkg/glob"
+	"github.com/telepresenceio/telepresence/v2/pkg/shellquote"
+)
+
+// FileName is the name of the workspace file.
+const FileName = "telepresence.yaml"
+
+// Connection declares how to connect to the cluster.
+type Connection struct {
+	// Context is the kubeconfig context to use
+	Context string
+
+	// Namespace is the namespace that intercepts default to
+	Namespace string
+
+	// MappedNamespaces limits the namespaces that the connection will map
+	MappedNamespaces []string
+
+	// AlsoProxy are additional subnets that will be proxied to the cluster
+	AlsoProxy []*net.IPNet
+}
+
+// Intercept declares one intercept and the handler that serves it.
+type Intercept struct {
+	// Name of the intercept
+	Name string
+
+	// Workload to intercept. Defaults to Name
+	Workload string
+
+	// Namespace of the workload. Defaults to the connection namespace
+	Namespace string
+
+	// Service to intercept, if the workload has more than one
+	Service string
+
+	// Port in the same format as the intercept --port flag
+	Port string
+
+	// EnvFile is a file that the intercepted environment is written to
+	EnvFile string
+
+	// EnvSyntax is the syntax of the EnvFile
+	EnvSyntax string
+
+	// EnvJSON is a file that the intercepted environment is written to as JSON
+	EnvJSON string
+
+	// Mount is "true", "false", or the desired mount point. Empty means default
+	Mount string
+
+	// MountMode is one of the client.MountModes. Empty means default
+	MountMode string
+
+	// ToPod are additional ports to forward from the intercepted pod
+	ToPod []string
+
+	// DockerRun, when true, means that the Handler is passed to "docker run"
+	DockerRun bool
+
+	// DockerMount is where to mount remote volumes in the docker container
+	DockerMount string
+
+	// Restart is one of the client.RestartPolicies. Empty means default
+	Restart string
+
+	// Watch are glob patterns of files that restart the Handler when they change
+	Watch []string
+
+	// Handler is the command that serves the intercepted traffic
+	Handler []string
+}
+
+// Workspace is the parsed contents of a telepresence.yaml file.
+type Workspace struct {
+	Connection Connection
+	Intercepts []*Intercept
+}
+
+// Find returns the path of the workspace file in the given directory, or an error
+// if no such file exists.
+func Find(dir string) (string, error) {
+	path := filepath.Join(dir, FileName)
+	if _, err := os.Stat(path); err != nil {
+		if os.IsNotExist(err) {
+			return "", errcat.User.Newf("no %s found in %s", FileName, dir)
+		}
+		return "", err
+	}
+	return path, nil
+}
+
+// Load reads and validates the workspace file at the given path.
+func Load(path string) (*Workspace, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errcat.User.New(err)
+	}
+	ws := &Workspace{}
+	if err = yaml.Unmarshal(data, ws); err != nil {
+		return nil, errcat.User.Newf("file %s, %s", path, strings.TrimPrefix(err.Error(), "yaml: "))
+	}
+	if len(ws.Intercepts) == 0 {
+		return nil, errcat.User.Newf("file %s: no intercepts declared", path)
+	}
+	return ws, nil
+}
+
+// Namespace returns the namespace of the given intercept.
+func (ws *Workspace) Namespace(ic *Intercept) string {
+	if ic.Namespace != "" {
+		return ic.Namespace
+	}
+	return ws.Connection.Namespace
+}
+
+func withLoc(s string, n *yaml.Node) error {
+	return fmt.Errorf("line %d: %s", n.Line, s)
+}
+
+func stringKey(n *yaml.Node) (string, error) {
+	var s string
+	if err := n.Decode(&s); err != nil {
+		return "", withLoc("key must be a string", n)
+	}
+	return s, nil
+}
+
+func stringValue(kv string, n *yaml.Node) (string, error) {
+	if n.Kind != yaml.ScalarNode {
+		return "", withLoc(fmt.Sprintf("value of %q must be a string", kv), n)
+	}
+	return n.Value, nil
+}
+
+func stringList(kv string, n *yaml.Node) ([]string, error) {
+	if n.Kind != yaml.SequenceNode {
+		return nil, withLoc(fmt.Sprintf("value of %q must be a list", kv), n)
+	}
+	ss := make([]string, len(n.Content))
+	for i, e := range n.Content {
+		s, err := stringValue(kv, e)
+		if err != nil {
+			return nil, err
+		}
+		ss[i] = s
+	}
+	return ss, nil
+}
+
+func (ws *Workspace) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.MappingNode {
+		return withLoc("workspace must be an object", node)
+	}
+	ms := node.Content
+	top := len(ms)
+	for i := 0; i < top; i += 2 {
+		kv, err := stringKey(ms[i])
+		if err != nil {
+			return err
+		}
+		v := ms[i+1]
+		switch kv {
+		case "connection":
+			err = v.Decode(&ws.Connection)
+		case "intercepts":
+			err = ws.unmarshalIntercepts(v)
+		default:
+			err = withLoc(fmt.Sprintf("unknown key %q", kv), ms[i])
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (ws *Workspace) unmarshalIntercepts(node *yaml.Node) error {
+	if node.Kind != yaml.SequenceNode {
+		return withLoc("intercepts must be a list", node)
+	}
+	names := make(map[string]int, len(node.Content))
+	ws.Intercepts = make([]*Intercept, len(node.Content))
+	for i, n := range node.Content {
+		ic := &Intercept{}
+		if err := n.Decode(ic); err != nil {
+			return err
+		}
+		if line, ok := names[ic.Name]; ok {
+			return withLoc(fmt.Sprintf("intercept %q is already declared at line %d", ic.Name, line), n)
+		}
+		names[ic.Name] = n.Line
+		ws.Intercepts[i] = ic
+	}
+	return nil
+}
+
+func (c *Connection) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.MappingNode {
+		return withLoc("connection must be an object", node)
+	}
+	ms := node.Content
+	top := len(ms)
+	for i := 0; i < top; i += 2 {
+		kv, err := stringKey(ms[i])
+		if err != nil {
+			return err
+		}
+		v := ms[i+1]
+		switch kv {
+		case "context":
+			c.Context, err = stringValue(kv, v)
+		case "namespace":
+			c.Namespace, err = stringValue(kv, v)
+		case "mappedNamespaces":
+			c.MappedNamespaces, err = stringList(kv, v)
+		case "alsoProxy":
+			var ss []string
+			if ss, err = stringList(kv, v); err != nil {
+				break
+			}
+			c.AlsoProxy = make([]*net.IPNet, len(ss))
+			for si, s := range ss {
+				if _, c.AlsoProxy[si], err = net.ParseCIDR(s); err != nil {
+					err = withLoc(fmt.Sprintf("%q is not a valid subnet", s), v.Content[si])
+					break
+				}
+			}
+		default:
+			err = withLoc(fmt.Sprintf("unknown key %q", kv), ms[i])
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (ic *Intercept) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.MappingNode {
+		return withLoc("intercept must be an object", node)
+	}
+	ms := node.Content
+	top := len(ms)
+	for i := 0; i < top; i += 2 {
+		kv, err := stringKey(ms[i])
+		if err != nil {
+			return err
+		}
+		v := ms[i+1]
+		switch kv {
+		case "name":
+			ic.Name, err = stringValue(kv, v)
+		case "workload":
+			ic.Workload, err = stringValue(kv, v)
+		case "namespace":
+			ic.Namespace, err = stringValue(kv, v)
+		case "service":
+			ic.Service, err = stringValue(kv, v)
+		case "port":
+			ic.Port, err = stringValue(kv, v)
+		case "envFile":
+			ic.EnvFile, err = stringValue(kv, v)
+		case "envSyntax":
+			if ic.EnvSyntax, err = stringValue(kv, v); err == nil {
+				if _, serr := envfile.ParseSyntax(ic.EnvSyntax); serr != nil {
+					err = withLoc(serr.Error(), v)
+				}
+			}
+		case "envJSON":
+			ic.EnvJSON, err = stringValue(kv, v)
+		case "mount":
+			ic.Mount, err = stringValue(kv, v)
+		case "mountMode":
+			if ic.MountMode, err = stringValue(kv, v); err == nil && !client.IsMountMode(ic.MountMode) {
+				err = withLoc(fmt.Sprintf("invalid mountMode %q, must be one of %s", ic.MountMode, client.MountModesString()), v)
+			}
+		case "toPod":
+			if ic.ToPod, err = stringList(kv, v); err == nil {
+				for pi, tp := range ic.ToPod {
+					if _, perr := agentconfig.NewPortAndProto(tp); perr != nil {
+						err = withLoc(perr.Error(), v.Content[pi])
+						break
+					}
+				}
+			}
+		case "dockerRun":
+			var s string
+			if s, err = stringValue(kv, v); err == nil {
+				if ic.DockerRun, err = strconv.ParseBool(s); err != nil {
+					err = withLoc(fmt.Sprintf("bool expected for key %q", kv), v)
+				}
+			}
+		case "dockerMount":
+			ic.DockerMount, err = stringValue(kv, v)
+		case "restart":
+			if ic.Restart, err = stringValue(kv, v); err == nil && !client.IsRestartPolicy(ic.Restart) {
+				err = withLoc(fmt.Sprintf("invalid restart %q, must be one of %s", ic.Restart, client.RestartPoliciesString()), v)
+			}
+		case "watch":
+			if ic.Watch, err = stringList(kv, v); err == nil {
+				for wi, w := range ic.Watch {
+					if !glob.Valid(w) {
+						err = withLoc(fmt.Sprintf("%q is not a valid glob pattern", w), v.Content[wi])
+						break
+					}
+				}
+			}
+		case "handler":
+			if v.Kind == yaml.ScalarNode {
+				if ic.Handler, err = shellquote.Split(v.Value); err != nil {
+					err = withLoc(fmt.Sprintf("invalid handler: %v", err), v)
+				}
+			} else {
+				ic.Handler, err = stringList(kv, v)
+			}
+		default:
+			err = withLoc(fmt.Sprintf("unknown key %q", kv), ms[i])
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return ic.validate(node)
+}
+
+func (ic *Intercept) validate(node *yaml.Node) error {
+	if ic.Name == "" {
+		if ic.Workload == "" {
+			return withLoc("intercept must have a name or a workload", node)
+		}
+		ic.Name = ic.Workload
+	}
+	if ic.Workload == "" {
+		ic.Workload = ic.Name
+	}
+	if ic.DockerRun && len(ic.Handler) == 0 {
+		return withLoc(fmt.Sprintf("intercept %q: dockerRun requires a handler", ic.Name), node)
+	}
+	if ic.EnvSyntax != "" && ic.EnvFile == "" {
+		return withLoc(fmt.Sprintf("intercept %q: envSyntax must be used together with envFile", ic.Name), node)
+	}
+	if ((ic.Restart != "" && ic.Restart != client.RestartNo) || len(ic.Watch) > 0) && len(ic.Handler) == 0 {
+		return withLoc(fmt.Sprintf("intercept %q: restart and watch require a handler", ic.Name), node)
+	}
+	if ic.DockerMount != "" && !ic.DockerRun {
+		return withLoc(fmt.Sprintf("intercept %q: dockerMount must be used together with dockerRun", ic.Name), node)
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+package workspace
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeWorkspace(t *testing.T, content string) string {
+	file := filepath.Join(t.TempDir(), FileName)
+	require.NoError(t, os.WriteFile(file, []byte(content), 0o644))
+	return file
+}
+
+func TestLoad(t *testing.T) {
+	file := writeWorkspace(t, `
+connection:
+  context: dev
+  namespace: team-a
+  mappedNamespaces: [team-a, shared]
+  alsoProxy:
+    - 10.10.0.0/16
+intercepts:
+  - name: orders
+    port: 8080:http
+    envFile: orders.env
+    envSyntax: sh
+    mount: "false"
+    toPod: [5432, 8125/UDP]
+    handler: go run ./cmd/orders --greeting 'hello world'
+    restart: on-failure
+    watch: ["**/*.go", go.mod]
+  - workload: web
+    namespace: team-b
+    dockerRun: true
+    dockerMount: /mnt
+    mountMode: sync
+    handler: [--rm, web:dev]
+`)
+	ws, err := Load(file)
+	require.NoError(t, err)
+
+	cn := ws.Connection
+	assert.Equal(t, "dev", cn.Context)
+	assert.Equal(t, []string{"team-a", "shared"}, cn.MappedNamespaces)
+	require.Len(t, cn.AlsoProxy, 1)
+	assert.Equal(t, "10.10.0.0/16", cn.AlsoProxy[0].String())
+
+	require.Len(t, ws.Intercepts, 2)
+	orders := ws.Intercepts[0]
+	assert.Equal(t, &Intercept{
+		Name:      "orders",
+		Workload:  "orders",
+		Port:      "8080:http",
+		EnvFile:   "orders.env",
+		EnvSyntax: "sh",
+		Mount:     "false",
+		ToPod:     []string{"5432", "8125/UDP"},
+		Restart:   "on-failure",
+		Watch:     []string{"**/*.go", "go.mod"},
+		Handler:   []string{"go", "run", "./cmd/orders", "--greeting", "hello world"},
+	}, orders)
+	assert.Equal(t, "team-a", ws.Namespace(orders))
+
+	web := ws.Intercepts[1]
+	assert.Equal(t, "web", web.Name)
+	assert.True(t, web.DockerRun)
+	assert.Equal(t, "sync", web.MountMode)
+	assert.Equal(t, []string{"--rm", "web:dev"}, web.Handler)
+	assert.Equal(t, "team-b", ws.Namespace(web))
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		err     string
+	}{
+		{
+			name:    "unknown top-level key",
+			content: "intercepts:\n  - name: a\nconnections: {}\n",
+			err:     `line 3: unknown key "connections"`,
+		},
+		{
+			name:    "unknown intercept key",
+			content: "intercepts:\n  - name: a\n    prot: 8080\n",
+			err:     `line 3: unknown key "prot"`,
+		},
+		{
+			name:    "bad subnet",
+			content: "connection:\n  alsoProxy:\n    - 10.0.0.0/8\n    - 10.1.2.3\nintercepts:\n  - name: a\n",
+			err:     `line 4: "10.1.2.3" is not a valid subnet`,
+		},
+		{
+			name:    "bad to-pod port",
+			content: "intercepts:\n  - name: a\n    toPod:\n      - 99999\n",
+			err:     "line 4: ",
+		},
+		{
+			name:    "bad bool",
+			content: "intercepts:\n  - name: a\n    dockerRun: sure\n",
+			err:     `line 3: bool expected for key "dockerRun"`,
+		},
+		{
+			name:    "nameless intercept",
+			content: "intercepts:\n  - port: 8080\n",
+			err:     "line 2: intercept must have a name or a workload",
+		},
+		{
+			name:    "duplicate intercept",
+			content: "intercepts:\n  - name: a\n  - workload: a\n",
+			err:     `line 3: intercept "a" is already declared at line 2`,
+		},
+		{
+			name:    "docker-run without handler",
+			content: "intercepts:\n  - name: a\n    dockerRun: true\n",
+			err:     `line 2: intercept "a": dockerRun requires a handler`,
+		},
+		{
+			name:    "bad env syntax",
+			content: "intercepts:\n  - name: a\n    envFile: a.env\n    envSyntax: csh\n",
+			err:     `line 4: invalid env syntax "csh"`,
+		},
+		{
+			name:    "bad mount mode",
+			content: "intercepts:\n  - name: a\n    mountMode: fuse\n",
+			err:     `line 3: invalid mountMode "fuse", must be one of "sshfs", "nfs", "copy", or "sync"`,
+		},
+		{
+			name:    "bad restart",
+			content: "intercepts:\n  - name: a\n    handler: run\n    restart: sometimes\n",
+			err:     `line 4: invalid restart "sometimes", must be one of "no", "on-failure", or "always"`,
+		},
+		{
+			name:    "bad watch pattern",
+			content: "intercepts:\n  - name: a\n    handler: run\n    watch:\n      - \"*.go\"\n      - \"[a\"\n",
+			err:     `line 6: "[a" is not a valid glob pattern`,
+		},
+		{
+			name:    "restart without handler",
+			content: "intercepts:\n  - name: a\n    restart: always\n",
+			err:     `line 2: intercept "a": restart and watch require a handler`,
+		},
+		{
+			name:    "bad handler",
+			content: "intercepts:\n  - name: a\n    handler: echo 'hello\n",
+			err:     "line 3: invalid handler: unterminated single quote",
+		},
+		{
+			name:    "no intercepts",
+			content: "connection:\n  namespace: a\n",
+			err:     "no intercepts declared",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			file := writeWorkspace(t, tt.content)
+			_, err := Load(file)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.err)
+			assert.Contains(t, err.Error(), file)
+		})
+	}
+}
+
+func TestFind(t *testing.T) {
+	dir := t.TempDir()
+	_, err := Find(dir)
+	assert.ErrorContains(t, err, "no "+FileName+" found")
+
+	file := writeWorkspace(t, "intercepts:\n  - name: a\n")
+	path, err := Find(filepath.Dir(file))
+	require.NoError(t, err)
+	assert.Equal(t, file, path)
+}
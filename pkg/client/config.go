@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"math"
 	"os"
+	"path"
 	"path/filepath"
 	"strconv"
 	"sync/atomic"
@@ -21,6 +22,7 @@ import (
 	"github.com/telepresenceio/telepresence/v2/pkg/client/errcat"
 	"github.com/telepresenceio/telepresence/v2/pkg/filelocation"
 	"github.com/telepresenceio/telepresence/v2/pkg/k8sapi"
+	"github.com/telepresenceio/telepresence/v2/pkg/shellquote"
 )
 
 const configFile = "config.yml"
@@ -770,6 +772,14 @@ var defaultIntercept = Intercept{
 type Intercept struct {
 	AppProtocolStrategy k8sapi.AppProtocolStrategy `json:"appProtocolStrategy,omitempty" yaml:"appProtocolStrategy,omitempty"`
 	DefaultPort         int                        `json:"defaultPort,omitempty" yaml:"defaultPort,omitempty"`
+	Hooks               InterceptHooks             `json:"hooks,omitempty" yaml:"hooks,omitempty"`
+	Env                 EnvRules                   `json:"env,omitempty" yaml:"env,omitempty"`
+
+	// ResolveLocally makes the DNS resolver answer queries for the names of services that are intercepted
+	// by this client with the address of the intercept handler, so that calls from other processes on the
+	// workstation reach the handler directly. Such calls use the service port, so only intercepts whose
+	// target port equals the intercepted service port are resolved locally.
+	ResolveLocally bool `json:"resolveLocally,omitempty" yaml:"resolveLocally,omitempty"`
 }
 
 func (ic *Intercept) merge(o *Intercept) {
@@ -779,11 +789,20 @@ func (ic *Intercept) merge(o *Intercept) {
 	if o.DefaultPort != 0 {
 		ic.DefaultPort = o.DefaultPort
 	}
+	ic.Hooks.merge(&o.Hooks)
+	ic.Env.merge(&o.Env)
+	if o.ResolveLocally {
+		ic.ResolveLocally = true
+	}
 }
 
 // IsZero controls whether this element will be included in marshalled output
 func (ic Intercept) IsZero() bool {
-	return ic == defaultIntercept
+	return ic.AppProtocolStrategy == defaultIntercept.AppProtocolStrategy &&
+		ic.DefaultPort == defaultIntercept.DefaultPort &&
+		ic.Hooks.IsZero() &&
+		ic.Env.IsZero() &&
+		!ic.ResolveLocally
 }
 
 // MarshalYAML is not using pointer receiver here, because Intercept is not pointer in the Config struct
@@ -795,9 +814,259 @@ func (ic Intercept) MarshalYAML() (any, error) {
 	if ic.AppProtocolStrategy != k8sapi.Http2Probe {
 		im["appProtocolStrategy"] = ic.AppProtocolStrategy.String()
 	}
+	if !ic.Hooks.IsZero() {
+		im["hooks"] = ic.Hooks
+	}
+	if !ic.Env.IsZero() {
+		im["env"] = ic.Env
+	}
+	if ic.ResolveLocally {
+		im["resolveLocally"] = true
+	}
 	return im, nil
 }
 
+// EnvRules transform the environment of an intercepted container before it is handed to the
+// intercept handler or written to files. The rules are applied in the order include, exclude,
+// rename, override. Include and exclude use glob patterns such as "KUBERNETES_*".
+type EnvRules struct {
+	// Include, when non-empty, drops all variables that don't match one of its patterns
+	Include []string `json:"include,omitempty" yaml:"include,omitempty"`
+
+	// Exclude drops the variables that match one of its patterns
+	Exclude []string `json:"exclude,omitempty" yaml:"exclude,omitempty"`
+
+	// Rename maps old variable names to new ones
+	Rename map[string]string `json:"rename,omitempty" yaml:"rename,omitempty"`
+
+	// Override sets variables to the given values, adding them if they don't exist
+	Override map[string]string `json:"override,omitempty" yaml:"override,omitempty"`
+}
+
+func (r *EnvRules) merge(o *EnvRules) {
+	if len(o.Include) > 0 {
+		r.Include = o.Include
+	}
+	if len(o.Exclude) > 0 {
+		r.Exclude = o.Exclude
+	}
+	if len(o.Rename) > 0 {
+		r.Rename = o.Rename
+	}
+	if len(o.Override) > 0 {
+		r.Override = o.Override
+	}
+}
+
+// IsZero controls whether this element will be included in marshalled output
+func (r EnvRules) IsZero() bool {
+	return len(r.Include) == 0 && len(r.Exclude) == 0 && len(r.Rename) == 0 && len(r.Override) == 0
+}
+
+// UnmarshalYAML parses the rules and validates their patterns.
+func (r *EnvRules) UnmarshalYAML(node *yaml.Node) error {
+	type plain EnvRules
+	if err := node.Decode((*plain)(r)); err != nil {
+		return err
+	}
+	for _, p := range append(append([]string{}, r.Include...), r.Exclude...) {
+		if _, err := path.Match(p, ""); err != nil {
+			return errors.New(withLoc(fmt.Sprintf("%q is not a valid pattern", p), node))
+		}
+	}
+	return nil
+}
+
+func matchesAny(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Apply returns a copy of the given environment with the rules applied.
+func (r *EnvRules) Apply(env map[string]string) map[string]string {
+	result := make(map[string]string, len(env)+len(r.Override))
+	for k, v := range env {
+		if len(r.Include) > 0 && !matchesAny(r.Include, k) || matchesAny(r.Exclude, k) {
+			continue
+		}
+		result[k] = v
+	}
+	// All renames take place simultaneously, so that the result doesn't depend on the map order.
+	renamed := make(map[string]string, len(r.Rename))
+	for from, to := range r.Rename {
+		if v, ok := result[from]; ok {
+			renamed[to] = v
+		}
+	}
+	for from := range r.Rename {
+		delete(result, from)
+	}
+	for k, v := range renamed {
+		result[k] = v
+	}
+	for k, v := range r.Override {
+		result[k] = v
+	}
+	return result
+}
+
+// The phases of an intercept's lifecycle in which hooks can run.
+const (
+	HookPreStart  = "preStart"
+	HookPostStart = "postStart"
+	HookPreStop   = "preStop"
+	HookPostStop  = "postStop"
+)
+
+// HookFailurePolicy controls what happens when a hook fails.
+type HookFailurePolicy string
+
+const (
+	// HookAbort stops the intercept from being created when a preStart or postStart hook fails,
+	// and skips the remaining hooks of the same phase.
+	HookAbort = HookFailurePolicy("abort")
+
+	// HookIgnore logs the failure and continues as if the hook succeeded.
+	HookIgnore = HookFailurePolicy("ignore")
+)
+
+const defaultHookTimeout = 1 * time.Minute
+
+// InterceptHooks are commands that the user daemon runs when intercepts start and stop.
+type InterceptHooks struct {
+	PreStart  []*InterceptHook `json:"preStart,omitempty" yaml:"preStart,omitempty"`
+	PostStart []*InterceptHook `json:"postStart,omitempty" yaml:"postStart,omitempty"`
+	PreStop   []*InterceptHook `json:"preStop,omitempty" yaml:"preStop,omitempty"`
+	PostStop  []*InterceptHook `json:"postStop,omitempty" yaml:"postStop,omitempty"`
+}
+
+// InterceptHook is a command that runs in one phase of an intercept's lifecycle.
+type InterceptHook struct {
+	Command   []string          `json:"command" yaml:"command"`
+	Timeout   time.Duration     `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+	OnFailure HookFailurePolicy `json:"onFailure,omitempty" yaml:"onFailure,omitempty"`
+}
+
+func (h *InterceptHooks) merge(o *InterceptHooks) {
+	if len(o.PreStart) > 0 {
+		h.PreStart = o.PreStart
+	}
+	if len(o.PostStart) > 0 {
+		h.PostStart = o.PostStart
+	}
+	if len(o.PreStop) > 0 {
+		h.PreStop = o.PreStop
+	}
+	if len(o.PostStop) > 0 {
+		h.PostStop = o.PostStop
+	}
+}
+
+// IsZero controls whether this element will be included in marshalled output
+func (h InterceptHooks) IsZero() bool {
+	return len(h.PreStart) == 0 && len(h.PostStart) == 0 && len(h.PreStop) == 0 && len(h.PostStop) == 0
+}
+
+// Phase returns the hooks of the given phase.
+func (h *InterceptHooks) Phase(phase string) []*InterceptHook {
+	switch phase {
+	case HookPreStart:
+		return h.PreStart
+	case HookPostStart:
+		return h.PostStart
+	case HookPreStop:
+		return h.PreStop
+	case HookPostStop:
+		return h.PostStop
+	default:
+		return nil
+	}
+}
+
+// GetTimeout returns the timeout of the hook, or the default timeout if none has been set.
+func (h *InterceptHook) GetTimeout() time.Duration {
+	if h.Timeout > 0 {
+		return h.Timeout
+	}
+	return defaultHookTimeout
+}
+
+// UnmarshalYAML parses a hook. The command can be given as a string, which is split into
+// arguments using shell quoting rules, or as a list of arguments.
+func (h *InterceptHook) UnmarshalYAML(node *yaml.Node) (err error) {
+	if node.Kind != yaml.MappingNode {
+		return errors.New(withLoc("hook must be an object", node))
+	}
+	ms := node.Content
+	top := len(ms)
+	for i := 0; i < top; i += 2 {
+		kv, err := stringKey(ms[i])
+		if err != nil {
+			return err
+		}
+		v := ms[i+1]
+		switch kv {
+		case "command":
+			if v.Kind == yaml.ScalarNode {
+				if h.Command, err = shellquote.Split(v.Value); err != nil {
+					return errors.New(withLoc(fmt.Sprintf("invalid command: %v", err), v))
+				}
+			} else if err = v.Decode(&h.Command); err != nil {
+				return errors.New(withLoc("command must be a string or a list of strings", v))
+			}
+		case "timeout":
+			var vv any
+			if err = v.Decode(&vv); err != nil {
+				return errors.New(withLoc("unable to parse value", v))
+			}
+			switch vv := vv.(type) {
+			case int:
+				h.Timeout = time.Duration(vv) * time.Second
+			case float64:
+				h.Timeout = time.Duration(vv * float64(time.Second))
+			case string:
+				if h.Timeout, err = time.ParseDuration(vv); err != nil {
+					return errors.New(withLoc(fmt.Sprintf("%q is not a valid duration", vv), v))
+				}
+			}
+		case "onFailure":
+			switch p := HookFailurePolicy(v.Value); p {
+			case HookAbort, HookIgnore:
+				h.OnFailure = p
+			default:
+				return errors.New(withLoc(fmt.Sprintf("onFailure must be %q or %q", HookAbort, HookIgnore), v))
+			}
+		default:
+			if parseContext != nil {
+				dlog.Warn(parseContext, withLoc(fmt.Sprintf("unknown key %q", kv), ms[i]))
+			}
+		}
+	}
+	if len(h.Command) == 0 {
+		return errors.New(withLoc("hook must have a command", node))
+	}
+	if h.OnFailure == "" {
+		h.OnFailure = HookAbort
+	}
+	return nil
+}
+
+// MarshalYAML uses a value receiver so that it applies to both values and pointers
+func (h InterceptHook) MarshalYAML() (any, error) {
+	hm := map[string]any{"command": h.Command}
+	if h.Timeout != 0 {
+		hm["timeout"] = h.Timeout.String()
+	}
+	if h.OnFailure != "" && h.OnFailure != HookAbort {
+		hm["onFailure"] = h.OnFailure
+	}
+	return hm, nil
+}
+
 var parseContext context.Context
 
 type parsedFile struct{}
@@ -46,6 +46,12 @@ telepresenceAPI:
 intercept:
   appProtocolStrategy: portName
   defaultPort: 9080
+  hooks:
+    preStart:
+      - command: ./seed-db.sh --fresh --label 'dev db'
+        timeout: 2m
+      - command: [curl, -s, https://hooks.example.com]
+        onFailure: ignore
 `,
 	}
 
@@ -84,6 +90,30 @@ intercept:
 	assert.Equal(t, 1234, cfg.TelepresenceAPI.Port)                                            // from user
 	assert.Equal(t, k8sapi.PortName, cfg.Intercept.AppProtocolStrategy)                        // from user
 	assert.Equal(t, 9080, cfg.Intercept.DefaultPort)                                           // from user
+	assert.Equal(t, []*InterceptHook{
+		{Command: []string{"./seed-db.sh", "--fresh", "--label", "dev db"}, Timeout: 2 * time.Minute, OnFailure: HookAbort},
+		{Command: []string{"curl", "-s", "https://hooks.example.com"}, OnFailure: HookIgnore},
+	}, cfg.Intercept.Hooks.PreStart) // from user
+}
+
+func TestInterceptHookErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		hook string
+		err  string
+	}{
+		{"no command", "timeout: 10s\n", "line 1: hook must have a command"},
+		{"bad timeout", "command: make\ntimeout: soon\n", `line 2: "soon" is not a valid duration`},
+		{"bad policy", "command: make\nonFailure: retry\n", `line 2: onFailure must be "abort" or "ignore"`},
+		{"bad command", "command: make 'all\n", "line 1: invalid command: unterminated single quote"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			var h InterceptHook
+			assert.EqualError(t, yaml.Unmarshal([]byte(tt.hook), &h), tt.err)
+		})
+	}
 }
 
 func Test_ConfigMarshalYAML(t *testing.T) {
@@ -100,6 +130,17 @@ func Test_ConfigMarshalYAML(t *testing.T) {
 	cfg.TelepresenceAPI.Port = 4567
 	cfg.Intercept.AppProtocolStrategy = k8sapi.PortName
 	cfg.Intercept.DefaultPort = 9080
+	cfg.Intercept.Hooks.PostStart = []*InterceptHook{
+		{Command: []string{"make", "seed"}, Timeout: 2 * time.Minute, OnFailure: HookIgnore},
+	}
+	cfg.Intercept.Hooks.PreStop = []*InterceptHook{
+		{Command: []string{"./notify.sh", "leaving"}, OnFailure: HookAbort},
+	}
+	cfg.Intercept.Env = EnvRules{
+		Exclude:  []string{"KUBERNETES_*"},
+		Override: map[string]string{"DB_HOST": "localhost"},
+	}
+	cfg.Intercept.ResolveLocally = true
 	cfgBytes, err := yaml.Marshal(cfg)
 	require.NoError(t, err)
 
@@ -119,3 +160,35 @@ func Test_ConfigMarshalYAMLDefaults(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, "{}\n", string(cfgBytes))
 }
+
+func TestEnvRules(t *testing.T) {
+	var rules EnvRules
+	require.NoError(t, yaml.Unmarshal([]byte(`
+exclude: [KUBERNETES_*, "*_TOKEN"]
+rename:
+  DB_HOST: DATABASE_HOST
+  DATABASE_HOST: OLD_DATABASE_HOST
+override:
+  DB_PORT: "5433"
+`), &rules))
+
+	env := rules.Apply(map[string]string{
+		"KUBERNETES_PORT": "tcp://10.0.0.1:443",
+		"API_TOKEN":       "secret",
+		"DB_HOST":         "postgres.default",
+		"DATABASE_HOST":   "legacy.default",
+		"DB_PORT":         "5432",
+		"LOG_LEVEL":       "debug",
+	})
+	assert.Equal(t, map[string]string{
+		"DATABASE_HOST":     "postgres.default",
+		"OLD_DATABASE_HOST": "legacy.default",
+		"DB_PORT":           "5433",
+		"LOG_LEVEL":         "debug",
+	}, env)
+
+	rules = EnvRules{Include: []string{"DB_*"}, Exclude: []string{"DB_PASSWORD"}}
+	assert.Equal(t, map[string]string{"DB_HOST": "h"}, rules.Apply(map[string]string{"DB_HOST": "h", "DB_PASSWORD": "p", "HOME": "/root"}))
+
+	assert.EqualError(t, yaml.Unmarshal([]byte("include: [\"DB_[\"]\n"), &rules), `line 1: "DB_[" is not a valid pattern`)
+}
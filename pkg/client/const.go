@@ -3,6 +3,8 @@ package client
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
 )
 
 const (
@@ -10,6 +12,79 @@ const (
 	APIVersion = 3
 )
 
+// Modes that control how the remote volumes of an intercepted container are made available locally.
+const (
+	// MountModeSSHFS mounts the remote volumes using sshfs. This is the default.
+	MountModeSSHFS = "sshfs"
+
+	// MountModeCopy copies the remote volumes to the mount point once.
+	MountModeCopy = "copy"
+
+	// MountModeSync copies the remote volumes to the mount point and keeps polling for remote changes.
+	MountModeSync = "sync"
+
+	// MountModeNFS mounts the remote volumes using the kernel's NFS client and the traffic-agent's NFS server.
+	MountModeNFS = "nfs"
+)
+
+// MountModes are the valid mount modes, with the default first.
+var MountModes = []string{MountModeSSHFS, MountModeNFS, MountModeCopy, MountModeSync} //nolint:gochecknoglobals // constant
+
+// IsMountMode returns true if the given mode is one of the MountModes.
+func IsMountMode(mode string) bool {
+	for _, m := range MountModes {
+		if m == mode {
+			return true
+		}
+	}
+	return false
+}
+
+// MountModesString returns the MountModes quoted and separated for use in messages, e.g.
+// `"sshfs", "nfs", "copy", or "sync"`.
+func MountModesString() string {
+	return quotedList(MountModes)
+}
+
+// Policies that control if the command that serves an intercept is restarted when it exits.
+const (
+	// RestartNo never restarts the command. This is the default.
+	RestartNo = "no"
+
+	// RestartOnFailure restarts the command when it exits with an error.
+	RestartOnFailure = "on-failure"
+
+	// RestartAlways restarts the command whenever it exits.
+	RestartAlways = "always"
+)
+
+// RestartPolicies are the valid restart policies, with the default first.
+var RestartPolicies = []string{RestartNo, RestartOnFailure, RestartAlways} //nolint:gochecknoglobals // constant
+
+// IsRestartPolicy returns true if the given policy is one of the RestartPolicies.
+func IsRestartPolicy(policy string) bool {
+	for _, p := range RestartPolicies {
+		if p == policy {
+			return true
+		}
+	}
+	return false
+}
+
+// RestartPoliciesString returns the RestartPolicies quoted and separated for use in messages, e.g.
+// `"no", "on-failure", or "always"`.
+func RestartPoliciesString() string {
+	return quotedList(RestartPolicies)
+}
+
+func quotedList(ss []string) string {
+	qs := make([]string, len(ss))
+	for i, s := range ss {
+		qs[i] = strconv.Quote(s)
+	}
+	return strings.Join(qs[:len(qs)-1], ", ") + ", or " + qs[len(qs)-1]
+}
+
 // DisplayVersion returns a printable version for `telepresence`
 func DisplayVersion() string {
 	return fmt.Sprintf("%s (api v%d)", Version(), APIVersion)
@@ -0,0 +1,161 @@
+package dns
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/datawire/dlib/dlog"
+	rpc "github.com/telepresenceio/telepresence/rpc/v2/daemon"
+	"github.com/telepresenceio/telepresence/v2/pkg/dnsproxy"
+)
+
+// cacheTTL is the default time to live for an entry with records in the local DNS cache.
+const cacheTTL = 60 * time.Second
+
+const (
+	evictLRU  = "lru"
+	evictFIFO = "fifo"
+)
+
+// cacheKey identifies an entry in the cache.
+type cacheKey struct {
+	name  string
+	qType uint16
+}
+
+type cacheEntry struct {
+	created      time.Time
+	ttl          time.Duration // set when the answer is known, before wait is closed
+	currentQType int32         // will be set to the current qType during call to cluster
+	answer       *dnsproxy.Sections
+	wait         chan struct{}
+}
+
+func (dv *cacheEntry) expired() bool {
+	return time.Since(dv.created) > dv.ttl
+}
+
+// cachePolicy decides how long answers are cached, and how many of them.
+type cachePolicy struct {
+	// ttl is the time to live for an entry with records.
+	ttl time.Duration
+
+	// negativeTTL is the time to live for an entry without records. Such entries are not cached when it's zero.
+	negativeTTL time.Duration
+
+	// maxEntries is the maximum number of entries. The number is unlimited when it's zero.
+	maxEntries int
+
+	// eviction is either evictLRU or evictFIFO.
+	eviction string
+}
+
+func newCachePolicy(cp *rpc.DNSCachePolicy) cachePolicy {
+	p := cachePolicy{ttl: cacheTTL, eviction: evictLRU}
+	if cp == nil {
+		return p
+	}
+	if ttl := cp.Ttl.AsDuration(); ttl > 0 {
+		p.ttl = ttl
+	}
+	if ttl := cp.NegativeTtl.AsDuration(); ttl > 0 {
+		p.negativeTTL = ttl
+	}
+	if cp.MaxEntries > 0 {
+		p.maxEntries = int(cp.MaxEntries)
+	}
+	if cp.Eviction == evictFIFO {
+		p.eviction = evictFIFO
+	}
+	return p
+}
+
+type cacheItem struct {
+	key   cacheKey
+	entry *cacheEntry
+}
+
+// cache is a map of cache entries that is bounded by the maxEntries of its policy. The order list has the entry
+// that is the next to be evicted at its back.
+type cache struct {
+	sync.Mutex
+	policy  cachePolicy
+	entries map[cacheKey]*list.Element
+	order   *list.List
+}
+
+func newCache(policy cachePolicy) *cache {
+	return &cache{
+		policy:  policy,
+		entries: make(map[cacheKey]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// loadOrStore returns the existing entry for the given key and true if one is present. Otherwise, it stores the
+// given entry and returns it with false.
+func (c *cache) loadOrStore(key cacheKey, dv *cacheEntry) (*cacheEntry, bool) {
+	c.Lock()
+	defer c.Unlock()
+	if el, ok := c.entries[key]; ok {
+		if c.policy.eviction == evictLRU {
+			c.order.MoveToFront(el)
+		}
+		return el.Value.(*cacheItem).entry, true
+	}
+	c.storeLocked(key, dv)
+	return dv, false
+}
+
+// store stores the given entry, replacing any existing entry for the given key.
+func (c *cache) store(key cacheKey, dv *cacheEntry) {
+	c.Lock()
+	defer c.Unlock()
+	if el, ok := c.entries[key]; ok {
+		c.order.Remove(el)
+	}
+	c.storeLocked(key, dv)
+}
+
+func (c *cache) storeLocked(key cacheKey, dv *cacheEntry) {
+	c.entries[key] = c.order.PushFront(&cacheItem{key: key, entry: dv})
+	if max := c.policy.maxEntries; max > 0 {
+		for c.order.Len() > max {
+			el := c.order.Back()
+			c.order.Remove(el)
+			delete(c.entries, el.Value.(*cacheItem).key)
+		}
+	}
+}
+
+// delete deletes the entry for the given key, provided that it is the given entry.
+func (c *cache) delete(key cacheKey, dv *cacheEntry) {
+	c.Lock()
+	defer c.Unlock()
+	if el, ok := c.entries[key]; ok && el.Value.(*cacheItem).entry == dv {
+		c.order.Remove(el)
+		delete(c.entries, key)
+	}
+}
+
+// flush deletes all entries.
+func (c *cache) flush() {
+	c.Lock()
+	c.entries = make(map[cacheKey]*list.Element)
+	c.order.Init()
+	c.Unlock()
+}
+
+func (c *cache) len() int {
+	c.Lock()
+	defer c.Unlock()
+	return c.order.Len()
+}
+
+// Flush removes all entries from the cache of this server.
+func (s *Server) Flush(ctx context.Context) {
+	dlog.Debug(ctx, "Flushing DNS cache")
+	s.flushDNS()
+}
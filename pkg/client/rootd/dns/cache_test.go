@@ -0,0 +1,90 @@
+package dns
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"github.com/datawire/dlib/dlog"
+	rpc "github.com/telepresenceio/telepresence/rpc/v2/daemon"
+	"github.com/telepresenceio/telepresence/v2/pkg/dnsproxy"
+)
+
+func TestCacheEviction(t *testing.T) {
+	a := cacheKey{name: "a.", qType: dns.TypeA}
+	b := cacheKey{name: "b.", qType: dns.TypeA}
+	c := cacheKey{name: "c.", qType: dns.TypeA}
+	for _, eviction := range []string{evictLRU, evictFIFO} {
+		t.Run(eviction, func(t *testing.T) {
+			ch := newCache(cachePolicy{maxEntries: 2, eviction: eviction})
+			ch.store(a, &cacheEntry{})
+			ch.store(b, &cacheEntry{})
+
+			// Using a makes b the least recently used entry, but a is still the oldest
+			_, loaded := ch.loadOrStore(a, &cacheEntry{})
+			require.True(t, loaded)
+			ch.store(c, &cacheEntry{})
+			assert.Equal(t, 2, ch.len())
+
+			_, aLoaded := ch.loadOrStore(a, &cacheEntry{})
+			if eviction == evictLRU {
+				assert.True(t, aLoaded)
+				_, ok := ch.entries[b]
+				assert.False(t, ok)
+			} else {
+				assert.False(t, aLoaded)
+			}
+		})
+	}
+}
+
+func TestNegativeCaching(t *testing.T) {
+	lookups := 0
+	clusterLookup := func(_ context.Context, name string, qType uint16) (*dnsproxy.Sections, int, error) {
+		lookups++
+		return nil, dns.RcodeNameError, nil
+	}
+	newServer := func(negativeTTL time.Duration) *Server {
+		s := NewServer(&rpc.DNSConfig{Cache: &rpc.DNSCachePolicy{
+			RecordTtl:   durationpb.New(10 * time.Second),
+			NegativeTtl: durationpb.New(negativeTTL),
+		}}, clusterLookup)
+		s.ctx = dlog.NewTestContext(t, false)
+		s.resolve = s.resolveInCluster
+		s.cacheResolve = s.resolveThruCache
+		return s
+	}
+	q := &dns.Question{Name: "zookeeper.ns.", Qtype: dns.TypeA, Qclass: dns.ClassINET}
+
+	// Without negative caching, every query for a name that doesn't exist is a cluster lookup
+	s := newServer(0)
+	assert.Equal(t, uint32(10), s.recordTTL)
+	for i := 0; i < 2; i++ {
+		rrs, err := s.cacheResolve(q)
+		require.NoError(t, err)
+		assert.Nil(t, rrs)
+	}
+	assert.Equal(t, 2, lookups)
+	assert.Equal(t, 0, s.cache.len())
+
+	// With negative caching, the NXDOMAIN is cached until flushed
+	lookups = 0
+	s = newServer(time.Minute)
+	for i := 0; i < 2; i++ {
+		rrs, err := s.cacheResolve(q)
+		require.NoError(t, err)
+		assert.Nil(t, rrs)
+	}
+	assert.Equal(t, 1, lookups)
+	assert.Equal(t, 1, s.cache.len())
+	s.Flush(s.ctx)
+	assert.Equal(t, 0, s.cache.len())
+	_, err := s.cacheResolve(q)
+	require.NoError(t, err)
+	assert.Equal(t, 2, lookups)
+}
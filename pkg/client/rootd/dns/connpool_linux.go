@@ -5,11 +5,17 @@ import (
 	"context"
 	"fmt"
 	"net"
+	"syscall"
 	"time"
 
 	"github.com/miekg/dns"
+	"golang.org/x/sys/unix"
 )
 
+// fallbackMark is the SO_MARK that is set on the TCP connections to the fallback DNS server so that
+// routeDNS can exempt them from being redirected to the local DNS server.
+const fallbackMark = 0x7e1
+
 type ConnPool struct {
 	items      map[*dns.Conn]bool
 	newArrival chan *waitingClient
@@ -54,7 +60,12 @@ func (cp *ConnPool) RemoteAddr() string {
 	return cp.remoteAddr
 }
 
+// Exchange sends the given message to the remote DNS server. A pooled connection is used unless the
+// client's network is "tcp", in which case a new TCP connection is established for the exchange.
 func (cp *ConnPool) Exchange(ctx context.Context, client *dns.Client, msg *dns.Msg) (r *dns.Msg, rtt time.Duration, err error) {
+	if client.Net == "tcp" {
+		return cp.exchangeTCP(ctx, client, msg)
+	}
 	conn, err := cp.getConnection(ctx)
 	if err != nil {
 		return nil, time.Duration(0), err
@@ -63,6 +74,26 @@ func (cp *ConnPool) Exchange(ctx context.Context, client *dns.Client, msg *dns.M
 	return client.ExchangeWithConn(msg, conn)
 }
 
+func (cp *ConnPool) exchangeTCP(ctx context.Context, client *dns.Client, msg *dns.Msg) (*dns.Msg, time.Duration, error) {
+	d := net.Dialer{Timeout: client.Timeout, Control: markFallbackConn}
+	conn, err := d.DialContext(ctx, "tcp", net.JoinHostPort(cp.remoteAddr, "53"))
+	if err != nil {
+		return nil, time.Duration(0), fmt.Errorf("unable to create DNS TCP conn to %s: %w", cp.remoteAddr, err)
+	}
+	defer conn.Close()
+	return client.ExchangeWithConn(msg, &dns.Conn{Conn: conn})
+}
+
+func markFallbackConn(_, _ string, rc syscall.RawConn) error {
+	var err error
+	if cerr := rc.Control(func(fd uintptr) {
+		err = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_MARK, fallbackMark)
+	}); cerr != nil {
+		return cerr
+	}
+	return err
+}
+
 func (cp *ConnPool) Close() {
 	cp.cancel()
 	for conn := range cp.items {
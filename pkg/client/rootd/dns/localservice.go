@@ -0,0 +1,41 @@
+package dns
+
+import (
+	"context"
+	"net"
+	"strings"
+
+	"github.com/miekg/dns"
+
+	"github.com/datawire/dlib/dlog"
+	"github.com/telepresenceio/telepresence/v2/pkg/dnsproxy"
+)
+
+// SetLocalServices replaces the services that are intercepted by this client and served on the workstation.
+// The given map is keyed by "<service name>.<namespace>".
+func (s *Server) SetLocalServices(ctx context.Context, services map[string]net.IP) {
+	s.domainsLock.Lock()
+	s.localServices = services
+	s.domainsLock.Unlock()
+	dlog.Debugf(ctx, "local services set to %v", services)
+	s.flushDNS()
+}
+
+// resolveLocalService returns the address records of a service that is served on the workstation, or nil if
+// the name of the given question isn't the name of such a service. The name can be in the form
+// "<service>.<namespace>", "<service>.<namespace>.svc", or "<service>.<namespace>.svc.<cluster domain>".
+func (s *Server) resolveLocalService(q *dns.Question) dnsproxy.RRs {
+	s.domainsLock.RLock()
+	defer s.domainsLock.RUnlock()
+	if len(s.localServices) == 0 {
+		return nil
+	}
+	name := strings.TrimSuffix(strings.ToLower(q.Name), tel2SubDomainDot)
+	name = strings.TrimSuffix(name, ".")
+	name = strings.TrimSuffix(name, "."+strings.TrimSuffix(s.clusterDomain, "."))
+	name = strings.TrimSuffix(name, ".svc")
+	if ip, ok := s.localServices[name]; ok {
+		return dnsproxy.AddressRRs(q.Name, q.Qtype, []net.IP{ip}, s.recordTTL)
+	}
+	return nil
+}
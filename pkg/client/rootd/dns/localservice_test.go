@@ -0,0 +1,39 @@
+package dns
+
+import (
+	"net"
+	"testing"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/datawire/dlib/dlog"
+)
+
+func TestResolveLocalService(t *testing.T) {
+	ctx := dlog.NewTestContext(t, false)
+	s := NewServer(nil, nil)
+	s.SetLocalServices(ctx, map[string]net.IP{"orders.ns": {127, 0, 0, 1}})
+
+	for _, name := range []string{"orders.ns.", "Orders.NS.", "orders.ns.svc.", "orders.ns.svc.cluster.local.", "orders.ns.tel2-search."} {
+		t.Run(name, func(t *testing.T) {
+			rrs := s.resolveLocalService(&dns.Question{Name: name, Qtype: dns.TypeA, Qclass: dns.ClassINET})
+			require.Len(t, rrs, 1)
+			a := rrs[0].(*dns.A)
+			assert.Equal(t, name, a.Hdr.Name)
+			assert.Equal(t, "127.0.0.1", a.A.String())
+		})
+	}
+
+	// The name exists, but has no records of other types
+	rrs := s.resolveLocalService(&dns.Question{Name: "orders.ns.", Qtype: dns.TypeAAAA, Qclass: dns.ClassINET})
+	assert.NotNil(t, rrs)
+	assert.Empty(t, rrs)
+
+	assert.Nil(t, s.resolveLocalService(&dns.Question{Name: "orders.other.", Qtype: dns.TypeA, Qclass: dns.ClassINET}))
+	assert.Nil(t, s.resolveLocalService(&dns.Question{Name: "billing.ns.", Qtype: dns.TypeA, Qclass: dns.ClassINET}))
+
+	s.SetLocalServices(ctx, nil)
+	assert.Nil(t, s.resolveLocalService(&dns.Question{Name: "orders.ns.", Qtype: dns.TypeA, Qclass: dns.ClassINET}))
+}
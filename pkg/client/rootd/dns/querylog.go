@@ -0,0 +1,141 @@
+package dns
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	rpc "github.com/telepresenceio/telepresence/rpc/v2/daemon"
+)
+
+const (
+	// queryLogSize is the number of recent queries that the queryLog retains.
+	queryLogSize = 500
+
+	// maxSuffixes is the maximum number of suffixes that requests are counted for. Requests for names
+	// with other suffixes are counted under otherSuffixes once that number is reached.
+	maxSuffixes   = 500
+	otherSuffixes = "*"
+)
+
+// queryLog is a ring buffer of the most recent queries handled by the Server, and the per-suffix
+// request counts.
+type queryLog struct {
+	sync.Mutex
+	entries      []*rpc.DNSQuery
+	next         int
+	watchers     map[chan *rpc.DNSQuery]struct{}
+	suffixCounts map[string]int64
+}
+
+func newQueryLog() *queryLog {
+	return &queryLog{
+		entries:      make([]*rpc.DNSQuery, 0, queryLogSize),
+		watchers:     make(map[chan *rpc.DNSQuery]struct{}),
+		suffixCounts: make(map[string]int64),
+	}
+}
+
+// querySuffix returns the given name without its first label.
+func querySuffix(name string) string {
+	name = strings.ToLower(name)
+	if i := strings.IndexByte(name, '.'); i >= 0 && i < len(name)-1 {
+		return name[i+1:]
+	}
+	return "."
+}
+
+func (l *queryLog) add(q *rpc.DNSQuery) {
+	sfx := querySuffix(q.Name)
+	l.Lock()
+	defer l.Unlock()
+	if len(l.entries) < queryLogSize {
+		l.entries = append(l.entries, q)
+	} else {
+		l.entries[l.next] = q
+	}
+	l.next = (l.next + 1) % queryLogSize
+	if _, ok := l.suffixCounts[sfx]; !ok && len(l.suffixCounts) >= maxSuffixes {
+		sfx = otherSuffixes
+	}
+	l.suffixCounts[sfx]++
+	for ch := range l.watchers {
+		select {
+		case ch <- q:
+		default:
+			// The watcher doesn't keep up, so the query is dropped.
+		}
+	}
+}
+
+// subscribe returns the retained queries, oldest first. A channel that receives the queries that are
+// added after this call is also returned when follow is true.
+func (l *queryLog) subscribe(follow bool) ([]*rpc.DNSQuery, chan *rpc.DNSQuery) {
+	l.Lock()
+	defer l.Unlock()
+	recent := make([]*rpc.DNSQuery, 0, len(l.entries))
+	if len(l.entries) == queryLogSize {
+		recent = append(recent, l.entries[l.next:]...)
+		recent = append(recent, l.entries[:l.next]...)
+	} else {
+		recent = append(recent, l.entries...)
+	}
+	var ch chan *rpc.DNSQuery
+	if follow {
+		ch = make(chan *rpc.DNSQuery, 100)
+		l.watchers[ch] = struct{}{}
+	}
+	return recent, ch
+}
+
+func (l *queryLog) unsubscribe(ch chan *rpc.DNSQuery) {
+	l.Lock()
+	delete(l.watchers, ch)
+	l.Unlock()
+}
+
+// WatchQueries calls send with each one of the most recent queries that this server has handled, oldest first.
+// When follow is true, it then continues to call send for each new query until the given context is cancelled.
+func (s *Server) WatchQueries(ctx context.Context, follow bool, send func(*rpc.DNSQuery) error) error {
+	recent, ch := s.queryLog.subscribe(follow)
+	if ch != nil {
+		defer s.queryLog.unsubscribe(ch)
+	}
+	for _, q := range recent {
+		if err := send(q); err != nil {
+			return err
+		}
+	}
+	if ch == nil {
+		return nil
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case q := <-ch:
+			if err := send(q); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Stats returns the request and cache statistics of this server.
+func (s *Server) Stats() *rpc.DNSStats {
+	l := s.queryLog
+	l.Lock()
+	suffixCounts := make(map[string]int64, len(l.suffixCounts))
+	for sfx, count := range l.suffixCounts {
+		suffixCounts[sfx] = count
+	}
+	l.Unlock()
+	return &rpc.DNSStats{
+		RequestCount: atomic.LoadInt64(&s.requestCount),
+		CacheHits:    atomic.LoadInt64(&s.cacheHits),
+		CacheMisses:  atomic.LoadInt64(&s.cacheMisses),
+		SuffixCounts: suffixCounts,
+		CacheEntries: int64(s.cache.len()),
+	}
+}
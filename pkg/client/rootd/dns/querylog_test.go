@@ -0,0 +1,57 @@
+package dns
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	rpc "github.com/telepresenceio/telepresence/rpc/v2/daemon"
+)
+
+func TestQuerySuffix(t *testing.T) {
+	assert.Equal(t, "ns.", querySuffix("orders.NS."))
+	assert.Equal(t, "ns.svc.cluster.local.", querySuffix("orders.ns.svc.cluster.local."))
+	assert.Equal(t, ".", querySuffix("orders."))
+	assert.Equal(t, ".", querySuffix("."))
+}
+
+func TestQueryLog(t *testing.T) {
+	s := NewServer(nil, nil)
+	for i := 0; i < queryLogSize+10; i++ {
+		s.queryLog.add(&rpc.DNSQuery{Name: "svc-" + strconv.Itoa(i) + ".ns."})
+	}
+
+	// Only the most recent queries are retained, oldest first
+	var names []string
+	require.NoError(t, s.WatchQueries(context.Background(), false, func(q *rpc.DNSQuery) error {
+		names = append(names, q.Name)
+		return nil
+	}))
+	require.Len(t, names, queryLogSize)
+	assert.Equal(t, "svc-10.ns.", names[0])
+	assert.Equal(t, "svc-"+strconv.Itoa(queryLogSize+9)+".ns.", names[queryLogSize-1])
+	assert.Equal(t, map[string]int64{"ns.": queryLogSize + 10}, s.Stats().SuffixCounts)
+
+	// A follower receives the queries that are added after the retained ones
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	received := make(chan string, queryLogSize+1)
+	done := make(chan error, 1)
+	go func() {
+		done <- s.WatchQueries(ctx, true, func(q *rpc.DNSQuery) error {
+			received <- q.Name
+			return nil
+		})
+	}()
+	for i := 0; i < queryLogSize; i++ {
+		<-received
+	}
+	s.queryLog.add(&rpc.DNSQuery{Name: "new.ns."})
+	assert.Equal(t, "new.ns.", <-received)
+	cancel()
+	assert.NoError(t, <-done)
+	assert.Empty(t, s.queryLog.watchers)
+}
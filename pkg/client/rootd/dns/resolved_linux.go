@@ -111,7 +111,7 @@ func (s *Server) updateLinkDomains(c context.Context, paths []string, dev *vif.D
 			paths[i] = "~" + path
 		}
 	}
-	for _, sfx := range s.config.IncludeSuffixes {
+	for _, sfx := range s.routedSuffixes() {
 		paths = append(paths, "~"+strings.TrimPrefix(sfx, "."))
 	}
 	paths = append(paths, "~"+s.clusterDomain)
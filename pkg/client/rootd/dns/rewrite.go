@@ -0,0 +1,118 @@
+package dns
+
+import (
+	"net"
+	"regexp"
+	"strings"
+
+	"github.com/miekg/dns"
+
+	rpc "github.com/telepresenceio/telepresence/rpc/v2/daemon"
+	"github.com/telepresenceio/telepresence/v2/pkg/dnsproxy"
+)
+
+// rewriteRule maps the names that end with a suffix or match a regex to another name that is resolved in the
+// cluster, or to a fixed IP. The suffix is kept without leading and trailing dots and matches whole labels
+// only.
+type rewriteRule struct {
+	suffix      string
+	regex       *regexp.Regexp
+	replacement string
+	ip          net.IP
+}
+
+// newRewriteRules compiles the given rewrite rules. Rules that cannot be compiled are skipped. That will not
+// happen in practice, because the user daemon validates the rules before they are sent to the root daemon.
+func newRewriteRules(rws []*rpc.DNSRewrite) []*rewriteRule {
+	rules := make([]*rewriteRule, 0, len(rws))
+	for _, rw := range rws {
+		r := &rewriteRule{
+			suffix:      strings.ToLower(strings.Trim(rw.Suffix, ".")),
+			replacement: rw.Replacement,
+			ip:          rw.Ip,
+		}
+		if rw.Regex != "" {
+			var err error
+			if r.regex, err = regexp.Compile(rw.Regex); err != nil {
+				continue
+			}
+		} else if r.suffix == "" {
+			continue
+		}
+		rules = append(rules, r)
+	}
+	return rules
+}
+
+// apply returns the name that the given lower case name, without its trailing dot, is rewritten to, and
+// true if the rule applies to the name. The returned name is empty when the rule maps to an IP.
+func (r *rewriteRule) apply(name string) (string, bool) {
+	if r.regex != nil {
+		m := r.regex.FindStringSubmatchIndex(name)
+		if m == nil {
+			return "", false
+		}
+		if r.ip != nil {
+			return "", true
+		}
+		return string(r.regex.ExpandString(nil, r.replacement, name, m)), true
+	}
+	var prefix string
+	switch {
+	case name == r.suffix:
+	case strings.HasSuffix(name, "."+r.suffix):
+		prefix = name[:len(name)-len(r.suffix)-1]
+	default:
+		return "", false
+	}
+	if r.ip != nil {
+		return "", true
+	}
+	replacement := strings.TrimPrefix(r.replacement, ".")
+	switch {
+	case prefix == "":
+		return replacement, true
+	case replacement == "":
+		return prefix, true
+	default:
+		return prefix + "." + replacement, true
+	}
+}
+
+// resolveRewrite applies the first rewrite rule that matches the name of the given question. The answer of a
+// rule that maps to another name starts with a CNAME record that points to that name, followed by the records
+// of that name. The boolean return value is false when no rule matches.
+func (s *Server) resolveRewrite(q *dns.Question) (*dnsproxy.Sections, bool, error) {
+	if len(s.rewrites) == 0 {
+		return nil, false, nil
+	}
+	name := strings.ToLower(strings.TrimSuffix(q.Name, "."))
+	for _, r := range s.rewrites {
+		target, ok := r.apply(name)
+		if !ok {
+			continue
+		}
+		if r.ip != nil {
+			return &dnsproxy.Sections{Answer: dnsproxy.AddressRRs(q.Name, q.Qtype, []net.IP{r.ip}, s.recordTTL)}, true, nil
+		}
+		target = dns.Fqdn(strings.ToLower(target))
+		if target == name+"." {
+			answer, err := s.cacheResolve(q)
+			return answer, true, err
+		}
+		cname := &dns.CNAME{
+			Hdr:    dns.RR_Header{Name: q.Name, Rrtype: dns.TypeCNAME, Class: dns.ClassINET, Ttl: s.recordTTL},
+			Target: target,
+		}
+		if q.Qtype == dns.TypeCNAME {
+			return &dnsproxy.Sections{Answer: dnsproxy.RRs{cname}}, true, nil
+		}
+		answer, err := s.cacheResolve(&dns.Question{Name: target, Qtype: q.Qtype, Qclass: q.Qclass})
+		if err != nil || answer == nil {
+			return nil, true, err
+		}
+		answer.Answer = append(dnsproxy.RRs{cname}, answer.Answer...)
+		return answer, true, nil
+	}
+	return nil, false, nil
+}
@@ -0,0 +1,100 @@
+package dns
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/datawire/dlib/dlog"
+	rpc "github.com/telepresenceio/telepresence/rpc/v2/daemon"
+	"github.com/telepresenceio/telepresence/v2/pkg/dnsproxy"
+)
+
+func TestRewriteRuleApply(t *testing.T) {
+	rules := newRewriteRules([]*rpc.DNSRewrite{
+		{Suffix: ".internal.acme.com.", Replacement: ".staging"},
+		{Regex: `^(\w+)-db\.acme\.com$`, Replacement: "$1.databases.svc.cluster.local"},
+		{Regex: "(", Replacement: "invalid"},
+		{Suffix: ".local.acme.com", Ip: net.IP{127, 0, 0, 1}},
+		{Suffix: "acme.net", Replacement: "staging"},
+	})
+	require.Len(t, rules, 4)
+
+	tests := []struct {
+		name   string
+		rule   int
+		target string
+		ok     bool
+	}{
+		{"orders.internal.acme.com", 0, "orders.staging", true},
+		{"orders.acme.com", 0, "", false},
+		{"internal.acme.com", 0, "staging", true},
+		{"orders.notinternal.acme.com", 0, "", false},
+		{"orders.local.acme.com.evil.org", 2, "", false},
+		{"orders.xlocal.acme.com", 2, "", false},
+		{"orders.acme.net", 3, "orders.staging", true},
+		{"orders.nacme.net", 3, "", false},
+		{"orders-db.acme.com", 1, "orders.databases.svc.cluster.local", true},
+		{"orders-db.acme.org", 1, "", false},
+		{"orders.local.acme.com", 2, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target, ok := rules[tt.rule].apply(tt.name)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.target, target)
+		})
+	}
+}
+
+func TestResolveRewrite(t *testing.T) {
+	clusterLookup := func(_ context.Context, name string, qType uint16) (*dnsproxy.Sections, int, error) {
+		if name != "orders.staging" {
+			return nil, dns.RcodeNameError, nil
+		}
+		return &dnsproxy.Sections{Answer: dnsproxy.AddressRRs(name+".", qType, []net.IP{{10, 1, 2, 3}}, 30)}, dns.RcodeSuccess, nil
+	}
+	s := NewServer(&rpc.DNSConfig{Rewrites: []*rpc.DNSRewrite{
+		{Suffix: ".internal.acme.com", Replacement: ".staging"},
+		{Suffix: ".local.acme.com", Ip: net.IP{127, 0, 0, 1}},
+	}}, clusterLookup)
+	s.ctx = dlog.NewTestContext(t, false)
+	s.resolve = s.resolveInCluster
+	s.cacheResolve = s.resolveThruCache
+	assert.Equal(t, []string{".internal.acme.com", ".local.acme.com"}, s.routedSuffixes())
+
+	// A name that is rewritten to a cluster name is answered with a CNAME followed by the cluster's records
+	answer, rewritten, err := s.resolveRewrite(&dns.Question{Name: "Orders.internal.acme.com.", Qtype: dns.TypeA, Qclass: dns.ClassINET})
+	require.NoError(t, err)
+	require.True(t, rewritten)
+	rrs := answer.Answer
+	require.Len(t, rrs, 2)
+	cname := rrs[0].(*dns.CNAME)
+	assert.Equal(t, "Orders.internal.acme.com.", cname.Hdr.Name)
+	assert.Equal(t, "orders.staging.", cname.Target)
+	a := rrs[1].(*dns.A)
+	assert.Equal(t, "orders.staging.", a.Hdr.Name)
+	assert.Equal(t, "10.1.2.3", a.A.String())
+
+	// A name that is rewritten to a cluster name that doesn't exist isn't found
+	answer, rewritten, err = s.resolveRewrite(&dns.Question{Name: "billing.internal.acme.com.", Qtype: dns.TypeA, Qclass: dns.ClassINET})
+	require.NoError(t, err)
+	assert.True(t, rewritten)
+	assert.Nil(t, answer)
+
+	// A name that is rewritten to an IP gets an address record
+	answer, rewritten, err = s.resolveRewrite(&dns.Question{Name: "orders.local.acme.com.", Qtype: dns.TypeA, Qclass: dns.ClassINET})
+	require.NoError(t, err)
+	assert.True(t, rewritten)
+	require.Len(t, answer.Answer, 1)
+	assert.Equal(t, "127.0.0.1", answer.Answer[0].(*dns.A).A.String())
+
+	// Names that match no rule are left alone
+	_, rewritten, err = s.resolveRewrite(&dns.Question{Name: "orders.acme.com.", Qtype: dns.TypeA, Qclass: dns.ClassINET})
+	require.NoError(t, err)
+	assert.False(t, rewritten)
+}
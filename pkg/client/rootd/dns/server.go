@@ -12,6 +12,7 @@ import (
 
 	"github.com/miekg/dns"
 	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
 
 	"github.com/datawire/dlib/dcontext"
 	"github.com/datawire/dlib/dgroup"
@@ -19,11 +20,14 @@ import (
 	"github.com/datawire/dlib/dtime"
 	rpc "github.com/telepresenceio/telepresence/rpc/v2/daemon"
 	"github.com/telepresenceio/telepresence/v2/pkg/client"
+	"github.com/telepresenceio/telepresence/v2/pkg/dnsproxy"
 	"github.com/telepresenceio/telepresence/v2/pkg/iputil"
 	"github.com/telepresenceio/telepresence/v2/pkg/vif"
 )
 
-type Resolver func(ctx context.Context, domain string) ([]net.IP, error)
+// Resolver resolves a DNS question. A nil result means that the name wasn't found, and a result with an
+// empty answer means that the name was found but has no records of the requested type.
+type Resolver func(ctx context.Context, q *dns.Question) (*dnsproxy.Sections, error)
 
 // recursionCheck is a special host name in a well known namespace that isn't expected to exist. It
 // is used once for determining if the cluster's DNS resolver will call the Telepresence DNS resolver
@@ -52,16 +56,23 @@ type Server struct {
 	fallbackPool FallbackPool
 	resolve      Resolver
 	requestCount int64
-	cache        sync.Map
-	recursive    int32 // one of the recursionXXX constants declared above (unique type avoided because it just gets messy with the atomic calls)
-	cacheResolve func(*dns.Question) ([]dns.RR, error)
+	cacheHits    int64
+	cacheMisses  int64
+	queryLog     *queryLog
+	cache        *cache
+	recordTTL    uint32 // TTL of the records in the answers
+	recursive    int32  // one of the recursionXXX constants declared above (unique type avoided because it just gets messy with the atomic calls)
+	cacheResolve func(*dns.Question) (*dnsproxy.Sections, error)
 
 	// Namespaces, accessible using <service-name>.<namespace-name>
 	namespaces map[string]struct{}
 	domains    map[string]struct{}
 	search     []string
 
-	// The domainsLock locks usage of namespaces, domains, and search
+	// Services that are intercepted and served on the workstation, keyed by <service-name>.<namespace-name>
+	localServices map[string]net.IP
+
+	// The domainsLock locks usage of namespaces, domains, search, and localServices
 	domainsLock sync.RWMutex
 
 	// searchPathCh receives requests to change the search path.
@@ -69,29 +80,18 @@ type Server struct {
 
 	config *rpc.DNSConfig
 
+	// rewrites are the rules that are applied to names before they are resolved
+	rewrites []*rewriteRule
+
 	// clusterDomain reported by the traffic-manager
 	clusterDomain string
 
-	// Function that sends a lookup requrest to the traffic-manager
-	clusterLookup func(context.Context, string) ([][]byte, error)
-}
-
-type cacheEntry struct {
-	created      time.Time
-	currentQType int32 // will be set to the current qType during call to cluster
-	answer       []dns.RR
-	wait         chan struct{}
-}
-
-// cacheTTL is the time to live for an entry in the local DNS cache.
-const cacheTTL = 60 * time.Second
-
-func (dv *cacheEntry) expired() bool {
-	return time.Since(dv.created) > cacheTTL
+	// Function that sends a lookup request to the traffic-manager
+	clusterLookup func(context.Context, string, uint16) (*dnsproxy.Sections, int, error)
 }
 
 // NewServer returns a new dns.Server
-func NewServer(config *rpc.DNSConfig, clusterLookup func(context.Context, string) ([][]byte, error)) *Server {
+func NewServer(config *rpc.DNSConfig, clusterLookup func(context.Context, string, uint16) (*dnsproxy.Sections, int, error)) *Server {
 	if config == nil {
 		config = &rpc.DNSConfig{}
 	}
@@ -116,6 +116,13 @@ func NewServer(config *rpc.DNSConfig, clusterLookup func(context.Context, string
 		searchPathCh:  make(chan []string, 5),
 		clusterDomain: defaultClusterDomain,
 		clusterLookup: clusterLookup,
+		rewrites:      newRewriteRules(config.Rewrites),
+		queryLog:      newQueryLog(),
+		cache:         newCache(newCachePolicy(config.Cache)),
+		recordTTL:     dnsTTL,
+	}
+	if ttl := config.Cache.GetRecordTtl().AsDuration(); ttl >= time.Second {
+		s.recordTTL = uint32(ttl / time.Second)
 	}
 	s.cacheResolve = s.resolveWithRecursionCheck
 	return s
@@ -176,8 +183,21 @@ func (s *Server) shouldDoClusterLookup(query string) bool {
 	return true
 }
 
-func (s *Server) resolveInCluster(c context.Context, query string) (results []net.IP, err error) {
-	query = strings.ToLower(query)
+// routedSuffixes returns the suffixes for which queries must be routed to this resolver by resolvers
+// that route queries based on domains. Those are the include suffixes and the suffixes of the rewrite
+// rules.
+func (s *Server) routedSuffixes() []string {
+	sfxs := s.config.IncludeSuffixes
+	for _, r := range s.rewrites {
+		if r.regex == nil {
+			sfxs = append(sfxs[:len(sfxs):len(sfxs)], "."+r.suffix)
+		}
+	}
+	return sfxs
+}
+
+func (s *Server) resolveInCluster(c context.Context, q *dns.Question) (*dnsproxy.Sections, error) {
+	query := strings.ToLower(q.Name)
 	query = strings.TrimSuffix(query, tel2SubDomainDot)
 
 	if query == "localhost." {
@@ -188,7 +208,7 @@ func (s *Server) resolveInCluster(c context.Context, query string) (results []ne
 		// But it does, so I need this in order to be
 		// productive at home.  We should really
 		// root-cause this, because it's weird.
-		return localhostIPs, nil
+		return &dnsproxy.Sections{Answer: dnsproxy.AddressRRs(q.Name, q.Qtype, localhostIPs, s.recordTTL)}, nil
 	}
 
 	if !s.shouldDoClusterLookup(query) {
@@ -199,18 +219,32 @@ func (s *Server) resolveInCluster(c context.Context, query string) (results []ne
 	c, cancel := context.WithTimeout(c, s.config.LookupTimeout.AsDuration())
 	defer cancel()
 
-	result, err := s.clusterLookup(c, query[:len(query)-1])
+	sections, rCode, err := s.clusterLookup(c, query[:len(query)-1], q.Qtype)
 	if err != nil {
 		return nil, client.CheckTimeout(c, err)
 	}
-	if len(result) == 0 {
+	switch rCode {
+	case dns.RcodeSuccess:
+		if sections == nil {
+			sections = &dnsproxy.Sections{}
+		}
+		renameRRs(sections.Answer, query, q.Name)
+		return sections, nil
+	case dns.RcodeNameError:
 		return nil, nil
+	default:
+		return nil, fmt.Errorf("cluster lookup of %s %s failed with %s", dns.TypeToString[q.Qtype], query, dns.RcodeToString[rCode])
 	}
-	ips := make(iputil.IPs, len(result))
-	for i, ip := range result {
-		ips[i] = ip
+}
+
+// renameRRs changes the owner name of the records that are owned by the name from to the name to. Records
+// that are owned by other names, such as the targets of CNAME records, are left as is.
+func renameRRs(rrs dnsproxy.RRs, from, to string) {
+	for _, rr := range rrs {
+		if h := rr.Header(); strings.EqualFold(h.Name, from) {
+			h.Name = to
+		}
 	}
-	return ips, nil
 }
 
 func (s *Server) GetConfig() *rpc.DNSConfig {
@@ -220,6 +254,8 @@ func (s *Server) GetConfig() *rpc.DNSConfig {
 		dnsConfig.ExcludeSuffixes = s.config.ExcludeSuffixes
 		dnsConfig.IncludeSuffixes = s.config.IncludeSuffixes
 		dnsConfig.LookupTimeout = s.config.LookupTimeout
+		dnsConfig.Rewrites = s.config.Rewrites
+		dnsConfig.Cache = s.config.Cache
 	}
 	return dnsConfig
 }
@@ -299,10 +335,7 @@ func (s *Server) processSearchPaths(g *dgroup.Group, processor func(context.Cont
 }
 
 func (s *Server) flushDNS() {
-	s.cache.Range(func(key, _ any) bool {
-		s.cache.Delete(key)
-		return true
-	})
+	s.cache.flush()
 }
 
 // splitToUDPAddr splits the given address into an UDPAddr. It's
@@ -320,35 +353,23 @@ func (s *Server) RequestCount() int {
 	return int(atomic.LoadInt64(&s.requestCount))
 }
 
-func copyRRs(rrs []dns.RR, qType uint16) []dns.RR {
-	if len(rrs) == 0 {
-		return rrs
-	}
-	cp := make([]dns.RR, 0, len(rrs))
-	for _, rr := range rrs {
-		if rr.Header().Rrtype == qType {
-			cp = append(cp, dns.Copy(rr))
-		}
-	}
-	return cp
-}
-
 // resolveThruCache resolves the given query by first performing a cache lookup. If a cached
 // entry is found that hasn't expired, it's returned. If not, this function will call
 // resolveQuery() to resolve and store in the case.
-func (s *Server) resolveThruCache(q *dns.Question) ([]dns.RR, error) {
+func (s *Server) resolveThruCache(q *dns.Question) (*dnsproxy.Sections, error) {
 	newDv := &cacheEntry{wait: make(chan struct{}), created: time.Now()}
-	if v, loaded := s.cache.LoadOrStore(q.Name, newDv); loaded {
-		oldDv := v.(*cacheEntry)
+	key := cacheKey{name: q.Name, qType: q.Qtype}
+	if oldDv, loaded := s.cache.loadOrStore(key, newDv); loaded {
 		if atomic.LoadInt32(&s.recursive) == recursionDetected && atomic.LoadInt32(&oldDv.currentQType) == int32(q.Qtype) {
 			// We have to assume that this is a recursion from the cluster.
 			return nil, nil
 		}
 		<-oldDv.wait
 		if !oldDv.expired() {
-			return copyRRs(oldDv.answer, q.Qtype), nil
+			atomic.AddInt64(&s.cacheHits, 1)
+			return oldDv.answer.Copy(), nil
 		}
-		s.cache.Store(q.Name, newDv)
+		s.cache.store(key, newDv)
 	}
 	return s.resolveQuery(q, newDv)
 }
@@ -356,10 +377,10 @@ func (s *Server) resolveThruCache(q *dns.Question) ([]dns.RR, error) {
 // resolveWithRecursionCheck is a special version of resolveThruCache which is only used until the
 // recursionCheck query has completed, and it has been determined whether a query that is propagated
 // to the cluster will recurse back to this resolver or not.
-func (s *Server) resolveWithRecursionCheck(q *dns.Question) ([]dns.RR, error) {
+func (s *Server) resolveWithRecursionCheck(q *dns.Question) (*dnsproxy.Sections, error) {
 	newDv := &cacheEntry{wait: make(chan struct{}), created: time.Now()}
-	if v, loaded := s.cache.LoadOrStore(q.Name, newDv); loaded {
-		oldDv := v.(*cacheEntry)
+	key := cacheKey{name: q.Name, qType: q.Qtype}
+	if oldDv, loaded := s.cache.loadOrStore(key, newDv); loaded {
 		if atomic.LoadInt32(&oldDv.currentQType) == int32(q.Qtype) {
 			if q.Name == recursionCheck {
 				atomic.StoreInt32(&s.recursive, recursionDetected)
@@ -370,9 +391,10 @@ func (s *Server) resolveWithRecursionCheck(q *dns.Question) ([]dns.RR, error) {
 		}
 		<-oldDv.wait
 		if !oldDv.expired() {
-			return copyRRs(oldDv.answer, q.Qtype), nil
+			atomic.AddInt64(&s.cacheHits, 1)
+			return oldDv.answer.Copy(), nil
 		}
-		s.cache.Store(q.Name, newDv)
+		s.cache.store(key, newDv)
 	}
 
 	answer, err := s.resolveQuery(q, newDv)
@@ -444,6 +466,7 @@ func (s *Server) ServeDNS(w dns.ResponseWriter, r *dns.Msg) {
 
 	q := &r.Question[0]
 	atomic.AddInt64(&s.requestCount, 1)
+	start := time.Now()
 
 	answerString := func(a []dns.RR) string {
 		if a == nil {
@@ -460,7 +483,12 @@ func (s *Server) ServeDNS(w dns.ResponseWriter, r *dns.Msg) {
 	}
 
 	qts := dns.TypeToString[q.Qtype]
-	answer, err := s.cacheResolve(q)
+	answer, path, err := s.resolveOverride(q)
+	overridden := path != ""
+	if !overridden {
+		path = "cluster"
+		answer, err = s.cacheResolve(q)
+	}
 	var rc int
 	var pfx dfs = func() string { return "" }
 	var txt dfs = func() string { return "" }
@@ -470,6 +498,16 @@ func (s *Server) ServeDNS(w dns.ResponseWriter, r *dns.Msg) {
 
 	defer func() {
 		dlog.Debugf(c, "%s%-6s %s -> %s %s", pfx, qts, q.Name, rct, txt)
+		s.queryLog.add(&rpc.DNSQuery{
+			Time:    timestamppb.New(start),
+			Name:    q.Name,
+			Type:    qts,
+			Path:    path,
+			RCode:   rct.String(),
+			Answers: int32(len(msg.Answer)),
+			Latency: durationpb.New(time.Since(start)),
+		})
+		fitResponse(w, r, msg)
 		_ = w.WriteMsg(msg)
 	}()
 
@@ -477,7 +515,9 @@ func (s *Server) ServeDNS(w dns.ResponseWriter, r *dns.Msg) {
 		rc = dns.RcodeSuccess
 		msg = new(dns.Msg)
 		msg.SetReply(r)
-		msg.Answer = answer
+		msg.Answer = answer.Answer
+		msg.Ns = answer.Ns
+		msg.Extra = answer.Extra
 		msg.Authoritative = true
 		// mac dns seems to fallback if you don't
 		// support recursion, if you have more than a
@@ -488,9 +528,9 @@ func (s *Server) ServeDNS(w dns.ResponseWriter, r *dns.Msg) {
 		return
 	}
 
-	// The recursion check query, or queries that end with the cluster domain name, are not dispatched to the
-	// fallback DNS-server.
-	if s.fallbackPool == nil || strings.HasPrefix(q.Name, recursionCheck) || strings.HasSuffix(q.Name, s.clusterDomain) {
+	// The recursion check query, queries that end with the cluster domain name, and overridden queries are not
+	// dispatched to the fallback DNS-server.
+	if s.fallbackPool == nil || overridden || strings.HasPrefix(q.Name, recursionCheck) || strings.HasSuffix(q.Name, s.clusterDomain) {
 		if err == nil {
 			rc = dns.RcodeNameError
 		} else {
@@ -506,9 +546,19 @@ func (s *Server) ServeDNS(w dns.ResponseWriter, r *dns.Msg) {
 		return
 	}
 
+	path = "fallback"
 	pfx = func() string { return fmt.Sprintf("(%s) ", s.fallbackPool.RemoteAddr()) }
 	dc := &dns.Client{Net: "udp", Timeout: s.config.LookupTimeout.AsDuration()}
+	if isTCP(w) {
+		dc.Net = "tcp"
+	}
 	msg, _, err = s.fallbackPool.Exchange(c, dc, r)
+	if err == nil && msg.Truncated && dc.Net == "udp" {
+		// The answer didn't fit in a UDP response. Ask again over TCP and let fitResponse truncate
+		// the answer if it doesn't fit in what the client can receive.
+		dc.Net = "tcp"
+		msg, _, err = s.fallbackPool.Exchange(c, dc, r)
+	}
 	if err != nil {
 		msg = new(dns.Msg)
 		rc = dns.RcodeServerFailure
@@ -529,59 +579,86 @@ func (s *Server) ServeDNS(w dns.ResponseWriter, r *dns.Msg) {
 	}
 }
 
-// dnsTTL is the number of seconds that a found DNS record should be allowed to live in the callers cache. We
-// keep this low to avoid such caching.
+// resolveOverride resolves the names of services that are served on the workstation, and names that match a
+// rewrite rule. The returned path is "local" or "rewrite" respectively, and empty when the name is neither.
+func (s *Server) resolveOverride(q *dns.Question) (*dnsproxy.Sections, string, error) {
+	if answer := s.resolveLocalService(q); answer != nil {
+		return &dnsproxy.Sections{Answer: answer}, "local", nil
+	}
+	answer, rewritten, err := s.resolveRewrite(q)
+	if rewritten {
+		return answer, "rewrite", err
+	}
+	return nil, "", nil
+}
+
+// isTCP returns true if the given writer writes to a TCP connection.
+func isTCP(w dns.ResponseWriter) bool {
+	_, ok := w.LocalAddr().(*net.TCPAddr)
+	return ok
+}
+
+// fitResponse ensures that the given response fits into the buffer of the client that sent the given
+// request. The size of that buffer is unlimited when TCP is used, and otherwise given by the EDNS0 OPT
+// record of the request, or 512 bytes when there is no such record. The TC bit of the response is set
+// when records must be removed, which tells the client to repeat the request over TCP.
+func fitResponse(w dns.ResponseWriter, r, msg *dns.Msg) {
+	size := dns.MinMsgSize
+	opt := r.IsEdns0()
+	if opt != nil {
+		if msg.IsEdns0() == nil {
+			msg.SetEdns0(dns.DefaultMsgSize, false)
+		}
+		size = int(opt.UDPSize())
+	}
+	if isTCP(w) {
+		size = dns.MaxMsgSize
+	}
+	msg.Truncate(size)
+}
+
+// dnsTTL is the default number of seconds that a found DNS record should be allowed to live in the callers
+// cache. We keep this low to avoid such caching.
 const dnsTTL = 4
 
-func (s *Server) resolveQuery(q *dns.Question, dv *cacheEntry) ([]dns.RR, error) {
+func (s *Server) resolveQuery(q *dns.Question, dv *cacheEntry) (*dnsproxy.Sections, error) {
+	atomic.AddInt64(&s.cacheMisses, 1)
 	atomic.StoreInt32(&dv.currentQType, int32(q.Qtype))
 	defer func() {
 		atomic.StoreInt32(&dv.currentQType, int32(dns.TypeNone))
 		close(dv.wait)
 	}()
 
-	var err error
-	switch q.Qtype {
-	case dns.TypeA, dns.TypeAAAA:
-		var ips []net.IP
-		if ips, err = s.resolve(s.ctx, q.Name); err != nil || len(ips) == 0 {
-			break
-		}
-		answer := make([]dns.RR, 0, len(ips))
-		for _, ip := range ips {
-			var rr dns.RR
-			if ip4 := ip.To4(); ip4 != nil {
-				rr = &dns.A{
-					Hdr: dns.RR_Header{Name: q.Name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: dnsTTL},
-					A:   ip4,
-				}
-			} else {
-				rr = &dns.AAAA{
-					Hdr:  dns.RR_Header{Name: q.Name, Rrtype: dns.TypeAAAA, Class: dns.ClassINET, Ttl: dnsTTL},
-					AAAA: ip,
+	answer, err := s.resolve(s.ctx, q)
+	if err == nil && answer != nil {
+		for _, rrs := range []dnsproxy.RRs{answer.Answer, answer.Ns, answer.Extra} {
+			for _, rr := range rrs {
+				if h := rr.Header(); h.Ttl == 0 || h.Ttl > s.recordTTL {
+					h.Ttl = s.recordTTL
 				}
 			}
-			answer = append(answer, rr)
 		}
 		dv.answer = answer
-	default:
-		var ips []net.IP
-		if ips, err = s.resolve(s.ctx, q.Name); err != nil {
-			break
-		}
-		if len(ips) > 0 {
-			// a reply exists, but for another type, so our reply here is EMPTY
-			dv.answer = []dns.RR{}
-		}
 	}
-	if err != nil || len(dv.answer) == 0 {
-		s.cache.Delete(q.Name) // Don't cache unless the entry is found.
+
+	// Entries that are removed here are still used by those who wait for this answer, so they get
+	// the ttl of positive entries.
+	policy := &s.cache.policy
+	dv.ttl = policy.ttl
+	switch {
+	case err != nil:
+		s.cache.delete(cacheKey{name: q.Name, qType: q.Qtype}, dv) // Errors are never cached.
+	case dv.answer != nil && len(dv.answer.Answer) > 0:
+	case policy.negativeTTL > 0:
+		dv.ttl = policy.negativeTTL
+	default:
+		s.cache.delete(cacheKey{name: q.Name, qType: q.Qtype}, dv) // Don't cache unless the entry is found.
 	}
 
-	// Return a result for the correct query type. The result will be nil (nxdomain) if nothing was found. It might
-	// also be empty if no RRs were found for the given query type and that is OK.
+	// The result will be nil (nxdomain) if nothing was found. It might also be empty if no RRs were found for
+	// the given query type and that is OK.
 	// See https://datatracker.ietf.org/doc/html/rfc4074#section-3
-	return copyRRs(dv.answer, q.Qtype), err
+	return dv.answer.Copy(), err
 }
 
 // Run starts the DNS server(s) and waits for them to end
@@ -591,9 +668,8 @@ func (s *Server) Run(c context.Context, initDone chan<- struct{}, listeners []ne
 	s.resolve = resolve
 
 	g := dgroup.NewGroup(c, dgroup.GroupConfig{})
-	for _, listener := range listeners {
-		srv := &dns.Server{PacketConn: listener, Handler: s, ReadTimeout: time.Second}
-		g.Go(listener.LocalAddr().String(), func(c context.Context) error {
+	serve := func(name string, srv *dns.Server) {
+		g.Go(name, func(c context.Context) error {
 			go func() {
 				<-c.Done()
 				dlog.Debugf(c, "Shutting down DNS server")
@@ -602,6 +678,20 @@ func (s *Server) Run(c context.Context, initDone chan<- struct{}, listeners []ne
 			return srv.ActivateAndServe()
 		})
 	}
+	for _, listener := range listeners {
+		addr := listener.LocalAddr().String()
+		serve(addr, &dns.Server{PacketConn: listener, Handler: s, ReadTimeout: time.Second})
+
+		// Clients repeat queries over TCP when a UDP response is truncated, so a TCP listener is added on
+		// the same address and port.
+		lc := &net.ListenConfig{}
+		tl, err := lc.Listen(c, "tcp", addr)
+		if err != nil {
+			dlog.Errorf(c, "unable to listen for DNS requests over TCP at %s: %v", addr, err)
+			continue
+		}
+		serve("tcp-"+addr, &dns.Server{Listener: tl, Handler: s, ReadTimeout: time.Second})
+	}
 	close(initDone)
 	return g.Wait()
 }
@@ -203,12 +203,13 @@ func (s *Server) updateResolverFiles(c context.Context, resolverDirName, resolve
 	}
 	namespaces[tel2SubDomain] = struct{}{}
 
-	// All namespaces and include suffixes become domains
-	domains := make(map[string]struct{}, len(namespaces)+len(s.config.IncludeSuffixes))
+	// All namespaces, include suffixes, and suffixes of rewrite rules become domains
+	sfxs := s.routedSuffixes()
+	domains := make(map[string]struct{}, len(namespaces)+len(sfxs))
 	for ns, v := range namespaces {
 		domains[ns] = v
 	}
-	for _, sfx := range s.config.IncludeSuffixes {
+	for _, sfx := range sfxs {
 		domains[strings.TrimPrefix(sfx, ".")] = struct{}{}
 	}
 
@@ -11,10 +11,13 @@ import (
 	"strings"
 	"time"
 
+	"github.com/miekg/dns"
+
 	"github.com/datawire/dlib/dexec"
 	"github.com/datawire/dlib/dgroup"
 	"github.com/datawire/dlib/dlog"
 	"github.com/datawire/dlib/dtime"
+	"github.com/telepresenceio/telepresence/v2/pkg/dnsproxy"
 	"github.com/telepresenceio/telepresence/v2/pkg/vif"
 )
 
@@ -76,8 +79,8 @@ func (s *Server) shouldApplySearch(query string) bool {
 // TODO: With the DNS lookups now being done in the cluster, there's only one reason left to have a search path,
 // and that's the local-only intercepts which means that using search-paths really should be limited to that
 // use-case.
-func (s *Server) resolveInSearch(c context.Context, query string) ([]net.IP, error) {
-	query = strings.ToLower(query)
+func (s *Server) resolveInSearch(c context.Context, q *dns.Question) (*dnsproxy.Sections, error) {
+	query := strings.ToLower(q.Name)
 	query = strings.TrimSuffix(query, tel2SubDomainDot)
 
 	if !s.shouldDoClusterLookup(query) {
@@ -86,12 +89,17 @@ func (s *Server) resolveInSearch(c context.Context, query string) ([]net.IP, err
 
 	if s.shouldApplySearch(query) {
 		for _, sp := range s.search {
-			if ips, err := s.resolveInCluster(c, query+sp); err != nil || len(ips) > 0 {
-				return ips, err
+			sq := *q
+			sq.Name = query + sp
+			if sections, err := s.resolveInCluster(c, &sq); err != nil || sections != nil {
+				if sections != nil {
+					renameRRs(sections.Answer, sq.Name, q.Name)
+				}
+				return sections, err
 			}
 		}
 	}
-	return s.resolveInCluster(c, query)
+	return s.resolveInCluster(c, q)
 }
 
 func (s *Server) runOverridingServer(c context.Context, dev *vif.Device) error {
@@ -291,10 +299,10 @@ func runNatTableCmd(c context.Context, args ...string) error {
 
 const tpDNSChain = "TELEPRESENCE_DNS"
 
-// routeDNS creates a new chain in the "nat" table with two rules in it. One rule ensures
-// that all packets sent to the currently configured DNS service are rerouted to our local
-// DNS service. Another rule ensures that when our local DNS service cannot resolve and
-// uses a fallback, that fallback reaches the original DNS service.
+// routeDNS creates a new chain in the "nat" table with rules in it. Some rules ensure that
+// all UDP packets and TCP connections sent to the currently configured DNS service are
+// rerouted to our local DNS service. Others ensure that when our local DNS service cannot
+// resolve and uses a fallback, that fallback reaches the original DNS service.
 func routeDNS(c context.Context, dnsIP net.IP, toAddr *net.UDPAddr, localDNSs []*net.UDPAddr) (err error) {
 	// create the chain
 	unrouteDNS(c)
@@ -316,17 +324,29 @@ func routeDNS(c context.Context, dnsIP net.IP, toAddr *net.UDPAddr, localDNSs []
 			return err
 		}
 	}
-	// This rule redirects all packets intended for the DNS service to our local DNS service
+	// The TCP connections that the fallback uses are recognized by their mark.
 	if err = runNatTableCmd(c, "-A", tpDNSChain,
-		"-p", "udp",
-		"--dest", dnsIP.String()+"/32",
-		"--dport", "53",
-		"-j", "DNAT",
-		"--to-destination", toAddr.String(),
+		"-p", "tcp",
+		"-m", "mark", "--mark", strconv.Itoa(fallbackMark),
+		"-j", "RETURN",
 	); err != nil {
 		return err
 	}
 
+	// These rules redirect all packets intended for the DNS service to our local DNS service,
+	// which listens to the same port for both UDP and TCP.
+	for _, proto := range []string{"udp", "tcp"} {
+		if err = runNatTableCmd(c, "-A", tpDNSChain,
+			"-p", proto,
+			"--dest", dnsIP.String()+"/32",
+			"--dport", "53",
+			"-j", "DNAT",
+			"--to-destination", toAddr.String(),
+		); err != nil {
+			return err
+		}
+	}
+
 	// Alter locally generated packets before routing
 	return runNatTableCmd(c, "-I", "OUTPUT", "1", "-j", tpDNSChain)
 }
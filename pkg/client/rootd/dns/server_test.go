@@ -0,0 +1,162 @@
+package dns
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/datawire/dlib/dlog"
+	"github.com/telepresenceio/telepresence/v2/pkg/dnsproxy"
+)
+
+func TestResolveQuery(t *testing.T) {
+	lookups := 0
+	clusterLookup := func(_ context.Context, name string, qType uint16) (*dnsproxy.Sections, int, error) {
+		lookups++
+		if name != "kafka.ns" {
+			return nil, dns.RcodeNameError, nil
+		}
+		switch qType {
+		case dns.TypeSRV:
+			return &dnsproxy.Sections{
+				Answer: dnsproxy.RRs{&dns.SRV{
+					Hdr:    dns.RR_Header{Name: "kafka.ns.", Rrtype: dns.TypeSRV, Class: dns.ClassINET, Ttl: 30},
+					Port:   9092,
+					Target: "kafka-0.kafka.ns.svc.cluster.local.",
+				}},
+				Extra: dnsproxy.RRs{&dns.A{
+					Hdr: dns.RR_Header{Name: "kafka-0.kafka.ns.svc.cluster.local.", Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 30},
+					A:   []byte{10, 1, 2, 3},
+				}},
+			}, dns.RcodeSuccess, nil
+		case dns.TypeA:
+			return &dnsproxy.Sections{Answer: dnsproxy.RRs{
+				&dns.CNAME{
+					Hdr:    dns.RR_Header{Name: "kafka.ns.", Rrtype: dns.TypeCNAME, Class: dns.ClassINET, Ttl: 30},
+					Target: "kafka-0.kafka.ns.svc.cluster.local.",
+				},
+				&dns.A{
+					Hdr: dns.RR_Header{Name: "kafka-0.kafka.ns.svc.cluster.local.", Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 30},
+					A:   []byte{10, 1, 2, 3},
+				},
+			}}, dns.RcodeSuccess, nil
+		default:
+			return &dnsproxy.Sections{Ns: dnsproxy.RRs{&dns.SOA{
+				Hdr:    dns.RR_Header{Name: "cluster.local.", Rrtype: dns.TypeSOA, Class: dns.ClassINET, Ttl: 30},
+				Ns:     "ns.dns.cluster.local.",
+				Mbox:   "hostmaster.cluster.local.",
+				Minttl: 5,
+			}}}, dns.RcodeSuccess, nil
+		}
+	}
+	s := NewServer(nil, clusterLookup)
+	s.ctx = dlog.NewTestContext(t, false)
+	s.resolve = s.resolveInCluster
+	s.cacheResolve = s.resolveThruCache
+
+	// The records are returned under the name of the question, with the TTL capped
+	answer, err := s.cacheResolve(&dns.Question{Name: "Kafka.ns.", Qtype: dns.TypeSRV, Qclass: dns.ClassINET})
+	require.NoError(t, err)
+	require.Len(t, answer.Answer, 1)
+	srv := answer.Answer[0].(*dns.SRV)
+	assert.Equal(t, "Kafka.ns.", srv.Hdr.Name)
+	assert.Equal(t, uint32(dnsTTL), srv.Hdr.Ttl)
+	assert.Equal(t, uint16(9092), srv.Port)
+
+	// The additional records are retained too
+	require.Len(t, answer.Extra, 1)
+	assert.Equal(t, uint32(dnsTTL), answer.Extra[0].Header().Ttl)
+
+	// Records of other types, such as the CNAME that leads to the address, are retained
+	answer, err = s.cacheResolve(&dns.Question{Name: "kafka.ns.", Qtype: dns.TypeA, Qclass: dns.ClassINET})
+	require.NoError(t, err)
+	rrs := answer.Answer
+	require.Len(t, rrs, 2)
+	assert.Equal(t, dns.TypeCNAME, rrs[0].Header().Rrtype)
+	assert.Equal(t, "kafka-0.kafka.ns.svc.cluster.local.", rrs[1].Header().Name)
+	assert.Equal(t, 2, lookups)
+
+	// Cached answers are kept per record type
+	answer, err = s.cacheResolve(&dns.Question{Name: "Kafka.ns.", Qtype: dns.TypeSRV, Qclass: dns.ClassINET})
+	require.NoError(t, err)
+	assert.Len(t, answer.Answer, 1)
+	assert.Equal(t, 2, lookups)
+
+	// An existing name without records of the requested type gives an empty answer, and the authority
+	// section is retained
+	answer, err = s.cacheResolve(&dns.Question{Name: "kafka.ns.", Qtype: dns.TypeTXT, Qclass: dns.ClassINET})
+	require.NoError(t, err)
+	require.NotNil(t, answer)
+	assert.Empty(t, answer.Answer)
+	require.Len(t, answer.Ns, 1)
+	assert.Equal(t, dns.TypeSOA, answer.Ns[0].Header().Rrtype)
+
+	// A name that doesn't exist gives a nil answer
+	answer, err = s.cacheResolve(&dns.Question{Name: "zookeeper.ns.", Qtype: dns.TypeSRV, Qclass: dns.ClassINET})
+	require.NoError(t, err)
+	assert.Nil(t, answer)
+}
+
+func TestServeLargeAnswer(t *testing.T) {
+	const recordCount = 100
+	resolve := func(_ context.Context, q *dns.Question) (*dnsproxy.Sections, error) {
+		rrs := make(dnsproxy.RRs, recordCount)
+		for i := range rrs {
+			rrs[i] = &dns.A{
+				Hdr: dns.RR_Header{Name: q.Name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 30},
+				A:   net.IP{10, 0, byte(i / 256), byte(i % 256)},
+			}
+		}
+		return &dnsproxy.Sections{Answer: rrs}, nil
+	}
+
+	ctx, cancel := context.WithCancel(dlog.NewTestContext(t, false))
+	defer cancel()
+	pc, err := newLocalUDPListener(ctx)
+	require.NoError(t, err)
+	addr := pc.LocalAddr().String()
+	s := NewServer(nil, nil)
+	s.cacheResolve = s.resolveThruCache
+	initDone := make(chan struct{})
+	errCh := make(chan error, 1)
+	go func() { errCh <- s.Run(ctx, initDone, []net.PacketConn{pc}, nil, resolve) }()
+	<-initDone
+
+	exchange := func(network string, udpSize uint16) *dns.Msg {
+		t.Helper()
+		q := new(dns.Msg)
+		q.SetQuestion("large.ns.", dns.TypeA)
+		if udpSize > 0 {
+			q.SetEdns0(udpSize, false)
+		}
+		c := dns.Client{Net: network, Timeout: 2 * time.Second}
+		r, _, err := c.Exchange(q, addr)
+		require.NoError(t, err)
+		return r
+	}
+
+	// Without EDNS0, a UDP response is limited to 512 bytes.
+	r := exchange("udp", 0)
+	assert.True(t, r.Truncated)
+	assert.Less(t, len(r.Answer), recordCount)
+	assert.Nil(t, r.IsEdns0())
+
+	// The client's EDNS0 buffer size is honored.
+	r = exchange("udp", dns.DefaultMsgSize)
+	assert.False(t, r.Truncated)
+	assert.Len(t, r.Answer, recordCount)
+	assert.NotNil(t, r.IsEdns0())
+
+	// A TCP response is never truncated.
+	r = exchange("tcp", 0)
+	assert.False(t, r.Truncated)
+	assert.Len(t, r.Answer, recordCount)
+
+	cancel()
+	assert.NoError(t, <-errCh)
+}
@@ -0,0 +1,127 @@
+package rootd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	empty "google.golang.org/protobuf/types/known/emptypb"
+
+	"github.com/datawire/dlib/dlog"
+	rpc "github.com/telepresenceio/telepresence/rpc/v2/daemon"
+	"github.com/telepresenceio/telepresence/v2/pkg/proc"
+)
+
+func (d *service) MountNFS(ctx context.Context, req *rpc.NFSMountRequest) (*empty.Empty, error) {
+	if err := validateNFSMount(ctx, req); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	err := d.withSession(ctx, func(_ context.Context, _ *session) error {
+		if _, loaded := d.nfsMounts.LoadOrStore(req.MountPoint, struct{}{}); loaded {
+			return status.Errorf(codes.AlreadyExists, "%s is already mounted", req.MountPoint)
+		}
+		dlog.Infof(ctx, "Mounting %s:%s on %s using NFS", req.PodIp, req.RemotePath, req.MountPoint)
+		if err := mountNFS(ctx, req); err != nil {
+			d.nfsMounts.Delete(req.MountPoint)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &empty.Empty{}, nil
+}
+
+func (d *service) UnmountNFS(ctx context.Context, req *rpc.NFSMountRequest) (*empty.Empty, error) {
+	if _, ok := d.nfsMounts.LoadAndDelete(req.MountPoint); !ok {
+		return nil, status.Errorf(codes.NotFound, "%s was not mounted using NFS", req.MountPoint)
+	}
+	dlog.Infof(ctx, "Unmounting NFS mount %s", req.MountPoint)
+	if err := unmountNFS(ctx, req.MountPoint); err != nil {
+		return nil, err
+	}
+	return &empty.Empty{}, nil
+}
+
+// unmountAllNFS unmounts the NFS mounts that remain. They cannot be used once the session ends,
+// because the pods that they're mounted from are no longer reachable.
+func (d *service) unmountAllNFS(ctx context.Context) {
+	d.nfsMounts.Range(func(key, _ any) bool {
+		mountPoint := key.(string)
+		d.nfsMounts.Delete(mountPoint)
+		if err := unmountNFS(ctx, mountPoint); err != nil {
+			dlog.Errorf(ctx, "failed to unmount NFS mount %s: %v", mountPoint, err)
+		}
+		return true
+	})
+}
+
+// validateNFSMount checks that the request is well-formed, and that the mount point is an empty
+// directory so that a mount doesn't hide any local files. Unless the request comes from root, the
+// mount point must be owned by the requesting user, because anyone that can reach this daemon's
+// socket could otherwise mount over a directory that they have no access to.
+func validateNFSMount(ctx context.Context, req *rpc.NFSMountRequest) error {
+	if net.ParseIP(req.PodIp) == nil {
+		return fmt.Errorf("invalid pod IP %q", req.PodIp)
+	}
+	if req.Port <= 0 || req.Port > 0xffff {
+		return fmt.Errorf("invalid port %d", req.Port)
+	}
+	if !strings.HasPrefix(req.RemotePath, "/") {
+		return fmt.Errorf("remote path %q is not absolute", req.RemotePath)
+	}
+	if !filepath.IsAbs(req.MountPoint) {
+		return fmt.Errorf("mount point %q is not absolute", req.MountPoint)
+	}
+	fi, err := os.Lstat(req.MountPoint)
+	if err != nil {
+		return err
+	}
+	if !fi.IsDir() {
+		// Symlinks are rejected too, since the mount would follow them.
+		return fmt.Errorf("mount point %s is not a directory", req.MountPoint)
+	}
+	uid, ok := peerUIDFromContext(ctx)
+	if !ok {
+		return errors.New("unable to determine the requesting user")
+	}
+	if uid != 0 {
+		if err = checkMountPointOwner(req.MountPoint, fi, uid); err != nil {
+			return err
+		}
+	}
+	dir, err := os.Open(req.MountPoint)
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	if _, err = dir.Readdirnames(1); err != io.EOF {
+		if err == nil {
+			err = fmt.Errorf("mount point %s is not empty", req.MountPoint)
+		}
+		return err
+	}
+	return nil
+}
+
+// runMount runs a mount or umount command, and includes its output in the error that is
+// returned when it fails.
+func runMount(ctx context.Context, exe string, args ...string) error {
+	cmd := proc.CommandContext(ctx, exe, args...)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			return fmt.Errorf("%s %s: %w: %s", exe, strings.Join(args, " "), err, msg)
+		}
+		return fmt.Errorf("%s %s: %w", exe, strings.Join(args, " "), err)
+	}
+	return nil
+}
@@ -0,0 +1,20 @@
+package rootd
+
+import (
+	"context"
+	"fmt"
+
+	rpc "github.com/telepresenceio/telepresence/rpc/v2/daemon"
+)
+
+func mountNFS(ctx context.Context, req *rpc.NFSMountRequest) error {
+	// The agent serves no portmapper and no lock manager, so the ports are explicit and
+	// locking is local. The files are served by a remote pod, so setuid binaries and device
+	// files must never take effect.
+	opts := fmt.Sprintf("nosuid,nodev,vers=3,tcp,port=%d,mountport=%d,nolocks,locallocks,soft,intr,timeo=10,retrans=2", req.Port, req.Port)
+	return runMount(ctx, "mount", "-t", "nfs", "-o", opts, req.PodIp+":"+req.RemotePath, req.MountPoint)
+}
+
+func unmountNFS(ctx context.Context, mountPoint string) error {
+	return runMount(ctx, "umount", "-f", mountPoint)
+}
@@ -0,0 +1,22 @@
+//go:build !windows
+// +build !windows
+
+package rootd
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// checkMountPointOwner returns an error unless the given mount point is owned by the given user.
+func checkMountPointOwner(mountPoint string, fi os.FileInfo, uid int) error {
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return fmt.Errorf("unable to determine the owner of mount point %s", mountPoint)
+	}
+	if int(st.Uid) != uid {
+		return fmt.Errorf("mount point %s is not owned by the requesting user", mountPoint)
+	}
+	return nil
+}
@@ -0,0 +1,21 @@
+package rootd
+
+import (
+	"context"
+	"os"
+
+	rpc "github.com/telepresenceio/telepresence/rpc/v2/daemon"
+	"github.com/telepresenceio/telepresence/v2/pkg/client/errcat"
+)
+
+func mountNFS(context.Context, *rpc.NFSMountRequest) error {
+	return errcat.User.New("NFS mounts are not supported on Windows")
+}
+
+func unmountNFS(context.Context, string) error {
+	return nil
+}
+
+func checkMountPointOwner(string, os.FileInfo, int) error {
+	return nil
+}
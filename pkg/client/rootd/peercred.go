@@ -0,0 +1,23 @@
+package rootd
+
+import (
+	"context"
+	"net"
+)
+
+type peerUIDKey struct{}
+
+// withPeerUID is the ConnContext of the gRPC server. It adds the user id of the process at the
+// other end of the connection to the context, so that RPCs can act on behalf of that user.
+func withPeerUID(ctx context.Context, conn net.Conn) context.Context {
+	if uid, err := peerUID(conn); err == nil {
+		ctx = context.WithValue(ctx, peerUIDKey{}, uid)
+	}
+	return ctx
+}
+
+// peerUIDFromContext returns the user id that withPeerUID added to the given context.
+func peerUIDFromContext(ctx context.Context) (int, bool) {
+	uid, ok := ctx.Value(peerUIDKey{}).(int)
+	return uid, ok
+}
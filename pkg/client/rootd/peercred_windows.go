@@ -0,0 +1,11 @@
+package rootd
+
+import (
+	"errors"
+	"net"
+)
+
+// peerUID isn't supported for the named pipes used on Windows.
+func peerUID(net.Conn) (int, error) {
+	return 0, errors.New("peer credentials are not supported on Windows")
+}
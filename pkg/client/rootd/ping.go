@@ -0,0 +1,82 @@
+package rootd
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"github.com/datawire/dlib/dlog"
+	"github.com/telepresenceio/telepresence/rpc/v2/manager"
+	"github.com/telepresenceio/telepresence/v2/pkg/client"
+	"github.com/telepresenceio/telepresence/v2/pkg/vif/icmp"
+)
+
+// maxConcurrentPings is the maximum number of echo requests that are proxied concurrently. Echo requests that
+// arrive when that many are in flight are dropped.
+const maxConcurrentPings = 64
+
+// ping asks the traffic-manager to send the given echo request to its destination from within the cluster, and
+// writes the echo reply, or the ICMP error that was received instead, to the TUN device. Nothing is written when
+// the destination doesn't respond.
+func (s *session) ping(c context.Context, pkt icmp.Packet) {
+	defer pkt.Release()
+	if atomic.LoadInt32(&s.noPing) != 0 {
+		return
+	}
+	ipHdr := pkt.IPHeader()
+	tc := client.GetConfig(c).Timeouts
+	timeout := tc.Get(client.TimeoutEndpointDial)
+	c, cancel := context.WithTimeout(c, timeout+tc.Get(client.TimeoutRoundtripLatency))
+	defer cancel()
+	rs, err := s.managerClient.Ping(c, &manager.PingRequest{
+		Session: s.session,
+		Ip:      ipHdr.Destination(),
+		Data:    pkt.Header().Payload(),
+		Timeout: durationpb.New(timeout),
+	})
+	if err != nil {
+		switch status.Code(err) {
+		case codes.Unimplemented:
+			dlog.Debug(c, "traffic-manager doesn't support Ping, echo requests will be dropped")
+			atomic.StoreInt32(&s.noPing, 1)
+		case codes.DeadlineExceeded:
+			dlog.Tracef(c, "no echo reply from %s", ipHdr.Destination())
+		default:
+			dlog.Debugf(c, "ping %s failed: %v", ipHdr.Destination(), err)
+		}
+		return
+	}
+
+	var reply icmp.Packet
+	if isEchoReply(ipHdr.Version(), int(rs.Type)) {
+		reply = icmp.EchoReplyPacket(pkt, rs.Data)
+	} else {
+		src := net.IP(rs.Source)
+		if ipHdr.Version() == ipv4.Version {
+			src = src.To4()
+		} else if src.To4() != nil {
+			src = nil
+		}
+		if len(src) == 0 {
+			src = ipHdr.Destination()
+		}
+		reply = icmp.ErrorPacket(ipHdr, int(rs.Type), int(rs.Code), src)
+	}
+	defer reply.Release()
+	if err = (vifWriter{s.dev}).Write(c, reply); err != nil {
+		dlog.Errorf(c, "TUN write failed: %v", err)
+	}
+}
+
+func isEchoReply(ipVersion, msgType int) bool {
+	if ipVersion == ipv4.Version {
+		return msgType == int(ipv4.ICMPTypeEchoReply)
+	}
+	return msgType == int(ipv6.ICMPTypeEchoReply)
+}
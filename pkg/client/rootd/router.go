@@ -86,7 +86,14 @@ func (s *session) handlePacket(c context.Context, data *buffer.Data) {
 			}
 			ipHdr = ip.V4Header(data.Buf())
 		}
-	} // TODO: similar for ipv6 using segments
+	} else if ipHdr.L4Protocol() == ipproto.IPV6Frag {
+		dlog.Debug(c, "Packet concat")
+		data = ipHdr.(ip.V6Header).ConcatFragments(data, s.fragmentMapV6)
+		if data == nil {
+			return
+		}
+		ipHdr = ip.V6Header(data.Buf())
+	}
 
 	switch ipHdr.L4Protocol() {
 	case ipproto.TCP:
@@ -111,10 +118,21 @@ func (s *session) handlePacket(c context.Context, data *buffer.Data) {
 		}
 		data = nil
 		s.udp(c, dg)
-	case ipproto.ICMP:
-	case ipproto.ICMPV6:
+	case ipproto.ICMP, ipproto.ICMPV6:
 		pkt := icmp.PacketFromData(ipHdr, data)
 		dlog.Tracef(c, "<- TUN %s", pkt)
+		if icmp.IsEchoRequest(pkt) {
+			select {
+			case s.pingSem <- struct{}{}:
+				data = nil
+				go func() {
+					defer func() { <-s.pingSem }()
+					s.ping(c, pkt)
+				}()
+			default:
+				dlog.Tracef(c, "too many echo requests in flight, dropping %s", pkt)
+			}
+		}
 	default:
 		// An L4 protocol that we don't handle.
 		dlog.Tracef(c, "Unhandled protocol %d", ipHdr.L4Protocol())
@@ -64,6 +64,9 @@ type service struct {
 	cancelCh       chan struct{}
 	timedLogLevel  log.TimedLevel
 
+	// nfsMounts are the mount points of the NFS mounts made by MountNFS
+	nfsMounts sync.Map
+
 	scout *scout.Reporter
 }
 
@@ -115,6 +118,56 @@ func (d *service) SetDnsSearchPath(ctx context.Context, paths *rpc.Paths) (*empt
 	return &empty.Empty{}, err
 }
 
+func (d *service) SetLocalServices(ctx context.Context, lss *rpc.LocalServices) (*empty.Empty, error) {
+	err := d.withSession(ctx, func(ctx context.Context, session *session) error {
+		session.SetLocalServices(ctx, lss.Services)
+		return nil
+	})
+	return &empty.Empty{}, err
+}
+
+func (d *service) GetDNSStats(ctx context.Context, _ *empty.Empty) (*rpc.DNSStats, error) {
+	var stats *rpc.DNSStats
+	err := d.withSession(ctx, func(ctx context.Context, session *session) error {
+		stats = session.dnsServer.Stats()
+		return nil
+	})
+	return stats, err
+}
+
+func (d *service) FlushDNS(ctx context.Context, _ *empty.Empty) (*empty.Empty, error) {
+	err := d.withSession(ctx, func(ctx context.Context, session *session) error {
+		session.dnsServer.Flush(ctx)
+		return nil
+	})
+	return &empty.Empty{}, err
+}
+
+func (d *service) WatchDNSQueries(rq *rpc.WatchDNSQueriesRequest, stream rpc.Daemon_WatchDNSQueriesServer) error {
+	// The session lock must not be held while following the queries, so the stream ends when either the
+	// client or the session is done.
+	var (
+		sessionCtx context.Context
+		s          *session
+	)
+	if err := d.withSession(stream.Context(), func(ctx context.Context, session *session) error {
+		sessionCtx, s = ctx, session
+		return nil
+	}); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+	go func() {
+		select {
+		case <-sessionCtx.Done():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return s.dnsServer.WatchQueries(ctx, rq.Follow, stream.Send)
+}
+
 func (d *service) Connect(ctx context.Context, info *rpc.OutboundInfo) (*rpc.DaemonStatus, error) {
 	dlog.Debug(ctx, "Received gRPC Connect")
 	select {
@@ -132,6 +185,7 @@ func (d *service) Connect(ctx context.Context, info *rpc.OutboundInfo) (*rpc.Dae
 
 func (d *service) Disconnect(ctx context.Context, _ *empty.Empty) (*empty.Empty, error) {
 	dlog.Debug(ctx, "Received gRPC Disconnect")
+	d.unmountAllNFS(ctx)
 	d.cancelSession()
 	return &empty.Empty{}, nil
 }
@@ -179,9 +233,7 @@ func (d *service) GetClusterSubnets(ctx context.Context, _ *empty.Empty) (*rpc.C
 				}
 				return err
 			}
-			if mgrInfo.ServiceSubnet != nil {
-				svcSubnets = append(svcSubnets, mgrInfo.ServiceSubnet)
-			}
+			svcSubnets = append(svcSubnets, serviceSubnets(mgrInfo)...)
 			podSubnets = append(podSubnets, mgrInfo.PodSubnets...)
 		}
 	})
@@ -314,7 +366,8 @@ func (d *service) serveGrpc(c context.Context, l net.Listener) error {
 	rpc.RegisterDaemonServer(svc, d)
 
 	sc := &dhttp.ServerConfig{
-		Handler: svc,
+		Handler:     svc,
+		ConnContext: withPeerUID,
 	}
 	dlog.Info(c, "gRPC server started")
 	err := sc.Serve(c, l)
@@ -13,6 +13,7 @@ import (
 	"time"
 
 	"github.com/blang/semver"
+	dns2 "github.com/miekg/dns"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
@@ -30,6 +31,7 @@ import (
 	"github.com/telepresenceio/telepresence/v2/pkg/client/errcat"
 	"github.com/telepresenceio/telepresence/v2/pkg/client/rootd/dns"
 	"github.com/telepresenceio/telepresence/v2/pkg/client/scout"
+	"github.com/telepresenceio/telepresence/v2/pkg/dnsproxy"
 	"github.com/telepresenceio/telepresence/v2/pkg/iputil"
 	"github.com/telepresenceio/telepresence/v2/pkg/subnet"
 	"github.com/telepresenceio/telepresence/v2/pkg/tunnel"
@@ -87,6 +89,9 @@ type session struct {
 	// fragmentMap is when concatenating ipv4 fragments
 	fragmentMap map[uint16][]*buffer.Data
 
+	// fragmentMapV6 is used when concatenating ipv6 fragments
+	fragmentMapV6 map[uint32][]*buffer.Data
+
 	// The local dns server
 	dnsServer *dns.Server
 
@@ -128,6 +133,15 @@ type session struct {
 	dnsLookups  int
 	dnsFailures int
 
+	// noLookupDNS is set to 1 when the traffic-manager doesn't support LookupDNS
+	noLookupDNS int32
+
+	// noPing is set to 1 when the traffic-manager doesn't support Ping
+	noPing int32
+
+	// pingSem limits the number of echo requests that are proxied concurrently
+	pingSem chan struct{}
+
 	// Whether pods and services should be proxied by the TUN-device
 	proxyCluster bool
 }
@@ -220,6 +234,8 @@ func newSession(c context.Context, scout *scout.Reporter, mi *rpc.OutboundInfo)
 		dev:               dev,
 		handlers:          tunnel.NewPool(),
 		fragmentMap:       make(map[uint16][]*buffer.Data),
+		fragmentMapV6:     make(map[uint32][]*buffer.Data),
+		pingSem:           make(chan struct{}, maxConcurrentPings),
 		rndSource:         rand.NewSource(time.Now().UnixNano()),
 		session:           mi.Session,
 		managerClient:     mc,
@@ -232,10 +248,35 @@ func newSession(c context.Context, scout *scout.Reporter, mi *rpc.OutboundInfo)
 	return s, nil
 }
 
-// clusterLookup sends a LookupHost request to the traffic-manager and returns the result
-func (s *session) clusterLookup(ctx context.Context, key string) ([][]byte, error) {
-	dlog.Debugf(ctx, "LookupHost %q", key)
+// clusterLookup sends a LookupDNS request to the traffic-manager and returns the result. A LookupHost
+// request is sent instead when the traffic-manager doesn't support LookupDNS.
+func (s *session) clusterLookup(ctx context.Context, key string, qType uint16) (*dnsproxy.Sections, int, error) {
+	dlog.Debugf(ctx, "LookupDNS %s %q", dns2.TypeToString[qType], key)
 	s.dnsLookups++
+	if atomic.LoadInt32(&s.noLookupDNS) == 0 {
+		r, err := s.managerClient.LookupDNS(ctx, &manager.DNSRequest{
+			Session: s.session,
+			Name:    key,
+			Type:    uint32(qType),
+		})
+		if status.Code(err) != codes.Unimplemented {
+			var sections *dnsproxy.Sections
+			if err == nil {
+				sections, err = dnsproxy.FromPacked(r.Rrs)
+			}
+			if err != nil {
+				s.dnsFailures++
+				return nil, dns2.RcodeServerFailure, err
+			}
+			if len(sections.Answer) == 0 {
+				s.dnsFailures++
+			}
+			return sections, int(r.RCode), nil
+		}
+		dlog.Debug(ctx, "traffic-manager doesn't support LookupDNS, falling back to LookupHost")
+		atomic.StoreInt32(&s.noLookupDNS, 1)
+	}
+
 	r, err := s.managerClient.LookupHost(ctx, &manager.LookupHostRequest{
 		Session: s.session,
 		Host:    key,
@@ -244,9 +285,12 @@ func (s *session) clusterLookup(ctx context.Context, key string) ([][]byte, erro
 		s.dnsFailures++
 	}
 	if err != nil {
-		return nil, err
+		return nil, dns2.RcodeServerFailure, err
+	}
+	if len(r.Ips) == 0 {
+		return nil, dns2.RcodeNameError, nil
 	}
-	return r.Ips, nil
+	return &dnsproxy.Sections{Answer: dnsproxy.AddressRRs(key+".", qType, iputil.IPsFromBytesSlice(r.Ips), 0)}, dns2.RcodeSuccess, nil
 }
 
 func (s *session) getInfo() *rpc.OutboundInfo {
@@ -421,10 +465,11 @@ func (s *session) watchClusterInfo(ctx context.Context, cfgComplete chan<- struc
 func (s *session) onClusterInfo(ctx context.Context, mgrInfo *manager.ClusterInfo) {
 	dlog.Debugf(ctx, "WatchClusterInfo update")
 
-	subnets := make([]*net.IPNet, 0, 1+len(mgrInfo.PodSubnets))
+	svcSubnets := serviceSubnets(mgrInfo)
+	subnets := make([]*net.IPNet, 0, len(svcSubnets)+len(mgrInfo.PodSubnets))
 	if s.proxyCluster {
-		if mgrInfo.ServiceSubnet != nil {
-			cidr := iputil.IPNetFromRPC(mgrInfo.ServiceSubnet)
+		for _, sn := range svcSubnets {
+			cidr := iputil.IPNetFromRPC(sn)
 			dlog.Infof(ctx, "Adding service subnet %s", cidr)
 			subnets = append(subnets, cidr)
 		}
@@ -442,6 +487,18 @@ func (s *session) onClusterInfo(ctx context.Context, mgrInfo *manager.ClusterInf
 	}
 }
 
+// serviceSubnets returns the service subnets of the given ClusterInfo. A traffic-manager that
+// doesn't know about multiple service subnets only declares one.
+func serviceSubnets(mgrInfo *manager.ClusterInfo) []*manager.IPNet {
+	if len(mgrInfo.ServiceSubnets) > 0 {
+		return mgrInfo.ServiceSubnets
+	}
+	if mgrInfo.ServiceSubnet != nil {
+		return []*manager.IPNet{mgrInfo.ServiceSubnet}
+	}
+	return nil
+}
+
 func (s *session) checkConnectivity(ctx context.Context, info *manager.ClusterInfo) {
 	if info.ManagerPodIp == nil {
 		return
@@ -540,3 +597,11 @@ func (s *session) stop(c context.Context) {
 func (s *session) SetSearchPath(ctx context.Context, paths []string, namespaces []string) {
 	s.dnsServer.SetSearchPath(ctx, paths, namespaces)
 }
+
+func (s *session) SetLocalServices(ctx context.Context, lss []*rpc.LocalService) {
+	services := make(map[string]net.IP, len(lss))
+	for _, ls := range lss {
+		services[ls.Name+"."+ls.Namespace] = ls.Ip
+	}
+	s.dnsServer.SetLocalServices(ctx, services)
+}
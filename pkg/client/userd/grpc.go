@@ -15,6 +15,7 @@ import (
 	"google.golang.org/grpc/status"
 	grpcStatus "google.golang.org/grpc/status"
 	empty "google.golang.org/protobuf/types/known/emptypb"
+	"k8s.io/apimachinery/pkg/labels"
 
 	"github.com/datawire/dlib/derror"
 	"github.com/datawire/dlib/dgroup"
@@ -299,7 +300,13 @@ func (s *service) RemoveInterceptor(ctx context.Context, interceptor *rpc.Interc
 
 func (s *service) List(c context.Context, lr *rpc.ListRequest) (result *rpc.WorkloadInfoSnapshot, err error) {
 	err = s.withSession(c, "List", func(c context.Context, session trafficmgr.Session) error {
-		result, err = session.WorkloadInfoSnapshot(c, []string{lr.Namespace}, lr.Filter, true)
+		var selector labels.Selector
+		if lr.LabelSelector != "" {
+			if selector, err = labels.Parse(lr.LabelSelector); err != nil {
+				return status.Errorf(codes.InvalidArgument, "invalid label selector %q: %v", lr.LabelSelector, err)
+			}
+		}
+		result, err = session.WorkloadInfoSnapshot(c, []string{lr.Namespace}, lr.Filter, selector, true)
 		return err
 	})
 	return
@@ -440,6 +447,44 @@ func (s *service) GatherLogs(ctx context.Context, request *rpc.LogsRequest) (res
 	return
 }
 
+func (s *service) WatchLogs(request *manager.WatchLogsRequest, server rpc.Connector_WatchLogsServer) error {
+	return s.withSession(server.Context(), "WatchLogs", func(c context.Context, session trafficmgr.Session) error {
+		return session.WatchLogs(c, request, server)
+	})
+}
+
+func (s *service) Exec(server rpc.Connector_ExecServer) error {
+	return s.withSession(server.Context(), "Exec", func(c context.Context, session trafficmgr.Session) error {
+		return session.Exec(c, server)
+	})
+}
+
+func (s *service) Copy(request *rpc.CopyRequest, server rpc.Connector_CopyServer) error {
+	return s.withSession(server.Context(), "Copy", func(c context.Context, session trafficmgr.Session) error {
+		return session.Copy(c, request, server)
+	})
+}
+
+func (s *service) GetEnvironment(c context.Context, rq *rpc.EnvironmentRequest) (result *rpc.EnvironmentResponse, err error) {
+	err = s.withSession(c, "GetEnvironment", func(c context.Context, session trafficmgr.Session) error {
+		result, err = session.GetEnvironment(c, rq)
+		return err
+	})
+	return result, err
+}
+
+func (s *service) Expose(c context.Context, rq *manager.ExposeRequest) (*empty.Empty, error) {
+	return &empty.Empty{}, s.withSession(c, "Expose", func(c context.Context, session trafficmgr.Session) error {
+		return session.Expose(c, rq)
+	})
+}
+
+func (s *service) Unexpose(c context.Context, rq *manager.UnexposeRequest) (*empty.Empty, error) {
+	return &empty.Empty{}, s.withSession(c, "Unexpose", func(c context.Context, session trafficmgr.Session) error {
+		return session.Unexpose(c, rq)
+	})
+}
+
 func (s *service) SetLogLevel(ctx context.Context, request *manager.LogLevelRequest) (result *empty.Empty, err error) {
 	s.logCall(ctx, "SetLogLevel", func(c context.Context) {
 		duration := time.Duration(0)
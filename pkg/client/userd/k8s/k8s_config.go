@@ -3,7 +3,10 @@ package k8s
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
+	"regexp"
 
 	"github.com/spf13/pflag"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -39,6 +42,79 @@ type dnsConfig struct {
 
 	// The maximum time to wait for a cluster side host lookup.
 	LookupTimeout metav1.Duration `json:"lookup-timeout,omitempty"`
+
+	// Rewrites are rules that map names to other names that are resolved in the cluster, or to fixed IPs.
+	// The first rule that matches a name is applied.
+	Rewrites []*dnsRewrite `json:"rewrites,omitempty"`
+
+	// Cache controls the TTL of the records in the answers and how answers are cached.
+	Cache *dnsCache `json:"cache,omitempty"`
+}
+
+// The dnsCache is part of the dnsConfig struct.
+type dnsCache struct {
+	// RecordTTL is the TTL of the records in the answers. Defaults to 4 seconds.
+	RecordTTL metav1.Duration `json:"record-ttl,omitempty"`
+
+	// TTL is the time that an answer with records is cached. Defaults to 60 seconds.
+	TTL metav1.Duration `json:"ttl,omitempty"`
+
+	// NegativeTTL is the time that an answer without records is cached. Such answers are
+	// not cached unless this is set.
+	NegativeTTL metav1.Duration `json:"negative-ttl,omitempty"`
+
+	// MaxEntries is the maximum number of cached answers. Unlimited unless set.
+	MaxEntries int32 `json:"max-entries,omitempty"`
+
+	// Eviction is either "lru" (the default) or "fifo".
+	Eviction string `json:"eviction,omitempty"`
+}
+
+func (c *dnsCache) validate() error {
+	switch c.Eviction {
+	case "", "lru", "fifo":
+	default:
+		return fmt.Errorf("invalid eviction %q, must be one of lru or fifo", c.Eviction)
+	}
+	if c.MaxEntries < 0 {
+		return errors.New("max-entries cannot be negative")
+	}
+	return nil
+}
+
+// The dnsRewrite is part of the dnsConfig struct. It has either a Suffix or a Regex, and either a
+// Replacement or an IP.
+type dnsRewrite struct {
+	// Suffix is a domain that a name must be equal to, or a subdomain of, for the rule to apply, e.g.
+	// ".internal.acme.com". The suffix matches whole labels only, so ".acme.com" doesn't match "nacme.com".
+	Suffix string `json:"suffix,omitempty"`
+
+	// Regex is a regular expression that a name, without its trailing dot, must match for the rule
+	// to apply, e.g. "^(.+)\.internal\.acme\.com$".
+	Regex string `json:"regex,omitempty"`
+
+	// Replacement is either the string that replaces the Suffix, e.g. ".staging", or the template that
+	// is expanded using the submatches of the Regex, e.g. "$1.staging". The result is resolved in the
+	// cluster.
+	Replacement string `json:"replacement,omitempty"`
+
+	// IP is the address that all names that match the rule resolve to.
+	IP iputil.IPKey `json:"ip,omitempty"`
+}
+
+func (r *dnsRewrite) validate() error {
+	if (r.Suffix == "") == (r.Regex == "") {
+		return errors.New("exactly one of suffix and regex must be set")
+	}
+	if (r.Replacement == "") == (r.IP == "") {
+		return errors.New("exactly one of replacement and ip must be set")
+	}
+	if r.Regex != "" {
+		if _, err := regexp.Compile(r.Regex); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // The managerConfig is part of the kubeconfigExtension struct. It configures discovery of the traffic manager
@@ -144,6 +220,18 @@ func NewConfig(c context.Context, flagMap map[string]string) (*Config, error) {
 		if err = json.Unmarshal(ext.Raw, &k.kubeconfigExtension); err != nil {
 			return nil, errcat.Config.Newf("unable to parse extension %s in kubeconfig: %w", configExtension, err)
 		}
+		if dns := k.kubeconfigExtension.DNS; dns != nil {
+			for i, rw := range dns.Rewrites {
+				if err = rw.validate(); err != nil {
+					return nil, errcat.Config.Newf("invalid dns rewrite rule %d in extension %s in kubeconfig: %w", i+1, configExtension, err)
+				}
+			}
+			if dns.Cache != nil {
+				if err = dns.Cache.validate(); err != nil {
+					return nil, errcat.Config.Newf("invalid dns cache in extension %s in kubeconfig: %w", configExtension, err)
+				}
+			}
+		}
 	}
 
 	if k.kubeconfigExtension.Manager == nil {
@@ -0,0 +1,75 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	"github.com/datawire/dlib/dlog"
+	"github.com/telepresenceio/telepresence/rpc/v2/manager"
+	"github.com/telepresenceio/telepresence/v2/pkg/client"
+	"github.com/telepresenceio/telepresence/v2/pkg/ipproto"
+	"github.com/telepresenceio/telepresence/v2/pkg/iputil"
+	"github.com/telepresenceio/telepresence/v2/pkg/tunnel"
+)
+
+type managerDialer struct {
+	managerClient manager.ManagerClient
+	session       *manager.SessionInfo
+}
+
+// NewManagerDialer returns a Dialer that resolves names using the traffic-manager's LookupHost and opens its
+// streams using the traffic-manager's Tunnel.
+func NewManagerDialer(managerClient manager.ManagerClient, session *manager.SessionInfo) Dialer {
+	return &managerDialer{managerClient: managerClient, session: session}
+}
+
+func (d *managerDialer) DialStream(ctx context.Context, src net.Addr, host string, port uint16) (tunnel.Stream, error) {
+	ip, err := d.resolve(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	srcIP, srcPort, err := iputil.SplitToIPPort(src)
+	if err != nil {
+		return nil, err
+	}
+	id := tunnel.NewConnID(ipproto.TCP, srcIP, ip, srcPort, port)
+	dlog.Debugf(ctx, "Opening tunnel for id %s", id)
+	ct, err := d.managerClient.Tunnel(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tc := client.GetConfig(ctx).Timeouts
+	s, err := tunnel.NewClientStream(ctx, ct, id, d.session.SessionId, tc.Get(client.TimeoutRoundtripLatency), tc.Get(client.TimeoutEndpointDial))
+	if err != nil {
+		return nil, err
+	}
+
+	// The dialer at the other end responds with DialOK or DialReject once it knows the outcome of its dial.
+	m, err := s.Receive(ctx)
+	if err != nil {
+		_ = s.CloseSend(ctx)
+		return nil, fmt.Errorf("failed to read dial response: %w", err)
+	}
+	if m.Code() != tunnel.DialOK {
+		_ = s.CloseSend(ctx)
+		return nil, fmt.Errorf("%w: %s", ErrDialRejected, net.JoinHostPort(ip.String(), fmt.Sprint(port)))
+	}
+	return s, nil
+}
+
+// resolve returns the IP of the given host, using the traffic-manager to look up names.
+func (d *managerDialer) resolve(ctx context.Context, host string) (net.IP, error) {
+	if ip := iputil.Parse(host); ip != nil {
+		return ip, nil
+	}
+	r, err := d.managerClient.LookupHost(ctx, &manager.LookupHostRequest{Session: d.session, Host: host})
+	if err != nil {
+		return nil, err
+	}
+	ips := iputil.IPsFromBytesSlice(r.Ips)
+	if len(ips) == 0 {
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+	return ips[0], nil
+}
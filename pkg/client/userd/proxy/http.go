@@ -0,0 +1,101 @@
+package proxy
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/datawire/dlib/dlog"
+)
+
+func handleHTTP(ctx context.Context, conn net.Conn, d Dialer) {
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	_ = conn.SetDeadline(time.Now().Add(handshakeTimeout))
+	br := bufio.NewReader(conn)
+	host, port, status, err := readConnectRequest(br)
+	if err != nil {
+		dlog.Errorf(ctx, "HTTP proxy request failed: %v", err)
+		if status != 0 {
+			_ = writeHTTPResponse(conn, status, err.Error())
+		}
+		return
+	}
+	_ = conn.SetDeadline(time.Time{})
+
+	addr := net.JoinHostPort(host, strconv.Itoa(int(port)))
+	dlog.Debugf(ctx, "HTTP CONNECT %s", addr)
+	stream, err := d.DialStream(ctx, conn.RemoteAddr(), host, port)
+	if err != nil {
+		dlog.Errorf(ctx, "HTTP CONNECT %s failed: %v", addr, err)
+		_ = writeHTTPResponse(conn, httpStatusCode(err), err.Error())
+		return
+	}
+	if _, err = io.WriteString(conn, "HTTP/1.1 200 Connection established\r\n\r\n"); err != nil {
+		dlog.Errorf(ctx, "HTTP proxy reply to %s failed: %v", conn.RemoteAddr(), err)
+		_ = stream.CloseSend(ctx)
+		return
+	}
+	if br.Buffered() > 0 {
+		// The client didn't wait for our reply before it started sending.
+		conn = &bufferedConn{Conn: conn, r: br}
+	}
+	pipe(ctx, conn, stream)
+}
+
+// readConnectRequest reads a CONNECT request and returns its destination host and port. When an error
+// is returned, the returned status, unless zero, is the HTTP status that should be sent to the client.
+func readConnectRequest(br *bufio.Reader) (string, uint16, int, error) {
+	rq, err := http.ReadRequest(br)
+	if err != nil {
+		return "", 0, http.StatusBadRequest, err
+	}
+	if rq.Method != http.MethodConnect {
+		return "", 0, http.StatusMethodNotAllowed, fmt.Errorf("method %s is not supported, only %s", rq.Method, http.MethodConnect)
+	}
+	host, portStr, err := net.SplitHostPort(rq.Host)
+	if err != nil {
+		return "", 0, http.StatusBadRequest, err
+	}
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil {
+		return "", 0, http.StatusBadRequest, fmt.Errorf("invalid port %q", portStr)
+	}
+	return host, uint16(port), 0, nil
+}
+
+func writeHTTPResponse(w io.Writer, status int, msg string) error {
+	_, err := fmt.Fprintf(w, "HTTP/1.1 %d %s\r\nContent-Type: text/plain\r\nContent-Length: %d\r\nConnection: close\r\n\r\n%s",
+		status, http.StatusText(status), len(msg), msg)
+	return err
+}
+
+func httpStatusCode(err error) int {
+	var dnsErr *net.DNSError
+	switch {
+	case errors.Is(err, ErrDialRejected):
+		return http.StatusBadGateway
+	case errors.As(err, &dnsErr):
+		return http.StatusNotFound
+	default:
+		return http.StatusServiceUnavailable
+	}
+}
+
+// bufferedConn is a net.Conn that reads what's been buffered by a bufio.Reader before reading the conn itself.
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.r.Read(b)
+}
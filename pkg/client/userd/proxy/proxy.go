@@ -0,0 +1,63 @@
+// Package proxy implements a SOCKS5 proxy and an HTTP CONNECT proxy that open their connections through
+// the traffic-manager's Tunnel. Proxy-aware tools can use them to reach the cluster without a root daemon.
+package proxy
+
+import (
+	"context"
+	"errors"
+	"net"
+
+	"github.com/datawire/dlib/dlog"
+	"github.com/telepresenceio/telepresence/v2/pkg/tunnel"
+)
+
+// ErrDialRejected is returned by a Dialer when the destination refused the connection or couldn't be reached.
+var ErrDialRejected = errors.New("dial rejected")
+
+// A Dialer establishes a tunnel.Stream to a host and port in the cluster. The host is either an IP or a
+// name that must be resolved in the cluster. The src is the address of the proxy client.
+type Dialer interface {
+	DialStream(ctx context.Context, src net.Addr, host string, port uint16) (tunnel.Stream, error)
+}
+
+// connHandler handles a connection accepted by a proxy listener.
+type connHandler func(ctx context.Context, conn net.Conn, d Dialer)
+
+// ServeSOCKS serves SOCKS5 CONNECT requests on the given listener until the context is cancelled.
+func ServeSOCKS(ctx context.Context, l net.Listener, d Dialer) error {
+	return serve(ctx, l, d, handleSOCKS)
+}
+
+// ServeHTTP serves HTTP CONNECT requests on the given listener until the context is cancelled.
+func ServeHTTP(ctx context.Context, l net.Listener, d Dialer) error {
+	return serve(ctx, l, d, handleHTTP)
+}
+
+func serve(ctx context.Context, l net.Listener, d Dialer, h connHandler) error {
+	go func() {
+		<-ctx.Done()
+		_ = l.Close()
+	}()
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			var ne net.Error
+			if errors.As(err, &ne) && ne.Timeout() {
+				dlog.Errorf(ctx, "proxy accept on %s: %v", l.Addr(), err)
+				continue
+			}
+			return err
+		}
+		go h(dlog.WithField(ctx, "client", conn.RemoteAddr().String()), conn, d)
+	}
+}
+
+// pipe passes data between the given connection and stream until one of them is closed.
+func pipe(ctx context.Context, conn net.Conn, stream tunnel.Stream) {
+	ep := tunnel.NewConnEndpoint(stream, conn)
+	ep.Start(ctx)
+	<-ep.Done()
+}
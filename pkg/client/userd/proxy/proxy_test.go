@@ -0,0 +1,163 @@
+package proxy
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// socksConn is a client side of a SOCKS handshake with a prepared input.
+type socksConn struct {
+	io.Reader
+	bytes.Buffer
+}
+
+func newSOCKSConn(in ...[]byte) *socksConn {
+	return &socksConn{Reader: bytes.NewReader(bytes.Join(in, nil))}
+}
+
+func (c *socksConn) Read(b []byte) (int, error) {
+	return c.Reader.Read(b)
+}
+
+func TestSOCKSHandshake(t *testing.T) {
+	greeting := []byte{socksVersion, 2, 0x02, socksNoAuth}
+	tests := []struct {
+		name    string
+		request []byte
+		host    string
+		port    uint16
+	}{
+		{
+			"IPv4",
+			[]byte{socksVersion, socksConnect, 0, socksIPv4, 10, 0, 0, 1, 0x1f, 0x90},
+			"10.0.0.1",
+			8080,
+		},
+		{
+			"IPv6",
+			append(append([]byte{socksVersion, socksConnect, 0, socksIPv6}, net.ParseIP("fd00::1")...), 0, 80),
+			"fd00::1",
+			80,
+		},
+		{
+			"domain",
+			append(append([]byte{socksVersion, socksConnect, 0, socksDomain, 13}, "echo.default."...), 0x01, 0xbb),
+			"echo.default.",
+			443,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := newSOCKSConn(greeting, tt.request)
+			host, port, err := socksHandshake(conn)
+			require.NoError(t, err)
+			assert.Equal(t, tt.host, host)
+			assert.Equal(t, tt.port, port)
+			assert.Equal(t, []byte{socksVersion, socksNoAuth}, conn.Bytes())
+		})
+	}
+
+	t.Run("no acceptable method", func(t *testing.T) {
+		conn := newSOCKSConn([]byte{socksVersion, 1, 0x02})
+		_, _, err := socksHandshake(conn)
+		require.Error(t, err)
+		assert.Equal(t, []byte{socksVersion, socksNoAcceptable}, conn.Bytes())
+	})
+
+	t.Run("unsupported command", func(t *testing.T) {
+		conn := newSOCKSConn(greeting, []byte{socksVersion, 0x03, 0, socksIPv4, 10, 0, 0, 1, 0, 53})
+		_, _, err := socksHandshake(conn)
+		var se *socksError
+		require.True(t, errors.As(err, &se))
+		assert.Equal(t, byte(socksCommandNotSupported), se.reply)
+	})
+
+	t.Run("unsupported address type", func(t *testing.T) {
+		conn := newSOCKSConn(greeting, []byte{socksVersion, socksConnect, 0, 0x02})
+		_, _, err := socksHandshake(conn)
+		var se *socksError
+		require.True(t, errors.As(err, &se))
+		assert.Equal(t, byte(socksAddrNotSupported), se.reply)
+	})
+
+	t.Run("not SOCKS5", func(t *testing.T) {
+		_, _, err := socksHandshake(newSOCKSConn([]byte{4, 1, 0, 80, 10, 0, 0, 1, 0}))
+		require.Error(t, err)
+	})
+}
+
+func TestReplyCodes(t *testing.T) {
+	rejected := fmt.Errorf("%w: 10.0.0.1:80", ErrDialRejected)
+	notFound := &net.DNSError{Err: "no such host", Name: "nothere", IsNotFound: true}
+	other := errors.New("boom")
+
+	assert.Equal(t, byte(socksConnectionRefused), socksReplyCode(rejected))
+	assert.Equal(t, byte(socksHostUnreachable), socksReplyCode(notFound))
+	assert.Equal(t, byte(socksGeneralFailure), socksReplyCode(other))
+
+	assert.Equal(t, http.StatusBadGateway, httpStatusCode(rejected))
+	assert.Equal(t, http.StatusNotFound, httpStatusCode(notFound))
+	assert.Equal(t, http.StatusServiceUnavailable, httpStatusCode(other))
+}
+
+func TestReadConnectRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		request string
+		host    string
+		port    uint16
+		status  int
+	}{
+		{
+			"name",
+			"CONNECT echo.default:443 HTTP/1.1\r\nHost: echo.default:443\r\n\r\n",
+			"echo.default",
+			443,
+			0,
+		},
+		{
+			"IPv6",
+			"CONNECT [fd00::1]:8080 HTTP/1.1\r\nHost: [fd00::1]:8080\r\n\r\n",
+			"fd00::1",
+			8080,
+			0,
+		},
+		{
+			"not CONNECT",
+			"GET http://echo.default/ HTTP/1.1\r\nHost: echo.default\r\n\r\n",
+			"",
+			0,
+			http.StatusMethodNotAllowed,
+		},
+		{
+			"no port",
+			"CONNECT echo.default HTTP/1.1\r\nHost: echo.default\r\n\r\n",
+			"",
+			0,
+			http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			host, port, status, err := readConnectRequest(bufio.NewReader(strings.NewReader(tt.request)))
+			if tt.status != 0 {
+				require.Error(t, err)
+				assert.Equal(t, tt.status, status)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.host, host)
+			assert.Equal(t, tt.port, port)
+		})
+	}
+}
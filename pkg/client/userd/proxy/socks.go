@@ -0,0 +1,172 @@
+package proxy
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/datawire/dlib/dlog"
+)
+
+// handshakeTimeout is the max time that a client may spend on the proxy handshake.
+const handshakeTimeout = 10 * time.Second
+
+// SOCKS5 protocol constants, see RFC 1928.
+const (
+	socksVersion = 5
+
+	socksNoAuth       = 0x00
+	socksNoAcceptable = 0xff
+
+	socksConnect = 0x01
+
+	socksIPv4   = 0x01
+	socksDomain = 0x03
+	socksIPv6   = 0x04
+
+	socksSucceeded           = 0x00
+	socksGeneralFailure      = 0x01
+	socksHostUnreachable     = 0x04
+	socksConnectionRefused   = 0x05
+	socksCommandNotSupported = 0x07
+	socksAddrNotSupported    = 0x08
+)
+
+// socksError is an error that is reported to the client using the given reply code.
+type socksError struct {
+	reply byte
+	msg   string
+}
+
+func (e *socksError) Error() string {
+	return e.msg
+}
+
+func handleSOCKS(ctx context.Context, conn net.Conn, d Dialer) {
+	defer func() {
+		// The endpoint created by pipe closes the conn, but it's harmless to close it twice.
+		_ = conn.Close()
+	}()
+
+	_ = conn.SetDeadline(time.Now().Add(handshakeTimeout))
+	host, port, err := socksHandshake(conn)
+	if err != nil {
+		dlog.Errorf(ctx, "SOCKS handshake failed: %v", err)
+		var se *socksError
+		if errors.As(err, &se) {
+			_ = writeSOCKSReply(conn, se.reply)
+		}
+		return
+	}
+	_ = conn.SetDeadline(time.Time{})
+
+	addr := net.JoinHostPort(host, strconv.Itoa(int(port)))
+	dlog.Debugf(ctx, "SOCKS CONNECT %s", addr)
+	stream, err := d.DialStream(ctx, conn.RemoteAddr(), host, port)
+	if err != nil {
+		dlog.Errorf(ctx, "SOCKS CONNECT %s failed: %v", addr, err)
+		_ = writeSOCKSReply(conn, socksReplyCode(err))
+		return
+	}
+	if err = writeSOCKSReply(conn, socksSucceeded); err != nil {
+		dlog.Errorf(ctx, "SOCKS reply to %s failed: %v", conn.RemoteAddr(), err)
+		_ = stream.CloseSend(ctx)
+		return
+	}
+	pipe(ctx, conn, stream)
+}
+
+// socksHandshake negotiates the authentication method with the client and then reads its CONNECT
+// request. The destination host and port of that request is returned.
+func socksHandshake(conn io.ReadWriter) (string, uint16, error) {
+	// Greeting: VER NMETHODS METHODS...
+	hdr := make([]byte, 2)
+	if _, err := io.ReadFull(conn, hdr); err != nil {
+		return "", 0, err
+	}
+	if hdr[0] != socksVersion {
+		return "", 0, fmt.Errorf("unsupported SOCKS version %d", hdr[0])
+	}
+	methods := make([]byte, hdr[1])
+	if _, err := io.ReadFull(conn, methods); err != nil {
+		return "", 0, err
+	}
+	method := byte(socksNoAcceptable)
+	for _, m := range methods {
+		if m == socksNoAuth {
+			method = socksNoAuth
+			break
+		}
+	}
+	if _, err := conn.Write([]byte{socksVersion, method}); err != nil {
+		return "", 0, err
+	}
+	if method == socksNoAcceptable {
+		return "", 0, errors.New("client doesn't support the no-authentication method")
+	}
+
+	// Request: VER CMD RSV ATYP DST.ADDR DST.PORT
+	req := make([]byte, 4)
+	if _, err := io.ReadFull(conn, req); err != nil {
+		return "", 0, err
+	}
+	if req[0] != socksVersion {
+		return "", 0, fmt.Errorf("unsupported SOCKS version %d", req[0])
+	}
+	if req[1] != socksConnect {
+		return "", 0, &socksError{reply: socksCommandNotSupported, msg: fmt.Sprintf("unsupported SOCKS command %d", req[1])}
+	}
+	var host string
+	switch req[3] {
+	case socksIPv4, socksIPv6:
+		ip := make(net.IP, net.IPv4len)
+		if req[3] == socksIPv6 {
+			ip = make(net.IP, net.IPv6len)
+		}
+		if _, err := io.ReadFull(conn, ip); err != nil {
+			return "", 0, err
+		}
+		host = ip.String()
+	case socksDomain:
+		n := make([]byte, 1)
+		if _, err := io.ReadFull(conn, n); err != nil {
+			return "", 0, err
+		}
+		name := make([]byte, n[0])
+		if _, err := io.ReadFull(conn, name); err != nil {
+			return "", 0, err
+		}
+		host = string(name)
+	default:
+		return "", 0, &socksError{reply: socksAddrNotSupported, msg: fmt.Sprintf("unsupported SOCKS address type %d", req[3])}
+	}
+	pb := make([]byte, 2)
+	if _, err := io.ReadFull(conn, pb); err != nil {
+		return "", 0, err
+	}
+	return host, binary.BigEndian.Uint16(pb), nil
+}
+
+// writeSOCKSReply writes a reply with the given code. The bound address is always reported as 0.0.0.0:0 since
+// the connection in the cluster is made by the traffic-manager or a traffic-agent.
+func writeSOCKSReply(w io.Writer, reply byte) error {
+	_, err := w.Write([]byte{socksVersion, reply, 0, socksIPv4, 0, 0, 0, 0, 0, 0})
+	return err
+}
+
+func socksReplyCode(err error) byte {
+	var dnsErr *net.DNSError
+	switch {
+	case errors.Is(err, ErrDialRejected):
+		return socksConnectionRefused
+	case errors.As(err, &dnsErr):
+		return socksHostUnreachable
+	default:
+		return socksGeneralFailure
+	}
+}
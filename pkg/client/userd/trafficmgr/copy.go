@@ -0,0 +1,262 @@
+package trafficmgr
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/sftp"
+
+	rpc "github.com/telepresenceio/telepresence/rpc/v2/connector"
+	"github.com/telepresenceio/telepresence/rpc/v2/manager"
+	"github.com/telepresenceio/telepresence/v2/pkg/client/errcat"
+)
+
+// progressInterval is the minimum time between two progress reports for the same file.
+const progressInterval = 200 * time.Millisecond
+
+// Copy copies files between the volumes of a workload's app container and the local filesystem, using
+// the sftp-server of the workload's traffic-agent. Paths that the agent's mount policy hides can't be
+// copied, and nothing can be copied to a volume that the policy makes read-only.
+func (tm *TrafficManager) Copy(ctx context.Context, rq *rpc.CopyRequest, stream CopyStream) error {
+	ns := tm.ActualNamespace(rq.Namespace)
+	if ns == "" {
+		return errcat.User.Newf("namespace %q is not mapped by this client", rq.Namespace)
+	}
+	ag, ok := tm.getCurrentAgentsInNamespace(ns)[rq.Name]
+	if !ok {
+		return errcat.User.Newf("no traffic-agent found for %s.%s", rq.Name, ns)
+	}
+	if ag.SftpPort == 0 {
+		return errcat.User.Newf("the traffic-agent of %s.%s has no sftp-server. Either it's too old, or there's nothing to copy", rq.Name, ns)
+	}
+	mountPoint, err := agentMountPoint(ag, rq.Container)
+	if err != nil {
+		return err
+	}
+	if !path.IsAbs(rq.RemotePath) {
+		return errcat.User.Newf("remote path %q is not absolute", rq.RemotePath)
+	}
+	remotePath, err := remotePathIn(mountPoint, rq.RemotePath)
+	if err != nil {
+		return err
+	}
+	if !filepath.IsAbs(rq.LocalPath) {
+		return errcat.User.Newf("local path %q is not absolute", rq.LocalPath)
+	}
+
+	conn, err := dialAgentSftp(ctx, ag.PodIp, ag.SftpPort)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	sc, err := sftp.NewClientPipe(conn, conn)
+	if err != nil {
+		return err
+	}
+	defer sc.Close()
+
+	c := &copier{sc: sc, stream: stream, recursive: rq.Recursive}
+	if rq.ToRemote {
+		return c.upload(ctx, rq.LocalPath, remotePath)
+	}
+	return c.download(ctx, remotePath, rq.LocalPath)
+}
+
+// agentMountPoint returns where the volumes of the given app container are found in the agent's
+// sftp-server. The first container is used when no name is given.
+func agentMountPoint(ag *manager.AgentInfo, container string) (string, error) {
+	for _, cn := range ag.Containers {
+		if container == "" || cn.Name == container {
+			return cn.MountPoint, nil
+		}
+	}
+	if container == "" {
+		return "", errcat.User.Newf("the traffic-agent of %s.%s serves no app containers", ag.Name, ag.Namespace)
+	}
+	return "", errcat.User.Newf("the traffic-agent of %s.%s doesn't serve a container named %q", ag.Name, ag.Namespace, container)
+}
+
+// remotePathIn returns where the given absolute path of an app container is found in the agent's
+// sftp-server, given the mount point of the container. The path is cleaned as an absolute path first,
+// so ".." elements cannot lead out of the mount point.
+func remotePathIn(mountPoint, remotePath string) (string, error) {
+	mountPoint = path.Clean(mountPoint)
+	p := path.Join(mountPoint, path.Join("/", remotePath))
+	if p != mountPoint && !strings.HasPrefix(p, mountPoint+"/") {
+		return "", errcat.User.Newf("remote path %q is outside of the container's volumes", remotePath)
+	}
+	return p, nil
+}
+
+// copier copies files over an SFTP session and reports the progress of each file.
+type copier struct {
+	sc        *sftp.Client
+	stream    CopyStream
+	recursive bool
+}
+
+// download copies the remote path to the local path. A remote directory is copied into the local path
+// when the local path is an existing directory, and to the local path otherwise, just like cp(1) does.
+func (c *copier) download(ctx context.Context, src, dst string) error {
+	fi, err := c.sc.Stat(src)
+	if err != nil {
+		return errcat.User.Newf("%s: %v", src, err)
+	}
+	if lfi, err := os.Stat(dst); err == nil && lfi.IsDir() {
+		dst = filepath.Join(dst, path.Base(src))
+	}
+	if !fi.IsDir() {
+		return c.downloadFile(src, dst, path.Base(src), fi)
+	}
+	if !c.recursive {
+		return errcat.User.Newf("%s is a directory, use --recursive to copy it", src)
+	}
+
+	w := c.sc.Walk(src)
+	for w.Step() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := w.Err(); err != nil {
+			return err
+		}
+		rp := w.Path()
+		rel := strings.TrimPrefix(strings.TrimPrefix(rp, src), "/")
+		lp := filepath.Join(dst, filepath.FromSlash(rel))
+		fi := w.Stat()
+		if fi.IsDir() {
+			if err := os.MkdirAll(lp, 0o755); err != nil {
+				return err
+			}
+			continue
+		}
+		if fi.Mode()&os.ModeSymlink != 0 {
+			// Follow the link, but only when it leads to a file
+			if fi, err = c.sc.Stat(rp); err != nil || fi.IsDir() {
+				continue
+			}
+		}
+		if !fi.Mode().IsRegular() {
+			continue
+		}
+		if err := c.downloadFile(rp, lp, rel, fi); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *copier) downloadFile(src, dst, rel string, fi os.FileInfo) error {
+	rf, err := c.sc.Open(src)
+	if err != nil {
+		return err
+	}
+	defer rf.Close()
+	lf, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, fi.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(lf, c.progressReader(rf, rel, fi.Size()))
+	if cerr := lf.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to copy %s: %w", src, err)
+	}
+	return os.Chtimes(dst, fi.ModTime(), fi.ModTime())
+}
+
+// upload copies the local path to the remote path, using the same rules as download.
+func (c *copier) upload(ctx context.Context, src, dst string) error {
+	fi, err := os.Stat(src)
+	if err != nil {
+		return errcat.User.New(err)
+	}
+	if rfi, err := c.sc.Stat(dst); err == nil && rfi.IsDir() {
+		dst = path.Join(dst, filepath.Base(src))
+	}
+	if !fi.IsDir() {
+		return c.uploadFile(src, dst, filepath.Base(src), fi)
+	}
+	if !c.recursive {
+		return errcat.User.Newf("%s is a directory, use --recursive to copy it", src)
+	}
+
+	return filepath.Walk(src, func(lp string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, lp)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		rp := path.Join(dst, rel)
+		if fi.IsDir() {
+			return c.sc.MkdirAll(rp)
+		}
+		if !fi.Mode().IsRegular() {
+			return nil
+		}
+		return c.uploadFile(lp, rp, rel, fi)
+	})
+}
+
+func (c *copier) uploadFile(src, dst, rel string, fi os.FileInfo) error {
+	lf, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer lf.Close()
+	rf, err := c.sc.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", dst, err)
+	}
+	_, err = io.Copy(rf, c.progressReader(lf, rel, fi.Size()))
+	if cerr := rf.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to copy %s: %w", src, err)
+	}
+	if err = c.sc.Chmod(dst, fi.Mode().Perm()); err != nil {
+		return err
+	}
+	return c.sc.Chtimes(dst, fi.ModTime(), fi.ModTime())
+}
+
+// progressReader returns a reader that reports the progress of the copy of a file while it's read.
+// The last progress is always reported.
+func (c *copier) progressReader(r io.Reader, rel string, size int64) io.Reader {
+	return &progressReader{r: r, lastSent: time.Now(), send: func(copied int64) error {
+		return c.stream.Send(&rpc.CopyProgress{Path: rel, Size: size, Copied: copied})
+	}}
+}
+
+type progressReader struct {
+	r        io.Reader
+	send     func(int64) error
+	copied   int64
+	lastSent time.Time
+}
+
+func (pr *progressReader) Read(p []byte) (int, error) {
+	n, err := pr.r.Read(p)
+	pr.copied += int64(n)
+	if err == io.EOF || time.Since(pr.lastSent) >= progressInterval {
+		pr.lastSent = time.Now()
+		if serr := pr.send(pr.copied); serr != nil && err == nil {
+			err = serr
+		}
+	}
+	return n, err
+}
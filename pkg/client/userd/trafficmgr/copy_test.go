@@ -0,0 +1,114 @@
+package trafficmgr
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pkg/sftp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/datawire/dlib/dlog"
+	rpc "github.com/telepresenceio/telepresence/rpc/v2/connector"
+	"github.com/telepresenceio/telepresence/rpc/v2/manager"
+)
+
+type progressRecorder []*rpc.CopyProgress
+
+func (r *progressRecorder) Send(p *rpc.CopyProgress) error {
+	*r = append(*r, p)
+	return nil
+}
+
+func TestCopier(t *testing.T) {
+	ctx := dlog.NewTestContext(t, false)
+	remote := t.TempDir()
+	local := t.TempDir()
+
+	writeFile := func(dir, name, content string) {
+		t.Helper()
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(p), 0o755))
+		require.NoError(t, os.WriteFile(p, []byte(content), 0o644))
+	}
+	readFile := func(dir, name string) string {
+		t.Helper()
+		data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		require.NoError(t, err)
+		return string(data)
+	}
+	writeFile(remote, "app/config.json", "{}")
+	writeFile(remote, "app/data/a.txt", "a")
+
+	sc, sv := net.Pipe()
+	srv, err := sftp.NewServer(sv)
+	require.NoError(t, err)
+	go func() { _ = srv.Serve() }()
+	defer srv.Close()
+	client, err := sftp.NewClientPipe(sc, sc)
+	require.NoError(t, err)
+	defer client.Close()
+
+	var progress progressRecorder
+	c := &copier{sc: client, stream: &progress}
+	rp := func(name string) string {
+		return filepath.ToSlash(filepath.Join(remote, name))
+	}
+
+	// A single file is copied into an existing directory
+	require.NoError(t, c.download(ctx, rp("app/config.json"), local))
+	assert.Equal(t, "{}", readFile(local, "config.json"))
+	require.NotEmpty(t, progress)
+	last := progress[len(progress)-1]
+	assert.Equal(t, "config.json", last.Path)
+	assert.Equal(t, int64(2), last.Copied)
+
+	// Directories require recursive
+	assert.Error(t, c.download(ctx, rp("app"), local))
+	c.recursive = true
+	require.NoError(t, c.download(ctx, rp("app"), filepath.Join(local, "copy")))
+	assert.Equal(t, "a", readFile(local, "copy/data/a.txt"))
+
+	// And back again
+	writeFile(local, "copy/data/b.txt", "b")
+	require.NoError(t, c.upload(ctx, filepath.Join(local, "copy"), rp("app")))
+	assert.Equal(t, "b", readFile(remote, "app/copy/data/b.txt"))
+}
+
+func TestAgentMountPoint(t *testing.T) {
+	ag := &manager.AgentInfo{Name: "echo", Namespace: "default", Containers: []*manager.AgentInfo_ContainerInfo{
+		{Name: "app", MountPoint: "/tel_app_exports/app"},
+		{Name: "sidecar", MountPoint: "/tel_app_exports/sidecar"},
+	}}
+	mp, err := agentMountPoint(ag, "")
+	require.NoError(t, err)
+	assert.Equal(t, "/tel_app_exports/app", mp)
+	mp, err = agentMountPoint(ag, "sidecar")
+	require.NoError(t, err)
+	assert.Equal(t, "/tel_app_exports/sidecar", mp)
+	_, err = agentMountPoint(ag, "nope")
+	assert.Error(t, err)
+}
+
+func TestRemotePathIn(t *testing.T) {
+	mp := "/tel_app_exports/app"
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/data/a.txt", "/tel_app_exports/app/data/a.txt"},
+		{"/", "/tel_app_exports/app"},
+		{"/data/../b.txt", "/tel_app_exports/app/b.txt"},
+		{"/../sidecar/secrets", "/tel_app_exports/app/sidecar/secrets"},
+		{"/../../etc/passwd", "/tel_app_exports/app/etc/passwd"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			p, err := remotePathIn(mp, tt.path)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, p)
+		})
+	}
+}
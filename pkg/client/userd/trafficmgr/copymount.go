@@ -0,0 +1,248 @@
+package trafficmgr
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/sftp"
+
+	"github.com/datawire/dlib/dlog"
+	rpc "github.com/telepresenceio/telepresence/rpc/v2/connector"
+	"github.com/telepresenceio/telepresence/v2/pkg/client"
+	"github.com/telepresenceio/telepresence/v2/pkg/glob"
+)
+
+// syncInterval is the time between polls for remote changes when the mount mode is "sync".
+const syncInterval = 2 * time.Second
+
+// remoteStamp identifies a version of a remote file.
+type remoteStamp struct {
+	size    int64
+	modTime time.Time
+}
+
+// copyMount keeps track of the remote files that have been copied to a local directory so that
+// unchanged files aren't copied again, and so that only what was copied is removed when the
+// intercept ends.
+type copyMount struct {
+	sync.Mutex
+	poll bool
+	root string
+
+	// temporary is true when the root is a temporary directory that is removed with all its
+	// content when the intercept ends.
+	temporary bool
+
+	include []string
+	exclude []string
+	maxSize int64
+
+	// files are the copied files, keyed by their slash separated path relative to the root
+	files map[string]remoteStamp
+
+	// dirs are the local directories that were created, in the order they were created
+	dirs []string
+}
+
+func newCopyMount(mode, root string, temporary bool, opts *rpc.MountCopyOptions) *copyMount {
+	return &copyMount{
+		poll:      mode == client.MountModeSync,
+		root:      root,
+		temporary: temporary,
+		include:   opts.GetInclude(),
+		exclude:   opts.GetExclude(),
+		maxSize:   opts.GetMaxFileSize(),
+		files:     make(map[string]remoteStamp),
+	}
+}
+
+func matchesGlob(patterns []string, rel string) bool {
+	for _, p := range patterns {
+		if glob.Match(p, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+func (cm *copyMount) wanted(rel string) bool {
+	return !matchesGlob(cm.exclude, rel) && (len(cm.include) == 0 || matchesGlob(cm.include, rel))
+}
+
+// run copies the remote directory using an SFTP session on the given connection. In sync
+// mode, it keeps polling for remote changes until the context is cancelled.
+func (cm *copyMount) run(ctx context.Context, conn net.Conn, remoteRoot string) error {
+	sc, err := sftp.NewClientPipe(conn, conn)
+	if err != nil {
+		return err
+	}
+	defer sc.Close()
+	for {
+		if err = cm.copyOnce(ctx, sc, remoteRoot); err != nil {
+			return err
+		}
+		if !cm.poll {
+			dlog.Infof(ctx, "Copied %d files to %q", len(cm.files), cm.root)
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(syncInterval):
+		}
+	}
+}
+
+// copyOnce walks the remote directory, copies new and modified files, and removes the local
+// copies of files that no longer exist or are no longer wanted.
+func (cm *copyMount) copyOnce(ctx context.Context, sc *sftp.Client, remoteRoot string) error {
+	cm.Lock()
+	defer cm.Unlock()
+	seen := make(map[string]struct{}, len(cm.files))
+	w := sc.Walk(remoteRoot)
+	for w.Step() {
+		if ctx.Err() != nil {
+			return nil
+		}
+		rp := w.Path()
+		if err := w.Err(); err != nil {
+			if rp == remoteRoot {
+				return err
+			}
+			dlog.Debugf(ctx, "unable to read %s: %v", rp, err)
+			continue
+		}
+		rel := strings.TrimPrefix(strings.TrimPrefix(rp, remoteRoot), "/")
+		if rel == "" {
+			continue
+		}
+		fi := w.Stat()
+		if fi.IsDir() {
+			if matchesGlob(cm.exclude, rel) {
+				w.SkipDir()
+			}
+			continue
+		}
+		if fi.Mode()&os.ModeSymlink != 0 {
+			// Follow the link, but only when it leads to a file
+			var err error
+			if fi, err = sc.Stat(rp); err != nil {
+				continue
+			}
+		}
+		if !fi.Mode().IsRegular() || !cm.wanted(rel) {
+			continue
+		}
+		if cm.maxSize > 0 && fi.Size() > cm.maxSize {
+			dlog.Debugf(ctx, "not copying %s, size %d exceeds %d", rp, fi.Size(), cm.maxSize)
+			continue
+		}
+		seen[rel] = struct{}{}
+		stamp := remoteStamp{size: fi.Size(), modTime: fi.ModTime()}
+		if old, ok := cm.files[rel]; ok && old == stamp {
+			continue
+		}
+		if err := cm.download(sc, rp, rel, fi); err != nil {
+			dlog.Errorf(ctx, "failed to copy %s: %v", rp, err)
+			continue
+		}
+		cm.files[rel] = stamp
+	}
+
+	for rel := range cm.files {
+		if _, ok := seen[rel]; !ok {
+			dlog.Debugf(ctx, "removing %s, it no longer exists in the remote volume", rel)
+			_ = os.Remove(filepath.Join(cm.root, filepath.FromSlash(rel)))
+			delete(cm.files, rel)
+		}
+	}
+	return nil
+}
+
+// download copies one remote file. The file is first written to a temporary file in the
+// destination directory, and then renamed, so that readers never see a partially copied file.
+func (cm *copyMount) download(sc *sftp.Client, remotePath, rel string, fi os.FileInfo) error {
+	dst := filepath.Join(cm.root, filepath.FromSlash(rel))
+	dir := filepath.Dir(dst)
+	if err := cm.mkdirs(dir); err != nil {
+		return err
+	}
+	src, err := sc.Open(remotePath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	tmp, err := os.CreateTemp(dir, ".tel-copy-*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	_, err = src.WriteTo(tmp)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		if err = os.Chmod(tmpName, fi.Mode().Perm()); err == nil {
+			if err = os.Chtimes(tmpName, fi.ModTime(), fi.ModTime()); err == nil {
+				err = os.Rename(tmpName, dst)
+			}
+		}
+	}
+	if err != nil {
+		_ = os.Remove(tmpName)
+	}
+	return err
+}
+
+// mkdirs creates the given directory and its missing parents below the root, and remembers
+// the ones that it created.
+func (cm *copyMount) mkdirs(dir string) error {
+	if dir == cm.root {
+		return nil
+	}
+	if _, err := os.Stat(dir); err == nil {
+		return nil
+	}
+	if err := cm.mkdirs(filepath.Dir(dir)); err != nil {
+		return err
+	}
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		if os.IsExist(err) {
+			return nil
+		}
+		return err
+	}
+	cm.dirs = append(cm.dirs, dir)
+	return nil
+}
+
+// remove removes the copied files and the directories that were created for them. Files that
+// were added locally are left alone, and so are the directories that contain them.
+func (cm *copyMount) remove(ctx context.Context) {
+	cm.Lock()
+	defer cm.Unlock()
+	if cm.temporary {
+		if err := os.RemoveAll(cm.root); err != nil {
+			dlog.Errorf(ctx, "failed to remove temporary mount point: %v", err)
+		}
+		cm.files = make(map[string]remoteStamp)
+		cm.dirs = nil
+		return
+	}
+	for rel := range cm.files {
+		if err := os.Remove(filepath.Join(cm.root, filepath.FromSlash(rel))); err != nil && !os.IsNotExist(err) {
+			dlog.Errorf(ctx, "failed to remove copied file: %v", err)
+		}
+	}
+	cm.files = make(map[string]remoteStamp)
+	for i := len(cm.dirs) - 1; i >= 0; i-- {
+		_ = os.Remove(cm.dirs[i])
+	}
+	cm.dirs = nil
+}
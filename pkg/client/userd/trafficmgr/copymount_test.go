@@ -0,0 +1,89 @@
+package trafficmgr
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pkg/sftp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/datawire/dlib/dlog"
+	rpc "github.com/telepresenceio/telepresence/rpc/v2/connector"
+	"github.com/telepresenceio/telepresence/v2/pkg/client"
+)
+
+func TestCopyMount(t *testing.T) {
+	ctx := dlog.NewTestContext(t, false)
+	remote := filepath.ToSlash(t.TempDir())
+	local := t.TempDir()
+
+	writeFile := func(dir, name, content string) {
+		t.Helper()
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(p), 0o755))
+		require.NoError(t, os.WriteFile(p, []byte(content), 0o644))
+	}
+	writeFile(remote, "app/config.json", "{}")
+	writeFile(remote, "app/big.bin", "0123456789")
+	writeFile(remote, "app/node_modules/x/index.js", "x")
+	writeFile(remote, "secrets/token", "t")
+
+	sc, sv := net.Pipe()
+	srv, err := sftp.NewServer(sv)
+	require.NoError(t, err)
+	go func() { _ = srv.Serve() }()
+	defer srv.Close()
+	sftpClient, err := sftp.NewClientPipe(sc, sc)
+	require.NoError(t, err)
+	defer sftpClient.Close()
+
+	cm := newCopyMount(client.MountModeSync, local, false, &rpc.MountCopyOptions{
+		Include:     []string{"app/**"},
+		Exclude:     []string{"node_modules"},
+		MaxFileSize: 8,
+	})
+	require.NoError(t, cm.copyOnce(ctx, sftpClient, remote))
+
+	data, err := os.ReadFile(filepath.Join(local, "app", "config.json"))
+	require.NoError(t, err)
+	assert.Equal(t, "{}", string(data))
+	assert.NoFileExists(t, filepath.Join(local, "app", "big.bin"), "exceeds max file size")
+	assert.NoDirExists(t, filepath.Join(local, "app", "node_modules"), "excluded")
+	assert.NoDirExists(t, filepath.Join(local, "secrets"), "not included")
+
+	// A modified file is copied again, and a removed one is removed locally
+	writeFile(remote, "app/config.json", `{"a":1}`)
+	require.NoError(t, os.Chtimes(filepath.Join(remote, "app", "config.json"), time.Now().Add(time.Hour), time.Now().Add(time.Hour)))
+	writeFile(remote, "app/data/a.txt", "a")
+	require.NoError(t, cm.copyOnce(ctx, sftpClient, remote))
+	data, err = os.ReadFile(filepath.Join(local, "app", "config.json"))
+	require.NoError(t, err)
+	assert.Equal(t, `{"a":1}`, string(data))
+	assert.FileExists(t, filepath.Join(local, "app", "data", "a.txt"))
+
+	require.NoError(t, os.Remove(filepath.Join(remote, "app", "data", "a.txt")))
+	require.NoError(t, cm.copyOnce(ctx, sftpClient, remote))
+	assert.NoFileExists(t, filepath.Join(local, "app", "data", "a.txt"))
+
+	// Only what was copied is removed
+	writeFile(local, "app/local.txt", "mine")
+	cm.remove(ctx)
+	assert.NoFileExists(t, filepath.Join(local, "app", "config.json"))
+	assert.NoDirExists(t, filepath.Join(local, "app", "data"))
+	assert.FileExists(t, filepath.Join(local, "app", "local.txt"))
+}
+
+func TestCopyMount_RemoveTemporary(t *testing.T) {
+	ctx := dlog.NewTestContext(t, false)
+	local := filepath.Join(t.TempDir(), "telfs-123")
+	require.NoError(t, os.MkdirAll(filepath.Join(local, "app"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(local, "app", "local.txt"), []byte("mine"), 0o644))
+
+	cm := newCopyMount(client.MountModeCopy, local, true, nil)
+	cm.remove(ctx)
+	assert.NoDirExists(t, local, "a temporary mount point is removed with all its content")
+}
@@ -0,0 +1,88 @@
+package trafficmgr
+
+import (
+	"context"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	core "k8s.io/api/core/v1"
+	errors2 "k8s.io/apimachinery/pkg/api/errors"
+
+	rpc "github.com/telepresenceio/telepresence/rpc/v2/connector"
+	"github.com/telepresenceio/telepresence/rpc/v2/manager"
+	"github.com/telepresenceio/telepresence/v2/pkg/agentconfig"
+	"github.com/telepresenceio/telepresence/v2/pkg/agentmap"
+	"github.com/telepresenceio/telepresence/v2/pkg/client/errcat"
+	"github.com/telepresenceio/telepresence/v2/pkg/k8sapi"
+)
+
+// GetEnvironment returns the environment of a workload's app container without intercepting it. The
+// environment is obtained through the traffic-manager from the workload's traffic-agent when it has one
+// that serves the container. Otherwise, it's computed from the workload's pod template, and the
+// environment policy of the traffic-manager, overridden by the annotations of the workload and its
+// namespace, is applied.
+func (tm *TrafficManager) GetEnvironment(ctx context.Context, rq *rpc.EnvironmentRequest) (*rpc.EnvironmentResponse, error) {
+	ns := tm.ActualNamespace(rq.Namespace)
+	if ns == "" {
+		return nil, errcat.User.Newf("namespace %q is not mapped by this client", rq.Namespace)
+	}
+	var mgrPolicy *agentconfig.EnvPolicy
+	mr, err := tm.managerClient.GetEnvironment(ctx, &manager.EnvironmentRequest{
+		Session:   tm.session(),
+		Name:      rq.Name,
+		Namespace: ns,
+		Container: rq.Container,
+	})
+	switch {
+	case err == nil:
+		if cn := mr.Container; cn != nil {
+			return &rpc.EnvironmentResponse{
+				Container:           cn.Name,
+				Environment:         cn.Environment,
+				RedactedEnvironment: cn.RedactedEnvironment,
+				FromAgent:           true,
+			}, nil
+		}
+		if ep := mr.EnvPolicy; ep != nil {
+			mgrPolicy = &agentconfig.EnvPolicy{
+				Redact:   ep.Redact,
+				Withhold: ep.Withhold,
+				Secrets:  agentconfig.EnvAction(ep.Secrets),
+			}
+		}
+	case status.Code(err) == codes.Unimplemented:
+		// Older traffic-managers have no environment policy of their own.
+	default:
+		return nil, err
+	}
+
+	wl, err := k8sapi.GetWorkload(ctx, rq.Name, ns, "")
+	if err != nil {
+		if errors2.IsNotFound(err) {
+			return nil, errcat.User.Newf("workload %s.%s not found", rq.Name, ns)
+		}
+		return nil, err
+	}
+	pod := wl.GetPodTemplate()
+	var cn *core.Container
+	for i := range pod.Spec.Containers {
+		c := &pod.Spec.Containers[i]
+		if rq.Container == "" && c.Name != agentconfig.ContainerName || c.Name == rq.Container {
+			cn = c
+			break
+		}
+	}
+	if cn == nil {
+		return nil, errcat.User.Newf("workload %s.%s has no container named %q", rq.Name, ns, rq.Container)
+	}
+	env, secretKeys := k8sapi.ContainerEnvironment(ctx, cn, ns)
+	ep, err := agentmap.EnvPolicy(ctx, ns, pod.Annotations, mgrPolicy)
+	if err != nil {
+		return nil, errcat.User.New(err)
+	}
+	return &rpc.EnvironmentResponse{
+		Container:           cn.Name,
+		Environment:         env,
+		RedactedEnvironment: ep.Apply(env, secretKeys),
+	}, nil
+}
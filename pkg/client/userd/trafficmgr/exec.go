@@ -0,0 +1,72 @@
+package trafficmgr
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/telepresenceio/telepresence/rpc/v2/manager"
+	"github.com/telepresenceio/telepresence/v2/pkg/client/errcat"
+)
+
+// Exec runs a command in the traffic-agent container of a workload through the traffic-manager, and
+// relays the standard I/O of the command between the given stream and the manager. The first message
+// of the stream must contain the request.
+func (tm *TrafficManager) Exec(ctx context.Context, stream ExecStream) error {
+	msg, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	rq := msg.Request
+	if rq == nil {
+		return errcat.User.New("the first message must contain the request")
+	}
+	rq.Session = tm.session()
+	ns := tm.ActualNamespace(rq.Namespace)
+	if ns == "" {
+		return errcat.User.Newf("namespace %q is not mapped by this client", rq.Namespace)
+	}
+	rq.Namespace = ns
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	mgrStream, err := tm.managerClient.Exec(ctx)
+	if err != nil {
+		return err
+	}
+	if err = mgrStream.Send(msg); err != nil {
+		return err
+	}
+
+	// Relay standard input to the manager
+	go func() {
+		for {
+			msg, err := stream.Recv()
+			if err != nil {
+				if errors.Is(err, io.EOF) {
+					_ = mgrStream.Send(&manager.ExecMessage{CloseStdin: true})
+				}
+				return
+			}
+			if err = mgrStream.Send(msg); err != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		msg, err := mgrStream.Recv()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		if err = stream.Send(msg); err != nil {
+			return err
+		}
+		if msg.Exited || msg.Error != "" {
+			return nil
+		}
+	}
+}
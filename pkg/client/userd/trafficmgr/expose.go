@@ -0,0 +1,37 @@
+package trafficmgr
+
+import (
+	"context"
+
+	"github.com/telepresenceio/telepresence/rpc/v2/manager"
+	"github.com/telepresenceio/telepresence/v2/pkg/client"
+	"github.com/telepresenceio/telepresence/v2/pkg/client/errcat"
+)
+
+// Expose asks the traffic-manager to create a Service that relays the connections that it receives to
+// a local port. The Service is removed by the traffic-manager when this session ends.
+func (tm *TrafficManager) Expose(ctx context.Context, rq *manager.ExposeRequest) error {
+	ns := tm.ActualNamespace(rq.Namespace)
+	if ns == "" {
+		return errcat.User.Newf("namespace %q is not mapped by this client", rq.Namespace)
+	}
+	tc := client.GetConfig(ctx).Timeouts
+	rq.Session = tm.session()
+	rq.Namespace = ns
+	rq.RoundtripLatency = int64(tc.Get(client.TimeoutRoundtripLatency))
+	rq.DialTimeout = int64(tc.Get(client.TimeoutEndpointDial))
+	_, err := tm.managerClient.Expose(ctx, rq)
+	return err
+}
+
+// Unexpose asks the traffic-manager to remove a Service that was created by Expose.
+func (tm *TrafficManager) Unexpose(ctx context.Context, rq *manager.UnexposeRequest) error {
+	ns := tm.ActualNamespace(rq.Namespace)
+	if ns == "" {
+		return errcat.User.Newf("namespace %q is not mapped by this client", rq.Namespace)
+	}
+	rq.Session = tm.session()
+	rq.Namespace = ns
+	_, err := tm.managerClient.Unexpose(ctx, rq)
+	return err
+}
@@ -0,0 +1,204 @@
+package trafficmgr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/datawire/dlib/dlog"
+	"github.com/telepresenceio/telepresence/rpc/v2/manager"
+	"github.com/telepresenceio/telepresence/v2/pkg/client"
+	"github.com/telepresenceio/telepresence/v2/pkg/client/cli/envfile"
+	"github.com/telepresenceio/telepresence/v2/pkg/filelocation"
+	"github.com/telepresenceio/telepresence/v2/pkg/proc"
+	"github.com/telepresenceio/telepresence/v2/pkg/shellquote"
+)
+
+// hookState is the environment and the results of the lifecycle hooks of one intercept.
+type hookState struct {
+	env     map[string]string
+	results []*manager.InterceptHookResult
+}
+
+// maxHookOutput is the max number of bytes of a failing hook's output that is included in its error.
+const maxHookOutput = 256
+
+// startHooks registers the hook state for the given intercept. It must be called before any other
+// hook function is called for that intercept.
+func (tm *TrafficManager) startHooks(spec *manager.InterceptSpec, mountPoint string) {
+	env := map[string]string{
+		"TELEPRESENCE_INTERCEPT_NAME":      spec.Name,
+		"TELEPRESENCE_INTERCEPT_NAMESPACE": spec.Namespace,
+	}
+	if mountPoint != "" {
+		env["TELEPRESENCE_INTERCEPT_MOUNT_POINT"] = mountPoint
+	}
+	tm.currentInterceptsLock.Lock()
+	tm.interceptHooks[spec.Name] = &hookState{env: env}
+	tm.currentInterceptsLock.Unlock()
+}
+
+// hookEnvFile returns the path of the file that the intercepted environment is written to for the
+// benefit of the postStart, preStop, and postStop hooks.
+func hookEnvFile(ctx context.Context, name string) (string, error) {
+	dir, err := filelocation.AppUserCacheDir(ctx)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "hooks", name+".env"), nil
+}
+
+// setHookEnv writes the environment of an intercept that has become active to a file, and makes
+// the intercept's id and the path of that file available to the remaining hooks. The file is a
+// shell script that exports each variable, so a hook can source it. The intercept.env rules of the
+// config are applied, so the hooks see the same variables as the intercept handler.
+func (tm *TrafficManager) setHookEnv(ctx context.Context, ii *manager.InterceptInfo) error {
+	name := ii.Spec.Name
+	envFile, err := hookEnvFile(ctx, name)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(envFile), 0o700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(envFile, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	env := client.GetConfig(ctx).Intercept.Env.Apply(ii.Environment)
+	err = envfile.Write(f, env, envfile.Sh, name)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+
+	tm.currentInterceptsLock.Lock()
+	if hs, ok := tm.interceptHooks[name]; ok {
+		hs.env["TELEPRESENCE_INTERCEPT_ID"] = ii.Id
+		hs.env["TELEPRESENCE_INTERCEPT_ENV_FILE"] = envFile
+	}
+	tm.currentInterceptsLock.Unlock()
+	return nil
+}
+
+// endHooks forgets the hook state of the given intercept and removes its environment file.
+func (tm *TrafficManager) endHooks(ctx context.Context, name string) {
+	tm.currentInterceptsLock.Lock()
+	delete(tm.interceptHooks, name)
+	tm.currentInterceptsLock.Unlock()
+	if envFile, err := hookEnvFile(ctx, name); err == nil {
+		_ = os.Remove(envFile)
+	}
+}
+
+// hookResults returns the results of the hooks that have run for the given intercept.
+// The currentInterceptsLock must be held when calling this function.
+func (tm *TrafficManager) hookResults(name string) []*manager.InterceptHookResult {
+	if hs, ok := tm.interceptHooks[name]; ok {
+		return hs.results
+	}
+	return nil
+}
+
+// hookError returns an error that wraps the given error and describes the results of all hooks that
+// have run for the given intercept. It's used when the intercept fails because of a hook, since the
+// results are then discarded together with the intercept and never shown by "telepresence list".
+func (tm *TrafficManager) hookError(name string, err error) error {
+	tm.currentInterceptsLock.Lock()
+	results := tm.hookResults(name)
+	tm.currentInterceptsLock.Unlock()
+	if len(results) == 0 {
+		return err
+	}
+	sb := strings.Builder{}
+	sb.WriteString("hook results:")
+	for _, hr := range results {
+		fmt.Fprintf(&sb, "\n  %s %s: ", hr.Phase, hr.Command)
+		switch {
+		case hr.Error == "":
+			sb.WriteString("ok")
+		case hr.Ignored:
+			fmt.Fprintf(&sb, "failed (ignored): %s", hr.Error)
+		default:
+			fmt.Fprintf(&sb, "failed: %s", hr.Error)
+		}
+	}
+	return fmt.Errorf("%w\n%s", err, sb.String())
+}
+
+// runHooks runs the hooks configured for the given phase of the given intercept's lifecycle
+// and records their results. An error is returned if a hook with the abort policy fails, in
+// which case the remaining hooks of the phase are skipped.
+func (tm *TrafficManager) runHooks(ctx context.Context, phase, name string) error {
+	hooks := client.GetConfig(ctx).Intercept.Hooks.Phase(phase)
+	if len(hooks) == 0 {
+		return nil
+	}
+	tm.currentInterceptsLock.Lock()
+	hs, ok := tm.interceptHooks[name]
+	var env map[string]string
+	if ok {
+		env = make(map[string]string, len(hs.env)+1)
+		for k, v := range hs.env {
+			env[k] = v
+		}
+	}
+	tm.currentInterceptsLock.Unlock()
+	if !ok {
+		return nil
+	}
+	env["TELEPRESENCE_HOOK_PHASE"] = phase
+
+	for _, hook := range hooks {
+		result := &manager.InterceptHookResult{
+			Phase:   phase,
+			Command: shellquote.ShellArgsString(hook.Command),
+		}
+		err := runHook(ctx, hook, env)
+		if err != nil {
+			result.Error = err.Error()
+			result.Ignored = hook.OnFailure == client.HookIgnore
+			dlog.Errorf(ctx, "%s hook %q for intercept %s failed: %v", phase, result.Command, name, err)
+		} else {
+			dlog.Debugf(ctx, "%s hook %q for intercept %s succeeded", phase, result.Command, name)
+		}
+		tm.currentInterceptsLock.Lock()
+		hs.results = append(hs.results, result)
+		tm.currentInterceptsLock.Unlock()
+		if err != nil && !result.Ignored {
+			return fmt.Errorf("%s hook %q failed: %w", phase, result.Command, err)
+		}
+	}
+	return nil
+}
+
+func runHook(ctx context.Context, hook *client.InterceptHook, env map[string]string) error {
+	timeout := hook.GetTimeout()
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	cmd := proc.CommandContext(ctx, hook.Command[0], hook.Command[1:]...)
+	cmd.DisableLogging = true
+	cmd.Env = os.Environ()
+	for k, v := range env {
+		cmd.Env = append(cmd.Env, k+"="+v)
+	}
+	out, err := cmd.CombinedOutput()
+	if err == nil {
+		return nil
+	}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("timed out after %s", timeout)
+	}
+	if msg := strings.TrimSpace(string(out)); msg != "" {
+		if len(msg) > maxHookOutput {
+			msg = "..." + msg[len(msg)-maxHookOutput:]
+		}
+		return fmt.Errorf("%w: %s", err, msg)
+	}
+	return err
+}
@@ -0,0 +1,105 @@
+package trafficmgr
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/datawire/dlib/dlog"
+	"github.com/telepresenceio/telepresence/rpc/v2/manager"
+	"github.com/telepresenceio/telepresence/v2/pkg/client"
+	"github.com/telepresenceio/telepresence/v2/pkg/filelocation"
+)
+
+func TestRunHooks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hooks in this test are shell scripts")
+	}
+	tmp := t.TempDir()
+	out := filepath.Join(tmp, "out")
+
+	ctx := dlog.NewTestContext(t, false)
+	ctx = filelocation.WithUserHomeDir(ctx, tmp)
+	cfg := client.GetDefaultConfig()
+	cfg.Intercept.Hooks = client.InterceptHooks{
+		PreStart: []*client.InterceptHook{
+			{Command: []string{"sh", "-c", `echo "$TELEPRESENCE_HOOK_PHASE $TELEPRESENCE_INTERCEPT_NAME.$TELEPRESENCE_INTERCEPT_NAMESPACE" > ` + out}},
+			{Command: []string{"sh", "-c", "echo oops; exit 3"}, OnFailure: client.HookIgnore},
+		},
+		PostStart: []*client.InterceptHook{
+			{Command: []string{"sh", "-c", `cp "$TELEPRESENCE_INTERCEPT_ENV_FILE" ` + out}},
+			{Command: []string{"sleep", "5"}, Timeout: 10 * time.Millisecond, OnFailure: client.HookAbort},
+			{Command: []string{"true"}},
+		},
+	}
+	ctx = client.WithConfig(ctx, &cfg)
+
+	tm := &TrafficManager{interceptHooks: map[string]*hookState{}}
+	tm.startHooks(&manager.InterceptSpec{Name: "echo", Namespace: "default"}, "")
+
+	require.NoError(t, tm.runHooks(ctx, client.HookPreStart, "echo"))
+	data, err := os.ReadFile(out)
+	require.NoError(t, err)
+	assert.Equal(t, "preStart echo.default\n", string(data))
+
+	require.NoError(t, tm.setHookEnv(ctx, &manager.InterceptInfo{
+		Id:          "abc:echo",
+		Spec:        &manager.InterceptSpec{Name: "echo"},
+		Environment: map[string]string{"B": "it's 2", "A": "1"},
+	}))
+	hookErr := tm.runHooks(ctx, client.HookPostStart, "echo")
+	assert.ErrorContains(t, hookErr, `postStart hook "sleep 5" failed: timed out after 10ms`)
+	data, err = os.ReadFile(out)
+	require.NoError(t, err)
+	assert.Equal(t, "export A=1\nexport B=it\\''s 2'\n", string(data))
+
+	hookErr = tm.hookError("echo", hookErr)
+	assert.ErrorContains(t, hookErr, "\n  preStart sh -c 'echo oops; exit 3': failed (ignored): exit status 3: oops\n")
+	assert.True(t, strings.HasSuffix(hookErr.Error(), "\n  postStart sleep 5: failed: timed out after 10ms"))
+
+	results := tm.hookResults("echo")
+	require.Len(t, results, 4, "the hook after the failed abort hook must not run")
+	assert.Equal(t, "", results[0].Error)
+	assert.Equal(t, "exit status 3: oops", results[1].Error)
+	assert.True(t, results[1].Ignored)
+	assert.Equal(t, client.HookPostStart, results[3].Phase)
+	assert.False(t, results[3].Ignored)
+
+	envFile, err := hookEnvFile(ctx, "echo")
+	require.NoError(t, err)
+	tm.endHooks(ctx, "echo")
+	assert.NoFileExists(t, envFile)
+	assert.Nil(t, tm.hookResults("echo"))
+}
+
+func TestSetHookEnv_EnvRules(t *testing.T) {
+	ctx := dlog.NewTestContext(t, false)
+	ctx = filelocation.WithUserHomeDir(ctx, t.TempDir())
+	cfg := client.GetDefaultConfig()
+	cfg.Intercept.Env = client.EnvRules{
+		Exclude:  []string{"SECRET_*"},
+		Rename:   map[string]string{"A": "X"},
+		Override: map[string]string{"C": "3"},
+	}
+	ctx = client.WithConfig(ctx, &cfg)
+
+	tm := &TrafficManager{interceptHooks: map[string]*hookState{}}
+	tm.startHooks(&manager.InterceptSpec{Name: "echo", Namespace: "default"}, "")
+	require.NoError(t, tm.setHookEnv(ctx, &manager.InterceptInfo{
+		Id:          "abc:echo",
+		Spec:        &manager.InterceptSpec{Name: "echo"},
+		Environment: map[string]string{"A": "1", "SECRET_TOKEN": "s3cr3t"},
+	}))
+	envFile, err := hookEnvFile(ctx, "echo")
+	require.NoError(t, err)
+	data, err := os.ReadFile(envFile)
+	require.NoError(t, err)
+	assert.Equal(t, "export C=3\nexport X=1\n", string(data))
+	tm.endHooks(ctx, "echo")
+}
@@ -28,6 +28,7 @@ import (
 	"github.com/datawire/dlib/dlog"
 	"github.com/datawire/dlib/dtime"
 	rpc "github.com/telepresenceio/telepresence/rpc/v2/connector"
+	"github.com/telepresenceio/telepresence/rpc/v2/daemon"
 	"github.com/telepresenceio/telepresence/rpc/v2/manager"
 	"github.com/telepresenceio/telepresence/rpc/v2/userdaemon"
 	"github.com/telepresenceio/telepresence/v2/pkg/a8rcloud"
@@ -38,6 +39,7 @@ import (
 	"github.com/telepresenceio/telepresence/v2/pkg/dpipe"
 	"github.com/telepresenceio/telepresence/v2/pkg/forwarder"
 	"github.com/telepresenceio/telepresence/v2/pkg/install"
+	"github.com/telepresenceio/telepresence/v2/pkg/iputil"
 	"github.com/telepresenceio/telepresence/v2/pkg/k8sapi"
 	"github.com/telepresenceio/telepresence/v2/pkg/matcher"
 	"github.com/telepresenceio/telepresence/v2/pkg/proc"
@@ -52,6 +54,7 @@ type forwardKey struct {
 type mountForward struct {
 	forwardKey
 	SftpPort         int32
+	NfsPort          int32
 	RemoteMountPoint string
 }
 
@@ -101,7 +104,8 @@ func (lpf livePortForwards) start(ctx context.Context, tm *TrafficManager, ii *m
 		if _, isLive := lpf.live[fk]; !isLive {
 			pfCtx, pfCancel := context.WithCancel(ctx)
 			livePortForward := &livePortForward{cancel: pfCancel}
-			tm.startForwards(pfCtx, &livePortForward.wg, fk, ii.SftpPort, ii.MountPoint, ii.Spec.LocalPorts)
+			mf := mountForward{forwardKey: fk, SftpPort: ii.SftpPort, NfsPort: ii.NfsPort, RemoteMountPoint: ii.MountPoint}
+			tm.startForwards(pfCtx, &livePortForward.wg, mf, ii.Spec.LocalPorts)
 			dlog.Debugf(ctx, "Started forward for %+v", fk)
 			lpf.live[fk] = livePortForward
 		}
@@ -140,7 +144,11 @@ func (tm *TrafficManager) reconcileMountPoints(ctx context.Context, existingInte
 			// Execute the removal in a separate go-routine so that we don't hang the daemon in case
 			// the removal hangs on a "resource busy".
 			go func(mountPoint string) {
-				if runtime.GOOS == "darwin" {
+				// NFS mounts are unmounted by the mount-forward worker when its context is cancelled
+				tm.nfsMounts.Delete(mountPoint)
+				if cm, ok := tm.copyMounts.LoadAndDelete(mountPoint); ok {
+					cm.(*copyMount).remove(ctx)
+				} else if runtime.GOOS == "darwin" {
 					//  macFUSE will sometimes not unmount in a timely manner so we do this to avoid "resource busy" and
 					//  "Device not configured" errors.
 					_ = proc.CommandContext(ctx, "umount", mountPoint).Run()
@@ -198,6 +206,7 @@ func (tm *TrafficManager) workerPortForwardIntercepts(ctx context.Context) error
 
 			portForwards.initSnapshot()
 			namespaces := make(map[string]struct{})
+			var localServices []*daemon.LocalService
 			for _, intercept := range intercepts {
 				allNames[intercept.Spec.Name] = struct{}{}
 
@@ -229,12 +238,18 @@ func (tm *TrafficManager) workerPortForwardIntercepts(ctx context.Context) error
 				if iceptError == nil {
 					namespaces[intercept.Spec.Namespace] = struct{}{}
 					portForwards.start(ctx, tm, intercept)
+					if ls := localService(ctx, intercept.Spec); ls != nil {
+						localServices = append(localServices, ls)
+					}
 				}
 			}
 			portForwards.cancelUnwanted(ctx)
 			tm.reconcileMountPoints(ctx, allNames)
 			if ctx.Err() == nil {
 				tm.setInterceptedNamespaces(ctx, namespaces)
+				if client.GetConfig(ctx).Intercept.ResolveLocally {
+					tm.setLocalServices(ctx, localServices)
+				}
 			}
 		}
 
@@ -250,6 +265,74 @@ func (tm *TrafficManager) workerPortForwardIntercepts(ctx context.Context) error
 	return nil
 }
 
+// localService returns the service of the given intercept, or nil if the intercept has no service, its
+// target host isn't an IP, or its target port differs from the intercepted service port. Local calls
+// to the service use the service port, so they wouldn't reach the handler in the latter case.
+func localService(ctx context.Context, spec *manager.InterceptSpec) *daemon.LocalService {
+	if spec.ServiceName == "" {
+		return nil
+	}
+	ip := iputil.Parse(spec.TargetHost)
+	if ip == nil {
+		return nil
+	}
+	port, err := interceptedServicePort(ctx, spec)
+	if err != nil {
+		dlog.Errorf(ctx, "unable to determine the service port of intercept %s: %v", spec.Name, err)
+		return nil
+	}
+	if port != spec.TargetPort {
+		dlog.Debugf(ctx, "service %s.%s is not resolved locally because its port %d differs from the target port %d of intercept %s",
+			spec.ServiceName, spec.Namespace, port, spec.TargetPort, spec.Name)
+		return nil
+	}
+	return &daemon.LocalService{Name: spec.ServiceName, Namespace: spec.Namespace, Ip: ip}
+}
+
+// interceptedServicePort returns the number of the service port that the given intercept intercepts. The
+// service is only consulted when the port identifier of the intercept is a name or empty.
+func interceptedServicePort(ctx context.Context, spec *manager.InterceptSpec) (int32, error) {
+	_, name, number := agentconfig.PortIdentifier(spec.ServicePortIdentifier).ProtoAndNameOrNumber()
+	if number != 0 {
+		return int32(number), nil
+	}
+	obj, err := k8sapi.GetService(ctx, spec.ServiceName, spec.Namespace)
+	if err != nil {
+		return 0, err
+	}
+	svc, _ := k8sapi.ServiceImpl(obj)
+	ports := svc.Spec.Ports
+	if name == "" && len(ports) == 1 {
+		return ports[0].Port, nil
+	}
+	for _, p := range ports {
+		if name != "" && p.Name == name {
+			return p.Port, nil
+		}
+	}
+	return 0, fmt.Errorf("service %s.%s has no port matching %q", spec.ServiceName, spec.Namespace, spec.ServicePortIdentifier)
+}
+
+// setLocalServices sends the given services to the root daemon's DNS resolver unless they are equal to the
+// ones that were sent last time. It is only called from the goroutine that watches intercepts.
+func (tm *TrafficManager) setLocalServices(ctx context.Context, services []*daemon.LocalService) {
+	if tm.rootDaemon == nil {
+		return
+	}
+	sort.Slice(services, func(i, j int) bool {
+		return services[i].Name+"."+services[i].Namespace < services[j].Name+"."+services[j].Namespace
+	})
+	lss := &daemon.LocalServices{Services: services}
+	if tm.localServices != nil && proto.Equal(lss, tm.localServices) {
+		return
+	}
+	if _, err := tm.rootDaemon.SetLocalServices(ctx, lss); err != nil {
+		dlog.Errorf(ctx, "error posting local services to root daemon: %v", err)
+		return
+	}
+	tm.localServices = lss
+}
+
 // getCurrentIntercepts returns a copy of the current intercept snapshot amended with
 // the local filesystem mount point.
 func (tm *TrafficManager) getCurrentIntercepts() []*manager.InterceptInfo {
@@ -258,6 +341,7 @@ func (tm *TrafficManager) getCurrentIntercepts() []*manager.InterceptInfo {
 	intercepts := make([]*manager.InterceptInfo, len(tm.currentIntercepts))
 	for i, ii := range tm.currentIntercepts {
 		intercepts[i] = proto.Clone(ii).(*manager.InterceptInfo)
+		intercepts[i].HookResults = tm.hookResults(ii.Spec.Name)
 	}
 	tm.currentInterceptsLock.Unlock()
 
@@ -610,10 +694,20 @@ func (tm *TrafficManager) AddIntercept(c context.Context, ir *rpc.CreateIntercep
 
 	deleteMount := false
 	if ir.MountPoint != "" {
+		if ir.MountMode != "" && !client.IsMountMode(ir.MountMode) {
+			return interceptError(rpc.InterceptError_FAILED_TO_ESTABLISH, errcat.User.Newf("invalid mount mode %q", ir.MountMode)), nil
+		}
+
 		// Ensure that the mount-point is free to use
 		if prev, loaded := tm.mountPoints.LoadOrStore(ir.MountPoint, spec.Name); loaded {
 			return interceptError(rpc.InterceptError_MOUNT_POINT_BUSY, errcat.User.Newf(prev.(string))), nil
 		}
+		switch ir.MountMode {
+		case client.MountModeCopy, client.MountModeSync:
+			tm.copyMounts.Store(ir.MountPoint, newCopyMount(ir.MountMode, ir.MountPoint, ir.MountPointTemporary, ir.MountCopy))
+		case client.MountModeNFS:
+			tm.nfsMounts.Store(ir.MountPoint, struct{}{})
+		}
 
 		// Assume that the mount-point should to be removed from the busy map. Only a happy path
 		// to successful intercept that actually has remote mounts will set this to false.
@@ -621,9 +715,24 @@ func (tm *TrafficManager) AddIntercept(c context.Context, ir *rpc.CreateIntercep
 		defer func() {
 			if deleteMount {
 				tm.mountPoints.Delete(ir.MountPoint)
+				if cm, ok := tm.copyMounts.LoadAndDelete(ir.MountPoint); ok {
+					cm.(*copyMount).remove(c)
+				}
+				tm.nfsMounts.Delete(ir.MountPoint)
 			}
 		}()
 	}
+
+	tm.startHooks(spec, ir.MountPoint)
+	if err = tm.runHooks(c, client.HookPreStart, spec.Name); err != nil {
+		err = tm.hookError(spec.Name, err)
+		tm.endHooks(c, spec.Name)
+		return interceptError(rpc.InterceptError_FAILED_TO_ESTABLISH, errcat.User.New(err)), nil
+	}
+
+	// The postStart hooks must not be constrained by the intercept timeout
+	hc := c
+
 	dlog.Debugf(c, "creating intercept %s", spec.Name)
 	tos := &client.GetConfig(c).Timeouts
 	spec.RoundtripLatency = int64(tos.Get(client.TimeoutRoundtripLatency)) * 2 // Account for extra hop
@@ -656,6 +765,7 @@ func (tm *TrafficManager) AddIntercept(c context.Context, ir *rpc.CreateIntercep
 		} else if errors.Is(err, context.Canceled) {
 			code = grpcCodes.Canceled
 		}
+		tm.endHooks(c, spec.Name)
 		return nil, grpcStatus.Error(code, err.Error())
 	}
 
@@ -673,6 +783,7 @@ func (tm *TrafficManager) AddIntercept(c context.Context, ir *rpc.CreateIntercep
 			if removeErr := tm.RemoveIntercept(rc, ii.Spec.Name); removeErr != nil {
 				dlog.Warnf(c, "failed to remove failed intercept %s: %v", ii.Spec.Name, removeErr)
 			}
+			tm.endHooks(rc, ii.Spec.Name)
 		}
 	}()
 
@@ -700,9 +811,16 @@ func (tm *TrafficManager) AddIntercept(c context.Context, ir *rpc.CreateIntercep
 			if agentEnv != nil {
 				ii.Environment = agentEnv
 			}
+			if err = tm.setHookEnv(hc, ii); err != nil {
+				dlog.Errorf(c, "unable to write environment file for hooks of intercept %s: %v", ii.Spec.Name, err)
+			}
+			if err = tm.runHooks(hc, client.HookPostStart, ii.Spec.Name); err != nil {
+				// The deferred removal of the intercept discards the hook results, so they're reported here.
+				return interceptError(rpc.InterceptError_FAILED_TO_ESTABLISH, errcat.User.New(tm.hookError(ii.Spec.Name, err))), nil
+			}
 			result.InterceptInfo = ii
 			mountPoint := tm.mountPointForIntercept(ii.Spec.Name)
-			if mountPoint != "" && ii.SftpPort > 0 {
+			if mountPoint != "" && (ii.SftpPort > 0 || ii.NfsPort > 0) {
 				deleteMount = false // Mount-point is busy until intercept ends
 				ii.ClientMountPoint = mountPoint
 			}
@@ -714,17 +832,18 @@ func (tm *TrafficManager) AddIntercept(c context.Context, ir *rpc.CreateIntercep
 
 // shouldForward returns true if the intercept info given should result in mounts or ports being forwarded
 func (tm *TrafficManager) shouldForward(ii *manager.InterceptInfo) bool {
-	return ii.SftpPort > 0 || len(ii.Spec.LocalPorts) > 0
+	return ii.SftpPort > 0 || ii.NfsPort > 0 || len(ii.Spec.LocalPorts) > 0
 }
 
 // startForwards starts port forwards and mounts for the given forwardKey.
 // It assumes that the user has called shouldForward and is sure that something will be started.
-func (tm *TrafficManager) startForwards(ctx context.Context, wg *sync.WaitGroup, fk forwardKey, sftpPort int32, remoteMountPoint string, localPorts []string) {
-	if sftpPort > 0 {
-		// There's nothing to mount if the SftpPort is zero
-		mntCtx := dgroup.WithGoroutineName(ctx, fmt.Sprintf("/%s:%d", fk.PodIP, sftpPort))
+func (tm *TrafficManager) startForwards(ctx context.Context, wg *sync.WaitGroup, mf mountForward, localPorts []string) {
+	fk := mf.forwardKey
+	if mf.SftpPort > 0 || mf.NfsPort > 0 {
+		// There's nothing to mount if both the SftpPort and the NfsPort are zero
+		mntCtx := dgroup.WithGoroutineName(ctx, fmt.Sprintf("/%s:%d", fk.PodIP, mf.SftpPort))
 		wg.Add(1)
-		go tm.workerMountForwardIntercept(mntCtx, mountForward{fk, sftpPort, remoteMountPoint}, wg)
+		go tm.workerMountForwardIntercept(mntCtx, mf, wg)
 	}
 	for _, port := range localPorts {
 		pfCtx := dgroup.WithGoroutineName(ctx, fmt.Sprintf("/%s:%s", fk.PodIP, port))
@@ -793,10 +912,22 @@ func (tm *TrafficManager) workerMountForwardIntercept(ctx context.Context, mf mo
 		mountMutex.Unlock()
 	}()
 
+	if _, ok := tm.nfsMounts.Load(mountPoint); ok {
+		tm.mountRemoteVolumesNFS(ctx, mf, mountPoint)
+		return
+	}
+	if mf.SftpPort == 0 {
+		// The agent only serves NFS
+		return
+	}
+	if cm, ok := tm.copyMounts.Load(mountPoint); ok {
+		tm.copyRemoteVolumes(ctx, mf, cm.(*copyMount))
+		return
+	}
+
 	// Retry mount in case it gets disconnected
 	err := client.Retry(ctx, "sshfs", func(ctx context.Context) error {
-		dl := &net.Dialer{Timeout: 3 * time.Second}
-		conn, err := dl.DialContext(ctx, "tcp", fmt.Sprintf("%s:%d", mf.PodIP, mf.SftpPort))
+		conn, err := dialAgentSftp(ctx, mf.PodIP, mf.SftpPort)
 		if err != nil {
 			return err
 		}
@@ -839,6 +970,64 @@ func (tm *TrafficManager) workerMountForwardIntercept(ctx context.Context, mf mo
 	}
 }
 
+// copyRemoteVolumes copies the remote volumes to the mount point using the agent's sftp-server.
+func (tm *TrafficManager) copyRemoteVolumes(ctx context.Context, mf mountForward, cm *copyMount) {
+	err := client.Retry(ctx, "sftp copy", func(ctx context.Context) error {
+		conn, err := dialAgentSftp(ctx, mf.PodIP, mf.SftpPort)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		return cm.run(ctx, conn, mf.RemoteMountPoint)
+	}, 3*time.Second, 6*time.Second)
+
+	if err != nil && ctx.Err() == nil {
+		dlog.Error(ctx, err)
+	}
+}
+
+// dialAgentSftp connects to the sftp-server of the traffic-agent with the given pod IP.
+func dialAgentSftp(ctx context.Context, podIP string, sftpPort int32) (net.Conn, error) {
+	dl := &net.Dialer{Timeout: 3 * time.Second}
+	return dl.DialContext(ctx, "tcp", fmt.Sprintf("%s:%d", podIP, sftpPort))
+}
+
+// mountRemoteVolumesNFS asks the root daemon to mount the remote volumes using the agent's
+// nfs-server, and to unmount them again when the context is cancelled.
+func (tm *TrafficManager) mountRemoteVolumesNFS(ctx context.Context, mf mountForward, mountPoint string) {
+	if mf.NfsPort == 0 {
+		dlog.Errorf(ctx, "Unable to mount %q using NFS. The traffic-agent has no nfs-server", mountPoint)
+		return
+	}
+	if tm.rootDaemon == nil {
+		dlog.Errorf(ctx, "Unable to mount %q using NFS. The session doesn't use the root daemon", mountPoint)
+		return
+	}
+	req := &daemon.NFSMountRequest{
+		PodIp:      mf.PodIP,
+		Port:       mf.NfsPort,
+		RemotePath: mf.RemoteMountPoint,
+		MountPoint: mountPoint,
+	}
+	err := client.Retry(ctx, "nfs mount", func(ctx context.Context) error {
+		_, err := tm.rootDaemon.MountNFS(ctx, req)
+		if err != nil && ctx.Err() == nil {
+			dlog.Errorf(ctx, "NFS mount of %q failed: %v", mountPoint, err)
+		}
+		return err
+	}, 3*time.Second, 6*time.Second)
+	if err != nil {
+		return
+	}
+
+	<-ctx.Done()
+	ctx, cancel := context.WithTimeout(dcontext.WithoutCancel(ctx), 10*time.Second)
+	defer cancel()
+	if _, err = tm.rootDaemon.UnmountNFS(ctx, req); err != nil {
+		dlog.Errorf(ctx, "NFS unmount of %q failed: %v", mountPoint, err)
+	}
+}
+
 // RemoveIntercept removes one intercept by name
 func (tm *TrafficManager) RemoveIntercept(c context.Context, name string) error {
 	dlog.Debugf(c, "Removing intercept %s", name)
@@ -884,11 +1073,24 @@ func (tm *TrafficManager) removeIntercept(c context.Context, ii *manager.Interce
 		}
 	}
 
+	// A failing stop hook must not prevent the intercept from being removed.
+	if err := tm.runHooks(c, client.HookPreStop, name); err != nil {
+		dlog.Error(c, err)
+	}
+
 	dlog.Debugf(c, "telling manager to remove intercept %s", name)
 	_, err := tm.managerClient.RemoveIntercept(c, &manager.RemoveInterceptRequest2{
 		Session: tm.session(),
 		Name:    name,
 	})
+	if err == nil {
+		if err := tm.runHooks(c, client.HookPostStop, name); err != nil {
+			dlog.Error(c, err)
+		}
+	}
+	// The intercept is gone from this daemon's point of view even if the traffic-manager couldn't
+	// be told, so its hook state and environment file must not be left behind.
+	tm.endHooks(c, name)
 	return err
 }
 
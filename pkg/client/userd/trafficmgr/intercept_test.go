@@ -6,6 +6,9 @@ import (
 
 	"github.com/blang/semver"
 	"github.com/stretchr/testify/assert"
+
+	"github.com/datawire/dlib/dlog"
+	"github.com/telepresenceio/telepresence/rpc/v2/manager"
 )
 
 func Test_makeFlagsCompatible(t *testing.T) {
@@ -101,3 +104,29 @@ func Test_makeFlagsCompatible(t *testing.T) {
 		})
 	}
 }
+
+func Test_localService(t *testing.T) {
+	ctx := dlog.NewTestContext(t, false)
+	spec := func(host string, targetPort int32, spi string) *manager.InterceptSpec {
+		return &manager.InterceptSpec{
+			Name:                  "echo",
+			Namespace:             "default",
+			ServiceName:           "echo",
+			ServicePortIdentifier: spi,
+			TargetHost:            host,
+			TargetPort:            targetPort,
+		}
+	}
+	ls := localService(ctx, spec("127.0.0.1", 8080, "8080"))
+	if assert.NotNil(t, ls) {
+		assert.Equal(t, "echo", ls.Name)
+		assert.Equal(t, "default", ls.Namespace)
+	}
+	assert.NotNil(t, localService(ctx, spec("127.0.0.1", 8080, "8080/TCP")))
+	assert.Nil(t, localService(ctx, spec("127.0.0.1", 8081, "8080")))
+	assert.Nil(t, localService(ctx, spec("localhost", 8080, "8080")))
+
+	noSvc := spec("127.0.0.1", 8080, "8080")
+	noSvc.ServiceName = ""
+	assert.Nil(t, localService(ctx, noSvc))
+}
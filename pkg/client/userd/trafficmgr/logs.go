@@ -0,0 +1,44 @@
+package trafficmgr
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/telepresenceio/telepresence/rpc/v2/manager"
+	"github.com/telepresenceio/telepresence/v2/pkg/client/errcat"
+	"github.com/telepresenceio/telepresence/v2/pkg/install"
+)
+
+// WatchLogs streams the log output of the traffic-manager, or of the traffic-agents of a workload, from
+// the traffic-manager. The manager only streams output from agents in the namespaces that this client watches.
+func (tm *TrafficManager) WatchLogs(ctx context.Context, rq *manager.WatchLogsRequest, stream WatchLogsStream) error {
+	rq = proto.Clone(rq).(*manager.WatchLogsRequest)
+	rq.Session = tm.session()
+	if mns := tm.GetManagerNamespace(); rq.Name == install.ManagerAppName && (rq.Namespace == "" || rq.Namespace == mns) {
+		rq.Namespace = mns
+	} else if ns := tm.ActualNamespace(rq.Namespace); ns != "" {
+		rq.Namespace = ns
+	} else {
+		return errcat.User.Newf("namespace %q is not mapped by this client", rq.Namespace)
+	}
+
+	cli, err := tm.managerClient.WatchLogs(ctx, rq)
+	if err != nil {
+		return err
+	}
+	for {
+		entry, err := cli.Recv()
+		if err != nil {
+			if errors.Is(err, io.EOF) || ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		if err = stream.Send(entry); err != nil {
+			return err
+		}
+	}
+}
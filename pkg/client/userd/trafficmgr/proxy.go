@@ -0,0 +1,113 @@
+package trafficmgr
+
+import (
+	"context"
+	"net"
+
+	"github.com/datawire/dlib/dgroup"
+	"github.com/datawire/dlib/dlog"
+	"github.com/telepresenceio/telepresence/rpc/v2/connector"
+	"github.com/telepresenceio/telepresence/v2/pkg/client/errcat"
+	"github.com/telepresenceio/telepresence/v2/pkg/client/userd/proxy"
+)
+
+// proxyListeners are the listeners of the SOCKS5 and HTTP CONNECT proxies that a session uses instead
+// of the root daemon. Either of them may be nil.
+type proxyListeners struct {
+	socks net.Listener
+	http  net.Listener
+}
+
+// isProxyOnly returns true if the given request asks for a session that uses proxies instead of the root daemon.
+func isProxyOnly(cr *connector.ConnectRequest) bool {
+	return cr.SocksProxy != "" || cr.HttpProxy != ""
+}
+
+// proxyListenAddress returns the address that a proxy declared with the given address listens on. An
+// address without a host defaults to 127.0.0.1. Addresses that aren't loopback addresses are rejected
+// unless allowRemote is true, because the proxies give everyone who can reach them access to the cluster.
+func proxyListenAddress(addr string, allowRemote bool) (string, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", errcat.User.New(err)
+	}
+	if host == "" {
+		return net.JoinHostPort("127.0.0.1", port), nil
+	}
+	if !allowRemote {
+		if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+			return "", errcat.User.Newf("%s is not a loopback address, use --proxy-allow-remote to listen on it", host)
+		}
+	}
+	return addr, nil
+}
+
+// listenProxies creates the listeners of the proxies declared in the given request.
+func listenProxies(c context.Context, cr *connector.ConnectRequest) (*proxyListeners, error) {
+	pl := &proxyListeners{}
+	lc := net.ListenConfig{}
+	if cr.SocksProxy != "" {
+		addr, err := proxyListenAddress(cr.SocksProxy, cr.ProxyAllowRemote)
+		if err != nil {
+			return nil, err
+		}
+		if pl.socks, err = lc.Listen(c, "tcp", addr); err != nil {
+			return nil, errcat.User.Newf("unable to listen for SOCKS5 proxy connections: %v", err)
+		}
+		dlog.Infof(c, "SOCKS5 proxy listening on %s", pl.socks.Addr())
+	}
+	if cr.HttpProxy != "" {
+		addr, err := proxyListenAddress(cr.HttpProxy, cr.ProxyAllowRemote)
+		if err != nil {
+			pl.close()
+			return nil, err
+		}
+		if pl.http, err = lc.Listen(c, "tcp", addr); err != nil {
+			pl.close()
+			return nil, errcat.User.Newf("unable to listen for HTTP proxy connections: %v", err)
+		}
+		dlog.Infof(c, "HTTP CONNECT proxy listening on %s", pl.http.Addr())
+	}
+	return pl, nil
+}
+
+func (pl *proxyListeners) close() {
+	if pl.socks != nil {
+		_ = pl.socks.Close()
+	}
+	if pl.http != nil {
+		_ = pl.http.Close()
+	}
+}
+
+// setConnectInfo sets the actual addresses of the proxies in the given ConnectInfo.
+func (pl *proxyListeners) setConnectInfo(ci *connector.ConnectInfo) {
+	if pl == nil {
+		return
+	}
+	if pl.socks != nil {
+		ci.SocksProxy = pl.socks.Addr().String()
+	}
+	if pl.http != nil {
+		ci.HttpProxy = pl.http.Addr().String()
+	}
+}
+
+// serveProxies serves the proxies of this session, if any, until the context is cancelled.
+func (tm *TrafficManager) serveProxies(g *dgroup.Group) {
+	pl := tm.proxies
+	if pl == nil {
+		return
+	}
+	d := proxy.NewManagerDialer(tm.managerClient, tm.session())
+	if pl.socks != nil {
+		g.Go("socks-proxy", func(c context.Context) error {
+			return proxy.ServeSOCKS(c, pl.socks, d)
+		})
+	}
+	if pl.http != nil {
+		g.Go("http-proxy", func(c context.Context) error {
+			return proxy.ServeHTTP(c, pl.http, d)
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package trafficmgr
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProxyListenAddress(t *testing.T) {
+	tests := []struct {
+		addr        string
+		allowRemote bool
+		want        string
+		wantErr     bool
+	}{
+		{":1080", false, "127.0.0.1:1080", false},
+		{":1080", true, "127.0.0.1:1080", false},
+		{"127.0.0.1:1080", false, "127.0.0.1:1080", false},
+		{"[::1]:1080", false, "[::1]:1080", false},
+		{"localhost:1080", false, "localhost:1080", false},
+		{"0.0.0.0:1080", false, "", true},
+		{"0.0.0.0:1080", true, "0.0.0.0:1080", false},
+		{"192.168.1.10:1080", false, "", true},
+		{"proxy.example.com:1080", false, "", true},
+		{"1080", false, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			got, err := proxyListenAddress(tt.addr, tt.allowRemote)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -341,6 +341,34 @@ func (p *mgrProxy) WatchLookupHost(*managerrpc.SessionInfo, managerrpc.Manager_W
 	return status.Error(codes.Unimplemented, "must call manager.WatchLookupHost from an agent (intercepted Pod), not from a client (workstation)")
 }
 
+func (p *mgrProxy) LookupDNS(ctx context.Context, arg *managerrpc.DNSRequest) (*managerrpc.DNSResponse, error) {
+	client, callOptions, err := p.get()
+	if err != nil {
+		return nil, err
+	}
+	return client.LookupDNS(ctx, arg, callOptions...)
+}
+
+func (p *mgrProxy) AgentLookupDNSResponse(ctx context.Context, arg *managerrpc.DNSAgentResponse) (*empty.Empty, error) {
+	client, callOptions, err := p.get()
+	if err != nil {
+		return nil, err
+	}
+	return client.AgentLookupDNSResponse(ctx, arg, callOptions...)
+}
+
+func (p *mgrProxy) Ping(ctx context.Context, arg *managerrpc.PingRequest) (*managerrpc.PingResponse, error) {
+	client, callOptions, err := p.get()
+	if err != nil {
+		return nil, err
+	}
+	return client.Ping(ctx, arg, callOptions...)
+}
+
+func (p *mgrProxy) WatchLookupDNS(*managerrpc.SessionInfo, managerrpc.Manager_WatchLookupDNSServer) error {
+	return status.Error(codes.Unimplemented, "must call manager.WatchLookupDNS from an agent (intercepted Pod), not from a client (workstation)")
+}
+
 func (p *mgrProxy) WatchClusterInfo(arg *managerrpc.SessionInfo, srv managerrpc.Manager_WatchClusterInfoServer) error {
 	client, callOptions, err := p.get()
 	if err != nil {
@@ -379,3 +407,62 @@ func (p *mgrProxy) GetLogs(context.Context, *managerrpc.GetLogsRequest) (*manage
 func (p *mgrProxy) WatchLogLevel(*empty.Empty, managerrpc.Manager_WatchLogLevelServer) error {
 	return status.Error(codes.Unimplemented, "must call manager.WatchLogLevel from an agent (intercepted Pod), not from a client (workstation)")
 }
+
+func (p *mgrProxy) WatchLogs(arg *managerrpc.WatchLogsRequest, srv managerrpc.Manager_WatchLogsServer) error {
+	client, callOptions, err := p.get()
+	if err != nil {
+		return err
+	}
+	cli, err := client.WatchLogs(srv.Context(), arg, callOptions...)
+	if err != nil {
+		return err
+	}
+	for {
+		entry, err := cli.Recv()
+		if err != nil {
+			if err == io.EOF || srv.Context().Err() != nil {
+				return nil
+			}
+			return err
+		}
+		if err = srv.Send(entry); err != nil {
+			return err
+		}
+	}
+}
+
+func (p *mgrProxy) WatchAgentLogsRequests(*managerrpc.SessionInfo, managerrpc.Manager_WatchAgentLogsRequestsServer) error {
+	return status.Error(codes.Unimplemented, "must call manager.WatchAgentLogsRequests from an agent (intercepted Pod), not from a client (workstation)")
+}
+
+func (p *mgrProxy) AgentLogs(managerrpc.Manager_AgentLogsServer) error {
+	return status.Error(codes.Unimplemented, "must call manager.AgentLogs from an agent (intercepted Pod), not from a client (workstation)")
+}
+
+func (p *mgrProxy) Exec(managerrpc.Manager_ExecServer) error {
+	return status.Error(codes.Unimplemented, "must call connector.Exec instead of manager.Exec")
+}
+
+func (p *mgrProxy) WatchExecRequests(*managerrpc.SessionInfo, managerrpc.Manager_WatchExecRequestsServer) error {
+	return status.Error(codes.Unimplemented, "must call manager.WatchExecRequests from an agent (intercepted Pod), not from a client (workstation)")
+}
+
+func (p *mgrProxy) AgentExec(managerrpc.Manager_AgentExecServer) error {
+	return status.Error(codes.Unimplemented, "must call manager.AgentExec from an agent (intercepted Pod), not from a client (workstation)")
+}
+
+func (p *mgrProxy) ReportEnvironment(context.Context, *managerrpc.AgentEnvironment) (*empty.Empty, error) {
+	return nil, status.Error(codes.Unimplemented, "must call manager.ReportEnvironment from an agent (intercepted Pod), not from a client (workstation)")
+}
+
+func (p *mgrProxy) GetEnvironment(context.Context, *managerrpc.EnvironmentRequest) (*managerrpc.EnvironmentResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "must call connector.GetEnvironment instead of manager.GetEnvironment")
+}
+
+func (p *mgrProxy) Expose(context.Context, *managerrpc.ExposeRequest) (*empty.Empty, error) {
+	return nil, status.Error(codes.Unimplemented, "must call connector.Expose instead of manager.Expose")
+}
+
+func (p *mgrProxy) Unexpose(context.Context, *managerrpc.UnexposeRequest) (*empty.Empty, error) {
+	return nil, status.Error(codes.Unimplemented, "must call connector.Unexpose instead of manager.Unexpose")
+}
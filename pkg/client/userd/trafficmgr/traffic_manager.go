@@ -24,6 +24,7 @@ import (
 	core "k8s.io/api/core/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/types"
 
 	"github.com/datawire/dlib/dcontext"
@@ -61,6 +62,19 @@ type WatchWorkloadsStream interface {
 	Send(*rpc.WorkloadInfoSnapshot) error
 }
 
+type WatchLogsStream interface {
+	Send(*manager.LogEntry) error
+}
+
+type CopyStream interface {
+	Send(*rpc.CopyProgress) error
+}
+
+type ExecStream interface {
+	Send(*manager.ExecMessage) error
+	Recv() (*manager.ExecMessage, error)
+}
+
 type Session interface {
 	restapi.AgentState
 	AddIntercept(context.Context, *rpc.CreateInterceptRequest) (*rpc.InterceptResult, error)
@@ -78,13 +92,19 @@ type Session interface {
 	UpdateStatus(context.Context, *rpc.ConnectRequest) *rpc.ConnectInfo
 	WatchWorkloads(context.Context, *rpc.WatchWorkloadsRequest, WatchWorkloadsStream) error
 	WithK8sInterface(context.Context) context.Context
-	WorkloadInfoSnapshot(context.Context, []string, rpc.ListRequest_Filter, bool) (*rpc.WorkloadInfoSnapshot, error)
+	WorkloadInfoSnapshot(context.Context, []string, rpc.ListRequest_Filter, labels.Selector, bool) (*rpc.WorkloadInfoSnapshot, error)
 	ManagerClient() manager.ManagerClient
 	GetCurrentNamespaces(forClientAccess bool) []string
 	ActualNamespace(string) string
 	RemainWithToken(context.Context) error
 	AddNamespaceListener(k8s.NamespaceListener)
 	GatherLogs(context.Context, *connector.LogsRequest) (*connector.LogsResponse, error)
+	WatchLogs(context.Context, *manager.WatchLogsRequest, WatchLogsStream) error
+	Exec(context.Context, ExecStream) error
+	Copy(context.Context, *rpc.CopyRequest, CopyStream) error
+	GetEnvironment(context.Context, *rpc.EnvironmentRequest) (*rpc.EnvironmentResponse, error)
+	Expose(context.Context, *manager.ExposeRequest) error
+	Unexpose(context.Context, *manager.UnexposeRequest) error
 }
 
 type Service interface {
@@ -123,9 +143,15 @@ type TrafficManager struct {
 	// version reported by the manager
 	managerVersion semver.Version
 
-	// search paths are propagated to the rootDaemon
+	// search paths are propagated to the rootDaemon. It's nil when the session uses proxies instead
 	rootDaemon daemon.DaemonClient
 
+	// proxies are the SOCKS5 and HTTP CONNECT proxies used instead of the rootDaemon
+	proxies *proxyListeners
+
+	// localServices are the services that were last sent to the root daemon's DNS resolver
+	localServices *daemon.LocalServices
+
 	sessionInfo *manager.SessionInfo // sessionInfo returned by the traffic-manager
 
 	// Map of desired mount points for intercepts
@@ -135,6 +161,12 @@ type TrafficManager struct {
 	// mount points concurrently
 	mountMutexes sync.Map
 
+	// Map of mount points to which remote volumes are copied rather than mounted
+	copyMounts sync.Map
+
+	// Set of mount points where remote volumes are mounted using NFS
+	nfsMounts sync.Map
+
 	wlWatcher *workloadsAndServicesWatcher
 
 	insLock sync.Mutex
@@ -158,6 +190,9 @@ type TrafficManager struct {
 	// the pid of that new command.
 	currentInterceptors map[string]int
 
+	// State of the lifecycle hooks keyed by intercept name. Guarded by currentInterceptsLock
+	interceptHooks map[string]*hookState
+
 	// currentAgents is the latest snapshot returned by the agent watcher
 	currentAgents     []*manager.AgentInfo
 	currentAgentsLock sync.Mutex
@@ -190,8 +225,20 @@ func NewSession(c context.Context, sr *scout.Reporter, cr *rpc.ConnectRequest, s
 	dlog.Info(c, "-- Starting new session")
 	sr.Report(c, "connect")
 
-	rootDaemon, err := svc.RootDaemonClient(c)
-	if err != nil {
+	var rootDaemon daemon.DaemonClient
+	var proxies *proxyListeners
+	var err error
+	if isProxyOnly(cr) {
+		// The proxies are used instead of the root daemon, so they are reported as daemon failures.
+		if proxies, err = listenProxies(c, cr); err != nil {
+			return nil, connectError(rpc.ConnectInfo_DAEMON_FAILED, err)
+		}
+		defer func() {
+			if proxies != nil {
+				proxies.close()
+			}
+		}()
+	} else if rootDaemon, err = svc.RootDaemonClient(c); err != nil {
 		return nil, connectError(rpc.ConnectInfo_DAEMON_FAILED, err)
 	}
 
@@ -223,6 +270,7 @@ func NewSession(c context.Context, sr *scout.Reporter, cr *rpc.ConnectRequest, s
 
 	tmgr.sessionServices = extraServices
 	tmgr.sr = sr
+	tmgr.proxies = proxies
 
 	// Must call SetManagerClient before calling daemon.Connect which tells the
 	// daemon to use the proxy.
@@ -235,20 +283,47 @@ func NewSession(c context.Context, sr *scout.Reporter, cr *rpc.ConnectRequest, s
 	}
 	svc.SetManagerClient(tmgr.managerClient, opts...)
 
-	// Tell daemon what it needs to know in order to establish outbound traffic to the cluster
-	oi := tmgr.getOutboundInfo(c)
+	if rootDaemon == nil {
+		dlog.Debug(c, "Using proxies instead of the root daemon")
+	} else if err = tmgr.connectRootDaemon(c, rootDaemon); err != nil {
+		return nil, connectError(rpc.ConnectInfo_DAEMON_FAILED, err)
+	}
+	tmgr.AddNamespaceListener(tmgr.updateDaemonNamespaces)
+
+	// Collect data on how long connection time took
+	dlog.Debug(c, "Finished connecting to traffic manager")
+	sr.Report(c, "finished_connecting_traffic_manager", scout.Entry{
+		Key: "connect_duration", Value: time.Since(connectStart).Seconds()})
+
+	ret := &rpc.ConnectInfo{
+		Error:          rpc.ConnectInfo_UNSPECIFIED,
+		ClusterContext: cluster.Config.Context,
+		ClusterServer:  cluster.Config.Server,
+		ClusterId:      cluster.GetClusterId(c),
+		SessionInfo:    tmgr.session(),
+		Intercepts:     &manager.InterceptInfoSnapshot{Intercepts: tmgr.getCurrentIntercepts()},
+	}
+	proxies.setConnectInfo(ret)
+	proxies = nil // The session owns them now
+	return tmgr, ret
+}
+
+// connectRootDaemon tells the root daemon what it needs to know in order to establish outbound traffic
+// to the cluster.
+func (tm *TrafficManager) connectRootDaemon(c context.Context, rootDaemon daemon.DaemonClient) error {
+	oi := tm.getOutboundInfo(c)
 
 	dlog.Debug(c, "Connecting to root daemon")
-	var rootStatus *daemon.DaemonStatus
 	for attempt := 1; ; attempt++ {
-		if rootStatus, err = rootDaemon.Connect(c, oi); err != nil {
+		rootStatus, err := rootDaemon.Connect(c, oi)
+		if err != nil {
 			dlog.Errorf(c, "failed to connect to root daemon: %v", err)
-			return nil, connectError(rpc.ConnectInfo_DAEMON_FAILED, err)
+			return err
 		}
 		oc := rootStatus.OutboundConfig
 		if oc == nil || oc.Session == nil {
 			// This is an internal error. Something is wrong with the root daemon.
-			return nil, connectError(rpc.ConnectInfo_DAEMON_FAILED, errors.New("root daemon's OutboundConfig has no Session"))
+			return errors.New("root daemon's OutboundConfig has no Session")
 		}
 		if oc.Session.SessionId == oi.Session.SessionId {
 			break
@@ -258,29 +333,14 @@ func NewSession(c context.Context, sr *scout.Reporter, cr *rpc.ConnectRequest, s
 		// crashed without disconnecting. So let's do that now, and then reconnect...
 		if attempt == 2 {
 			// ...or not, since we've already done it.
-			return nil, connectError(rpc.ConnectInfo_DAEMON_FAILED, errors.New("unable to reconnect"))
+			return errors.New("unable to reconnect")
 		}
 		if _, err = rootDaemon.Disconnect(c, &empty.Empty{}); err != nil {
-			return nil, connectError(rpc.ConnectInfo_DAEMON_FAILED, fmt.Errorf("failed to disconnect from the root daemon: %w", err))
+			return fmt.Errorf("failed to disconnect from the root daemon: %w", err)
 		}
 	}
 	dlog.Debug(c, "Connected to root daemon")
-	tmgr.AddNamespaceListener(tmgr.updateDaemonNamespaces)
-
-	// Collect data on how long connection time took
-	dlog.Debug(c, "Finished connecting to traffic manager")
-	sr.Report(c, "finished_connecting_traffic_manager", scout.Entry{
-		Key: "connect_duration", Value: time.Since(connectStart).Seconds()})
-
-	ret := &rpc.ConnectInfo{
-		Error:          rpc.ConnectInfo_UNSPECIFIED,
-		ClusterContext: cluster.Config.Context,
-		ClusterServer:  cluster.Config.Server,
-		ClusterId:      cluster.GetClusterId(c),
-		SessionInfo:    tmgr.session(),
-		Intercepts:     &manager.InterceptInfoSnapshot{Intercepts: tmgr.getCurrentIntercepts()},
-	}
-	return tmgr, ret
+	return nil
 }
 
 func (tm *TrafficManager) RemainWithToken(ctx context.Context) error {
@@ -308,6 +368,9 @@ func connectCluster(c context.Context, cr *rpc.ConnectRequest) (*k8s.Cluster, er
 	if err != nil {
 		return nil, err
 	}
+	for _, ap := range cr.AlsoProxy {
+		config.AlsoProxy = append(config.AlsoProxy, (*iputil.Subnet)(iputil.IPNetFromRPC(ap)))
+	}
 
 	mappedNamespaces := cr.MappedNamespaces
 	if len(mappedNamespaces) == 1 && mappedNamespaces[0] == "all" {
@@ -451,6 +514,7 @@ func connectMgr(c context.Context, cluster *k8s.Cluster, installID string, svc S
 		rootDaemon:          rootDaemon,
 		localIntercepts:     map[string]string{},
 		currentInterceptors: map[string]int{},
+		interceptHooks:      map[string]*hookState{},
 		wlWatcher:           newWASWatcher(),
 	}, nil
 }
@@ -474,6 +538,10 @@ func (tm *TrafficManager) setInterceptedNamespaces(c context.Context, intercepte
 // send it to the DNS-resolver in the daemon.
 func (tm *TrafficManager) updateDaemonNamespaces(c context.Context) {
 	tm.wlWatcher.setNamespacesToWatch(c, tm.GetCurrentNamespaces(true))
+	if tm.rootDaemon == nil {
+		// No DNS resolver. Names are resolved by the traffic-manager when the proxies dial.
+		return
+	}
 
 	tm.insLock.Lock()
 	namespaces := make([]string, 0, len(tm.interceptedNamespaces)+len(tm.localIntercepts))
@@ -514,6 +582,7 @@ func (tm *TrafficManager) Run(c context.Context) error {
 	g.Go("intercept-port-forward", tm.workerPortForwardIntercepts)
 	g.Go("agent-watcher", tm.agentInfoWatcher)
 	g.Go("dial-request-watcher", tm.dialRequestWatcher)
+	tm.serveProxies(g)
 	for _, svc := range tm.sessionServices {
 		func(svc SessionService) {
 			dlog.Infof(c, "Starting additional session service %s", svc.Name())
@@ -536,6 +605,7 @@ func (tm *TrafficManager) getInfosForWorkloads(
 	iMap map[string][]*manager.InterceptInfo,
 	aMap map[string]*manager.AgentInfo,
 	filter rpc.ListRequest_Filter,
+	selector labels.Selector,
 ) ([]*rpc.WorkloadInfo, error) {
 	wiMap := make(map[types.UID]*rpc.WorkloadInfo)
 	var err error
@@ -548,6 +618,9 @@ func (tm *TrafficManager) getInfosForWorkloads(
 			if _, ok := wiMap[workload.GetUID()]; ok {
 				continue
 			}
+			if selector != nil && !selector.Matches(labels.Set(workload.GetLabels())) {
+				continue
+			}
 			name := workload.GetName()
 			dlog.Debugf(ctx, "Getting info for %s %s.%s, matching service %s.%s", workload.GetKind(), name, workload.GetNamespace(), svc.Name, svc.Namespace)
 			ports := []*rpc.WorkloadInfo_ServiceReference_Port{}
@@ -617,7 +690,7 @@ func (tm *TrafficManager) WatchWorkloads(c context.Context, wr *rpc.WatchWorkloa
 		case <-c.Done():
 			return nil
 		case <-snapshotAvailable:
-			snapshot, err := tm.workloadInfoSnapshot(c, wr.GetNamespaces(), rpc.ListRequest_INTERCEPTABLE, false)
+			snapshot, err := tm.workloadInfoSnapshot(c, wr.GetNamespaces(), rpc.ListRequest_INTERCEPTABLE, nil, false)
 			if err != nil {
 				return status.Errorf(codes.Unavailable, "failed to create WorkloadInfoSnapshot: %v", err)
 			}
@@ -633,10 +706,11 @@ func (tm *TrafficManager) WorkloadInfoSnapshot(
 	ctx context.Context,
 	namespaces []string,
 	filter rpc.ListRequest_Filter,
+	selector labels.Selector,
 	includeLocalIntercepts bool,
 ) (*rpc.WorkloadInfoSnapshot, error) {
 	tm.waitForSync(ctx)
-	return tm.workloadInfoSnapshot(ctx, namespaces, filter, includeLocalIntercepts)
+	return tm.workloadInfoSnapshot(ctx, namespaces, filter, selector, includeLocalIntercepts)
 }
 
 func (tm *TrafficManager) ensureWatchers(ctx context.Context,
@@ -678,6 +752,7 @@ func (tm *TrafficManager) workloadInfoSnapshot(
 	ctx context.Context,
 	namespaces []string,
 	filter rpc.ListRequest_Filter,
+	selector labels.Selector,
 	includeLocalIntercepts bool,
 ) (*rpc.WorkloadInfoSnapshot, error) {
 	is := tm.getCurrentIntercepts()
@@ -731,7 +806,7 @@ nextIs:
 			aMap[k] = v
 		}
 	}
-	workloadInfos, err := tm.getInfosForWorkloads(ctx, nss, iMap, aMap, filter)
+	workloadInfos, err := tm.getInfosForWorkloads(ctx, nss, iMap, aMap, filter, selector)
 	if err != nil {
 		return nil, err
 	}
@@ -831,6 +906,7 @@ func (tm *TrafficManager) Status(c context.Context) *rpc.ConnectInfo {
 		SessionInfo:    tm.session(),
 		Intercepts:     &manager.InterceptInfoSnapshot{Intercepts: tm.getCurrentIntercepts()},
 	}
+	tm.proxies.setConnectInfo(ret)
 	return ret
 }
 
@@ -1092,6 +1168,26 @@ func (tm *TrafficManager) getOutboundInfo(ctx context.Context) *daemon.OutboundI
 		if len(tm.DNS.RemoteIP) > 0 {
 			info.Dns.RemoteIp = tm.DNS.RemoteIP.IP()
 		}
+		for _, rw := range tm.DNS.Rewrites {
+			drw := &daemon.DNSRewrite{
+				Suffix:      rw.Suffix,
+				Regex:       rw.Regex,
+				Replacement: rw.Replacement,
+			}
+			if len(rw.IP) > 0 {
+				drw.Ip = rw.IP.IP()
+			}
+			info.Dns.Rewrites = append(info.Dns.Rewrites, drw)
+		}
+		if c := tm.DNS.Cache; c != nil {
+			info.Dns.Cache = &daemon.DNSCachePolicy{
+				RecordTtl:   durationpb.New(c.RecordTTL.Duration),
+				Ttl:         durationpb.New(c.TTL.Duration),
+				NegativeTtl: durationpb.New(c.NegativeTTL.Duration),
+				MaxEntries:  c.MaxEntries,
+				Eviction:    c.Eviction,
+			}
+		}
 	}
 
 	if len(tm.AlsoProxy) > 0 {
@@ -0,0 +1,167 @@
+// Package dnsproxy contains the functions that the traffic-manager and the traffic-agent use when they
+// resolve DNS queries against the cluster's DNS on behalf of a client, and that the client uses when it
+// receives the result.
+package dnsproxy
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// RRs is a slice of resource records.
+type RRs []dns.RR
+
+// Sections are the answer, authority, and additional sections of a DNS response.
+type Sections struct {
+	Answer RRs
+	Ns     RRs
+	Extra  RRs
+}
+
+// resolvConf is the file that declares the DNS servers and the search path of the pod.
+const resolvConf = "/etc/resolv.conf"
+
+// Lookup resolves the given name and record type using the DNS servers and the search path that are
+// declared in the pod's /etc/resolv.conf. It returns the sections of the response together with the
+// DNS response code. The sections are nil only when an error is returned.
+func Lookup(ctx context.Context, qType uint16, name string) (*Sections, int, error) {
+	cfg, err := dns.ClientConfigFromFile(resolvConf)
+	if err != nil {
+		return nil, dns.RcodeServerFailure, err
+	}
+	return lookup(ctx, cfg, qType, name)
+}
+
+func lookup(ctx context.Context, cfg *dns.ClientConfig, qType uint16, name string) (*Sections, int, error) {
+	timeout := time.Duration(cfg.Timeout) * time.Second
+
+	// The authority section of the last NXDOMAIN response is returned when no name is found.
+	nxDomain := &Sections{}
+	for _, qName := range cfg.NameList(name) {
+		q := new(dns.Msg)
+		q.SetQuestion(qName, qType)
+		q.SetEdns0(dns.DefaultMsgSize, false) // reduces the need to repeat the query over TCP
+		r, err := exchange(ctx, cfg, q, timeout)
+		if err != nil {
+			return nil, dns.RcodeServerFailure, err
+		}
+		if r.Rcode == dns.RcodeNameError {
+			nxDomain.Ns = r.Ns
+			continue
+		}
+
+		// The records of the name that was found by applying the search path are returned under the
+		// name that was asked for.
+		fqn := dns.Fqdn(name)
+		for _, rr := range r.Answer {
+			if h := rr.Header(); strings.EqualFold(h.Name, qName) {
+				h.Name = fqn
+			}
+		}
+		return &Sections{Answer: r.Answer, Ns: r.Ns, Extra: withoutOPT(r.Extra)}, r.Rcode, nil
+	}
+	return nxDomain, dns.RcodeNameError, nil
+}
+
+// withoutOPT returns the given records without the OPT pseudo-record, which belongs to the exchange
+// with the server and not to the response that is forwarded.
+func withoutOPT(rrs RRs) RRs {
+	var ret RRs
+	for _, rr := range rrs {
+		if rr.Header().Rrtype != dns.TypeOPT {
+			ret = append(ret, rr)
+		}
+	}
+	return ret
+}
+
+// exchange sends the given query to each of the configured servers until one of them responds, and
+// repeats the query over TCP if the UDP response is truncated.
+func exchange(ctx context.Context, cfg *dns.ClientConfig, q *dns.Msg, timeout time.Duration) (r *dns.Msg, err error) {
+	if len(cfg.Servers) == 0 {
+		return nil, fmt.Errorf("no nameserver declared in %s", resolvConf)
+	}
+	for _, server := range cfg.Servers {
+		addr := net.JoinHostPort(server, cfg.Port)
+		c := dns.Client{Net: "udp", Timeout: timeout}
+		if r, _, err = c.ExchangeContext(ctx, q, addr); err == nil && r.Truncated {
+			c.Net = "tcp"
+			r, _, err = c.ExchangeContext(ctx, q, addr)
+		}
+		if err == nil {
+			return r, nil
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, err
+}
+
+// ToPacked returns the wire format of a DNS message that contains the given sections. A nil *Sections
+// is packed as a message without records.
+func (s *Sections) ToPacked() ([]byte, error) {
+	m := dns.Msg{}
+	if s != nil {
+		m.Answer, m.Ns, m.Extra = s.Answer, s.Ns, s.Extra
+	}
+	return m.Pack()
+}
+
+// FromPacked returns the sections of a DNS message in wire format that was created using ToPacked.
+func FromPacked(bs []byte) (*Sections, error) {
+	if len(bs) == 0 {
+		return &Sections{}, nil
+	}
+	var m dns.Msg
+	if err := m.Unpack(bs); err != nil {
+		return nil, err
+	}
+	return &Sections{Answer: m.Answer, Ns: m.Ns, Extra: m.Extra}, nil
+}
+
+// Copy returns a deep copy of the sections. The copy of a nil *Sections is nil.
+func (s *Sections) Copy() *Sections {
+	if s == nil {
+		return nil
+	}
+	return &Sections{Answer: copyRRs(s.Answer), Ns: copyRRs(s.Ns), Extra: copyRRs(s.Extra)}
+}
+
+func copyRRs(rrs RRs) RRs {
+	if len(rrs) == 0 {
+		return rrs
+	}
+	cp := make(RRs, len(rrs))
+	for i, rr := range rrs {
+		cp[i] = dns.Copy(rr)
+	}
+	return cp
+}
+
+// AddressRRs returns A or AAAA records, depending on the given record type, for those of the given
+// IPs that are of the corresponding IP family.
+func AddressRRs(name string, qType uint16, ips []net.IP, ttl uint32) RRs {
+	rrs := RRs{}
+	for _, ip := range ips {
+		ip4 := ip.To4()
+		switch {
+		case qType == dns.TypeA && ip4 != nil:
+			rrs = append(rrs, &dns.A{
+				Hdr: dns.RR_Header{Name: name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: ttl},
+				A:   ip4,
+			})
+		case qType == dns.TypeAAAA && ip4 == nil:
+			rrs = append(rrs, &dns.AAAA{
+				Hdr:  dns.RR_Header{Name: name, Rrtype: dns.TypeAAAA, Class: dns.ClassINET, Ttl: ttl},
+				AAAA: ip,
+			})
+		}
+	}
+	return rrs
+}
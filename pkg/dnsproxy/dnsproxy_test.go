@@ -0,0 +1,128 @@
+package dnsproxy
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLookup(t *testing.T) {
+	const svcName = "kafka.ns.svc.cluster.local."
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	srv := &dns.Server{PacketConn: pc, Handler: dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		q := r.Question[0]
+		if q.Name != svcName {
+			_ = w.WriteMsg(m.SetRcode(r, dns.RcodeNameError))
+			return
+		}
+		m.SetReply(r)
+		if q.Qtype == dns.TypeSRV {
+			m.Answer = append(m.Answer, &dns.SRV{
+				Hdr:    dns.RR_Header{Name: svcName, Rrtype: dns.TypeSRV, Class: dns.ClassINET, Ttl: 30},
+				Port:   9092,
+				Target: "kafka-0." + svcName,
+			})
+			m.Extra = append(m.Extra, &dns.A{
+				Hdr: dns.RR_Header{Name: "kafka-0." + svcName, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 30},
+				A:   net.IP{10, 1, 0, 7},
+			})
+		} else {
+			m.Ns = append(m.Ns, &dns.SOA{
+				Hdr:    dns.RR_Header{Name: "cluster.local.", Rrtype: dns.TypeSOA, Class: dns.ClassINET, Ttl: 30},
+				Ns:     "ns.dns.cluster.local.",
+				Mbox:   "hostmaster.cluster.local.",
+				Minttl: 5,
+			})
+		}
+		if opt := r.IsEdns0(); opt != nil {
+			m.SetEdns0(opt.UDPSize(), false)
+		}
+		_ = w.WriteMsg(m)
+	})}
+	go func() { _ = srv.ActivateAndServe() }()
+	defer func() { _ = srv.Shutdown() }()
+
+	cfg := &dns.ClientConfig{
+		Servers: []string{"127.0.0.1"},
+		Port:    strconv.Itoa(pc.LocalAddr().(*net.UDPAddr).Port),
+		Search:  []string{"ns.svc.cluster.local", "svc.cluster.local", "cluster.local"},
+		Ndots:   5,
+		Timeout: 2,
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	sections, rCode, err := lookup(ctx, cfg, dns.TypeSRV, "kafka")
+	require.NoError(t, err)
+	assert.Equal(t, dns.RcodeSuccess, rCode)
+	require.Len(t, sections.Answer, 1)
+	srv0 := sections.Answer[0].(*dns.SRV)
+	assert.Equal(t, "kafka.", srv0.Hdr.Name)
+	assert.Equal(t, "kafka-0."+svcName, srv0.Target)
+	assert.Equal(t, uint16(9092), srv0.Port)
+
+	// The additional records are forwarded, but not the OPT record of the exchange
+	require.Len(t, sections.Extra, 1)
+	assert.Equal(t, "10.1.0.7", sections.Extra[0].(*dns.A).A.String())
+
+	// The name exists but has no records of the requested type. The SOA record of the authority
+	// section tells for how long that can be cached.
+	sections, rCode, err = lookup(ctx, cfg, dns.TypeTXT, "kafka.ns")
+	require.NoError(t, err)
+	assert.Equal(t, dns.RcodeSuccess, rCode)
+	assert.Empty(t, sections.Answer)
+	require.Len(t, sections.Ns, 1)
+	assert.Equal(t, uint32(5), sections.Ns[0].(*dns.SOA).Minttl)
+
+	sections, rCode, err = lookup(ctx, cfg, dns.TypeSRV, "zookeeper")
+	require.NoError(t, err)
+	assert.Equal(t, dns.RcodeNameError, rCode)
+	assert.Empty(t, sections.Answer)
+}
+
+func TestPacked(t *testing.T) {
+	sections := &Sections{
+		Answer: RRs{
+			&dns.CNAME{Hdr: dns.RR_Header{Name: "db.", Rrtype: dns.TypeCNAME, Class: dns.ClassINET, Ttl: 4}, Target: "postgres.ns.svc.cluster.local."},
+			&dns.TXT{Hdr: dns.RR_Header{Name: "db.", Rrtype: dns.TypeTXT, Class: dns.ClassINET, Ttl: 4}, Txt: []string{"v=1"}},
+		},
+		Ns: RRs{
+			&dns.NS{Hdr: dns.RR_Header{Name: "cluster.local.", Rrtype: dns.TypeNS, Class: dns.ClassINET, Ttl: 4}, Ns: "ns.dns.cluster.local."},
+		},
+		Extra: RRs{
+			&dns.A{Hdr: dns.RR_Header{Name: "ns.dns.cluster.local.", Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 4}, A: net.IP{10, 0, 0, 10}},
+		},
+	}
+	bs, err := sections.ToPacked()
+	require.NoError(t, err)
+	sections2, err := FromPacked(bs)
+	require.NoError(t, err)
+	assertSameRRs := func(rrs, rrs2 RRs) {
+		require.Len(t, rrs2, len(rrs))
+		for i := range rrs {
+			assert.True(t, dns.IsDuplicate(rrs[i], rrs2[i]), rrs2[i].String())
+		}
+	}
+	assertSameRRs(sections.Answer, sections2.Answer)
+	assertSameRRs(sections.Ns, sections2.Ns)
+	assertSameRRs(sections.Extra, sections2.Extra)
+}
+
+func TestAddressRRs(t *testing.T) {
+	ips := []net.IP{net.ParseIP("10.0.0.1"), net.ParseIP("fd00::1")}
+	rrs := AddressRRs("db.", dns.TypeA, ips, 4)
+	require.Len(t, rrs, 1)
+	assert.Equal(t, "10.0.0.1", rrs[0].(*dns.A).A.String())
+	rrs = AddressRRs("db.", dns.TypeAAAA, ips, 4)
+	require.Len(t, rrs, 1)
+	assert.Equal(t, "fd00::1", rrs[0].(*dns.AAAA).AAAA.String())
+	assert.Empty(t, AddressRRs("db.", dns.TypeMX, ips, 4))
+}
@@ -2,6 +2,7 @@ package forwarder
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"io"
 	"net"
@@ -21,6 +22,7 @@ type Interceptor interface {
 	Serve(context.Context, chan<- net.Addr) error
 	SetIntercepting(*manager.InterceptInfo)
 	SetManager(*manager.SessionInfo, manager.ManagerClient, semver.Version)
+	SetTLSConfig(*tls.Config)
 	Target() (string, uint16)
 }
 
@@ -41,6 +43,9 @@ type interceptor struct {
 
 	intercept  *manager.InterceptInfo
 	mgrVersion semver.Version
+
+	// tlsConfig is used when an intercept requests that TLS is terminated by the agent
+	tlsConfig *tls.Config
 }
 
 func NewInterceptor(addr net.Addr, targetHost string, targetPort uint16) Interceptor {
@@ -62,6 +67,12 @@ func (f *interceptor) SetManager(sessionInfo *manager.SessionInfo, manager manag
 	f.mgrVersion = version
 }
 
+func (f *interceptor) SetTLSConfig(tlsConfig *tls.Config) {
+	f.mu.Lock()
+	f.tlsConfig = tlsConfig
+	f.mu.Unlock()
+}
+
 func (f *interceptor) Close() error {
 	f.lCancel()
 	return nil
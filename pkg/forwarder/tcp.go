@@ -2,6 +2,7 @@ package forwarder
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"io"
 	"net"
@@ -91,9 +92,10 @@ func (f *tcp) forwardConn(clientConn *net.TCPConn) error {
 	targetHost := f.targetHost
 	targetPort := f.targetPort
 	intercept := f.intercept
+	tlsConfig := f.tlsConfig
 	f.mu.Unlock()
 	if intercept != nil {
-		return f.interceptConn(ctx, clientConn, intercept)
+		return f.interceptConn(ctx, interceptedConn(clientConn, intercept.Spec, tlsConfig), intercept)
 	}
 
 	targetAddr, err := net.ResolveTCPAddr("tcp", fmt.Sprintf("%s:%d", targetHost, targetPort))
@@ -144,6 +146,19 @@ func (f *tcp) forwardConn(clientConn *net.TCPConn) error {
 	return nil
 }
 
+// interceptedConn returns the connection that the intercepting client reads from and writes to. It is the
+// decrypted stream when the intercept terminates TLS and the given config is non-nil.
+//
+// Connections that aren't intercepted are never decrypted. They reach the app container with the TLS that
+// the client established, so there's nothing to re-encrypt. Since the agent doesn't match requests, there
+// is no case where a decrypted stream must be re-encrypted toward the app container or the workstation.
+func interceptedConn(conn net.Conn, spec *manager.InterceptSpec, tlsConfig *tls.Config) net.Conn {
+	if spec.TerminateTls && tlsConfig != nil {
+		return tls.Server(conn, tlsConfig)
+	}
+	return conn
+}
+
 func (f *interceptor) interceptConn(ctx context.Context, conn net.Conn, iCept *manager.InterceptInfo) error {
 	addr := conn.RemoteAddr()
 	dlog.Infof(ctx, "Accept got connection from %s", addr)
@@ -0,0 +1,96 @@
+package forwarder
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/telepresenceio/telepresence/rpc/v2/manager"
+)
+
+// selfSignedCert returns a certificate for "echo.default" and a pool that trusts it.
+func selfSignedCert(t *testing.T) (tls.Certificate, *x509.CertPool) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "echo.default"},
+		DNSNames:     []string{"echo.default"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, pool
+}
+
+func TestInterceptedConn_TerminateTLS(t *testing.T) {
+	cert, pool := selfSignedCert(t)
+	tlsConfig := &tls.Config{Certificates: []tls.Certificate{cert}}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+
+	received := make(chan string, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			close(received)
+			return
+		}
+		// This is the side that the intercepting client would read from.
+		ic := interceptedConn(conn, &manager.InterceptSpec{TerminateTls: true}, tlsConfig)
+		defer ic.Close()
+		buf := make([]byte, 5)
+		if _, err := io.ReadFull(ic, buf); err != nil {
+			close(received)
+			return
+		}
+		received <- string(buf)
+		_, _ = ic.Write([]byte("world"))
+	}()
+
+	conn, err := tls.Dial("tcp", l.Addr().String(), &tls.Config{RootCAs: pool, ServerName: "echo.default"})
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("hello"))
+	require.NoError(t, err)
+	assert.Equal(t, "hello", <-received)
+
+	reply := make([]byte, 5)
+	_, err = io.ReadFull(conn, reply)
+	require.NoError(t, err)
+	assert.Equal(t, "world", string(reply))
+}
+
+func TestInterceptedConn_PassThrough(t *testing.T) {
+	cert, _ := selfSignedCert(t)
+	tlsConfig := &tls.Config{Certificates: []tls.Certificate{cert}}
+	conn, peer := net.Pipe()
+	defer conn.Close()
+	defer peer.Close()
+
+	// Not requested by the intercept
+	assert.Same(t, conn, interceptedConn(conn, &manager.InterceptSpec{}, tlsConfig))
+
+	// Requested, but the agent has no TLS Secret
+	assert.Same(t, conn, interceptedConn(conn, &manager.InterceptSpec{TerminateTls: true}, nil))
+}
@@ -0,0 +1,45 @@
+// Package glob matches slash separated paths against glob patterns that may contain "**".
+package glob
+
+import (
+	"path"
+	"strings"
+)
+
+// Match reports whether the slash separated name matches the pattern. The pattern uses the
+// syntax of path.Match, extended so that a "**" segment matches zero or more path segments. A
+// pattern without a slash is matched against the last element of the name only.
+func Match(pattern, name string) bool {
+	if !strings.Contains(pattern, "/") {
+		ok, _ := path.Match(pattern, name[strings.LastIndexByte(name, '/')+1:])
+		return ok
+	}
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+// Valid reports whether the pattern is well-formed.
+func Valid(pattern string) bool {
+	_, err := path.Match(strings.ReplaceAll(pattern, "**", "*"), "")
+	return err == nil
+}
+
+func matchSegments(ps, ss []string) bool {
+	for len(ps) > 0 {
+		if ps[0] == "**" {
+			for i := 0; i <= len(ss); i++ {
+				if matchSegments(ps[1:], ss[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(ss) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(ps[0], ss[0]); !ok {
+			return false
+		}
+		ps, ss = ps[1:], ss[1:]
+	}
+	return len(ss) == 0
+}
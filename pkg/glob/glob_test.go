@@ -0,0 +1,36 @@
+package glob
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		match   bool
+	}{
+		{"*.go", "main.go", true},
+		{"*.go", "cmd/server/main.go", true},
+		{"*.go", "main.go.orig", false},
+		{"cmd/*.go", "cmd/main.go", true},
+		{"cmd/*.go", "cmd/server/main.go", false},
+		{"cmd/**/*.go", "cmd/main.go", true},
+		{"cmd/**/*.go", "cmd/server/internal/main.go", true},
+		{"cmd/**/*.go", "pkg/main.go", false},
+		{"**/*.py", "app.py", true},
+		{"**/*.py", "src/app/views.py", true},
+		{"src/**", "src/app/views.py", true},
+		{"src/**", "test/views.py", false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.match, Match(tt.pattern, tt.name), "Match(%q, %q)", tt.pattern, tt.name)
+	}
+}
+
+func TestValid(t *testing.T) {
+	assert.True(t, Valid("src/**/*.go"))
+	assert.False(t, Valid("src/[a-"))
+}
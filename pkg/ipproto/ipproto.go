@@ -8,3 +8,13 @@ const (
 	ICMP   = 1
 	ICMPV6 = 58
 )
+
+// IPv6 extension headers
+const (
+	HOPOPT    = 0
+	IPV6Route = 43
+	IPV6Frag  = 44
+	AH        = 51
+	IPV6NoNxt = 59
+	IPV6Opts  = 60
+)
@@ -0,0 +1,131 @@
+package k8sapi
+
+import (
+	"context"
+	"strings"
+
+	core "k8s.io/api/core/v1"
+	errors2 "k8s.io/apimachinery/pkg/api/errors"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/datawire/dlib/dlog"
+)
+
+// ContainerEnvironment returns the environment that the given container declares in the given namespace,
+// with values from ConfigMaps and Secrets resolved. The keys of the variables that are sourced from
+// Secrets are returned in a separate set. Variables that refer to fields of a pod or to container
+// resources can't be resolved without a running pod, and are omitted, and so are variables that refer
+// to ConfigMaps and Secrets that don't exist or that can't be read.
+func ContainerEnvironment(c context.Context, cn *core.Container, namespace string) (map[string]string, map[string]struct{}) {
+	env := make(map[string]string, len(cn.Env))
+	secretKeys := make(map[string]struct{})
+	cms := make(map[string]map[string]string)
+	secrets := make(map[string]map[string][]byte)
+
+	getConfigMap := func(name string) map[string]string {
+		data, ok := cms[name]
+		if !ok {
+			cm, err := GetK8sInterface(c).CoreV1().ConfigMaps(namespace).Get(c, name, meta.GetOptions{})
+			if err != nil {
+				logRefError(c, "ConfigMap", name, namespace, err)
+			} else {
+				data = cm.Data
+			}
+			cms[name] = data
+		}
+		return data
+	}
+	getSecret := func(name string) map[string][]byte {
+		data, ok := secrets[name]
+		if !ok {
+			s, err := GetK8sInterface(c).CoreV1().Secrets(namespace).Get(c, name, meta.GetOptions{})
+			if err != nil {
+				logRefError(c, "Secret", name, namespace, err)
+			} else {
+				data = s.Data
+			}
+			secrets[name] = data
+		}
+		return data
+	}
+
+	// Variables declared using envFrom have lower precedence than those declared using env.
+	for _, ef := range cn.EnvFrom {
+		switch {
+		case ef.ConfigMapRef != nil:
+			for k, v := range getConfigMap(ef.ConfigMapRef.Name) {
+				env[ef.Prefix+k] = v
+			}
+		case ef.SecretRef != nil:
+			for k, v := range getSecret(ef.SecretRef.Name) {
+				env[ef.Prefix+k] = string(v)
+				secretKeys[ef.Prefix+k] = struct{}{}
+			}
+		}
+	}
+	for _, e := range cn.Env {
+		vf := e.ValueFrom
+		switch {
+		case vf == nil:
+			env[e.Name] = ExpandEnv(e.Value, env)
+		case vf.ConfigMapKeyRef != nil:
+			if v, ok := getConfigMap(vf.ConfigMapKeyRef.Name)[vf.ConfigMapKeyRef.Key]; ok {
+				env[e.Name] = v
+			}
+		case vf.SecretKeyRef != nil:
+			if v, ok := getSecret(vf.SecretKeyRef.Name)[vf.SecretKeyRef.Key]; ok {
+				env[e.Name] = string(v)
+				secretKeys[e.Name] = struct{}{}
+			}
+		case vf.FieldRef != nil && vf.FieldRef.FieldPath == "metadata.namespace":
+			env[e.Name] = namespace
+		default:
+			dlog.Debugf(c, "environment variable %s can't be resolved without a running pod", e.Name)
+		}
+	}
+	return env, secretKeys
+}
+
+func logRefError(c context.Context, kind, name, namespace string, err error) {
+	if errors2.IsNotFound(err) {
+		dlog.Debugf(c, "%s %s.%s not found", kind, name, namespace)
+	} else {
+		dlog.Errorf(c, "unable to get %s %s.%s: %v", kind, name, namespace, err)
+	}
+}
+
+// ExpandEnv replaces references to variables of the form $(NAME) in the given string with the value of
+// the variable in env, the same way as Kubernetes does when it expands the value of an environment
+// variable. References to undefined variables are left as is, and $$ is an escaped $.
+func ExpandEnv(s string, env map[string]string) string {
+	if !strings.Contains(s, "$") {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '$' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		switch s[i+1] {
+		case '$':
+			b.WriteByte('$')
+			i++
+		case '(':
+			if end := strings.IndexByte(s[i+2:], ')'); end >= 0 {
+				name := s[i+2 : i+2+end]
+				if v, ok := env[name]; ok {
+					b.WriteString(v)
+				} else {
+					b.WriteString(s[i : i+3+end])
+				}
+				i += 2 + end
+			} else {
+				b.WriteByte('$')
+			}
+		default:
+			b.WriteByte('$')
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,51 @@
+package k8sapi
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	core "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestContainerEnvironment(t *testing.T) {
+	ki := fake.NewSimpleClientset(
+		&core.ConfigMap{
+			ObjectMeta: meta.ObjectMeta{Name: "config", Namespace: "ns"},
+			Data:       map[string]string{"HOST": "db", "PORT": "5432"},
+		},
+		&core.Secret{
+			ObjectMeta: meta.ObjectMeta{Name: "creds", Namespace: "ns"},
+			Data:       map[string][]byte{"PASSWORD": []byte("s3cr3t")},
+		},
+	)
+	ctx := WithK8sInterface(context.Background(), ki)
+	cn := &core.Container{
+		EnvFrom: []core.EnvFromSource{
+			{ConfigMapRef: &core.ConfigMapEnvSource{LocalObjectReference: core.LocalObjectReference{Name: "config"}}, Prefix: "DB_"},
+			{SecretRef: &core.SecretEnvSource{LocalObjectReference: core.LocalObjectReference{Name: "creds"}}},
+			{ConfigMapRef: &core.ConfigMapEnvSource{LocalObjectReference: core.LocalObjectReference{Name: "missing"}}},
+		},
+		Env: []core.EnvVar{
+			{Name: "DB_PORT", Value: "6543"},
+			{Name: "URL", Value: "postgres://$(DB_HOST):$(DB_PORT)/$(UNKNOWN)$$(DB_HOST)"},
+			{Name: "TOKEN", ValueFrom: &core.EnvVarSource{SecretKeyRef: &core.SecretKeySelector{
+				LocalObjectReference: core.LocalObjectReference{Name: "creds"}, Key: "PASSWORD",
+			}}},
+			{Name: "NS", ValueFrom: &core.EnvVarSource{FieldRef: &core.ObjectFieldSelector{FieldPath: "metadata.namespace"}}},
+			{Name: "POD", ValueFrom: &core.EnvVarSource{FieldRef: &core.ObjectFieldSelector{FieldPath: "metadata.name"}}},
+		},
+	}
+	env, secretKeys := ContainerEnvironment(ctx, cn, "ns")
+	assert.Equal(t, map[string]string{
+		"DB_HOST":  "db",
+		"DB_PORT":  "6543",
+		"PASSWORD": "s3cr3t",
+		"URL":      "postgres://db:6543/$(UNKNOWN)$(DB_HOST)",
+		"TOKEN":    "s3cr3t",
+		"NS":       "ns",
+	}, env)
+	assert.Equal(t, map[string]struct{}{"PASSWORD": {}, "TOKEN": {}}, secretKeys)
+}
@@ -8,6 +8,9 @@ import (
 	"github.com/datawire/dlib/dlog"
 )
 
+// bufferCapacity is the number of log entries retained by the Buffer of the base logger.
+const bufferCapacity = 2000
+
 func MakeBaseLogger(ctx context.Context, logLevel string) context.Context {
 	logrusLogger := logrus.New()
 	logrusFormatter := NewFormatter("2006-01-02 15:04:05.0000")
@@ -15,8 +18,13 @@ func MakeBaseLogger(ctx context.Context, logLevel string) context.Context {
 
 	SetLogrusLevel(logrusLogger, logLevel)
 
+	// Retain recent output so that it can be streamed to clients
+	buffer := NewBuffer(bufferCapacity, logrusFormatter)
+	logrusLogger.AddHook(buffer)
+
 	logger := dlog.WrapLogrus(logrusLogger)
 	dlog.SetFallbackLogger(logger)
 	ctx = dlog.WithLogger(ctx, logger)
+	ctx = WithBuffer(ctx, buffer)
 	return WithLevelSetter(ctx, logrusLogger)
 }
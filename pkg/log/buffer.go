@@ -0,0 +1,129 @@
+package log
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Entry is a formatted line of log output.
+type Entry struct {
+	Time time.Time
+	Text string
+
+	// SessionID is the value of the session_id field of the entry, if any. It identifies the
+	// session that the entry was logged on behalf of.
+	SessionID string
+}
+
+// Buffer is a logrus.Hook that retains the most recent log output of a logger, so that it can be
+// retrieved and followed by other parties. Firing never blocks; a follower that can't keep up will
+// miss entries.
+type Buffer struct {
+	mu        sync.Mutex
+	formatter logrus.Formatter
+	entries   []Entry // ring of retained entries
+	start     int     // index of the oldest entry
+	count     int
+	followers map[chan Entry]struct{}
+}
+
+// NewBuffer returns a Buffer that retains at most capacity entries formatted by the given formatter.
+func NewBuffer(capacity int, formatter logrus.Formatter) *Buffer {
+	return &Buffer{
+		formatter: formatter,
+		entries:   make([]Entry, capacity),
+		followers: make(map[chan Entry]struct{}),
+	}
+}
+
+// Levels implements logrus.Hook
+func (b *Buffer) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire implements logrus.Hook
+func (b *Buffer) Fire(entry *logrus.Entry) error {
+	data, err := b.formatter.Format(entry)
+	if err != nil {
+		return err
+	}
+	e := Entry{Time: entry.Time, Text: strings.TrimSuffix(string(data), "\n")}
+	if id, ok := entry.Data["session_id"].(string); ok {
+		e.SessionID = id
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if n := len(b.entries); n > 0 {
+		if b.count < n {
+			b.entries[(b.start+b.count)%n] = e
+			b.count++
+		} else {
+			b.entries[b.start] = e
+			b.start = (b.start + 1) % n
+		}
+	}
+	for ch := range b.followers {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+	return nil
+}
+
+// Since returns the retained entries that were logged after the given time. A zero time
+// returns all retained entries.
+func (b *Buffer) Since(t time.Time) []Entry {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.unlockedSince(t)
+}
+
+func (b *Buffer) unlockedSince(t time.Time) []Entry {
+	var es []Entry
+	n := len(b.entries)
+	for i := 0; i < b.count; i++ {
+		if e := b.entries[(b.start+i)%n]; e.Time.After(t) {
+			es = append(es, e)
+		}
+	}
+	return es
+}
+
+// Follow returns the retained entries that were logged after the given time, and a channel
+// that receives all entries that are logged after that. The channel is closed when the
+// context is cancelled.
+func (b *Buffer) Follow(ctx context.Context, t time.Time) ([]Entry, <-chan Entry) {
+	ch := make(chan Entry, 256)
+	b.mu.Lock()
+	es := b.unlockedSince(t)
+	b.followers[ch] = struct{}{}
+	b.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		b.mu.Lock()
+		delete(b.followers, ch)
+		b.mu.Unlock()
+		close(ch)
+	}()
+	return es, ch
+}
+
+type bufferContextKey struct{}
+
+// WithBuffer returns a context that holds the given Buffer.
+func WithBuffer(ctx context.Context, b *Buffer) context.Context {
+	return context.WithValue(ctx, bufferContextKey{}, b)
+}
+
+// GetBuffer returns the Buffer of the given context, or nil if no Buffer has been set.
+func GetBuffer(ctx context.Context) *Buffer {
+	b, _ := ctx.Value(bufferContextKey{}).(*Buffer)
+	return b
+}
@@ -0,0 +1,58 @@
+package log
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuffer(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	b := NewBuffer(3, &logrus.TextFormatter{DisableTimestamp: true, DisableColors: true})
+	logger.AddHook(b)
+
+	start := time.Now()
+	for _, msg := range []string{"one", "two", "three", "four"} {
+		logger.Info(msg)
+	}
+	texts := func(es []Entry) []string {
+		ts := make([]string, len(es))
+		for i, e := range es {
+			ts[i] = e.Text
+		}
+		return ts
+	}
+
+	// The oldest entry is dropped
+	assert.Equal(t, []string{`level=info msg=two`, `level=info msg=three`, `level=info msg=four`}, texts(b.Since(time.Time{})))
+	assert.Empty(t, b.Since(time.Now()))
+	assert.Len(t, b.Since(start.Add(-time.Second)), 3)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	es, ch := b.Follow(ctx, time.Now())
+	assert.Empty(t, es)
+	logger.Warn("five")
+	select {
+	case e := <-ch:
+		assert.Equal(t, `level=warning msg=five`, e.Text)
+	case <-time.After(time.Second):
+		t.Fatal("no entry was received")
+	}
+	logger.WithField("session_id", "abc").Info("six")
+	select {
+	case e := <-ch:
+		assert.Equal(t, "abc", e.SessionID)
+	case <-time.After(time.Second):
+		t.Fatal("no entry was received")
+	}
+	cancel()
+	for range ch {
+	}
+	require.Len(t, b.followers, 0)
+}
@@ -0,0 +1,158 @@
+package nfs
+
+import (
+	"os"
+	"time"
+)
+
+// nfsStatus is an nfsstat3 of RFC 1813.
+type nfsStatus uint32
+
+const (
+	nfsOK             = nfsStatus(0)
+	nfsErrPerm        = nfsStatus(1)
+	nfsErrNoEnt       = nfsStatus(2)
+	nfsErrIO          = nfsStatus(5)
+	nfsErrAcces       = nfsStatus(13)
+	nfsErrExist       = nfsStatus(17)
+	nfsErrXDev        = nfsStatus(18)
+	nfsErrNotDir      = nfsStatus(20)
+	nfsErrIsDir       = nfsStatus(21)
+	nfsErrInval       = nfsStatus(22)
+	nfsErrFBig        = nfsStatus(27)
+	nfsErrNoSpc       = nfsStatus(28)
+	nfsErrROFS        = nfsStatus(30)
+	nfsErrNameTooLong = nfsStatus(63)
+	nfsErrNotEmpty    = nfsStatus(66)
+	nfsErrStale       = nfsStatus(70)
+	nfsErrBadHandle   = nfsStatus(10001)
+	nfsErrNotSupp     = nfsStatus(10004)
+	nfsErrServerFault = nfsStatus(10006)
+)
+
+// The ftype3 values of RFC 1813.
+const (
+	typeReg  = 1
+	typeDir  = 2
+	typeBlk  = 3
+	typeChr  = 4
+	typeLnk  = 5
+	typeSock = 6
+	typeFIFO = 7
+)
+
+// sysAttrs are the attributes that os.FileInfo doesn't provide in a portable way.
+type sysAttrs struct {
+	nlink  uint32
+	uid    uint32
+	gid    uint32
+	used   uint64
+	rdev   uint64
+	fsid   uint64
+	fileid uint64
+	atime  time.Time
+	ctime  time.Time
+}
+
+// fsStat is the file system information returned by FSSTAT.
+type fsStat struct {
+	totalBytes uint64
+	freeBytes  uint64
+	availBytes uint64
+	totalFiles uint64
+	freeFiles  uint64
+}
+
+func statFallback(fi os.FileInfo) sysAttrs {
+	return sysAttrs{nlink: 1, atime: fi.ModTime(), ctime: fi.ModTime()}
+}
+
+func fileType(m os.FileMode) uint32 {
+	switch {
+	case m.IsDir():
+		return typeDir
+	case m&os.ModeSymlink != 0:
+		return typeLnk
+	case m&os.ModeNamedPipe != 0:
+		return typeFIFO
+	case m&os.ModeSocket != 0:
+		return typeSock
+	case m&os.ModeCharDevice != 0:
+		return typeChr
+	case m&os.ModeDevice != 0:
+		return typeBlk
+	default:
+		return typeReg
+	}
+}
+
+// unixMode returns the permission bits of the given mode, including setuid, setgid, and sticky.
+func unixMode(m os.FileMode) uint32 {
+	um := uint32(m.Perm())
+	if m&os.ModeSetuid != 0 {
+		um |= 0o4000
+	}
+	if m&os.ModeSetgid != 0 {
+		um |= 0o2000
+	}
+	if m&os.ModeSticky != 0 {
+		um |= 0o1000
+	}
+	return um
+}
+
+func (e *encoder) time(t time.Time) {
+	e.uint32(uint32(t.Unix()))
+	e.uint32(uint32(t.Nanosecond()))
+}
+
+// fattr encodes the fattr3 of the given file.
+func (e *encoder) fattr(fi os.FileInfo, fallbackID uint64) {
+	sa := statSys(fi)
+	if sa.fileid == 0 {
+		sa.fileid = fallbackID
+	}
+	e.uint32(fileType(fi.Mode()))
+	e.uint32(unixMode(fi.Mode()))
+	e.uint32(sa.nlink)
+	e.uint32(sa.uid)
+	e.uint32(sa.gid)
+	e.uint64(uint64(fi.Size()))
+	e.uint64(sa.used)
+	e.uint32(uint32(sa.rdev >> 32))
+	e.uint32(uint32(sa.rdev))
+	e.uint64(sa.fsid)
+	e.uint64(sa.fileid)
+	e.time(sa.atime)
+	e.time(fi.ModTime())
+	e.time(sa.ctime)
+}
+
+// postOpAttr encodes the post_op_attr of the given path. The attributes are omitted when the
+// path cannot be stat'ed.
+func (s *Server) postOpAttr(e *encoder, path string) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		fi, err = os.Lstat(path)
+	}
+	if err != nil {
+		e.bool(false)
+		return
+	}
+	e.bool(true)
+	e.fattr(fi, s.fileID(path))
+}
+
+// wccData encodes wcc_data with no pre-operation attributes.
+func (s *Server) wccData(e *encoder, path string) {
+	e.bool(false)
+	s.postOpAttr(e, path)
+}
+
+// fileID returns the id of the handle of the given path, for use when the platform doesn't
+// provide inode numbers.
+func (s *Server) fileID(path string) uint64 {
+	h := s.handles.handle(path)
+	return uint64(h[8])<<56 | uint64(h[9])<<48 | uint64(h[10])<<40 | uint64(h[11])<<32 |
+		uint64(h[12])<<24 | uint64(h[13])<<16 | uint64(h[14])<<8 | uint64(h[15])
+}
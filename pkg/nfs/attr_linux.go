@@ -0,0 +1,40 @@
+package nfs
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+func statSys(fi os.FileInfo) sysAttrs {
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return statFallback(fi)
+	}
+	return sysAttrs{
+		nlink:  uint32(st.Nlink),
+		uid:    st.Uid,
+		gid:    st.Gid,
+		used:   uint64(st.Blocks) * 512,
+		rdev:   uint64(st.Rdev),
+		fsid:   uint64(st.Dev),
+		fileid: st.Ino,
+		atime:  time.Unix(int64(st.Atim.Sec), int64(st.Atim.Nsec)),
+		ctime:  time.Unix(int64(st.Ctim.Sec), int64(st.Ctim.Nsec)),
+	}
+}
+
+func statFS(path string) (fsStat, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return fsStat{}, err
+	}
+	bs := uint64(st.Bsize)
+	return fsStat{
+		totalBytes: st.Blocks * bs,
+		freeBytes:  st.Bfree * bs,
+		availBytes: st.Bavail * bs,
+		totalFiles: st.Files,
+		freeFiles:  st.Ffree,
+	}, nil
+}
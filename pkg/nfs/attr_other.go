@@ -0,0 +1,16 @@
+//go:build !linux
+// +build !linux
+
+package nfs
+
+import (
+	"os"
+)
+
+func statSys(fi os.FileInfo) sysAttrs {
+	return statFallback(fi)
+}
+
+func statFS(string) (fsStat, error) {
+	return fsStat{}, nil
+}
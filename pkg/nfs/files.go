@@ -0,0 +1,65 @@
+package nfs
+
+import (
+	"io/fs"
+	"os"
+	"time"
+)
+
+// Files opens and modifies the files that a Server serves. The methods behave like the functions of
+// the os package with the same names.
+type Files interface {
+	OpenFile(path string, flag int, perm fs.FileMode) (*os.File, error)
+	Mkdir(path string, perm fs.FileMode) error
+	Symlink(target, path string) error
+	Remove(path string) error
+	Rename(from, to string) error
+	Link(path, link string) error
+	Chmod(path string, mode fs.FileMode) error
+	Chown(path string, uid, gid int) error
+	Truncate(path string, size int64) error
+	Chtimes(path string, atime, mtime time.Time) error
+}
+
+// osFiles is the Files that uses the os package.
+type osFiles struct{}
+
+func (osFiles) OpenFile(path string, flag int, perm fs.FileMode) (*os.File, error) {
+	return os.OpenFile(path, flag, perm)
+}
+
+func (osFiles) Mkdir(path string, perm fs.FileMode) error {
+	return os.Mkdir(path, perm)
+}
+
+func (osFiles) Symlink(target, path string) error {
+	return os.Symlink(target, path)
+}
+
+func (osFiles) Remove(path string) error {
+	return os.Remove(path)
+}
+
+func (osFiles) Rename(from, to string) error {
+	return os.Rename(from, to)
+}
+
+func (osFiles) Link(path, link string) error {
+	return os.Link(path, link)
+}
+
+func (osFiles) Chmod(path string, mode fs.FileMode) error {
+	return os.Chmod(path, mode)
+}
+
+func (osFiles) Chown(path string, uid, gid int) error {
+	return os.Chown(path, uid, gid)
+}
+
+func (osFiles) Truncate(path string, size int64) error {
+	return os.Truncate(path, size)
+}
+
+func (osFiles) Chtimes(path string, atime, mtime time.Time) error {
+	return os.Chtimes(path, atime, mtime)
+}
@@ -0,0 +1,147 @@
+package nfs
+
+import (
+	"container/list"
+	"encoding/binary"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// handleSize is the size of the file handles that the server hands out. A handle consists of
+// the time when the server started, followed by the id of a path.
+const handleSize = 16
+
+// defaultMaxHandles is the max number of handles that a handleTable keeps.
+const defaultMaxHandles = 1 << 16
+
+// handleEntry is a path and the id of its handle.
+type handleEntry struct {
+	id   uint64
+	path string
+}
+
+// handleTable maps file handles to paths and back. Handles become stale when the server restarts,
+// and when the table is full and they are the least recently used ones. The handle of the root
+// is never evicted.
+type handleTable struct {
+	sync.Mutex
+	root   string
+	boot   uint64
+	nextID uint64
+	max    int
+
+	// lru holds the *handleEntry of each handle, the most recently used first
+	lru   *list.List
+	paths map[uint64]*list.Element
+	ids   map[string]*list.Element
+}
+
+func newHandleTable(root string) *handleTable {
+	return &handleTable{
+		root:  filepath.Clean(root),
+		boot:  uint64(time.Now().UnixNano()),
+		max:   defaultMaxHandles,
+		lru:   list.New(),
+		paths: make(map[uint64]*list.Element),
+		ids:   make(map[string]*list.Element),
+	}
+}
+
+// handle returns the file handle of the given path.
+func (t *handleTable) handle(path string) []byte {
+	t.Lock()
+	el, ok := t.ids[path]
+	if ok {
+		t.lru.MoveToFront(el)
+	} else {
+		t.nextID++
+		el = t.lru.PushFront(&handleEntry{id: t.nextID, path: path})
+		t.ids[path] = el
+		t.paths[t.nextID] = el
+		t.evict()
+	}
+	id := el.Value.(*handleEntry).id
+	t.Unlock()
+	h := make([]byte, handleSize)
+	binary.BigEndian.PutUint64(h, t.boot)
+	binary.BigEndian.PutUint64(h[8:], id)
+	return h
+}
+
+// evict removes the least recently used handles until the table is within its bounds.
+// The table must be locked when calling this function.
+func (t *handleTable) evict() {
+	for t.lru.Len() > t.max {
+		el := t.lru.Back()
+		if el.Value.(*handleEntry).path == t.root {
+			t.lru.MoveToFront(el)
+			continue
+		}
+		t.removeElement(el)
+	}
+}
+
+// removeElement removes the given element. The table must be locked when calling this function.
+func (t *handleTable) removeElement(el *list.Element) {
+	he := el.Value.(*handleEntry)
+	t.lru.Remove(el)
+	delete(t.ids, he.path)
+	delete(t.paths, he.id)
+}
+
+// path returns the path of the given file handle.
+func (t *handleTable) path(h []byte) (string, nfsStatus) {
+	if len(h) != handleSize {
+		return "", nfsErrBadHandle
+	}
+	if binary.BigEndian.Uint64(h) != t.boot {
+		return "", nfsErrStale
+	}
+	t.Lock()
+	defer t.Unlock()
+	el, ok := t.paths[binary.BigEndian.Uint64(h[8:])]
+	if !ok {
+		return "", nfsErrStale
+	}
+	t.lru.MoveToFront(el)
+	return el.Value.(*handleEntry).path, nfsOK
+}
+
+// rename moves the handles of the old path, and of all paths below it, to the new path, so
+// that clients that hold them can continue to use them.
+func (t *handleTable) rename(oldPath, newPath string) {
+	t.Lock()
+	defer t.Unlock()
+	if el, ok := t.ids[newPath]; ok {
+		t.removeElement(el)
+	}
+	prefix := oldPath + string(filepath.Separator)
+	var moved []*list.Element
+	for path, el := range t.ids {
+		if path == oldPath || strings.HasPrefix(path, prefix) {
+			moved = append(moved, el)
+		}
+	}
+	for _, el := range moved {
+		he := el.Value.(*handleEntry)
+		delete(t.ids, he.path)
+		he.path = newPath + he.path[len(oldPath):]
+		t.ids[he.path] = el
+	}
+}
+
+// remove forgets the handle of the given path.
+func (t *handleTable) remove(path string) {
+	t.Lock()
+	if el, ok := t.ids[path]; ok {
+		t.removeElement(el)
+	}
+	t.Unlock()
+}
+
+// contains reports whether the given path is the root of the table or a path below it.
+func (t *handleTable) contains(path string) bool {
+	return path == t.root || strings.HasPrefix(path, t.root+string(filepath.Separator)) || t.root == string(filepath.Separator)
+}
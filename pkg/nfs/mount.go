@@ -0,0 +1,75 @@
+package nfs
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"github.com/datawire/dlib/dlog"
+)
+
+// Procedures of the MOUNT protocol, RFC 1813 appendix I.
+const (
+	mountProcNull    = 0
+	mountProcMnt     = 1
+	mountProcDump    = 2
+	mountProcUmnt    = 3
+	mountProcUmntAll = 4
+	mountProcExport  = 5
+)
+
+// Status codes of the MOUNT protocol.
+const (
+	mountOK        = 0
+	mountErrNoEnt  = 2
+	mountErrAcces  = 13
+	mountErrNotDir = 20
+)
+
+const maxPathLen = 1024
+
+func (s *Server) handleMount(ctx context.Context, proc uint32, d *decoder, e *encoder) bool {
+	switch proc {
+	case mountProcNull, mountProcUmntAll:
+	case mountProcMnt:
+		dir := d.string(maxPathLen)
+		if d.err != nil {
+			return true
+		}
+		path := filepath.Clean(filepath.FromSlash(dir))
+		if !filepath.IsAbs(path) || !s.handles.contains(path) {
+			dlog.Infof(ctx, "nfs mount of %q denied, it is not below %s", dir, s.handles.root)
+			e.uint32(mountErrAcces)
+			return true
+		}
+		fi, err := os.Stat(path)
+		if err == nil && s.visible(path) != nfsOK {
+			err = os.ErrNotExist
+		}
+		switch {
+		case err != nil:
+			dlog.Infof(ctx, "nfs mount of %q failed: %v", dir, err)
+			e.uint32(mountErrNoEnt)
+		case !fi.IsDir():
+			e.uint32(mountErrNotDir)
+		default:
+			dlog.Infof(ctx, "nfs mount of %q", dir)
+			e.uint32(mountOK)
+			e.opaque(s.handles.handle(path))
+			e.uint32(1) // one auth flavor
+			e.uint32(authUnix)
+		}
+	case mountProcDump:
+		e.bool(false) // no mounts are listed
+	case mountProcUmnt:
+		d.string(maxPathLen)
+	case mountProcExport:
+		e.bool(true)
+		e.string(filepath.ToSlash(s.handles.root))
+		e.bool(false) // no groups
+		e.bool(false) // no more exports
+	default:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,951 @@
+package nfs
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Procedures of the NFSv3 protocol.
+const (
+	procNull        = 0
+	procGetAttr     = 1
+	procSetAttr     = 2
+	procLookup      = 3
+	procAccess      = 4
+	procReadlink    = 5
+	procRead        = 6
+	procWrite       = 7
+	procCreate      = 8
+	procMkdir       = 9
+	procSymlink     = 10
+	procMknod       = 11
+	procRemove      = 12
+	procRmdir       = 13
+	procRename      = 14
+	procLink        = 15
+	procReaddir     = 16
+	procReaddirPlus = 17
+	procFSStat      = 18
+	procFSInfo      = 19
+	procPathConf    = 20
+	procCommit      = 21
+)
+
+const (
+	// maxIO is the max number of bytes transferred by one READ or WRITE.
+	maxIO = 1 << 20
+
+	// prefIO is the preferred number of bytes transferred by one READ or WRITE.
+	prefIO = 64 << 10
+
+	maxFHSize  = 64
+	maxNameLen = 255
+
+	accessModify = 0x04
+	accessExtend = 0x08
+	accessDelete = 0x10
+
+	stableUnstable = 0
+	stableFileSync = 2
+
+	createUnchecked = 0
+	createGuarded   = 1
+	createExclusive = 2
+
+	timeDontChange = 0
+	timeServer     = 1
+	timeClient     = 2
+
+	// FSF3_LINK | FSF3_SYMLINK | FSF3_HOMOGENEOUS | FSF3_CANSETTIME
+	fsProperties = 0x1b
+)
+
+// sattr is a decoded sattr3.
+type sattr struct {
+	mode     *uint32
+	uid      *uint32
+	gid      *uint32
+	size     *uint64
+	atimeHow uint32
+	atime    time.Time
+	mtimeHow uint32
+	mtime    time.Time
+}
+
+func (d *decoder) sattr() *sattr {
+	sa := &sattr{}
+	if d.bool() {
+		v := d.uint32()
+		sa.mode = &v
+	}
+	if d.bool() {
+		v := d.uint32()
+		sa.uid = &v
+	}
+	if d.bool() {
+		v := d.uint32()
+		sa.gid = &v
+	}
+	if d.bool() {
+		v := d.uint64()
+		sa.size = &v
+	}
+	if sa.atimeHow = d.uint32(); sa.atimeHow == timeClient {
+		sa.atime = d.time()
+	}
+	if sa.mtimeHow = d.uint32(); sa.mtimeHow == timeClient {
+		sa.mtime = d.time()
+	}
+	return sa
+}
+
+func (d *decoder) time() time.Time {
+	sec := d.uint32()
+	nsec := d.uint32()
+	return time.Unix(int64(sec), int64(nsec))
+}
+
+// apply applies the attributes to the file at the given path.
+func (sa *sattr) apply(files Files, path string) error {
+	if sa.mode != nil {
+		if err := files.Chmod(path, fs.FileMode(*sa.mode&0o777)|modeBits(*sa.mode)); err != nil {
+			return err
+		}
+	}
+	if sa.uid != nil || sa.gid != nil {
+		uid, gid := -1, -1
+		if sa.uid != nil {
+			uid = int(*sa.uid)
+		}
+		if sa.gid != nil {
+			gid = int(*sa.gid)
+		}
+		if err := files.Chown(path, uid, gid); err != nil {
+			return err
+		}
+	}
+	if sa.size != nil {
+		if err := files.Truncate(path, int64(*sa.size)); err != nil {
+			return err
+		}
+	}
+	if sa.atimeHow != timeDontChange || sa.mtimeHow != timeDontChange {
+		fi, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		atime, mtime := statSys(fi).atime, fi.ModTime()
+		switch sa.atimeHow {
+		case timeServer:
+			atime = now
+		case timeClient:
+			atime = sa.atime
+		}
+		switch sa.mtimeHow {
+		case timeServer:
+			mtime = now
+		case timeClient:
+			mtime = sa.mtime
+		}
+		if err = files.Chtimes(path, atime, mtime); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// modeBits converts the setuid, setgid, and sticky bits of a unix mode to an fs.FileMode.
+func modeBits(m uint32) fs.FileMode {
+	var fm fs.FileMode
+	if m&0o4000 != 0 {
+		fm |= fs.ModeSetuid
+	}
+	if m&0o2000 != 0 {
+		fm |= fs.ModeSetgid
+	}
+	if m&0o1000 != 0 {
+		fm |= fs.ModeSticky
+	}
+	return fm
+}
+
+// statusOf returns the nfsstat3 that corresponds to the given error.
+func statusOf(err error) nfsStatus {
+	if err == nil {
+		return nfsOK
+	}
+	var errno syscall.Errno
+	if errors.As(err, &errno) {
+		switch errno {
+		case syscall.EPERM:
+			return nfsErrPerm
+		case syscall.ENOENT:
+			return nfsErrNoEnt
+		case syscall.EACCES:
+			return nfsErrAcces
+		case syscall.EEXIST:
+			return nfsErrExist
+		case syscall.EXDEV:
+			return nfsErrXDev
+		case syscall.ENOTDIR:
+			return nfsErrNotDir
+		case syscall.EISDIR:
+			return nfsErrIsDir
+		case syscall.EINVAL:
+			return nfsErrInval
+		case syscall.EFBIG:
+			return nfsErrFBig
+		case syscall.ENOSPC:
+			return nfsErrNoSpc
+		case syscall.EROFS:
+			return nfsErrROFS
+		case syscall.ENAMETOOLONG:
+			return nfsErrNameTooLong
+		case syscall.ENOTEMPTY:
+			return nfsErrNotEmpty
+		}
+	}
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return nfsErrNoEnt
+	case errors.Is(err, fs.ErrExist):
+		return nfsErrExist
+	case errors.Is(err, fs.ErrPermission):
+		return nfsErrAcces
+	}
+	return nfsErrIO
+}
+
+// stat returns the file info of the given path, following symbolic links unless they are dangling.
+func stat(path string) (os.FileInfo, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		if lfi, lerr := os.Lstat(path); lerr == nil {
+			return lfi, nil
+		}
+	}
+	return fi, err
+}
+
+// fail encodes a failed status followed by n absent attributes.
+func fail(e *encoder, st nfsStatus, n int) {
+	e.uint32(uint32(st))
+	for i := 0; i < n; i++ {
+		e.bool(false)
+	}
+}
+
+// resolve decodes a file handle and returns its path.
+func (s *Server) resolve(d *decoder) (string, nfsStatus) {
+	h := d.opaque(maxFHSize)
+	if d.err != nil {
+		return "", nfsErrBadHandle
+	}
+	path, st := s.handles.path(h)
+	if st == nfsOK {
+		st = s.visible(path)
+	}
+	return path, st
+}
+
+// visible returns nfsOK if the Check permits that the given path is accessed.
+func (s *Server) visible(path string) nfsStatus {
+	if s.Check == nil {
+		return nfsOK
+	}
+	return statusOf(s.Check(path, false))
+}
+
+// writable returns nfsOK if the given path can be modified.
+func (s *Server) writable(path string) nfsStatus {
+	switch {
+	case s.ReadOnly:
+		return nfsErrROFS
+	case s.Check == nil:
+		return nfsOK
+	}
+	return statusOf(s.Check(path, true))
+}
+
+// dirOp decodes diropargs3 and returns the paths of the directory and of the named entry.
+func (s *Server) dirOp(d *decoder) (string, string, nfsStatus) {
+	dir, st := s.resolve(d)
+	name := d.string(maxPathLen)
+	if d.err != nil || st != nfsOK {
+		return dir, "", st
+	}
+	switch {
+	case len(name) > maxNameLen:
+		return dir, "", nfsErrNameTooLong
+	case name == "" || strings.ContainsAny(name, "/\x00") || filepath.Base(name) != name:
+		return dir, "", nfsErrInval
+	case name == ".":
+		return dir, dir, nfsOK
+	case name == "..":
+		if dir == s.handles.root {
+			return dir, dir, nfsOK
+		}
+		return dir, filepath.Dir(dir), nfsOK
+	}
+	path := filepath.Join(dir, name)
+	return dir, path, s.visible(path)
+}
+
+func (s *Server) handleNFS(_ context.Context, proc uint32, d *decoder, e *encoder) bool {
+	switch proc {
+	case procNull:
+	case procGetAttr:
+		s.getAttr(d, e)
+	case procSetAttr:
+		s.setAttr(d, e)
+	case procLookup:
+		s.lookup(d, e)
+	case procAccess:
+		s.access(d, e)
+	case procReadlink:
+		s.readlink(d, e)
+	case procRead:
+		s.read(d, e)
+	case procWrite:
+		s.write(d, e)
+	case procCreate:
+		s.create(d, e)
+	case procMkdir:
+		s.mkdir(d, e)
+	case procSymlink:
+		s.symlink(d, e)
+	case procMknod:
+		fail(e, nfsErrNotSupp, 2)
+	case procRemove:
+		s.remove(d, e, false)
+	case procRmdir:
+		s.remove(d, e, true)
+	case procRename:
+		s.rename(d, e)
+	case procLink:
+		s.link(d, e)
+	case procReaddir:
+		s.readdir(d, e, false)
+	case procReaddirPlus:
+		s.readdir(d, e, true)
+	case procFSStat:
+		s.fsStat(d, e)
+	case procFSInfo:
+		s.fsInfo(d, e)
+	case procPathConf:
+		s.pathConf(d, e)
+	case procCommit:
+		s.commit(d, e)
+	default:
+		return false
+	}
+	return true
+}
+
+func (s *Server) getAttr(d *decoder, e *encoder) {
+	path, st := s.resolve(d)
+	if st != nfsOK {
+		fail(e, st, 0)
+		return
+	}
+	fi, err := stat(path)
+	if err != nil {
+		fail(e, statusOf(err), 0)
+		return
+	}
+	e.uint32(uint32(nfsOK))
+	e.fattr(fi, s.fileID(path))
+}
+
+func (s *Server) setAttr(d *decoder, e *encoder) {
+	path, st := s.resolve(d)
+	sa := d.sattr()
+	if d.bool() { // guard
+		d.time()
+	}
+	switch {
+	case d.err != nil:
+		return
+	case st != nfsOK:
+		fail(e, st, 2)
+		return
+	}
+	if st = s.writable(path); st != nfsOK {
+		fail(e, st, 2)
+		return
+	}
+	e.uint32(uint32(statusOf(sa.apply(s.files(), path))))
+	s.wccData(e, path)
+}
+
+func (s *Server) lookup(d *decoder, e *encoder) {
+	dir, path, st := s.dirOp(d)
+	if d.err != nil {
+		return
+	}
+	if st == nfsOK {
+		_, err := stat(path)
+		st = statusOf(err)
+	}
+	if st != nfsOK {
+		e.uint32(uint32(st))
+		s.postOpAttr(e, dir)
+		return
+	}
+	e.uint32(uint32(nfsOK))
+	e.opaque(s.handles.handle(path))
+	s.postOpAttr(e, path)
+	s.postOpAttr(e, dir)
+}
+
+func (s *Server) access(d *decoder, e *encoder) {
+	path, st := s.resolve(d)
+	access := d.uint32()
+	if d.err != nil {
+		return
+	}
+	if st != nfsOK {
+		fail(e, st, 1)
+		return
+	}
+	if s.writable(path) != nfsOK {
+		access &^= accessModify | accessExtend | accessDelete
+	}
+	e.uint32(uint32(nfsOK))
+	s.postOpAttr(e, path)
+	e.uint32(access)
+}
+
+func (s *Server) readlink(d *decoder, e *encoder) {
+	path, st := s.resolve(d)
+	if st != nfsOK {
+		fail(e, st, 1)
+		return
+	}
+	target, err := os.Readlink(path)
+	if err != nil {
+		e.uint32(uint32(statusOf(err)))
+		s.postOpAttr(e, path)
+		return
+	}
+	e.uint32(uint32(nfsOK))
+	s.postOpAttr(e, path)
+	e.string(target)
+}
+
+func (s *Server) read(d *decoder, e *encoder) {
+	path, st := s.resolve(d)
+	offset := d.uint64()
+	count := d.uint32()
+	if d.err != nil {
+		return
+	}
+	if st != nfsOK {
+		fail(e, st, 1)
+		return
+	}
+	if count > maxIO {
+		count = maxIO
+	}
+	data := make([]byte, count)
+	n, err := func() (int, error) {
+		f, err := s.files().OpenFile(path, os.O_RDONLY, 0)
+		if err != nil {
+			return 0, err
+		}
+		defer f.Close()
+		return f.ReadAt(data, int64(offset))
+	}()
+	eof := errors.Is(err, io.EOF)
+	if err != nil && !eof {
+		e.uint32(uint32(statusOf(err)))
+		s.postOpAttr(e, path)
+		return
+	}
+	e.uint32(uint32(nfsOK))
+	s.postOpAttr(e, path)
+	e.uint32(uint32(n))
+	e.bool(eof)
+	e.opaque(data[:n])
+}
+
+func (s *Server) write(d *decoder, e *encoder) {
+	path, st := s.resolve(d)
+	offset := d.uint64()
+	d.uint32() // count, the length of the data is used instead
+	stable := d.uint32()
+	data := d.opaque(maxIO)
+	switch {
+	case d.err != nil:
+		return
+	case st != nfsOK:
+		fail(e, st, 2)
+		return
+	}
+	if st = s.writable(path); st != nfsOK {
+		fail(e, st, 2)
+		return
+	}
+	err := func() error {
+		f, err := s.files().OpenFile(path, os.O_WRONLY, 0)
+		if err != nil {
+			return err
+		}
+		if _, err = f.WriteAt(data, int64(offset)); err == nil && stable != stableUnstable {
+			err = f.Sync()
+		}
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		return err
+	}()
+	if err != nil {
+		e.uint32(uint32(statusOf(err)))
+		s.wccData(e, path)
+		return
+	}
+	if stable != stableUnstable {
+		stable = stableFileSync
+	}
+	e.uint32(uint32(nfsOK))
+	s.wccData(e, path)
+	e.uint32(uint32(len(data)))
+	e.uint32(stable)
+	e.fixed(s.verf[:])
+}
+
+// created encodes the reply of a successful CREATE, MKDIR, or SYMLINK.
+func (s *Server) created(e *encoder, dir, path string) {
+	e.uint32(uint32(nfsOK))
+	e.bool(true)
+	e.opaque(s.handles.handle(path))
+	s.postOpAttr(e, path)
+	s.wccData(e, dir)
+}
+
+// failDir encodes a failed status followed by the wcc_data of a directory.
+func (s *Server) failDir(e *encoder, st nfsStatus, dir string) {
+	e.uint32(uint32(st))
+	if dir == "" {
+		e.bool(false)
+		e.bool(false)
+	} else {
+		s.wccData(e, dir)
+	}
+}
+
+func (s *Server) create(d *decoder, e *encoder) {
+	dir, path, st := s.dirOp(d)
+	how := d.uint32()
+	var sa *sattr
+	var verf []byte
+	if how == createExclusive {
+		verf = d.fixed(8)
+	} else {
+		sa = d.sattr()
+	}
+	switch {
+	case d.err != nil:
+		return
+	case st != nfsOK:
+		s.failDir(e, st, dir)
+		return
+	}
+	if st = s.writable(path); st != nfsOK {
+		s.failDir(e, st, dir)
+		return
+	}
+
+	flags := os.O_WRONLY | os.O_CREATE
+	if how != createUnchecked {
+		flags |= os.O_EXCL
+	}
+	mode := fs.FileMode(0o644)
+	if sa != nil && sa.mode != nil {
+		mode = fs.FileMode(*sa.mode & 0o777)
+	}
+	f, err := s.files().OpenFile(path, flags, mode)
+	if err == nil {
+		err = f.Close()
+	}
+	if how == createExclusive {
+		// The verifier is stored in the mtime so that a retransmitted request succeeds.
+		vt := time.Unix(int64(binary.BigEndian.Uint32(verf)), int64(binary.BigEndian.Uint32(verf[4:])%1e9))
+		if err == nil {
+			err = s.files().Chtimes(path, vt, vt)
+		} else if errors.Is(err, fs.ErrExist) {
+			if fi, serr := os.Stat(path); serr == nil && fi.ModTime().Equal(vt) {
+				err = nil
+			}
+		}
+	} else if err == nil {
+		sa.mode = nil
+		err = sa.apply(s.files(), path)
+	}
+	if err != nil {
+		s.failDir(e, statusOf(err), dir)
+		return
+	}
+	s.created(e, dir, path)
+}
+
+func (s *Server) mkdir(d *decoder, e *encoder) {
+	dir, path, st := s.dirOp(d)
+	sa := d.sattr()
+	switch {
+	case d.err != nil:
+		return
+	case st != nfsOK:
+		s.failDir(e, st, dir)
+		return
+	}
+	if st = s.writable(path); st != nfsOK {
+		s.failDir(e, st, dir)
+		return
+	}
+	mode := fs.FileMode(0o755)
+	if sa.mode != nil {
+		mode = fs.FileMode(*sa.mode & 0o777)
+		sa.mode = nil
+	}
+	err := s.files().Mkdir(path, mode)
+	if err == nil {
+		err = sa.apply(s.files(), path)
+	}
+	if err != nil {
+		s.failDir(e, statusOf(err), dir)
+		return
+	}
+	s.created(e, dir, path)
+}
+
+func (s *Server) symlink(d *decoder, e *encoder) {
+	dir, path, st := s.dirOp(d)
+	d.sattr() // symbolic links have no attributes of their own
+	target := d.string(maxPathLen)
+	switch {
+	case d.err != nil:
+		return
+	case st != nfsOK:
+		s.failDir(e, st, dir)
+		return
+	}
+	if st = s.writable(path); st != nfsOK {
+		s.failDir(e, st, dir)
+		return
+	}
+	// The target is checked like any other path, so that the link can't be used to reach what
+	// the Check denies, neither through this server nor through others that serve the same files.
+	if st = s.linkTarget(dir, target); st != nfsOK {
+		s.failDir(e, st, dir)
+		return
+	}
+	if err := s.files().Symlink(target, path); err != nil {
+		s.failDir(e, statusOf(err), dir)
+		return
+	}
+	s.created(e, dir, path)
+}
+
+// linkTarget returns nfsOK if a symbolic link in the given directory may point to the given target.
+func (s *Server) linkTarget(dir, target string) nfsStatus {
+	if target == "" || strings.ContainsRune(target, 0) {
+		return nfsErrInval
+	}
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(dir, target)
+	}
+	if !s.handles.contains(filepath.Clean(target)) {
+		return nfsErrAcces
+	}
+	return s.visible(target)
+}
+
+func (s *Server) remove(d *decoder, e *encoder, isDir bool) {
+	dir, path, st := s.dirOp(d)
+	switch {
+	case d.err != nil:
+		return
+	case st != nfsOK:
+		s.failDir(e, st, dir)
+		return
+	}
+	if st = s.writable(path); st != nfsOK {
+		s.failDir(e, st, dir)
+		return
+	}
+	fi, err := os.Lstat(path)
+	if err == nil {
+		switch {
+		case isDir && !fi.IsDir():
+			err = syscall.ENOTDIR
+		case !isDir && fi.IsDir():
+			err = syscall.EISDIR
+		default:
+			err = s.files().Remove(path)
+		}
+	}
+	if err == nil {
+		s.handles.remove(path)
+	}
+	e.uint32(uint32(statusOf(err)))
+	s.wccData(e, dir)
+}
+
+func (s *Server) rename(d *decoder, e *encoder) {
+	fromDir, from, st := s.dirOp(d)
+	toDir, to, toSt := s.dirOp(d)
+	if d.err != nil {
+		return
+	}
+	if st == nfsOK {
+		st = toSt
+	}
+	if st == nfsOK {
+		if st = s.writable(from); st == nfsOK {
+			st = s.writable(to)
+		}
+	}
+	if st == nfsOK {
+		if st = statusOf(s.files().Rename(from, to)); st == nfsOK {
+			s.handles.rename(from, to)
+		}
+	}
+	e.uint32(uint32(st))
+	s.wccData(e, fromDir)
+	s.wccData(e, toDir)
+}
+
+func (s *Server) link(d *decoder, e *encoder) {
+	path, st := s.resolve(d)
+	dir, link, dst := s.dirOp(d)
+	if d.err != nil {
+		return
+	}
+	if st == nfsOK {
+		st = dst
+	}
+	if st == nfsOK {
+		st = s.writable(link)
+	}
+	if st == nfsOK {
+		st = statusOf(s.files().Link(path, link))
+	}
+	e.uint32(uint32(st))
+	if path == "" {
+		e.bool(false)
+	} else {
+		s.postOpAttr(e, path)
+	}
+	if dir == "" {
+		e.bool(false)
+		e.bool(false)
+	} else {
+		s.wccData(e, dir)
+	}
+}
+
+// readdir serves READDIR, and READDIRPLUS when plus is true. The cookie of an entry is its
+// index in the sorted directory listing, which starts with "." and "..", plus one.
+func (s *Server) readdir(d *decoder, e *encoder, plus bool) {
+	dir, st := s.resolve(d)
+	cookie := d.uint64()
+	d.fixed(8) // cookie verifier, zero is always used
+	dirCount := d.uint32()
+	maxCount := dirCount
+	if plus {
+		maxCount = d.uint32()
+	}
+	if d.err != nil {
+		return
+	}
+	if st != nfsOK {
+		fail(e, st, 1)
+		return
+	}
+	entries, err := func() ([]fs.DirEntry, error) {
+		f, err := s.files().OpenFile(dir, os.O_RDONLY, 0)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		entries, err := f.ReadDir(-1)
+		sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+		return entries, err
+	}()
+	if err != nil {
+		e.uint32(uint32(statusOf(err)))
+		s.postOpAttr(e, dir)
+		return
+	}
+	names := make([]string, 0, len(entries)+2)
+	names = append(names, ".", "..")
+	for _, de := range entries {
+		names = append(names, de.Name())
+	}
+
+	e.uint32(uint32(nfsOK))
+	s.postOpAttr(e, dir)
+	e.uint64(0) // cookie verifier
+
+	// The dirCount limits the size of the fileids, names, and cookies, and the maxCount limits
+	// the size of the whole reply.
+	total := len(e.buf) + 8 // room for the end of list marker and the eof flag
+	dirBytes := 0
+	eof := true
+	for i := int(cookie); i < len(names); i++ {
+		name := names[i]
+		var path string
+		switch name {
+		case ".":
+			path = dir
+		case "..":
+			if dir == s.handles.root {
+				path = dir
+			} else {
+				path = filepath.Dir(dir)
+			}
+		default:
+			path = filepath.Join(dir, name)
+			if s.visible(path) != nfsOK {
+				continue
+			}
+		}
+
+		fi, err := stat(path)
+		if err != nil {
+			// Removed after the directory was read
+			continue
+		}
+		mark := len(e.buf)
+		e.bool(true)
+		e.uint64(s.entryID(fi, path))
+		e.string(name)
+		e.uint64(uint64(i + 1))
+		if plus {
+			e.bool(true)
+			e.fattr(fi, s.fileID(path))
+			e.bool(true)
+			e.opaque(s.handles.handle(path))
+		}
+		dirBytes += 24 + (len(name)+3)&^3
+		total += len(e.buf) - mark
+		if dirBytes > int(dirCount) || total > int(maxCount) {
+			e.buf = e.buf[:mark]
+			eof = false
+			break
+		}
+	}
+	e.bool(false)
+	e.bool(eof)
+}
+
+func (s *Server) entryID(fi os.FileInfo, path string) uint64 {
+	if id := statSys(fi).fileid; id != 0 {
+		return id
+	}
+	return s.fileID(path)
+}
+
+func (s *Server) fsStat(d *decoder, e *encoder) {
+	path, st := s.resolve(d)
+	if st != nfsOK {
+		fail(e, st, 1)
+		return
+	}
+	fst, err := statFS(path)
+	if err != nil {
+		e.uint32(uint32(statusOf(err)))
+		s.postOpAttr(e, path)
+		return
+	}
+	e.uint32(uint32(nfsOK))
+	s.postOpAttr(e, path)
+	e.uint64(fst.totalBytes)
+	e.uint64(fst.freeBytes)
+	e.uint64(fst.availBytes)
+	e.uint64(fst.totalFiles)
+	e.uint64(fst.freeFiles)
+	e.uint64(fst.freeFiles)
+	e.uint32(0) // invarsec
+}
+
+func (s *Server) fsInfo(d *decoder, e *encoder) {
+	path, st := s.resolve(d)
+	if st != nfsOK {
+		fail(e, st, 1)
+		return
+	}
+	e.uint32(uint32(nfsOK))
+	s.postOpAttr(e, path)
+	e.uint32(maxIO)  // rtmax
+	e.uint32(prefIO) // rtpref
+	e.uint32(4096)   // rtmult
+	e.uint32(maxIO)  // wtmax
+	e.uint32(prefIO) // wtpref
+	e.uint32(4096)   // wtmult
+	e.uint32(prefIO) // dtpref
+	e.uint64(1<<63 - 1)
+	e.uint32(0) // time_delta seconds
+	e.uint32(1) // time_delta nanoseconds
+	e.uint32(fsProperties)
+}
+
+func (s *Server) pathConf(d *decoder, e *encoder) {
+	path, st := s.resolve(d)
+	if st != nfsOK {
+		fail(e, st, 1)
+		return
+	}
+	e.uint32(uint32(nfsOK))
+	s.postOpAttr(e, path)
+	e.uint32(1 << 16)    // linkmax
+	e.uint32(maxNameLen) // name_max
+	e.bool(true)         // no_trunc
+	e.bool(true)         // chown_restricted
+	e.bool(false)        // case_insensitive
+	e.bool(true)         // case_preserving
+}
+
+func (s *Server) commit(d *decoder, e *encoder) {
+	path, st := s.resolve(d)
+	d.uint64() // offset
+	d.uint32() // count
+	switch {
+	case d.err != nil:
+		return
+	case st != nfsOK:
+		fail(e, st, 2)
+		return
+	}
+	if st = s.writable(path); st != nfsOK {
+		fail(e, st, 2)
+		return
+	}
+	err := func() error {
+		f, err := s.files().OpenFile(path, os.O_WRONLY, 0)
+		if err != nil {
+			return err
+		}
+		err = f.Sync()
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		return err
+	}()
+	e.uint32(uint32(statusOf(err)))
+	s.wccData(e, path)
+	if err == nil {
+		e.fixed(s.verf[:])
+	}
+}
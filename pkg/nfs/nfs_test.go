@@ -0,0 +1,340 @@
+package nfs
+
+import (
+	"bufio"
+	"encoding/binary"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/datawire/dlib/dlog"
+)
+
+type testClient struct {
+	t    *testing.T
+	conn net.Conn
+	r    *bufio.Reader
+	xid  uint32
+}
+
+func startServer(t *testing.T, s *Server) *testClient {
+	ctx := dlog.NewTestContext(t, false)
+	cc, sc := net.Pipe()
+	go func() { _ = s.ServeConn(ctx, sc) }()
+	t.Cleanup(func() { _ = cc.Close() })
+	return &testClient{t: t, conn: cc, r: bufio.NewReader(cc)}
+}
+
+// call sends a call and returns a decoder that is positioned after the accept status.
+func (c *testClient) call(prog, proc uint32, args func(e *encoder)) *decoder {
+	c.t.Helper()
+	c.xid++
+	e := &encoder{buf: make([]byte, 4)}
+	e.uint32(c.xid)
+	e.uint32(msgCall)
+	e.uint32(rpcVersion)
+	e.uint32(prog)
+	e.uint32(3)
+	e.uint32(proc)
+	e.uint32(authUnix)
+	e.opaque(make([]byte, 20))
+	e.uint32(authNone)
+	e.opaque(nil)
+	if args != nil {
+		args(e)
+	}
+	_, err := c.conn.Write(record(e))
+	require.NoError(c.t, err)
+
+	rec, err := readRecord(c.r)
+	require.NoError(c.t, err)
+	d := &decoder{buf: rec}
+	require.Equal(c.t, c.xid, d.uint32())
+	require.Equal(c.t, uint32(msgReply), d.uint32())
+	require.Equal(c.t, uint32(replyAccepted), d.uint32())
+	d.uint32() // verifier flavor
+	d.opaque(400)
+	require.Equal(c.t, uint32(acceptSuccess), d.uint32())
+	return d
+}
+
+func (c *testClient) mount(dir string) []byte {
+	c.t.Helper()
+	d := c.call(progMount, mountProcMnt, func(e *encoder) { e.string(dir) })
+	require.Equal(c.t, uint32(mountOK), d.uint32())
+	return d.opaque(maxFHSize)
+}
+
+// skipPostOpAttr skips a post_op_attr and returns the size from its attributes.
+func skipPostOpAttr(d *decoder) (size uint64) {
+	if d.bool() {
+		d.fixed(20)
+		size = d.uint64()
+		d.fixed(84 - 28)
+	}
+	return size
+}
+
+func diropArgs(fh []byte, name string) func(e *encoder) {
+	return func(e *encoder) {
+		e.opaque(fh)
+		e.string(name)
+	}
+}
+
+func TestServer(t *testing.T) {
+	root := t.TempDir()
+	export := filepath.Join(root, "app")
+	require.NoError(t, os.MkdirAll(filepath.Join(export, "conf"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(export, "conf", "app.yaml"), []byte("port: 8080\n"), 0o644))
+
+	c := startServer(t, NewServer(root, false))
+
+	// Mounts outside the root are denied
+	d := c.call(progMount, mountProcMnt, func(e *encoder) { e.string("/etc") })
+	assert.Equal(t, uint32(mountErrAcces), d.uint32())
+	fh := c.mount(export)
+
+	// LOOKUP and READ
+	d = c.call(progNFS, procLookup, diropArgs(fh, "conf"))
+	require.Equal(t, uint32(nfsOK), d.uint32())
+	confFH := d.opaque(maxFHSize)
+	d = c.call(progNFS, procLookup, diropArgs(confFH, "app.yaml"))
+	require.Equal(t, uint32(nfsOK), d.uint32())
+	fileFH := d.opaque(maxFHSize)
+	assert.Equal(t, uint64(11), skipPostOpAttr(d))
+
+	d = c.call(progNFS, procRead, func(e *encoder) {
+		e.opaque(fileFH)
+		e.uint64(6)
+		e.uint32(100)
+	})
+	require.Equal(t, uint32(nfsOK), d.uint32())
+	skipPostOpAttr(d)
+	assert.Equal(t, uint32(5), d.uint32())
+	assert.True(t, d.bool(), "eof")
+	assert.Equal(t, "8080\n", string(d.opaque(maxIO)))
+
+	d = c.call(progNFS, procLookup, diropArgs(fh, "missing"))
+	assert.Equal(t, uint32(nfsErrNoEnt), d.uint32())
+
+	// CREATE, WRITE, and RENAME
+	d = c.call(progNFS, procCreate, func(e *encoder) {
+		diropArgs(fh, "new.txt")(e)
+		e.uint32(createGuarded)
+		e.bool(true)
+		e.uint32(0o600)
+		e.bool(false)
+		e.bool(false)
+		e.bool(false)
+		e.uint32(timeDontChange)
+		e.uint32(timeDontChange)
+	})
+	require.Equal(t, uint32(nfsOK), d.uint32())
+	require.True(t, d.bool())
+	newFH := d.opaque(maxFHSize)
+
+	d = c.call(progNFS, procWrite, func(e *encoder) {
+		e.opaque(newFH)
+		e.uint64(0)
+		e.uint32(5)
+		e.uint32(stableFileSync)
+		e.opaque([]byte("hello"))
+	})
+	require.Equal(t, uint32(nfsOK), d.uint32())
+	d.bool()
+	assert.Equal(t, uint64(5), skipPostOpAttr(d))
+	assert.Equal(t, uint32(5), d.uint32())
+
+	d = c.call(progNFS, procRename, func(e *encoder) {
+		diropArgs(fh, "new.txt")(e)
+		diropArgs(confFH, "moved.txt")(e)
+	})
+	require.Equal(t, uint32(nfsOK), d.uint32())
+	data, err := os.ReadFile(filepath.Join(export, "conf", "moved.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(data))
+
+	// The handle follows the renamed file
+	d = c.call(progNFS, procGetAttr, func(e *encoder) { e.opaque(newFH) })
+	require.Equal(t, uint32(nfsOK), d.uint32())
+
+	// READDIRPLUS lists ".", "..", and the entries in name order
+	d = c.call(progNFS, procReaddirPlus, func(e *encoder) {
+		e.opaque(confFH)
+		e.uint64(0)
+		e.uint64(0)
+		e.uint32(4096)
+		e.uint32(32768)
+	})
+	require.Equal(t, uint32(nfsOK), d.uint32())
+	skipPostOpAttr(d)
+	d.uint64() // cookie verifier
+	var names []string
+	for d.bool() {
+		d.uint64()
+		names = append(names, d.string(maxNameLen))
+		d.uint64()
+		skipPostOpAttr(d)
+		if d.bool() {
+			d.opaque(maxFHSize)
+		}
+	}
+	assert.True(t, d.bool(), "eof")
+	require.NoError(t, d.err)
+	assert.Equal(t, []string{".", "..", "app.yaml", "moved.txt"}, names)
+
+	// REMOVE makes the handle stale
+	d = c.call(progNFS, procRemove, diropArgs(confFH, "moved.txt"))
+	require.Equal(t, uint32(nfsOK), d.uint32())
+	d = c.call(progNFS, procGetAttr, func(e *encoder) { e.opaque(newFH) })
+	assert.Equal(t, uint32(nfsErrStale), d.uint32())
+
+	// Handles from another server instance are stale
+	bad := make([]byte, handleSize)
+	binary.BigEndian.PutUint64(bad, 1)
+	d = c.call(progNFS, procGetAttr, func(e *encoder) { e.opaque(bad) })
+	assert.Equal(t, uint32(nfsErrStale), d.uint32())
+}
+
+func TestServerReadOnly(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, "a.txt"), []byte("a"), 0o644))
+
+	c := startServer(t, NewServer(root, true))
+	fh := c.mount(root)
+
+	d := c.call(progNFS, procRemove, diropArgs(fh, "a.txt"))
+	assert.Equal(t, uint32(nfsErrROFS), d.uint32())
+	assert.FileExists(t, filepath.Join(root, "a.txt"))
+
+	d = c.call(progNFS, procAccess, func(e *encoder) {
+		e.opaque(fh)
+		e.uint32(0x3f)
+	})
+	require.Equal(t, uint32(nfsOK), d.uint32())
+	skipPostOpAttr(d)
+	assert.Equal(t, uint32(0x3f&^(accessModify|accessExtend|accessDelete)), d.uint32())
+}
+
+func TestServerCheck(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(root, "secrets"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "a.txt"), []byte("a"), 0o644))
+
+	s := NewServer(root, false)
+	s.Check = func(path string, write bool) error {
+		switch {
+		case path == filepath.Join(root, "secrets"):
+			return os.ErrNotExist
+		case write:
+			return os.ErrPermission
+		}
+		return nil
+	}
+	c := startServer(t, s)
+	fh := c.mount(root)
+
+	d := c.call(progNFS, procLookup, diropArgs(fh, "secrets"))
+	assert.Equal(t, uint32(nfsErrNoEnt), d.uint32())
+
+	d = c.call(progNFS, procRemove, diropArgs(fh, "a.txt"))
+	assert.Equal(t, uint32(nfsErrAcces), d.uint32())
+	assert.FileExists(t, filepath.Join(root, "a.txt"))
+
+	d = c.call(progNFS, procReaddir, func(e *encoder) {
+		e.opaque(fh)
+		e.uint64(0)
+		e.uint64(0)
+		e.uint32(4096)
+	})
+	require.Equal(t, uint32(nfsOK), d.uint32())
+	skipPostOpAttr(d)
+	d.uint64() // cookie verifier
+	var names []string
+	for d.bool() {
+		d.uint64()
+		names = append(names, d.string(maxNameLen))
+		d.uint64()
+	}
+	require.NoError(t, d.err)
+	assert.Equal(t, []string{".", "..", "a.txt"}, names)
+}
+
+func TestHandleTable(t *testing.T) {
+	root := filepath.Join(string(filepath.Separator), "vol")
+	a := filepath.Join(root, "a")
+	b := filepath.Join(root, "b")
+	c := filepath.Join(root, "c")
+	ht := newHandleTable(root)
+	ht.max = 3
+
+	rh := ht.handle(root)
+	ah := ht.handle(a)
+	bh := ht.handle(b)
+	assert.Equal(t, ah, ht.handle(a), "a path keeps its handle")
+
+	// Using b and the root makes a the least recently used handle, so it's evicted.
+	_, st := ht.path(bh)
+	require.Equal(t, nfsOK, st)
+	_, st = ht.path(rh)
+	require.Equal(t, nfsOK, st)
+	ht.handle(c)
+	_, st = ht.path(ah)
+	assert.Equal(t, nfsErrStale, st)
+
+	// The root is never evicted, even when it's the least recently used handle.
+	ah = ht.handle(a)
+	ht.handle(filepath.Join(root, "d"))
+	p, st := ht.path(rh)
+	require.Equal(t, nfsOK, st)
+	assert.Equal(t, root, p)
+	assert.LessOrEqual(t, ht.lru.Len(), ht.max)
+
+	// Renamed handles keep their ids.
+	ht.rename(a, b)
+	p, st = ht.path(ah)
+	require.Equal(t, nfsOK, st)
+	assert.Equal(t, b, p)
+}
+
+func TestServerSymlinkTarget(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(root, "secrets"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "a.txt"), []byte("a"), 0o644))
+
+	s := NewServer(root, false)
+	s.Check = func(path string, write bool) error {
+		if strings.HasPrefix(path, filepath.Join(root, "secrets")) {
+			return os.ErrNotExist
+		}
+		return nil
+	}
+	c := startServer(t, s)
+	fh := c.mount(root)
+
+	symlink := func(name, target string) uint32 {
+		return c.call(progNFS, procSymlink, func(e *encoder) {
+			diropArgs(fh, name)(e)
+			e.bool(false)
+			e.bool(false)
+			e.bool(false)
+			e.bool(false)
+			e.uint32(timeDontChange)
+			e.uint32(timeDontChange)
+			e.string(target)
+		}).uint32()
+	}
+	assert.Equal(t, uint32(nfsOK), symlink("a-link", "a.txt"))
+	assert.Equal(t, uint32(nfsErrAcces), symlink("passwd", "/etc/passwd"))
+	assert.Equal(t, uint32(nfsErrAcces), symlink("up", "../../etc/passwd"))
+	assert.Equal(t, uint32(nfsErrNoEnt), symlink("token", "secrets/token"))
+	assert.NoFileExists(t, filepath.Join(root, "passwd"))
+	assert.NoFileExists(t, filepath.Join(root, "up"))
+	assert.NoFileExists(t, filepath.Join(root, "token"))
+}
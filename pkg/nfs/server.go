@@ -0,0 +1,253 @@
+// Package nfs is a userspace NFSv3 server (RFC 1813) that exports a directory tree over TCP.
+// The MOUNT protocol is served on the same port as NFS, so clients can mount without the
+// help of a portmapper. Network locking (NLM) isn't supported, so clients must mount with
+// local locking.
+package nfs
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+
+	"github.com/datawire/dlib/dlog"
+)
+
+const (
+	rpcVersion = 2
+
+	msgCall  = 0
+	msgReply = 1
+
+	replyAccepted = 0
+	replyDenied   = 1
+
+	acceptSuccess      = 0
+	acceptProgUnavail  = 1
+	acceptProgMismatch = 2
+	acceptProcUnavail  = 3
+	acceptGarbageArgs  = 4
+
+	rejectRPCMismatch = 0
+
+	authNone = 0
+	authUnix = 1
+
+	progNFS   = 100003
+	progMount = 100005
+
+	nfsVersion   = 3
+	mountVersion = 3
+
+	// maxRecord is the max size of an RPC record that the server accepts.
+	maxRecord = 4 << 20
+
+	// maxConcurrentCalls is the max number of calls that are served concurrently on one connection.
+	maxConcurrentCalls = 16
+)
+
+// Server serves the directory tree below Root over NFSv3.
+type Server struct {
+	// Root is the directory that contains all directories that clients can mount.
+	Root string
+
+	// ReadOnly, when true, rejects all calls that modify the file system.
+	ReadOnly bool
+
+	// Check, when set, is called with each path that a call accesses. The write argument is true
+	// when the call modifies the path. Paths that Check returns fs.ErrNotExist for are hidden from
+	// clients, and other errors deny access.
+	Check func(path string, write bool) error
+
+	// Files, when set, is used instead of the os package to open and modify files. The owner of the
+	// Check can use it to make sure that what is opened or modified is what was checked, even when a
+	// client swaps a symbolic link into the path after the check.
+	Files Files
+
+	handles *handleTable
+	verf    [8]byte
+}
+
+// NewServer returns a server that exports the directory tree below root.
+func NewServer(root string, readOnly bool) *Server {
+	s := &Server{Root: root, ReadOnly: readOnly, handles: newHandleTable(root)}
+	binary.BigEndian.PutUint64(s.verf[:], s.handles.boot)
+	return s
+}
+
+// files returns the Files that opens and modifies the files of this server.
+func (s *Server) files() Files {
+	if s.Files != nil {
+		return s.Files
+	}
+	return osFiles{}
+}
+
+// Serve accepts connections on the listener and serves them until the context is cancelled.
+func (s *Server) Serve(ctx context.Context, l net.Listener) error {
+	go func() {
+		<-ctx.Done()
+		_ = l.Close()
+	}()
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("listener on nfs-server connection failed: %w", err)
+		}
+		go func() {
+			dlog.Debugf(ctx, "Serving nfs connection from %s", conn.RemoteAddr())
+			if err := s.ServeConn(ctx, conn); err != nil && ctx.Err() == nil {
+				dlog.Errorf(ctx, "nfs connection from %s failed: %v", conn.RemoteAddr(), err)
+			}
+		}()
+	}
+}
+
+// ServeConn serves the calls that arrive on the given connection until the connection is
+// closed or the context is cancelled.
+func (s *Server) ServeConn(ctx context.Context, conn io.ReadWriteCloser) error {
+	defer conn.Close()
+	go func() {
+		<-ctx.Done()
+		_ = conn.Close()
+	}()
+
+	var wl sync.Mutex
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	sem := make(chan struct{}, maxConcurrentCalls)
+	r := bufio.NewReader(conn)
+	for {
+		rec, err := readRecord(r)
+		if err != nil {
+			if errors.Is(err, io.EOF) || ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			reply := s.handleCall(ctx, rec)
+			if reply == nil {
+				return
+			}
+			wl.Lock()
+			_, err := conn.Write(reply)
+			wl.Unlock()
+			if err != nil && ctx.Err() == nil {
+				dlog.Debugf(ctx, "unable to write nfs reply: %v", err)
+			}
+		}()
+	}
+}
+
+// readRecord reads one record using the record marking standard of RFC 5531, section 11.
+func readRecord(r io.Reader) ([]byte, error) {
+	var rec []byte
+	var hdr [4]byte
+	for {
+		if _, err := io.ReadFull(r, hdr[:]); err != nil {
+			return nil, err
+		}
+		h := binary.BigEndian.Uint32(hdr[:])
+		n := int(h & 0x7fffffff)
+		if len(rec)+n > maxRecord {
+			return nil, fmt.Errorf("rpc record exceeds %d bytes", maxRecord)
+		}
+		start := len(rec)
+		rec = append(rec, make([]byte, n)...)
+		if _, err := io.ReadFull(r, rec[start:]); err != nil {
+			return nil, err
+		}
+		if h&0x80000000 != 0 {
+			return rec, nil
+		}
+	}
+}
+
+// handleCall decodes one call, dispatches it, and returns the reply record including its
+// record mark, or nil if the record isn't a call.
+func (s *Server) handleCall(ctx context.Context, rec []byte) []byte {
+	d := &decoder{buf: rec}
+	xid := d.uint32()
+	if d.uint32() != msgCall {
+		return nil
+	}
+	rpcVers := d.uint32()
+	prog := d.uint32()
+	vers := d.uint32()
+	proc := d.uint32()
+	d.uint32()        // credential flavor
+	d.opaque(400)     // credential body
+	d.uint32()        // verifier flavor
+	d.opaque(400)     // verifier body
+	if d.err != nil { // can't even decode the header
+		return nil
+	}
+
+	e := &encoder{buf: make([]byte, 4, 512)}
+	e.uint32(xid)
+	e.uint32(msgReply)
+	if rpcVers != rpcVersion {
+		e.uint32(replyDenied)
+		e.uint32(rejectRPCMismatch)
+		e.uint32(rpcVersion)
+		e.uint32(rpcVersion)
+		return record(e)
+	}
+	e.uint32(replyAccepted)
+	e.uint32(authNone)
+	e.opaque(nil)
+
+	var handler func(context.Context, uint32, *decoder, *encoder) bool
+	switch prog {
+	case progNFS:
+		if vers != nfsVersion {
+			e.uint32(acceptProgMismatch)
+			e.uint32(nfsVersion)
+			e.uint32(nfsVersion)
+			return record(e)
+		}
+		handler = s.handleNFS
+	case progMount:
+		if vers != mountVersion {
+			e.uint32(acceptProgMismatch)
+			e.uint32(mountVersion)
+			e.uint32(mountVersion)
+			return record(e)
+		}
+		handler = s.handleMount
+	default:
+		e.uint32(acceptProgUnavail)
+		return record(e)
+	}
+
+	statusPos := len(e.buf)
+	e.uint32(acceptSuccess)
+	if !handler(ctx, proc, d, e) {
+		e.buf = e.buf[:statusPos]
+		e.uint32(acceptProcUnavail)
+	} else if d.err != nil {
+		e.buf = e.buf[:statusPos]
+		e.uint32(acceptGarbageArgs)
+	}
+	return record(e)
+}
+
+// record fills in the record mark of a reply that consists of one fragment.
+func record(e *encoder) []byte {
+	binary.BigEndian.PutUint32(e.buf, 0x80000000|uint32(len(e.buf)-4))
+	return e.buf
+}
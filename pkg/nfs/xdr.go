@@ -0,0 +1,103 @@
+package nfs
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// errGarbage is returned when the arguments of a call cannot be decoded.
+var errGarbage = errors.New("garbage arguments")
+
+// decoder reads XDR (RFC 4506) encoded values from a byte slice.
+type decoder struct {
+	buf []byte
+	err error
+}
+
+func (d *decoder) uint32() uint32 {
+	if d.err != nil || len(d.buf) < 4 {
+		d.err = errGarbage
+		return 0
+	}
+	v := binary.BigEndian.Uint32(d.buf)
+	d.buf = d.buf[4:]
+	return v
+}
+
+func (d *decoder) uint64() uint64 {
+	if d.err != nil || len(d.buf) < 8 {
+		d.err = errGarbage
+		return 0
+	}
+	v := binary.BigEndian.Uint64(d.buf)
+	d.buf = d.buf[8:]
+	return v
+}
+
+func (d *decoder) bool() bool {
+	return d.uint32() != 0
+}
+
+// fixed reads an opaque value of a known length.
+func (d *decoder) fixed(n int) []byte {
+	padded := (n + 3) &^ 3
+	if d.err != nil || len(d.buf) < padded {
+		d.err = errGarbage
+		return nil
+	}
+	v := d.buf[:n]
+	d.buf = d.buf[padded:]
+	return v
+}
+
+// opaque reads a variable length opaque value that is at most limit bytes long.
+func (d *decoder) opaque(limit int) []byte {
+	n := d.uint32()
+	if d.err == nil && n > uint32(limit) {
+		d.err = errGarbage
+	}
+	return d.fixed(int(n))
+}
+
+func (d *decoder) string(limit int) string {
+	return string(d.opaque(limit))
+}
+
+// encoder appends XDR encoded values to a byte slice.
+type encoder struct {
+	buf []byte
+}
+
+func (e *encoder) uint32(v uint32) {
+	e.buf = append(e.buf, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+func (e *encoder) uint64(v uint64) {
+	e.uint32(uint32(v >> 32))
+	e.uint32(uint32(v))
+}
+
+func (e *encoder) bool(v bool) {
+	if v {
+		e.uint32(1)
+	} else {
+		e.uint32(0)
+	}
+}
+
+// fixed appends an opaque value of a known length.
+func (e *encoder) fixed(v []byte) {
+	e.buf = append(e.buf, v...)
+	if pad := len(v) & 3; pad != 0 {
+		e.buf = append(e.buf, make([]byte, 4-pad)...)
+	}
+}
+
+func (e *encoder) opaque(v []byte) {
+	e.uint32(uint32(len(v)))
+	e.fixed(v)
+}
+
+func (e *encoder) string(v string) {
+	e.opaque([]byte(v))
+}
@@ -0,0 +1,64 @@
+package shellquote
+
+import (
+	"errors"
+	"strings"
+)
+
+// Split splits the given string into arguments using the quoting rules of a Unix shell, regardless
+// of the current platform. Arguments are separated by unquoted whitespace. Single quotes preserve
+// everything up to the next single quote, double quotes preserve everything except backslash escaped
+// double quotes and backslashes, and an unquoted backslash preserves the next character. It is the
+// inverse of joining arguments quoted by Unix.
+func Split(s string) ([]string, error) {
+	var args []string
+	b := strings.Builder{}
+	inArg := false
+	rs := []rune(s)
+	for i := 0; i < len(rs); i++ {
+		r := rs[i]
+		switch r {
+		case ' ', '\t', '\n', '\r':
+			if inArg {
+				args = append(args, b.String())
+				b.Reset()
+				inArg = false
+			}
+			continue
+		case '\\':
+			i++
+			if i == len(rs) {
+				return nil, errors.New("unterminated backslash escape")
+			}
+			b.WriteRune(rs[i])
+		case '\'':
+			e := i + 1
+			for e < len(rs) && rs[e] != '\'' {
+				e++
+			}
+			if e == len(rs) {
+				return nil, errors.New("unterminated single quote")
+			}
+			b.WriteString(string(rs[i+1 : e]))
+			i = e
+		case '"':
+			i++
+			for ; i < len(rs) && rs[i] != '"'; i++ {
+				if rs[i] == '\\' && i+1 < len(rs) && (rs[i+1] == '"' || rs[i+1] == '\\') {
+					i++
+				}
+				b.WriteRune(rs[i])
+			}
+			if i == len(rs) {
+				return nil, errors.New("unterminated double quote")
+			}
+		default:
+			b.WriteRune(r)
+		}
+		inArg = true
+	}
+	if inArg {
+		args = append(args, b.String())
+	}
+	return args, nil
+}
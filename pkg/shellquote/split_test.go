@@ -0,0 +1,47 @@
+package shellquote
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		in   string
+		args []string
+	}{
+		{"", nil},
+		{"  go run ./cmd/orders ", []string{"go", "run", "./cmd/orders"}},
+		{`sh -c 'echo "hello world"'`, []string{"sh", "-c", `echo "hello world"`}},
+		{`echo "it's \"quoted\"" a\ b`, []string{"echo", `it's "quoted"`, "a b"}},
+		{`--name=''`, []string{"--name="}},
+		{`''`, []string{""}},
+		{`pre'fix'"ed"`, []string{"prefixed"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			args, err := Split(tt.in)
+			require.NoError(t, err)
+			assert.Equal(t, tt.args, args)
+		})
+	}
+
+	for _, bad := range []string{`echo 'x`, `echo "x`, `echo x\`} {
+		_, err := Split(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestSplit_Unix(t *testing.T) {
+	args := []string{"sh", "-c", `echo "$HOME" it's`, "", "a b"}
+	quoted := make([]string, len(args))
+	for i, a := range args {
+		quoted[i] = Unix(a)
+	}
+	split, err := Split(strings.Join(quoted, " "))
+	require.NoError(t, err)
+	assert.Equal(t, args, split)
+}
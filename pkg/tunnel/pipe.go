@@ -0,0 +1,80 @@
+package tunnel
+
+import (
+	"context"
+	"io"
+	"net"
+	"sync"
+
+	"github.com/telepresenceio/telepresence/rpc/v2/manager"
+)
+
+// NewPipe returns the client and the server side of an in-process tunnel. It makes it possible for the
+// traffic-manager to create a Stream without making a gRPC call to itself. Just like with gRPC, the
+// server side receives io.EOF when the client side has called CloseSend. Both sides fail with
+// net.ErrClosed when the context is cancelled.
+func NewPipe(ctx context.Context) (GRPClientCStream, GRPCStream) {
+	p := &pipe{
+		cToS: make(chan *manager.TunnelMessage),
+		sToC: make(chan *manager.TunnelMessage),
+		done: ctx.Done(),
+	}
+	return &pipeClient{p}, &pipeServer{p}
+}
+
+type pipe struct {
+	cToS      chan *manager.TunnelMessage
+	sToC      chan *manager.TunnelMessage
+	done      <-chan struct{}
+	closeOnce sync.Once
+}
+
+func (p *pipe) recv(ch <-chan *manager.TunnelMessage) (*manager.TunnelMessage, error) {
+	select {
+	case <-p.done:
+		return nil, net.ErrClosed
+	case m, ok := <-ch:
+		if !ok {
+			return nil, io.EOF
+		}
+		return m, nil
+	}
+}
+
+func (p *pipe) send(ch chan<- *manager.TunnelMessage, m *manager.TunnelMessage) error {
+	select {
+	case <-p.done:
+		return net.ErrClosed
+	case ch <- m:
+		return nil
+	}
+}
+
+type pipeClient struct {
+	*pipe
+}
+
+func (c *pipeClient) Recv() (*manager.TunnelMessage, error) {
+	return c.recv(c.sToC)
+}
+
+func (c *pipeClient) Send(m *manager.TunnelMessage) error {
+	return c.send(c.cToS, m)
+}
+
+func (c *pipeClient) CloseSend() error {
+	c.closeOnce.Do(func() { close(c.cToS) })
+	return nil
+}
+
+type pipeServer struct {
+	*pipe
+}
+
+func (s *pipeServer) Recv() (*manager.TunnelMessage, error) {
+	return s.recv(s.cToS)
+}
+
+func (s *pipeServer) Send(m *manager.TunnelMessage) error {
+	return s.send(s.sToC, m)
+}
@@ -0,0 +1,55 @@
+package tunnel
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/telepresenceio/telepresence/v2/pkg/ipproto"
+	"github.com/telepresenceio/telepresence/v2/pkg/iputil"
+)
+
+func TestPipe(t *testing.T) {
+	ctx, cancel := testContext(t, 10*time.Second)
+	defer cancel()
+
+	id := NewConnID(ipproto.TCP, iputil.Parse("10.0.0.1"), iputil.Parse("127.0.0.1"), 1001, 8080)
+	si := uuid.New().String()
+	b := make([]byte, 0x1000)
+	for i := range b {
+		b[i] = byte(i & 0xff)
+	}
+	errs := make(chan error, 10)
+	clientSide, serverSide := NewPipe(ctx)
+
+	wg := sync.WaitGroup{}
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		if client, err := NewClientStream(ctx, clientSide, id, si, 0, 0); err != nil {
+			errs <- err
+		} else {
+			produce(ctx, client, NewMessage(Normal, b), errs)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		server, err := NewServerStream(ctx, serverSide)
+		if err != nil {
+			errs <- err
+			return
+		}
+		assert.Equal(t, id, server.ID())
+		assert.Equal(t, si, server.SessionID())
+		consume(ctx, server, b, errs)
+	}()
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+}
@@ -103,15 +103,22 @@ const IPv6MinMTU = 1280 // From RFC 2460, section 5
 
 func DestinationUnreachablePacket(origHdr ip.Header, code UnreachableCode) Packet {
 	var msgType int
-	var origSz int
 	if origHdr.Version() == ipv4.Version {
 		msgType = int(ipv4.ICMPTypeDestinationUnreachable)
+	} else {
+		msgType = int(ipv6.ICMPTypeDestinationUnreachable)
+	}
+	return ErrorPacket(origHdr, msgType, int(code), origHdr.Destination())
+}
 
+// ErrorPacket returns an ICMP error message of the given type and code that is sent from src to the source
+// of the packet that caused it.
+func ErrorPacket(origHdr ip.Header, msgType, code int, src net.IP) Packet {
+	var origSz int
+	if origHdr.Version() == ipv4.Version {
 		// include header + 64 bits of original payload
 		origSz = origHdr.HeaderLen() + 8
 	} else {
-		msgType = int(ipv6.ICMPTypeDestinationUnreachable)
-
 		// include as much of invoking packet as possible without the ICMPv6 packet
 		// exceeding the minimum IPv6 MTU
 		origSz = origHdr.HeaderLen() + origHdr.PayloadLen()
@@ -119,12 +126,43 @@ func DestinationUnreachablePacket(origHdr ip.Header, code UnreachableCode) Packe
 			origSz = IPv6MinMTU - HeaderLen
 		}
 	}
-	pkt := NewPacket(HeaderLen+origSz, origHdr.Source(), origHdr.Destination())
+	pkt := NewPacket(HeaderLen+origSz, src, origHdr.Source())
 	iph := pkt.IPHeader()
 	icmpHdr := Header(iph.Payload())
 	icmpHdr.SetMessageType(msgType)
-	icmpHdr.SetCode(int(code))
+	icmpHdr.SetCode(code)
+	copy(icmpHdr.RestOfHeader(), []byte{0, 0, 0, 0}) // unused, and the buffer might be recycled
 	copy(icmpHdr.Payload(), origHdr.Packet()[:origSz])
 	icmpHdr.SetChecksum(iph)
 	return pkt
 }
+
+// IsEchoRequest returns true if the given packet is an ICMP or ICMPv6 echo request.
+func IsEchoRequest(pkt Packet) bool {
+	t := pkt.Header().MessageType()
+	if pkt.IPHeader().Version() == ipv4.Version {
+		return t == int(ipv4.ICMPTypeEcho)
+	}
+	return t == int(ipv6.ICMPTypeEchoRequest)
+}
+
+// EchoReplyPacket returns the reply to the given echo request. The reply has the identifier and sequence number
+// of the request, and the given data.
+func EchoReplyPacket(echoRequest Packet, data []byte) Packet {
+	origHdr := echoRequest.IPHeader()
+	var msgType int
+	if origHdr.Version() == ipv4.Version {
+		msgType = int(ipv4.ICMPTypeEchoReply)
+	} else {
+		msgType = int(ipv6.ICMPTypeEchoReply)
+	}
+	pkt := NewPacket(HeaderLen+len(data), origHdr.Destination(), origHdr.Source())
+	iph := pkt.IPHeader()
+	icmpHdr := Header(iph.Payload())
+	icmpHdr.SetMessageType(msgType)
+	icmpHdr.SetCode(0)
+	copy(icmpHdr.RestOfHeader(), echoRequest.Header().RestOfHeader())
+	copy(icmpHdr.Payload(), data)
+	icmpHdr.SetChecksum(iph)
+	return pkt
+}
@@ -0,0 +1,112 @@
+package icmp
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+
+	"github.com/telepresenceio/telepresence/v2/pkg/ipproto"
+	"github.com/telepresenceio/telepresence/v2/pkg/vif/ip"
+)
+
+// validChecksum returns true if the checksum of the ICMP message in the given packet is correct.
+func validChecksum(pkt Packet) bool {
+	ipHdr := pkt.IPHeader()
+	var b []byte
+	if ipHdr.Version() == ipv6.Version {
+		b = append(b, ipHdr.PseudoHeader(ipproto.ICMPV6)...)
+	}
+	b = append(b, ipHdr.Payload()...)
+	if len(b)%2 != 0 {
+		b = append(b, 0)
+	}
+	s := 0
+	for i := 0; i < len(b); i += 2 {
+		s += int(b[i])<<8 | int(b[i+1])
+	}
+	for s > 0xffff {
+		s = (s >> 16) + (s & 0xffff)
+	}
+	return s == 0xffff
+}
+
+func echoRequest(t *testing.T, src, dst net.IP, data []byte) Packet {
+	pkt := NewPacket(HeaderLen+len(data), src, dst)
+	iph := pkt.IPHeader()
+	icmpHdr := Header(iph.Payload())
+	if iph.Version() == ipv4.Version {
+		icmpHdr.SetMessageType(int(ipv4.ICMPTypeEcho))
+	} else {
+		icmpHdr.SetMessageType(int(ipv6.ICMPTypeEchoRequest))
+	}
+	icmpHdr.SetCode(0)
+	copy(icmpHdr.RestOfHeader(), []byte{0x12, 0x34, 0x00, 0x07})
+	copy(icmpHdr.Payload(), data)
+	icmpHdr.SetChecksum(iph)
+	require.True(t, IsEchoRequest(pkt))
+	require.True(t, validChecksum(pkt))
+	return pkt
+}
+
+func TestEchoReplyPacket(t *testing.T) {
+	tests := []struct {
+		name      string
+		src       net.IP
+		dst       net.IP
+		replyType int
+	}{
+		{"IPv4", net.IP{192, 168, 0, 1}, net.IP{10, 0, 0, 1}, int(ipv4.ICMPTypeEchoReply)},
+		{"IPv6", net.ParseIP("fd00::1"), net.ParseIP("fd01::2"), int(ipv6.ICMPTypeEchoReply)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := []byte("some odd sized data")
+			rq := echoRequest(t, tt.src, tt.dst, data)
+			defer rq.Release()
+			rp := EchoReplyPacket(rq, data)
+			defer rp.Release()
+
+			iph := rp.IPHeader()
+			assert.True(t, tt.dst.Equal(iph.Source()))
+			assert.True(t, tt.src.Equal(iph.Destination()))
+			assert.False(t, IsEchoRequest(rp))
+			assert.Equal(t, tt.replyType, rp.Header().MessageType())
+			assert.Equal(t, rq.Header().RestOfHeader(), rp.Header().RestOfHeader())
+			assert.Equal(t, data, rp.Header().Payload())
+			assert.True(t, validChecksum(rp))
+		})
+	}
+}
+
+func TestErrorPacket(t *testing.T) {
+	src := net.IP{192, 168, 0, 1}
+	dst := net.IP{10, 0, 0, 1}
+	router := net.IP{10, 0, 0, 254}
+	rq := echoRequest(t, src, dst, []byte("data"))
+	defer rq.Release()
+
+	pkt := ErrorPacket(rq.IPHeader(), int(ipv4.ICMPTypeTimeExceeded), 0, router)
+	defer pkt.Release()
+	iph := pkt.IPHeader()
+	assert.True(t, router.Equal(iph.Source()))
+	assert.True(t, src.Equal(iph.Destination()))
+	assert.Equal(t, int(ipv4.ICMPTypeTimeExceeded), pkt.Header().MessageType())
+	assert.Equal(t, []byte{0, 0, 0, 0}, pkt.Header().RestOfHeader())
+
+	// The error quotes the IP header and the first 64 bits of the echo request
+	orig := ip.V4Header(pkt.Header().Payload())
+	assert.True(t, dst.Equal(orig.Destination()))
+	assert.Equal(t, rq.Header()[:HeaderLen], Header(pkt.Header().Payload()[orig.HeaderLen():]))
+	assert.True(t, validChecksum(pkt))
+
+	pkt = DestinationUnreachablePacket(rq.IPHeader(), HostUnreachable)
+	defer pkt.Release()
+	assert.True(t, dst.Equal(pkt.IPHeader().Source()))
+	assert.True(t, src.Equal(pkt.IPHeader().Destination()))
+	assert.Equal(t, int(HostUnreachable), pkt.Header().Code())
+	assert.True(t, validChecksum(pkt))
+}
@@ -112,10 +112,13 @@ func L4Checksum(ipHdr Header, checksumPosition, l4Proto int) {
 		s = int(p[pl]) << 8
 	}
 
-	h := ipHdr.PseudoHeader(l4Proto)
-	hl := len(h)
-	for i := 0; i < hl; i += 2 {
-		s += int(h[i])<<8 | int(h[i+1])
+	if l4Proto != ipproto.ICMP {
+		// Unlike all other protocols, ICMP for IPv4 doesn't include a pseudo header in its checksum
+		h := ipHdr.PseudoHeader(l4Proto)
+		hl := len(h)
+		for i := 0; i < hl; i += 2 {
+			s += int(h[i])<<8 | int(h[i+1])
+		}
 	}
 	for i := 0; i < pl; i += 2 {
 		s += int(p[i])<<8 | int(p[i+1])
@@ -0,0 +1,84 @@
+package ip
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/ipv4"
+
+	"github.com/telepresenceio/telepresence/v2/pkg/ipproto"
+	"github.com/telepresenceio/telepresence/v2/pkg/vif/buffer"
+)
+
+func v4Fragment(offset int, more bool, id int, payload []byte) *buffer.Data {
+	data := buffer.DataPool.Get(ipv4.HeaderLen + len(payload))
+	h := V4Header(data.Buf())
+	h.Initialize()
+	h.SetID(id)
+	h.SetSource(net.IP{192, 168, 0, 1})
+	h.SetDestination(net.IP{10, 0, 0, 1})
+	h.SetL4Protocol(ipproto.UDP)
+	h.SetPayloadLen(len(payload))
+	h.SetFragmentOffset(offset)
+	if more {
+		h.SetFlags(ipv4.MoreFragments)
+	}
+	h.SetChecksum()
+	copy(h.Payload(), payload)
+	return data
+}
+
+func TestV4Header_ConcatFragments(t *testing.T) {
+	payload := make([]byte, 40)
+	for i := range payload {
+		payload[i] = byte(i)
+	}
+	frags := func(id int) []*buffer.Data {
+		return []*buffer.Data{
+			v4Fragment(0, true, id, payload[:16]),
+			v4Fragment(2, true, id, payload[16:32]),
+			v4Fragment(4, false, id, payload[32:]),
+		}
+	}
+	assertConcatenated := func(t *testing.T, data *buffer.Data) {
+		require.NotNil(t, data)
+		h := V4Header(data.Buf())
+		assert.Equal(t, ipproto.UDP, h.L4Protocol())
+		assert.Equal(t, payload, h.Payload())
+		assert.Equal(t, ipv4.HeaderFlags(0), h.Flags()&ipv4.MoreFragments)
+		assert.Equal(t, 0, h.FragmentOffset())
+	}
+
+	t.Run("in order", func(t *testing.T) {
+		fm := make(map[uint16][]*buffer.Data)
+		fs := frags(1)
+		assert.Nil(t, V4Header(fs[0].Buf()).ConcatFragments(fs[0], fm))
+		assert.Nil(t, V4Header(fs[1].Buf()).ConcatFragments(fs[1], fm))
+		data := V4Header(fs[2].Buf()).ConcatFragments(fs[2], fm)
+		assertConcatenated(t, data)
+		buffer.DataPool.Put(data)
+		assert.Empty(t, fm)
+	})
+
+	t.Run("out of order", func(t *testing.T) {
+		fm := make(map[uint16][]*buffer.Data)
+		fs := frags(2)
+		assert.Nil(t, V4Header(fs[2].Buf()).ConcatFragments(fs[2], fm))
+		assert.Nil(t, V4Header(fs[0].Buf()).ConcatFragments(fs[0], fm))
+		data := V4Header(fs[1].Buf()).ConcatFragments(fs[1], fm)
+		assertConcatenated(t, data)
+		buffer.DataPool.Put(data)
+		assert.Empty(t, fm)
+	})
+
+	t.Run("with gap", func(t *testing.T) {
+		fm := make(map[uint16][]*buffer.Data)
+		fs := frags(3)
+		assert.Nil(t, V4Header(fs[0].Buf()).ConcatFragments(fs[0], fm))
+		assert.Nil(t, V4Header(fs[2].Buf()).ConcatFragments(fs[2], fm))
+		assert.Len(t, fm[3], 2)
+		buffer.DataPool.Put(fs[1])
+	})
+}
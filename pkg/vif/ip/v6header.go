@@ -3,9 +3,11 @@ package ip
 import (
 	"encoding/binary"
 	"net"
+	"sort"
 
 	"golang.org/x/net/ipv6"
 
+	"github.com/telepresenceio/telepresence/v2/pkg/ipproto"
 	"github.com/telepresenceio/telepresence/v2/pkg/vif/buffer"
 )
 
@@ -16,6 +18,7 @@ func (h V6Header) Initialize() {
 		h[i] = 0
 	}
 	h[0] = ipv6.Version << 4
+	h[6] = ipproto.IPV6NoNxt
 }
 
 func (h V6Header) Version() int {
@@ -30,8 +33,9 @@ func (h V6Header) FlowLabel() int {
 	return int(h[1]&0x0f)<<16 | int(h[2])<<8 | int(h[3])
 }
 
+// PayloadLen returns the length of the payload that follows the extension headers.
 func (h V6Header) PayloadLen() int {
-	return int(binary.BigEndian.Uint16(h[4:6]))
+	return int(binary.BigEndian.Uint16(h[4:6])) + ipv6.HeaderLen - h.HeaderLen()
 }
 
 func (h V6Header) NextHeader() int {
@@ -66,30 +70,71 @@ func (h V6Header) SetDestination(ip net.IP) {
 	}
 }
 
+// walk walks the extension headers of this header. It returns the protocol of the header that follows them,
+// the offset of that header, and the offset of the next header field that declares its protocol. The walk stops
+// after a fragment header, because only the first fragment contains the headers that follows it. The protocol
+// is then ipproto.IPV6Frag.
+func (h V6Header) walk() (proto, offset, nhPos int) {
+	proto, offset, nhPos = h.NextHeader(), ipv6.HeaderLen, 6
+	end := ipv6.HeaderLen + int(binary.BigEndian.Uint16(h[4:6]))
+	if end > len(h) {
+		end = len(h)
+	}
+	for offset+8 <= end {
+		var l int
+		switch proto {
+		case ipproto.HOPOPT, ipproto.IPV6Route, ipproto.IPV6Opts:
+			l = (int(h[offset+1]) + 1) * 8
+		case ipproto.AH:
+			l = (int(h[offset+1]) + 2) * 4
+		case ipproto.IPV6Frag:
+			return proto, offset + 8, nhPos
+		default:
+			return proto, offset, nhPos
+		}
+		if offset+l > end {
+			break
+		}
+		proto, nhPos = int(h[offset]), offset
+		offset += l
+	}
+	return proto, offset, nhPos
+}
+
+// HeaderLen returns the length of the fixed header and all extension headers.
 func (h V6Header) HeaderLen() int {
-	return ipv6.HeaderLen
+	_, offset, _ := h.walk()
+	return offset
 }
 
+// SetPayloadLen sets the length of the payload that follows the extension headers.
 func (h V6Header) SetPayloadLen(tl int) {
-	binary.BigEndian.PutUint16(h[4:], uint16(tl))
+	binary.BigEndian.PutUint16(h[4:], uint16(tl+h.HeaderLen()-ipv6.HeaderLen))
 }
 
+// SetL4Protocol sets the protocol in the next header field of the last extension header, or in the fixed
+// header when there are no extension headers.
 func (h V6Header) SetL4Protocol(proto int) {
-	h[6] = uint8(proto)
+	_, _, nhPos := h.walk()
+	h[nhPos] = uint8(proto)
 }
 
+// L4Protocol returns the protocol of the header that follows the extension headers. The protocol of a fragment
+// is ipproto.IPV6Frag until the fragments are concatenated.
 func (h V6Header) L4Protocol() int {
-	return h.NextHeader()
+	proto, _, _ := h.walk()
+	return proto
 }
 
 func (h V6Header) SetChecksum() {}
 
 func (h V6Header) Packet() []byte {
-	return h[:ipv6.HeaderLen+h.PayloadLen()]
+	return h[:ipv6.HeaderLen+int(binary.BigEndian.Uint16(h[4:6]))]
 }
 
 func (h V6Header) Payload() []byte {
-	return h[ipv6.HeaderLen : ipv6.HeaderLen+h.PayloadLen()]
+	_, offset, _ := h.walk()
+	return h[offset : ipv6.HeaderLen+int(binary.BigEndian.Uint16(h[4:6]))]
 }
 
 func (h V6Header) PseudoHeader(l4Proto int) []byte {
@@ -100,7 +145,116 @@ func (h V6Header) PseudoHeader(l4Proto int) []byte {
 	return b
 }
 
-func (h V6Header) ProcessFragments(data *buffer.Data, fragsMap map[uint16][]*buffer.Data) *buffer.Data {
-	// TODO: Implement based on Extension headers
-	return nil
+// fragmentHeader returns the fragment extension header, or nil when this header doesn't have one.
+func (h V6Header) fragmentHeader() []byte {
+	proto, offset, _ := h.walk()
+	if proto != ipproto.IPV6Frag {
+		return nil
+	}
+	return h[offset-8 : offset]
+}
+
+// FragmentOffset returns the offset of the fragment in units of 8 octets, or zero when the packet
+// isn't a fragment.
+func (h V6Header) FragmentOffset() int {
+	if fh := h.fragmentHeader(); fh != nil {
+		return int(binary.BigEndian.Uint16(fh[2:]) >> 3)
+	}
+	return 0
+}
+
+// MoreFragments returns true if the packet is a fragment that isn't the last one.
+func (h V6Header) MoreFragments() bool {
+	if fh := h.fragmentHeader(); fh != nil {
+		return fh[3]&1 != 0
+	}
+	return false
+}
+
+// FragmentID returns the identification of the fragment, or zero when the packet isn't a fragment.
+func (h V6Header) FragmentID() uint32 {
+	if fh := h.fragmentHeader(); fh != nil {
+		return binary.BigEndian.Uint32(fh[4:])
+	}
+	return 0
+}
+
+// ConcatFragments adds the given fragment to the fragments in the map that have the same identification.
+// It returns nil until all fragments have arrived. It then returns a packet without the fragment
+// header that has the concatenated payload of all fragments. A packet that isn't a fragment is
+// returned as is, and an atomic fragment, i.e. one with offset zero and no more fragments (RFC 6946),
+// is returned at once without its fragment header.
+func (h V6Header) ConcatFragments(data *buffer.Data, fragsMap map[uint32][]*buffer.Data) *buffer.Data {
+	if h.fragmentHeader() == nil {
+		return data
+	}
+	if h.FragmentOffset() == 0 && !h.MoreFragments() {
+		return reassemble([]*buffer.Data{data}, h.PayloadLen())
+	}
+
+	id := h.FragmentID()
+	fragments, ok := fragsMap[id]
+	if !ok {
+		// first fragment
+		fragsMap[id] = []*buffer.Data{data}
+		return nil
+	}
+
+	last := V6Header(fragments[len(fragments)-1].Buf())
+	fragments = append(fragments, data)
+	fragsMap[id] = fragments
+
+	if h.FragmentOffset() < last.FragmentOffset() {
+		// Fragments didn't arrive in order. Sort them
+		sort.Slice(fragments, func(i, j int) bool {
+			return V6Header(fragments[i].Buf()).FragmentOffset() < V6Header(fragments[j].Buf()).FragmentOffset()
+		})
+	} else {
+		last = h
+	}
+
+	if last.MoreFragments() {
+		// last fragment hasn't arrived yet.
+		return nil
+	}
+
+	// Ensure that there are no holes in the fragment chain
+	expectedOffset := 0
+	for _, data := range fragments {
+		eh := V6Header(data.Buf())
+		offset := eh.FragmentOffset() * 8
+		if offset > expectedOffset {
+			// There's a gap. Await more fragments
+			return nil
+		}
+		expectedOffset = offset + eh.PayloadLen()
+	}
+	delete(fragsMap, id)
+	return reassemble(fragments, expectedOffset)
+}
+
+// reassemble returns a packet without the fragment header that has the concatenated payload of the
+// given fragments, which are sorted by offset and have the given total payload length. The fragments
+// are returned to the pool.
+func reassemble(fragments []*buffer.Data, totalPayload int) *buffer.Data {
+	firstHeader := V6Header(fragments[0].Buf())
+
+	// The headers that precede the fragment header are retained. The next header field that declares
+	// the fragment header is given the protocol that the fragment header declares.
+	_, fragEnd, nhPos := firstHeader.walk()
+	unfragmentableLen := fragEnd - 8
+	nextHeader := firstHeader[unfragmentableLen]
+
+	final := buffer.DataPool.Get(unfragmentableLen + totalPayload)
+	fb := final.Buf()
+	copy(fb[:unfragmentableLen], firstHeader)
+	for _, data := range fragments {
+		eh := V6Header(data.Buf())
+		copy(fb[unfragmentableLen+eh.FragmentOffset()*8:], eh.Payload())
+		buffer.DataPool.Put(data)
+	}
+
+	fb[nhPos] = nextHeader
+	binary.BigEndian.PutUint16(fb[4:], uint16(unfragmentableLen+totalPayload-ipv6.HeaderLen))
+	return final
 }
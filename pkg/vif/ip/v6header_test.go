@@ -0,0 +1,214 @@
+package ip
+
+import (
+	"encoding/binary"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/ipv6"
+
+	"github.com/telepresenceio/telepresence/v2/pkg/ipproto"
+	"github.com/telepresenceio/telepresence/v2/pkg/vif/buffer"
+)
+
+var (
+	v6Src = net.ParseIP("fd00::1")
+	v6Dst = net.ParseIP("fd01::2")
+)
+
+// v6Packet returns a packet with the given extension headers, each given as its protocol followed by its
+// content (without the next header field), and the given payload with protocol l4Proto.
+func v6Packet(exts [][]byte, l4Proto int, payload []byte) *buffer.Data {
+	b := make([]byte, ipv6.HeaderLen)
+	V6Header(b).Initialize()
+	copy(b[8:], v6Src)
+	copy(b[24:], v6Dst)
+	nhPos := 6
+	for _, ext := range exts {
+		b[nhPos] = ext[0]
+		nhPos = len(b)
+		b = append(b, 0)
+		b = append(b, ext[1:]...)
+	}
+	b[nhPos] = uint8(l4Proto)
+	b = append(b, payload...)
+	binary.BigEndian.PutUint16(b[4:], uint16(len(b)-ipv6.HeaderLen))
+	data := buffer.DataPool.Get(len(b))
+	copy(data.Buf(), b)
+	return data
+}
+
+// hopByHop returns an 8 byte hop-by-hop options header with padding only.
+func hopByHop() []byte {
+	return []byte{ipproto.HOPOPT, 0, 1, 4, 0, 0, 0, 0}
+}
+
+// destOpts returns a 16 byte destination options header with padding only.
+func destOpts() []byte {
+	return []byte{ipproto.IPV6Opts, 1, 1, 12, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+}
+
+func fragment(offset int, more bool, id uint32) []byte {
+	fh := make([]byte, 8)
+	fh[0] = ipproto.IPV6Frag
+	om := uint16(offset << 3)
+	if more {
+		om |= 1
+	}
+	binary.BigEndian.PutUint16(fh[2:], om)
+	binary.BigEndian.PutUint32(fh[4:], id)
+	return fh
+}
+
+func TestV6Header_ExtensionHeaders(t *testing.T) {
+	payload := []byte("0123456789abcdef")
+	tests := []struct {
+		name      string
+		exts      [][]byte
+		headerLen int
+	}{
+		{"no extension headers", nil, ipv6.HeaderLen},
+		{"hop-by-hop", [][]byte{hopByHop()}, ipv6.HeaderLen + 8},
+		{"hop-by-hop and destination options", [][]byte{hopByHop(), destOpts()}, ipv6.HeaderLen + 24},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := v6Packet(tt.exts, ipproto.UDP, payload)
+			defer buffer.DataPool.Put(data)
+			h, err := ParseHeader(data.Buf())
+			require.NoError(t, err)
+			assert.Equal(t, ipproto.UDP, h.L4Protocol())
+			assert.Equal(t, tt.headerLen, h.HeaderLen())
+			assert.Equal(t, len(payload), h.PayloadLen())
+			assert.Equal(t, payload, h.Payload())
+			assert.Equal(t, data.Buf(), h.Packet())
+			assert.Equal(t, len(payload), int(binary.BigEndian.Uint32(h.PseudoHeader(ipproto.UDP)[32:])))
+
+			h.SetL4Protocol(ipproto.TCP)
+			assert.Equal(t, ipproto.TCP, h.L4Protocol())
+			assert.Equal(t, tt.headerLen, h.HeaderLen())
+
+			h.SetPayloadLen(len(payload) - 2)
+			assert.Equal(t, len(payload)-2, h.PayloadLen())
+			assert.Equal(t, payload[:len(payload)-2], h.Payload())
+		})
+	}
+}
+
+func TestV6Header_Initialize(t *testing.T) {
+	p := &testPacket{}
+	InitPacket(p, 8, v6Src, v6Dst)
+	h := p.IPHeader()
+	assert.Equal(t, ipproto.IPV6NoNxt, h.L4Protocol())
+	assert.Equal(t, ipv6.HeaderLen, h.HeaderLen())
+	assert.Equal(t, 8, h.PayloadLen())
+	h.SetL4Protocol(ipproto.UDP)
+	assert.Equal(t, ipproto.UDP, h.L4Protocol())
+}
+
+type testPacket struct {
+	ipHdr Header
+	data  *buffer.Data
+}
+
+func (p *testPacket) IPHeader() Header {
+	return p.ipHdr
+}
+
+func (p *testPacket) Data() *buffer.Data {
+	return p.data
+}
+
+func (p *testPacket) SetDataAndIPHeader(data *buffer.Data, ipHdr Header) {
+	p.ipHdr = ipHdr
+	p.data = data
+}
+
+func (p *testPacket) Release() {}
+
+func TestV6Header_ConcatFragments(t *testing.T) {
+	payload := make([]byte, 40)
+	for i := range payload {
+		payload[i] = byte(i)
+	}
+	frags := func(id uint32) []*buffer.Data {
+		return []*buffer.Data{
+			v6Packet([][]byte{hopByHop(), fragment(0, true, id)}, ipproto.UDP, payload[:16]),
+			v6Packet([][]byte{hopByHop(), fragment(2, true, id)}, ipproto.UDP, payload[16:32]),
+			v6Packet([][]byte{hopByHop(), fragment(4, false, id)}, ipproto.UDP, payload[32:]),
+		}
+	}
+	assertConcatenated := func(t *testing.T, data *buffer.Data) {
+		require.NotNil(t, data)
+		h := V6Header(data.Buf())
+		assert.Equal(t, ipproto.UDP, h.L4Protocol())
+		assert.Equal(t, ipv6.HeaderLen+8, h.HeaderLen())
+		assert.Equal(t, payload, h.Payload())
+		assert.Nil(t, h.fragmentHeader())
+		assert.Equal(t, 0, h.FragmentOffset())
+		assert.False(t, h.MoreFragments())
+	}
+
+	t.Run("in order", func(t *testing.T) {
+		fm := make(map[uint32][]*buffer.Data)
+		fs := frags(1)
+		h := V6Header(fs[1].Buf())
+		assert.Equal(t, ipproto.IPV6Frag, h.L4Protocol())
+		assert.Equal(t, 2, h.FragmentOffset())
+		assert.True(t, h.MoreFragments())
+		assert.Equal(t, uint32(1), h.FragmentID())
+		assert.Equal(t, 16, h.PayloadLen())
+
+		assert.Nil(t, V6Header(fs[0].Buf()).ConcatFragments(fs[0], fm))
+		assert.Nil(t, V6Header(fs[1].Buf()).ConcatFragments(fs[1], fm))
+		data := V6Header(fs[2].Buf()).ConcatFragments(fs[2], fm)
+		assertConcatenated(t, data)
+		buffer.DataPool.Put(data)
+		assert.Empty(t, fm)
+	})
+
+	t.Run("out of order", func(t *testing.T) {
+		fm := make(map[uint32][]*buffer.Data)
+		fs := frags(2)
+		assert.Nil(t, V6Header(fs[2].Buf()).ConcatFragments(fs[2], fm))
+		assert.Nil(t, V6Header(fs[0].Buf()).ConcatFragments(fs[0], fm))
+		data := V6Header(fs[1].Buf()).ConcatFragments(fs[1], fm)
+		assertConcatenated(t, data)
+		buffer.DataPool.Put(data)
+		assert.Empty(t, fm)
+	})
+
+	t.Run("with gap", func(t *testing.T) {
+		fm := make(map[uint32][]*buffer.Data)
+		fs := frags(3)
+		assert.Nil(t, V6Header(fs[0].Buf()).ConcatFragments(fs[0], fm))
+		assert.Nil(t, V6Header(fs[2].Buf()).ConcatFragments(fs[2], fm))
+		assert.Len(t, fm[3], 2)
+		buffer.DataPool.Put(fs[1])
+	})
+
+	t.Run("atomic fragment", func(t *testing.T) {
+		fm := make(map[uint32][]*buffer.Data)
+		pending := frags(4)
+		assert.Nil(t, V6Header(pending[0].Buf()).ConcatFragments(pending[0], fm))
+
+		// An atomic fragment is delivered at once, even when its identification is in use.
+		atomic := v6Packet([][]byte{hopByHop(), fragment(0, false, 4)}, ipproto.UDP, payload)
+		data := V6Header(atomic.Buf()).ConcatFragments(atomic, fm)
+		assertConcatenated(t, data)
+		buffer.DataPool.Put(data)
+		assert.Len(t, fm[4], 1)
+		buffer.DataPool.Put(pending[1])
+		buffer.DataPool.Put(pending[2])
+	})
+
+	t.Run("not a fragment", func(t *testing.T) {
+		fm := make(map[uint32][]*buffer.Data)
+		data := v6Packet([][]byte{hopByHop()}, ipproto.UDP, payload)
+		assert.Same(t, data, V6Header(data.Buf()).ConcatFragments(data, fm))
+		buffer.DataPool.Put(data)
+		assert.Empty(t, fm)
+	})
+}
@@ -8,6 +8,8 @@ import (
 	"sync/atomic"
 	"time"
 
+	"golang.org/x/net/ipv6"
+
 	"github.com/datawire/dlib/dlog"
 	"github.com/telepresenceio/telepresence/v2/pkg/ipproto"
 	"github.com/telepresenceio/telepresence/v2/pkg/tunnel"
@@ -352,7 +354,11 @@ func (h *handler) sendSyn(ctx context.Context) {
 	opts := tcpHdr.OptionBytes()
 	opts[0] = byte(maximumSegmentSize)
 	opts[1] = 4
-	binary.BigEndian.PutUint16(opts[2:], uint16(maxSegmentSize))
+	mss := maxSegmentSize
+	if ipHdr := pkt.IPHeader(); ipHdr.Version() == ipv6.Version {
+		mss -= ipv6.HeaderLen - 20 // the IPv6 header is 20 bytes longer than the IPv4 header
+	}
+	binary.BigEndian.PutUint16(opts[2:], uint16(mss))
 
 	opts[4] = byte(windowScale)
 	opts[5] = 3
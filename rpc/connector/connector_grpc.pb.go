@@ -80,6 +80,26 @@ type ConnectorClient interface {
 	// GatherLogs will acquire logs for the various Telepresence components in kubernetes
 	// (pending the request) and return them to the caller
 	GatherLogs(ctx context.Context, in *LogsRequest, opts ...grpc.CallOption) (*LogsResponse, error)
+	// WatchLogs streams the log output of the traffic-manager, or of the
+	// traffic-agents of a workload, through the traffic-manager. The session
+	// and the namespaces of the request are set by the connector.
+	WatchLogs(ctx context.Context, in *manager.WatchLogsRequest, opts ...grpc.CallOption) (Connector_WatchLogsClient, error)
+	// Exec runs a command in the traffic-agent container of a workload
+	// through the traffic-manager. The session and the namespaces of the
+	// request in the first message are set by the connector.
+	Exec(ctx context.Context, opts ...grpc.CallOption) (Connector_ExecClient, error)
+	// Copy copies files between the volumes that the traffic-agent of a
+	// workload exposes and the local filesystem, using the agent's sftp-server.
+	Copy(ctx context.Context, in *CopyRequest, opts ...grpc.CallOption) (Connector_CopyClient, error)
+	// GetEnvironment returns the environment of a workload's app container
+	// without intercepting it.
+	GetEnvironment(ctx context.Context, in *EnvironmentRequest, opts ...grpc.CallOption) (*EnvironmentResponse, error)
+	// Expose creates a Service that relays the connections that it receives
+	// to a local port. The session and the namespaces of the request are set
+	// by the connector.
+	Expose(ctx context.Context, in *manager.ExposeRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// Unexpose removes a Service that was created by Expose.
+	Unexpose(ctx context.Context, in *manager.UnexposeRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
 	// AddInterceptor tells the connector that a given process is serving a specific
 	// intercept. The connector must kill this process when the intercept ends
 	AddInterceptor(ctx context.Context, in *Interceptor, opts ...grpc.CallOption) (*emptypb.Empty, error)
@@ -348,6 +368,128 @@ func (c *connectorClient) GatherLogs(ctx context.Context, in *LogsRequest, opts
 	return out, nil
 }
 
+func (c *connectorClient) WatchLogs(ctx context.Context, in *manager.WatchLogsRequest, opts ...grpc.CallOption) (Connector_WatchLogsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Connector_ServiceDesc.Streams[2], "/telepresence.connector.Connector/WatchLogs", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &connectorWatchLogsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Connector_WatchLogsClient interface {
+	Recv() (*manager.LogEntry, error)
+	grpc.ClientStream
+}
+
+type connectorWatchLogsClient struct {
+	grpc.ClientStream
+}
+
+func (x *connectorWatchLogsClient) Recv() (*manager.LogEntry, error) {
+	m := new(manager.LogEntry)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *connectorClient) Exec(ctx context.Context, opts ...grpc.CallOption) (Connector_ExecClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Connector_ServiceDesc.Streams[3], "/telepresence.connector.Connector/Exec", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &connectorExecClient{stream}
+	return x, nil
+}
+
+type Connector_ExecClient interface {
+	Send(*manager.ExecMessage) error
+	Recv() (*manager.ExecMessage, error)
+	grpc.ClientStream
+}
+
+type connectorExecClient struct {
+	grpc.ClientStream
+}
+
+func (x *connectorExecClient) Send(m *manager.ExecMessage) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *connectorExecClient) Recv() (*manager.ExecMessage, error) {
+	m := new(manager.ExecMessage)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *connectorClient) Copy(ctx context.Context, in *CopyRequest, opts ...grpc.CallOption) (Connector_CopyClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Connector_ServiceDesc.Streams[4], "/telepresence.connector.Connector/Copy", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &connectorCopyClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Connector_CopyClient interface {
+	Recv() (*CopyProgress, error)
+	grpc.ClientStream
+}
+
+type connectorCopyClient struct {
+	grpc.ClientStream
+}
+
+func (x *connectorCopyClient) Recv() (*CopyProgress, error) {
+	m := new(CopyProgress)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *connectorClient) GetEnvironment(ctx context.Context, in *EnvironmentRequest, opts ...grpc.CallOption) (*EnvironmentResponse, error) {
+	out := new(EnvironmentResponse)
+	err := c.cc.Invoke(ctx, "/telepresence.connector.Connector/GetEnvironment", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *connectorClient) Expose(ctx context.Context, in *manager.ExposeRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, "/telepresence.connector.Connector/Expose", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *connectorClient) Unexpose(ctx context.Context, in *manager.UnexposeRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, "/telepresence.connector.Connector/Unexpose", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *connectorClient) AddInterceptor(ctx context.Context, in *Interceptor, opts ...grpc.CallOption) (*emptypb.Empty, error) {
 	out := new(emptypb.Empty)
 	err := c.cc.Invoke(ctx, "/telepresence.connector.Connector/AddInterceptor", in, out, opts...)
@@ -424,6 +566,26 @@ type ConnectorServer interface {
 	// GatherLogs will acquire logs for the various Telepresence components in kubernetes
 	// (pending the request) and return them to the caller
 	GatherLogs(context.Context, *LogsRequest) (*LogsResponse, error)
+	// WatchLogs streams the log output of the traffic-manager, or of the
+	// traffic-agents of a workload, through the traffic-manager. The session
+	// and the namespaces of the request are set by the connector.
+	WatchLogs(*manager.WatchLogsRequest, Connector_WatchLogsServer) error
+	// Exec runs a command in the traffic-agent container of a workload
+	// through the traffic-manager. The session and the namespaces of the
+	// request in the first message are set by the connector.
+	Exec(Connector_ExecServer) error
+	// Copy copies files between the volumes that the traffic-agent of a
+	// workload exposes and the local filesystem, using the agent's sftp-server.
+	Copy(*CopyRequest, Connector_CopyServer) error
+	// GetEnvironment returns the environment of a workload's app container
+	// without intercepting it.
+	GetEnvironment(context.Context, *EnvironmentRequest) (*EnvironmentResponse, error)
+	// Expose creates a Service that relays the connections that it receives
+	// to a local port. The session and the namespaces of the request are set
+	// by the connector.
+	Expose(context.Context, *manager.ExposeRequest) (*emptypb.Empty, error)
+	// Unexpose removes a Service that was created by Expose.
+	Unexpose(context.Context, *manager.UnexposeRequest) (*emptypb.Empty, error)
 	// AddInterceptor tells the connector that a given process is serving a specific
 	// intercept. The connector must kill this process when the intercept ends
 	AddInterceptor(context.Context, *Interceptor) (*emptypb.Empty, error)
@@ -505,6 +667,24 @@ func (UnimplementedConnectorServer) ResolveIngressInfo(context.Context, *userdae
 func (UnimplementedConnectorServer) GatherLogs(context.Context, *LogsRequest) (*LogsResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GatherLogs not implemented")
 }
+func (UnimplementedConnectorServer) WatchLogs(*manager.WatchLogsRequest, Connector_WatchLogsServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchLogs not implemented")
+}
+func (UnimplementedConnectorServer) Exec(Connector_ExecServer) error {
+	return status.Errorf(codes.Unimplemented, "method Exec not implemented")
+}
+func (UnimplementedConnectorServer) Copy(*CopyRequest, Connector_CopyServer) error {
+	return status.Errorf(codes.Unimplemented, "method Copy not implemented")
+}
+func (UnimplementedConnectorServer) GetEnvironment(context.Context, *EnvironmentRequest) (*EnvironmentResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetEnvironment not implemented")
+}
+func (UnimplementedConnectorServer) Expose(context.Context, *manager.ExposeRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Expose not implemented")
+}
+func (UnimplementedConnectorServer) Unexpose(context.Context, *manager.UnexposeRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Unexpose not implemented")
+}
 func (UnimplementedConnectorServer) AddInterceptor(context.Context, *Interceptor) (*emptypb.Empty, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method AddInterceptor not implemented")
 }
@@ -944,6 +1124,128 @@ func _Connector_GatherLogs_Handler(srv interface{}, ctx context.Context, dec fun
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Connector_WatchLogs_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(manager.WatchLogsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ConnectorServer).WatchLogs(m, &connectorWatchLogsServer{stream})
+}
+
+type Connector_WatchLogsServer interface {
+	Send(*manager.LogEntry) error
+	grpc.ServerStream
+}
+
+type connectorWatchLogsServer struct {
+	grpc.ServerStream
+}
+
+func (x *connectorWatchLogsServer) Send(m *manager.LogEntry) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Connector_Exec_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(ConnectorServer).Exec(&connectorExecServer{stream})
+}
+
+type Connector_ExecServer interface {
+	Send(*manager.ExecMessage) error
+	Recv() (*manager.ExecMessage, error)
+	grpc.ServerStream
+}
+
+type connectorExecServer struct {
+	grpc.ServerStream
+}
+
+func (x *connectorExecServer) Send(m *manager.ExecMessage) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *connectorExecServer) Recv() (*manager.ExecMessage, error) {
+	m := new(manager.ExecMessage)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _Connector_Copy_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(CopyRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ConnectorServer).Copy(m, &connectorCopyServer{stream})
+}
+
+type Connector_CopyServer interface {
+	Send(*CopyProgress) error
+	grpc.ServerStream
+}
+
+type connectorCopyServer struct {
+	grpc.ServerStream
+}
+
+func (x *connectorCopyServer) Send(m *CopyProgress) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Connector_GetEnvironment_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EnvironmentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConnectorServer).GetEnvironment(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/telepresence.connector.Connector/GetEnvironment",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConnectorServer).GetEnvironment(ctx, req.(*EnvironmentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Connector_Expose_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(manager.ExposeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConnectorServer).Expose(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/telepresence.connector.Connector/Expose",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConnectorServer).Expose(ctx, req.(*manager.ExposeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Connector_Unexpose_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(manager.UnexposeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConnectorServer).Unexpose(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/telepresence.connector.Connector/Unexpose",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConnectorServer).Unexpose(ctx, req.(*manager.UnexposeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _Connector_AddInterceptor_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(Interceptor)
 	if err := dec(in); err != nil {
@@ -1071,6 +1373,18 @@ var Connector_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GatherLogs",
 			Handler:    _Connector_GatherLogs_Handler,
 		},
+		{
+			MethodName: "GetEnvironment",
+			Handler:    _Connector_GetEnvironment_Handler,
+		},
+		{
+			MethodName: "Expose",
+			Handler:    _Connector_Expose_Handler,
+		},
+		{
+			MethodName: "Unexpose",
+			Handler:    _Connector_Unexpose_Handler,
+		},
 		{
 			MethodName: "AddInterceptor",
 			Handler:    _Connector_AddInterceptor_Handler,
@@ -1091,6 +1405,22 @@ var Connector_ServiceDesc = grpc.ServiceDesc{
 			Handler:       _Connector_UserNotifications_Handler,
 			ServerStreams: true,
 		},
+		{
+			StreamName:    "WatchLogs",
+			Handler:       _Connector_WatchLogs_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "Exec",
+			Handler:       _Connector_Exec_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "Copy",
+			Handler:       _Connector_Copy_Handler,
+			ServerStreams: true,
+		},
 	},
 	Metadata: "rpc/connector/connector.proto",
 }
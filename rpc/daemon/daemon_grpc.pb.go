@@ -39,8 +39,23 @@ type DaemonClient interface {
 	GetClusterSubnets(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*ClusterSubnets, error)
 	// SetDnsSearchPath sets a new search path.
 	SetDnsSearchPath(ctx context.Context, in *Paths, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// SetLocalServices declares the services that are intercepted by this client and served on
+	// the workstation. The DNS resolver answers queries for their names with the given addresses.
+	SetLocalServices(ctx context.Context, in *LocalServices, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// GetDNSStats returns the request and cache statistics of the DNS resolver.
+	GetDNSStats(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*DNSStats, error)
+	// FlushDNS removes all entries from the cache of the DNS resolver.
+	FlushDNS(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// WatchDNSQueries streams the most recent queries handled by the DNS resolver, and
+	// then, if follow is requested, each new query as it is handled.
+	WatchDNSQueries(ctx context.Context, in *WatchDNSQueriesRequest, opts ...grpc.CallOption) (Daemon_WatchDNSQueriesClient, error)
 	// SetLogLevel will temporarily set the log-level for the daemon for a duration that is determined b the request.
 	SetLogLevel(ctx context.Context, in *manager.LogLevelRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// MountNFS mounts a directory that a traffic-agent exports over NFS. It's done by this
+	// daemon because mounting requires privileges that the user daemon doesn't have.
+	MountNFS(ctx context.Context, in *NFSMountRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// UnmountNFS unmounts a directory that was mounted using MountNFS.
+	UnmountNFS(ctx context.Context, in *NFSMountRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
 }
 
 type daemonClient struct {
@@ -114,6 +129,65 @@ func (c *daemonClient) SetDnsSearchPath(ctx context.Context, in *Paths, opts ...
 	return out, nil
 }
 
+func (c *daemonClient) SetLocalServices(ctx context.Context, in *LocalServices, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, "/telepresence.daemon.Daemon/SetLocalServices", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *daemonClient) GetDNSStats(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*DNSStats, error) {
+	out := new(DNSStats)
+	err := c.cc.Invoke(ctx, "/telepresence.daemon.Daemon/GetDNSStats", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *daemonClient) FlushDNS(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, "/telepresence.daemon.Daemon/FlushDNS", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *daemonClient) WatchDNSQueries(ctx context.Context, in *WatchDNSQueriesRequest, opts ...grpc.CallOption) (Daemon_WatchDNSQueriesClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Daemon_ServiceDesc.Streams[0], "/telepresence.daemon.Daemon/WatchDNSQueries", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &daemonWatchDNSQueriesClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Daemon_WatchDNSQueriesClient interface {
+	Recv() (*DNSQuery, error)
+	grpc.ClientStream
+}
+
+type daemonWatchDNSQueriesClient struct {
+	grpc.ClientStream
+}
+
+func (x *daemonWatchDNSQueriesClient) Recv() (*DNSQuery, error) {
+	m := new(DNSQuery)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 func (c *daemonClient) SetLogLevel(ctx context.Context, in *manager.LogLevelRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
 	out := new(emptypb.Empty)
 	err := c.cc.Invoke(ctx, "/telepresence.daemon.Daemon/SetLogLevel", in, out, opts...)
@@ -123,6 +197,24 @@ func (c *daemonClient) SetLogLevel(ctx context.Context, in *manager.LogLevelRequ
 	return out, nil
 }
 
+func (c *daemonClient) MountNFS(ctx context.Context, in *NFSMountRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, "/telepresence.daemon.Daemon/MountNFS", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *daemonClient) UnmountNFS(ctx context.Context, in *NFSMountRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, "/telepresence.daemon.Daemon/UnmountNFS", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // DaemonServer is the server API for Daemon service.
 // All implementations must embed UnimplementedDaemonServer
 // for forward compatibility
@@ -141,8 +233,23 @@ type DaemonServer interface {
 	GetClusterSubnets(context.Context, *emptypb.Empty) (*ClusterSubnets, error)
 	// SetDnsSearchPath sets a new search path.
 	SetDnsSearchPath(context.Context, *Paths) (*emptypb.Empty, error)
+	// SetLocalServices declares the services that are intercepted by this client and served on
+	// the workstation. The DNS resolver answers queries for their names with the given addresses.
+	SetLocalServices(context.Context, *LocalServices) (*emptypb.Empty, error)
+	// GetDNSStats returns the request and cache statistics of the DNS resolver.
+	GetDNSStats(context.Context, *emptypb.Empty) (*DNSStats, error)
+	// FlushDNS removes all entries from the cache of the DNS resolver.
+	FlushDNS(context.Context, *emptypb.Empty) (*emptypb.Empty, error)
+	// WatchDNSQueries streams the most recent queries handled by the DNS resolver, and
+	// then, if follow is requested, each new query as it is handled.
+	WatchDNSQueries(*WatchDNSQueriesRequest, Daemon_WatchDNSQueriesServer) error
 	// SetLogLevel will temporarily set the log-level for the daemon for a duration that is determined b the request.
 	SetLogLevel(context.Context, *manager.LogLevelRequest) (*emptypb.Empty, error)
+	// MountNFS mounts a directory that a traffic-agent exports over NFS. It's done by this
+	// daemon because mounting requires privileges that the user daemon doesn't have.
+	MountNFS(context.Context, *NFSMountRequest) (*emptypb.Empty, error)
+	// UnmountNFS unmounts a directory that was mounted using MountNFS.
+	UnmountNFS(context.Context, *NFSMountRequest) (*emptypb.Empty, error)
 	mustEmbedUnimplementedDaemonServer()
 }
 
@@ -171,9 +278,27 @@ func (UnimplementedDaemonServer) GetClusterSubnets(context.Context, *emptypb.Emp
 func (UnimplementedDaemonServer) SetDnsSearchPath(context.Context, *Paths) (*emptypb.Empty, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method SetDnsSearchPath not implemented")
 }
+func (UnimplementedDaemonServer) SetLocalServices(context.Context, *LocalServices) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetLocalServices not implemented")
+}
+func (UnimplementedDaemonServer) GetDNSStats(context.Context, *emptypb.Empty) (*DNSStats, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetDNSStats not implemented")
+}
+func (UnimplementedDaemonServer) FlushDNS(context.Context, *emptypb.Empty) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method FlushDNS not implemented")
+}
+func (UnimplementedDaemonServer) WatchDNSQueries(*WatchDNSQueriesRequest, Daemon_WatchDNSQueriesServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchDNSQueries not implemented")
+}
 func (UnimplementedDaemonServer) SetLogLevel(context.Context, *manager.LogLevelRequest) (*emptypb.Empty, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method SetLogLevel not implemented")
 }
+func (UnimplementedDaemonServer) MountNFS(context.Context, *NFSMountRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method MountNFS not implemented")
+}
+func (UnimplementedDaemonServer) UnmountNFS(context.Context, *NFSMountRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UnmountNFS not implemented")
+}
 func (UnimplementedDaemonServer) mustEmbedUnimplementedDaemonServer() {}
 
 // UnsafeDaemonServer may be embedded to opt out of forward compatibility for this service.
@@ -313,6 +438,81 @@ func _Daemon_SetDnsSearchPath_Handler(srv interface{}, ctx context.Context, dec
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Daemon_SetLocalServices_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LocalServices)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DaemonServer).SetLocalServices(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/telepresence.daemon.Daemon/SetLocalServices",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DaemonServer).SetLocalServices(ctx, req.(*LocalServices))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Daemon_GetDNSStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DaemonServer).GetDNSStats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/telepresence.daemon.Daemon/GetDNSStats",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DaemonServer).GetDNSStats(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Daemon_FlushDNS_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DaemonServer).FlushDNS(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/telepresence.daemon.Daemon/FlushDNS",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DaemonServer).FlushDNS(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Daemon_WatchDNSQueries_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchDNSQueriesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(DaemonServer).WatchDNSQueries(m, &daemonWatchDNSQueriesServer{stream})
+}
+
+type Daemon_WatchDNSQueriesServer interface {
+	Send(*DNSQuery) error
+	grpc.ServerStream
+}
+
+type daemonWatchDNSQueriesServer struct {
+	grpc.ServerStream
+}
+
+func (x *daemonWatchDNSQueriesServer) Send(m *DNSQuery) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 func _Daemon_SetLogLevel_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(manager.LogLevelRequest)
 	if err := dec(in); err != nil {
@@ -331,6 +531,42 @@ func _Daemon_SetLogLevel_Handler(srv interface{}, ctx context.Context, dec func(
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Daemon_MountNFS_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(NFSMountRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DaemonServer).MountNFS(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/telepresence.daemon.Daemon/MountNFS",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DaemonServer).MountNFS(ctx, req.(*NFSMountRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Daemon_UnmountNFS_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(NFSMountRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DaemonServer).UnmountNFS(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/telepresence.daemon.Daemon/UnmountNFS",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DaemonServer).UnmountNFS(ctx, req.(*NFSMountRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Daemon_ServiceDesc is the grpc.ServiceDesc for Daemon service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -366,11 +602,37 @@ var Daemon_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "SetDnsSearchPath",
 			Handler:    _Daemon_SetDnsSearchPath_Handler,
 		},
+		{
+			MethodName: "SetLocalServices",
+			Handler:    _Daemon_SetLocalServices_Handler,
+		},
+		{
+			MethodName: "GetDNSStats",
+			Handler:    _Daemon_GetDNSStats_Handler,
+		},
+		{
+			MethodName: "FlushDNS",
+			Handler:    _Daemon_FlushDNS_Handler,
+		},
 		{
 			MethodName: "SetLogLevel",
 			Handler:    _Daemon_SetLogLevel_Handler,
 		},
+		{
+			MethodName: "MountNFS",
+			Handler:    _Daemon_MountNFS_Handler,
+		},
+		{
+			MethodName: "UnmountNFS",
+			Handler:    _Daemon_UnmountNFS_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchDNSQueries",
+			Handler:       _Daemon_WatchDNSQueries_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "rpc/daemon/daemon.proto",
 }
@@ -102,8 +102,52 @@ type ManagerClient interface {
 	AgentLookupHostResponse(ctx context.Context, in *LookupHostAgentResponse, opts ...grpc.CallOption) (*emptypb.Empty, error)
 	// WatchLookupHost lets an agent receive lookup requests
 	WatchLookupHost(ctx context.Context, in *SessionInfo, opts ...grpc.CallOption) (Manager_WatchLookupHostClient, error)
+	// LookupDNS performs a DNS lookup of any record type in the cluster and
+	// returns all resource records of the answer. If the caller has intercepts
+	// active, the lookup will be performed from the intercepted pods.
+	LookupDNS(ctx context.Context, in *DNSRequest, opts ...grpc.CallOption) (*DNSResponse, error)
+	// AgentLookupDNSResponse lets an agent respond for DNS requests
+	AgentLookupDNSResponse(ctx context.Context, in *DNSAgentResponse, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// WatchLookupDNS lets an agent receive DNS requests
+	WatchLookupDNS(ctx context.Context, in *SessionInfo, opts ...grpc.CallOption) (Manager_WatchLookupDNSClient, error)
+	// Ping sends an ICMP echo request to an IP in the cluster and returns the
+	// echo reply, or the ICMP error that was received instead. A DeadlineExceeded
+	// error is returned when nothing is received before the timeout.
+	Ping(ctx context.Context, in *PingRequest, opts ...grpc.CallOption) (*PingResponse, error)
 	// WatchLogLevel lets an agent receive log-level updates
 	WatchLogLevel(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (Manager_WatchLogLevelClient, error)
+	// WatchLogs streams the log output of the traffic-manager, or of the
+	// traffic-agents of a workload, to a client.
+	WatchLogs(ctx context.Context, in *WatchLogsRequest, opts ...grpc.CallOption) (Manager_WatchLogsClient, error)
+	// WatchAgentLogsRequests lets an agent receive requests for its log output.
+	WatchAgentLogsRequests(ctx context.Context, in *SessionInfo, opts ...grpc.CallOption) (Manager_WatchAgentLogsRequestsClient, error)
+	// AgentLogs lets an agent send the log output that was requested by an
+	// AgentLogsRequest. The call returns when the output is no longer wanted.
+	AgentLogs(ctx context.Context, opts ...grpc.CallOption) (Manager_AgentLogsClient, error)
+	// Exec runs a command in the traffic-agent container of a workload and
+	// streams its standard I/O. The first message must contain the request.
+	// The traffic-manager refuses the call unless exec has been enabled.
+	Exec(ctx context.Context, opts ...grpc.CallOption) (Manager_ExecClient, error)
+	// WatchExecRequests lets an agent receive requests to run commands.
+	WatchExecRequests(ctx context.Context, in *SessionInfo, opts ...grpc.CallOption) (Manager_WatchExecRequestsClient, error)
+	// AgentExec lets an agent stream the standard I/O of a command that it
+	// runs in response to an ExecRequest. The first message must contain
+	// the agent session and the request ID.
+	AgentExec(ctx context.Context, opts ...grpc.CallOption) (Manager_AgentExecClient, error)
+	// ReportEnvironment lets an agent tell the traffic-manager what the
+	// environment of its app containers is.
+	ReportEnvironment(ctx context.Context, in *AgentEnvironment, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// GetEnvironment returns the environment of a workload's app container,
+	// as reported by its traffic-agent. The traffic-manager refuses the call
+	// unless the workload's namespace is accessible to the client.
+	GetEnvironment(ctx context.Context, in *EnvironmentRequest, opts ...grpc.CallOption) (*EnvironmentResponse, error)
+	// Expose creates a Service that relays the connections that it receives
+	// to the client's workstation. The Service is removed when Unexpose is
+	// called or when the client session ends. The traffic-manager refuses
+	// the call unless expose has been enabled.
+	Expose(ctx context.Context, in *ExposeRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// Unexpose removes a Service that was created by Expose.
+	Unexpose(ctx context.Context, in *UnexposeRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
 	// A Tunnel represents one single connection where the client or
 	// traffic-agent represents one end (the client-side) and the
 	// traffic-manager represents the other (the server side). The first
@@ -523,8 +567,67 @@ func (x *managerWatchLookupHostClient) Recv() (*LookupHostRequest, error) {
 	return m, nil
 }
 
+func (c *managerClient) LookupDNS(ctx context.Context, in *DNSRequest, opts ...grpc.CallOption) (*DNSResponse, error) {
+	out := new(DNSResponse)
+	err := c.cc.Invoke(ctx, "/telepresence.manager.Manager/LookupDNS", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *managerClient) AgentLookupDNSResponse(ctx context.Context, in *DNSAgentResponse, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, "/telepresence.manager.Manager/AgentLookupDNSResponse", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *managerClient) WatchLookupDNS(ctx context.Context, in *SessionInfo, opts ...grpc.CallOption) (Manager_WatchLookupDNSClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Manager_ServiceDesc.Streams[7], "/telepresence.manager.Manager/WatchLookupDNS", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &managerWatchLookupDNSClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Manager_WatchLookupDNSClient interface {
+	Recv() (*DNSRequest, error)
+	grpc.ClientStream
+}
+
+type managerWatchLookupDNSClient struct {
+	grpc.ClientStream
+}
+
+func (x *managerWatchLookupDNSClient) Recv() (*DNSRequest, error) {
+	m := new(DNSRequest)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *managerClient) Ping(ctx context.Context, in *PingRequest, opts ...grpc.CallOption) (*PingResponse, error) {
+	out := new(PingResponse)
+	err := c.cc.Invoke(ctx, "/telepresence.manager.Manager/Ping", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *managerClient) WatchLogLevel(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (Manager_WatchLogLevelClient, error) {
-	stream, err := c.cc.NewStream(ctx, &Manager_ServiceDesc.Streams[7], "/telepresence.manager.Manager/WatchLogLevel", opts...)
+	stream, err := c.cc.NewStream(ctx, &Manager_ServiceDesc.Streams[8], "/telepresence.manager.Manager/WatchLogLevel", opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -555,8 +658,236 @@ func (x *managerWatchLogLevelClient) Recv() (*LogLevelRequest, error) {
 	return m, nil
 }
 
+func (c *managerClient) WatchLogs(ctx context.Context, in *WatchLogsRequest, opts ...grpc.CallOption) (Manager_WatchLogsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Manager_ServiceDesc.Streams[9], "/telepresence.manager.Manager/WatchLogs", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &managerWatchLogsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Manager_WatchLogsClient interface {
+	Recv() (*LogEntry, error)
+	grpc.ClientStream
+}
+
+type managerWatchLogsClient struct {
+	grpc.ClientStream
+}
+
+func (x *managerWatchLogsClient) Recv() (*LogEntry, error) {
+	m := new(LogEntry)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *managerClient) WatchAgentLogsRequests(ctx context.Context, in *SessionInfo, opts ...grpc.CallOption) (Manager_WatchAgentLogsRequestsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Manager_ServiceDesc.Streams[10], "/telepresence.manager.Manager/WatchAgentLogsRequests", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &managerWatchAgentLogsRequestsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Manager_WatchAgentLogsRequestsClient interface {
+	Recv() (*AgentLogsRequest, error)
+	grpc.ClientStream
+}
+
+type managerWatchAgentLogsRequestsClient struct {
+	grpc.ClientStream
+}
+
+func (x *managerWatchAgentLogsRequestsClient) Recv() (*AgentLogsRequest, error) {
+	m := new(AgentLogsRequest)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *managerClient) AgentLogs(ctx context.Context, opts ...grpc.CallOption) (Manager_AgentLogsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Manager_ServiceDesc.Streams[11], "/telepresence.manager.Manager/AgentLogs", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &managerAgentLogsClient{stream}
+	return x, nil
+}
+
+type Manager_AgentLogsClient interface {
+	Send(*AgentLogsResponse) error
+	CloseAndRecv() (*emptypb.Empty, error)
+	grpc.ClientStream
+}
+
+type managerAgentLogsClient struct {
+	grpc.ClientStream
+}
+
+func (x *managerAgentLogsClient) Send(m *AgentLogsResponse) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *managerAgentLogsClient) CloseAndRecv() (*emptypb.Empty, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(emptypb.Empty)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *managerClient) Exec(ctx context.Context, opts ...grpc.CallOption) (Manager_ExecClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Manager_ServiceDesc.Streams[12], "/telepresence.manager.Manager/Exec", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &managerExecClient{stream}
+	return x, nil
+}
+
+type Manager_ExecClient interface {
+	Send(*ExecMessage) error
+	Recv() (*ExecMessage, error)
+	grpc.ClientStream
+}
+
+type managerExecClient struct {
+	grpc.ClientStream
+}
+
+func (x *managerExecClient) Send(m *ExecMessage) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *managerExecClient) Recv() (*ExecMessage, error) {
+	m := new(ExecMessage)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *managerClient) WatchExecRequests(ctx context.Context, in *SessionInfo, opts ...grpc.CallOption) (Manager_WatchExecRequestsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Manager_ServiceDesc.Streams[13], "/telepresence.manager.Manager/WatchExecRequests", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &managerWatchExecRequestsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Manager_WatchExecRequestsClient interface {
+	Recv() (*ExecRequest, error)
+	grpc.ClientStream
+}
+
+type managerWatchExecRequestsClient struct {
+	grpc.ClientStream
+}
+
+func (x *managerWatchExecRequestsClient) Recv() (*ExecRequest, error) {
+	m := new(ExecRequest)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *managerClient) AgentExec(ctx context.Context, opts ...grpc.CallOption) (Manager_AgentExecClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Manager_ServiceDesc.Streams[14], "/telepresence.manager.Manager/AgentExec", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &managerAgentExecClient{stream}
+	return x, nil
+}
+
+type Manager_AgentExecClient interface {
+	Send(*ExecMessage) error
+	Recv() (*ExecMessage, error)
+	grpc.ClientStream
+}
+
+type managerAgentExecClient struct {
+	grpc.ClientStream
+}
+
+func (x *managerAgentExecClient) Send(m *ExecMessage) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *managerAgentExecClient) Recv() (*ExecMessage, error) {
+	m := new(ExecMessage)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *managerClient) ReportEnvironment(ctx context.Context, in *AgentEnvironment, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, "/telepresence.manager.Manager/ReportEnvironment", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *managerClient) GetEnvironment(ctx context.Context, in *EnvironmentRequest, opts ...grpc.CallOption) (*EnvironmentResponse, error) {
+	out := new(EnvironmentResponse)
+	err := c.cc.Invoke(ctx, "/telepresence.manager.Manager/GetEnvironment", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *managerClient) Expose(ctx context.Context, in *ExposeRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, "/telepresence.manager.Manager/Expose", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *managerClient) Unexpose(ctx context.Context, in *UnexposeRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, "/telepresence.manager.Manager/Unexpose", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *managerClient) Tunnel(ctx context.Context, opts ...grpc.CallOption) (Manager_TunnelClient, error) {
-	stream, err := c.cc.NewStream(ctx, &Manager_ServiceDesc.Streams[8], "/telepresence.manager.Manager/Tunnel", opts...)
+	stream, err := c.cc.NewStream(ctx, &Manager_ServiceDesc.Streams[15], "/telepresence.manager.Manager/Tunnel", opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -587,7 +918,7 @@ func (x *managerTunnelClient) Recv() (*TunnelMessage, error) {
 }
 
 func (c *managerClient) WatchDial(ctx context.Context, in *SessionInfo, opts ...grpc.CallOption) (Manager_WatchDialClient, error) {
-	stream, err := c.cc.NewStream(ctx, &Manager_ServiceDesc.Streams[9], "/telepresence.manager.Manager/WatchDial", opts...)
+	stream, err := c.cc.NewStream(ctx, &Manager_ServiceDesc.Streams[16], "/telepresence.manager.Manager/WatchDial", opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -701,8 +1032,52 @@ type ManagerServer interface {
 	AgentLookupHostResponse(context.Context, *LookupHostAgentResponse) (*emptypb.Empty, error)
 	// WatchLookupHost lets an agent receive lookup requests
 	WatchLookupHost(*SessionInfo, Manager_WatchLookupHostServer) error
+	// LookupDNS performs a DNS lookup of any record type in the cluster and
+	// returns all resource records of the answer. If the caller has intercepts
+	// active, the lookup will be performed from the intercepted pods.
+	LookupDNS(context.Context, *DNSRequest) (*DNSResponse, error)
+	// AgentLookupDNSResponse lets an agent respond for DNS requests
+	AgentLookupDNSResponse(context.Context, *DNSAgentResponse) (*emptypb.Empty, error)
+	// WatchLookupDNS lets an agent receive DNS requests
+	WatchLookupDNS(*SessionInfo, Manager_WatchLookupDNSServer) error
+	// Ping sends an ICMP echo request to an IP in the cluster and returns the
+	// echo reply, or the ICMP error that was received instead. A DeadlineExceeded
+	// error is returned when nothing is received before the timeout.
+	Ping(context.Context, *PingRequest) (*PingResponse, error)
 	// WatchLogLevel lets an agent receive log-level updates
 	WatchLogLevel(*emptypb.Empty, Manager_WatchLogLevelServer) error
+	// WatchLogs streams the log output of the traffic-manager, or of the
+	// traffic-agents of a workload, to a client.
+	WatchLogs(*WatchLogsRequest, Manager_WatchLogsServer) error
+	// WatchAgentLogsRequests lets an agent receive requests for its log output.
+	WatchAgentLogsRequests(*SessionInfo, Manager_WatchAgentLogsRequestsServer) error
+	// AgentLogs lets an agent send the log output that was requested by an
+	// AgentLogsRequest. The call returns when the output is no longer wanted.
+	AgentLogs(Manager_AgentLogsServer) error
+	// Exec runs a command in the traffic-agent container of a workload and
+	// streams its standard I/O. The first message must contain the request.
+	// The traffic-manager refuses the call unless exec has been enabled.
+	Exec(Manager_ExecServer) error
+	// WatchExecRequests lets an agent receive requests to run commands.
+	WatchExecRequests(*SessionInfo, Manager_WatchExecRequestsServer) error
+	// AgentExec lets an agent stream the standard I/O of a command that it
+	// runs in response to an ExecRequest. The first message must contain
+	// the agent session and the request ID.
+	AgentExec(Manager_AgentExecServer) error
+	// ReportEnvironment lets an agent tell the traffic-manager what the
+	// environment of its app containers is.
+	ReportEnvironment(context.Context, *AgentEnvironment) (*emptypb.Empty, error)
+	// GetEnvironment returns the environment of a workload's app container,
+	// as reported by its traffic-agent. The traffic-manager refuses the call
+	// unless the workload's namespace is accessible to the client.
+	GetEnvironment(context.Context, *EnvironmentRequest) (*EnvironmentResponse, error)
+	// Expose creates a Service that relays the connections that it receives
+	// to the client's workstation. The Service is removed when Unexpose is
+	// called or when the client session ends. The traffic-manager refuses
+	// the call unless expose has been enabled.
+	Expose(context.Context, *ExposeRequest) (*emptypb.Empty, error)
+	// Unexpose removes a Service that was created by Expose.
+	Unexpose(context.Context, *UnexposeRequest) (*emptypb.Empty, error)
 	// A Tunnel represents one single connection where the client or
 	// traffic-agent represents one end (the client-side) and the
 	// traffic-manager represents the other (the server side). The first
@@ -804,9 +1179,51 @@ func (UnimplementedManagerServer) AgentLookupHostResponse(context.Context, *Look
 func (UnimplementedManagerServer) WatchLookupHost(*SessionInfo, Manager_WatchLookupHostServer) error {
 	return status.Errorf(codes.Unimplemented, "method WatchLookupHost not implemented")
 }
+func (UnimplementedManagerServer) LookupDNS(context.Context, *DNSRequest) (*DNSResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method LookupDNS not implemented")
+}
+func (UnimplementedManagerServer) AgentLookupDNSResponse(context.Context, *DNSAgentResponse) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AgentLookupDNSResponse not implemented")
+}
+func (UnimplementedManagerServer) WatchLookupDNS(*SessionInfo, Manager_WatchLookupDNSServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchLookupDNS not implemented")
+}
+func (UnimplementedManagerServer) Ping(context.Context, *PingRequest) (*PingResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Ping not implemented")
+}
 func (UnimplementedManagerServer) WatchLogLevel(*emptypb.Empty, Manager_WatchLogLevelServer) error {
 	return status.Errorf(codes.Unimplemented, "method WatchLogLevel not implemented")
 }
+func (UnimplementedManagerServer) WatchLogs(*WatchLogsRequest, Manager_WatchLogsServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchLogs not implemented")
+}
+func (UnimplementedManagerServer) WatchAgentLogsRequests(*SessionInfo, Manager_WatchAgentLogsRequestsServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchAgentLogsRequests not implemented")
+}
+func (UnimplementedManagerServer) AgentLogs(Manager_AgentLogsServer) error {
+	return status.Errorf(codes.Unimplemented, "method AgentLogs not implemented")
+}
+func (UnimplementedManagerServer) Exec(Manager_ExecServer) error {
+	return status.Errorf(codes.Unimplemented, "method Exec not implemented")
+}
+func (UnimplementedManagerServer) WatchExecRequests(*SessionInfo, Manager_WatchExecRequestsServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchExecRequests not implemented")
+}
+func (UnimplementedManagerServer) AgentExec(Manager_AgentExecServer) error {
+	return status.Errorf(codes.Unimplemented, "method AgentExec not implemented")
+}
+func (UnimplementedManagerServer) ReportEnvironment(context.Context, *AgentEnvironment) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReportEnvironment not implemented")
+}
+func (UnimplementedManagerServer) GetEnvironment(context.Context, *EnvironmentRequest) (*EnvironmentResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetEnvironment not implemented")
+}
+func (UnimplementedManagerServer) Expose(context.Context, *ExposeRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Expose not implemented")
+}
+func (UnimplementedManagerServer) Unexpose(context.Context, *UnexposeRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Unexpose not implemented")
+}
 func (UnimplementedManagerServer) Tunnel(Manager_TunnelServer) error {
 	return status.Errorf(codes.Unimplemented, "method Tunnel not implemented")
 }
@@ -1325,6 +1742,81 @@ func (x *managerWatchLookupHostServer) Send(m *LookupHostRequest) error {
 	return x.ServerStream.SendMsg(m)
 }
 
+func _Manager_LookupDNS_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DNSRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ManagerServer).LookupDNS(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/telepresence.manager.Manager/LookupDNS",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ManagerServer).LookupDNS(ctx, req.(*DNSRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Manager_AgentLookupDNSResponse_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DNSAgentResponse)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ManagerServer).AgentLookupDNSResponse(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/telepresence.manager.Manager/AgentLookupDNSResponse",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ManagerServer).AgentLookupDNSResponse(ctx, req.(*DNSAgentResponse))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Manager_WatchLookupDNS_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SessionInfo)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ManagerServer).WatchLookupDNS(m, &managerWatchLookupDNSServer{stream})
+}
+
+type Manager_WatchLookupDNSServer interface {
+	Send(*DNSRequest) error
+	grpc.ServerStream
+}
+
+type managerWatchLookupDNSServer struct {
+	grpc.ServerStream
+}
+
+func (x *managerWatchLookupDNSServer) Send(m *DNSRequest) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Manager_Ping_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PingRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ManagerServer).Ping(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/telepresence.manager.Manager/Ping",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ManagerServer).Ping(ctx, req.(*PingRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _Manager_WatchLogLevel_Handler(srv interface{}, stream grpc.ServerStream) error {
 	m := new(emptypb.Empty)
 	if err := stream.RecvMsg(m); err != nil {
@@ -1346,6 +1838,219 @@ func (x *managerWatchLogLevelServer) Send(m *LogLevelRequest) error {
 	return x.ServerStream.SendMsg(m)
 }
 
+func _Manager_WatchLogs_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchLogsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ManagerServer).WatchLogs(m, &managerWatchLogsServer{stream})
+}
+
+type Manager_WatchLogsServer interface {
+	Send(*LogEntry) error
+	grpc.ServerStream
+}
+
+type managerWatchLogsServer struct {
+	grpc.ServerStream
+}
+
+func (x *managerWatchLogsServer) Send(m *LogEntry) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Manager_WatchAgentLogsRequests_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SessionInfo)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ManagerServer).WatchAgentLogsRequests(m, &managerWatchAgentLogsRequestsServer{stream})
+}
+
+type Manager_WatchAgentLogsRequestsServer interface {
+	Send(*AgentLogsRequest) error
+	grpc.ServerStream
+}
+
+type managerWatchAgentLogsRequestsServer struct {
+	grpc.ServerStream
+}
+
+func (x *managerWatchAgentLogsRequestsServer) Send(m *AgentLogsRequest) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Manager_AgentLogs_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(ManagerServer).AgentLogs(&managerAgentLogsServer{stream})
+}
+
+type Manager_AgentLogsServer interface {
+	SendAndClose(*emptypb.Empty) error
+	Recv() (*AgentLogsResponse, error)
+	grpc.ServerStream
+}
+
+type managerAgentLogsServer struct {
+	grpc.ServerStream
+}
+
+func (x *managerAgentLogsServer) SendAndClose(m *emptypb.Empty) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *managerAgentLogsServer) Recv() (*AgentLogsResponse, error) {
+	m := new(AgentLogsResponse)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _Manager_Exec_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(ManagerServer).Exec(&managerExecServer{stream})
+}
+
+type Manager_ExecServer interface {
+	Send(*ExecMessage) error
+	Recv() (*ExecMessage, error)
+	grpc.ServerStream
+}
+
+type managerExecServer struct {
+	grpc.ServerStream
+}
+
+func (x *managerExecServer) Send(m *ExecMessage) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *managerExecServer) Recv() (*ExecMessage, error) {
+	m := new(ExecMessage)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _Manager_WatchExecRequests_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SessionInfo)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ManagerServer).WatchExecRequests(m, &managerWatchExecRequestsServer{stream})
+}
+
+type Manager_WatchExecRequestsServer interface {
+	Send(*ExecRequest) error
+	grpc.ServerStream
+}
+
+type managerWatchExecRequestsServer struct {
+	grpc.ServerStream
+}
+
+func (x *managerWatchExecRequestsServer) Send(m *ExecRequest) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Manager_AgentExec_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(ManagerServer).AgentExec(&managerAgentExecServer{stream})
+}
+
+type Manager_AgentExecServer interface {
+	Send(*ExecMessage) error
+	Recv() (*ExecMessage, error)
+	grpc.ServerStream
+}
+
+type managerAgentExecServer struct {
+	grpc.ServerStream
+}
+
+func (x *managerAgentExecServer) Send(m *ExecMessage) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *managerAgentExecServer) Recv() (*ExecMessage, error) {
+	m := new(ExecMessage)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _Manager_ReportEnvironment_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AgentEnvironment)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ManagerServer).ReportEnvironment(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/telepresence.manager.Manager/ReportEnvironment",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ManagerServer).ReportEnvironment(ctx, req.(*AgentEnvironment))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Manager_GetEnvironment_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EnvironmentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ManagerServer).GetEnvironment(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/telepresence.manager.Manager/GetEnvironment",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ManagerServer).GetEnvironment(ctx, req.(*EnvironmentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Manager_Expose_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ExposeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ManagerServer).Expose(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/telepresence.manager.Manager/Expose",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ManagerServer).Expose(ctx, req.(*ExposeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Manager_Unexpose_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UnexposeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ManagerServer).Unexpose(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/telepresence.manager.Manager/Unexpose",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ManagerServer).Unexpose(ctx, req.(*UnexposeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _Manager_Tunnel_Handler(srv interface{}, stream grpc.ServerStream) error {
 	return srv.(ManagerServer).Tunnel(&managerTunnelServer{stream})
 }
@@ -1476,6 +2181,34 @@ var Manager_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "AgentLookupHostResponse",
 			Handler:    _Manager_AgentLookupHostResponse_Handler,
 		},
+		{
+			MethodName: "LookupDNS",
+			Handler:    _Manager_LookupDNS_Handler,
+		},
+		{
+			MethodName: "AgentLookupDNSResponse",
+			Handler:    _Manager_AgentLookupDNSResponse_Handler,
+		},
+		{
+			MethodName: "Ping",
+			Handler:    _Manager_Ping_Handler,
+		},
+		{
+			MethodName: "ReportEnvironment",
+			Handler:    _Manager_ReportEnvironment_Handler,
+		},
+		{
+			MethodName: "GetEnvironment",
+			Handler:    _Manager_GetEnvironment_Handler,
+		},
+		{
+			MethodName: "Expose",
+			Handler:    _Manager_Expose_Handler,
+		},
+		{
+			MethodName: "Unexpose",
+			Handler:    _Manager_Unexpose_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -1515,11 +2248,48 @@ var Manager_ServiceDesc = grpc.ServiceDesc{
 			Handler:       _Manager_WatchLookupHost_Handler,
 			ServerStreams: true,
 		},
+		{
+			StreamName:    "WatchLookupDNS",
+			Handler:       _Manager_WatchLookupDNS_Handler,
+			ServerStreams: true,
+		},
 		{
 			StreamName:    "WatchLogLevel",
 			Handler:       _Manager_WatchLogLevel_Handler,
 			ServerStreams: true,
 		},
+		{
+			StreamName:    "WatchLogs",
+			Handler:       _Manager_WatchLogs_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "WatchAgentLogsRequests",
+			Handler:       _Manager_WatchAgentLogsRequests_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "AgentLogs",
+			Handler:       _Manager_AgentLogs_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "Exec",
+			Handler:       _Manager_Exec_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "WatchExecRequests",
+			Handler:       _Manager_WatchExecRequests_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "AgentExec",
+			Handler:       _Manager_AgentExec_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
 		{
 			StreamName:    "Tunnel",
 			Handler:       _Manager_Tunnel_Handler,